@@ -0,0 +1,75 @@
+// cmd/createadmin/main.go
+// Crea un administrador de la consola. Pensado para registrar al primer
+// administrador después de ejecutar las migraciones:
+//
+//	$ go run ./cmd/createadmin -username admin -email admin@example.com
+//
+// El primer administrador queda como superadministrador; los siguientes solo
+// si se indica -super. Con -impersonate el administrador puede suplantar
+// usuarios de los sistemas sin ser superadministrador.
+package main
+
+import (
+	"accessv2/config"
+	"accessv2/internal/repositories"
+	"accessv2/internal/services"
+	"accessv2/pkg/password"
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+func main() {
+	username := flag.String("username", "", "usuario del administrador")
+	email := flag.String("email", "", "correo del administrador")
+	plainPassword := flag.String("password", "", "contraseña (si se omite se lee de ADMIN_PASSWORD o de la entrada estándar)")
+	superAdmin := flag.Bool("super", false, "crear como superadministrador (el primero siempre lo es)")
+	canImpersonate := flag.Bool("impersonate", false, "permitir suplantar usuarios de los sistemas")
+	flag.Parse()
+
+	// 1. Configuración inicial
+	if err := config.LoadEnv(); err != nil {
+		log.Printf("⚠️ No se pudo cargar el archivo .env: %v", err)
+	}
+
+	// 2. Inicialización de la base de datos
+	db, err := config.InitDB()
+	if err != nil {
+		log.Fatalf("Database initialization failed: %v", err)
+	}
+
+	// 3. Contraseña
+	if *plainPassword == "" {
+		*plainPassword = os.Getenv("ADMIN_PASSWORD")
+	}
+	if *plainPassword == "" {
+		fmt.Print("Contraseña: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("No se pudo leer la contraseña: %v", err)
+		}
+		*plainPassword = strings.TrimRight(line, "\r\n")
+	}
+
+	hasher, err := password.NewHasher(config.PasswordHasherConfig())
+	if err != nil {
+		log.Fatalf("Password hasher configuration failed: %v", err)
+	}
+
+	// 4. Crear administrador
+	throttle := services.NewSignInThrottleService(config.SignInThrottleConfig(), repositories.NewSignInThrottleRepository(db))
+	authService := services.NewAuthService(repositories.NewAdminRepository(db), hasher, throttle)
+	admin, err := authService.CreateAdmin(*username, *email, *plainPassword, *superAdmin, *canImpersonate)
+	if err != nil {
+		log.Fatalf("No se pudo crear el administrador: %v", err)
+	}
+
+	if admin.IsSuperAdmin {
+		log.Printf("Superadministrador '%s' creado con ID %d", admin.Username, admin.ID)
+		return
+	}
+	log.Printf("Administrador '%s' creado con ID %d", admin.Username, admin.ID)
+}
@@ -3,8 +3,8 @@ package main
 import (
 	"accessv2/config"
 	"log"
-
-	"github.com/gin-contrib/sessions/cookie"
+	"net"
+	"time"
 )
 
 func main() {
@@ -19,17 +19,33 @@ func main() {
 		log.Fatalf("Database initialization failed: %v", err)
 	}
 
-	// 4. Configuración de sesiones
-	store := cookie.NewStore([]byte(config.GetEnv("SESSION_SECRET", "default-secret-32-bytes-long!")))
+	// 4. Configuración de sesiones (guardadas en la base de datos)
+	store := config.NewSessionStore(db)
+	store.StartCleanup(time.Hour)
 
 	// 5. Configuración del router
-	router := config.SetupRouter(db, store)
+	router, grpcServer := config.SetupRouter(db, store)
 
 	// 6. Configuración de vistas y estáticos
 	router.LoadHTMLGlob("templates/**/*")
 	router.Static("/static", "./static") // Mejor nombre que 'public'
 
-	// 7. Inicio del servidor
+	// 7. Servidor gRPC en su propio puerto, si está habilitado
+	if grpcServer != nil {
+		grpcPort := config.GRPCPort()
+		listener, err := net.Listen("tcp", grpcPort)
+		if err != nil {
+			log.Fatalf("gRPC listener failed: %v", err)
+		}
+		go func() {
+			log.Printf("gRPC server starting on %s", grpcPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
+	// 8. Inicio del servidor
 	serverPort := config.GetEnv("SERVER_PORT", ":8085")
 	log.Printf("Server starting on %s", serverPort)
 	if err := router.Run(serverPort); err != nil {
@@ -0,0 +1,17 @@
+package config
+
+import (
+	"accessv2/internal/services"
+	"strings"
+	"time"
+)
+
+// AccountConfig arma la configuración de los flujos de cuenta por correo
+func AccountConfig() services.AccountConfig {
+	return services.AccountConfig{
+		AppName:       GetEnv("APP_NAME", "PipsAuthz"),
+		BaseURL:       strings.TrimRight(GetEnv("BASE_URL", "http://localhost:8085"), "/"),
+		ActivationTTL: GetEnvDuration("ACTIVATION_KEY_TTL", 72*time.Hour),
+		ResetTTL:      GetEnvDuration("RESET_KEY_TTL", time.Hour),
+	}
+}
@@ -1,34 +1,53 @@
 package config
 
 import (
+	"accessv2/internal/handlers/accessgrpc"
+	"accessv2/internal/handlers/account"
 	"accessv2/internal/handlers/auth"
+	"accessv2/internal/handlers/authz"
 	"accessv2/internal/handlers/common"
+	"accessv2/internal/handlers/groups"
+	"accessv2/internal/handlers/impersonations"
+	"accessv2/internal/handlers/oauth"
 	"accessv2/internal/handlers/permissions"
 	"accessv2/internal/handlers/roles"
 	"accessv2/internal/handlers/systems"
+	"accessv2/internal/handlers/tokens"
 	"accessv2/internal/handlers/users"
 	"accessv2/internal/repositories"
 	"accessv2/internal/services"
 
+	"accessv2/pkg/mailer"
 	"accessv2/pkg/middleware"
+	"accessv2/pkg/password"
 	"accessv2/pkg/utils"
 	"html/template"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 	"gorm.io/gorm"
 )
 
-func SetupRouter(db *gorm.DB, store sessions.Store) *gin.Engine {
+// SetupRouter arma el router HTTP y, si GRPC_PORT está definido, el servidor
+// gRPC con los mismos servicios; si no, el segundo valor es nil
+func SetupRouter(db *gorm.DB, store sessions.Store) (*gin.Engine, *grpc.Server) {
 	router := gin.Default()
 
+	// La IP del cliente alimenta los contadores de intentos fallidos; solo se
+	// confía en X-Forwarded-For si viene de un proxy conocido
+	if err := router.SetTrustedProxies(TrustedProxies()); err != nil {
+		log.Fatalf("Trusted proxies configuration failed: %v", err)
+	}
+
 	// Configuración de cookies (seguridad)
 	store.Options(sessions.Options{
-		MaxAge:   86400 * 7, // 1 semana
-		HttpOnly: true,      // Solo accesible por HTTP
-		Secure:   true,      // Solo HTTPS en producción
+		MaxAge:   int(SessionStoreConfig().MaxAge.Seconds()), // la sesión vence en el servidor
+		HttpOnly: true,                                       // Solo accesible por HTTP
+		Secure:   true,                                       // Solo HTTPS en producción
 		SameSite: http.SameSiteLaxMode,
 	})
 
@@ -56,35 +75,116 @@ func SetupRouter(db *gorm.DB, store sessions.Store) *gin.Engine {
 	})
 
 	// Inicialización de repositorios
+	adminRepo := repositories.NewAdminRepository(db)
 	systemRepo := repositories.NewSystemRepository(db)
 	userRepo := repositories.NewUserRepository(db)
 	roleRepo := repositories.NewRoleRepository(db)
 	permissionRepo := repositories.NewPermissionRepository(db)
 	userSystemRepo := repositories.NewSystemUserRepository(db)
 	userPermissionRepo := repositories.NewUserPermissionRepository(db)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(db)
+	accessTokenRepo := repositories.NewAccessTokenRepository(db)
+	signingKeyRepo := repositories.NewSigningKeyRepository(db)
+	oauthRepo := repositories.NewOAuthRepository(db)
+	systemClientRepo := repositories.NewSystemClientRepository(db)
+	systemAPIKeyRepo := repositories.NewSystemAPIKeyRepository(db)
+	mfaRepo := repositories.NewMFARepository(db)
+	signInThrottleRepo := repositories.NewSignInThrottleRepository(db)
+	passwordHistoryRepo := repositories.NewPasswordHistoryRepository(db)
+	adminSessionRepo := repositories.NewAdminSessionRepository(db)
+	systemLDAPSettingsRepo := repositories.NewSystemLDAPSettingsRepository(db)
+	impersonationRepo := repositories.NewImpersonationRepository(db)
+	groupRepo := repositories.NewGroupRepository(db)
+	roleAssignmentRepo := repositories.NewRoleAssignmentRepository(db)
+
+	// Nombres de usuario que la migración no pudo normalizar
+	if err := userRepo.NormalizeUsernames(); err != nil {
+		log.Fatalf("Username normalization failed: %v", err)
+	}
+
+	// Hash de contraseñas
+	passwordHasher, err := password.NewHasher(PasswordHasherConfig())
+	if err != nil {
+		log.Fatalf("Password hasher configuration failed: %v", err)
+	}
+	passwordPolicy, err := password.NewPolicy(PasswordPolicyConfig())
+	if err != nil {
+		log.Fatalf("Password policy configuration failed: %v", err)
+	}
+
+	// Envío de correos
+	mail, err := mailer.New(MailerConfig())
+	if err != nil {
+		log.Fatalf("Mailer configuration failed: %v", err)
+	}
 
 	// Inicialización de servicios
-	authService := services.NewAuthService()
+	keyService, err := services.NewKeyService(KeyConfig(), signingKeyRepo)
+	if err != nil {
+		log.Fatalf("Signing key configuration failed: %v", err)
+	}
+	keyService.StartRotation(time.Hour)
+	tokenService := services.NewTokenService(TokenConfig(), keyService, userRepo, systemRepo, systemClientRepo, refreshTokenRepo, accessTokenRepo)
+	signInThrottleService := services.NewSignInThrottleService(SignInThrottleConfig(), signInThrottleRepo)
+	passwordService := services.NewPasswordService(PasswordConfig(), passwordPolicy, passwordHasher, passwordHistoryRepo)
+	authService := services.NewAuthService(adminRepo, passwordHasher, signInThrottleService)
+	adminSessionService := services.NewAdminSessionService(AdminSessionConfig(), adminSessionRepo, adminRepo)
 	systemService := services.NewSystemService(systemRepo, userSystemRepo)
 	permissionService := services.NewPermissionService(permissionRepo)
 	userPermissionService := services.NewUserPermissionService(userPermissionRepo)
-	userService := services.NewUserService(db, userRepo)
+	accountService := services.NewAccountService(AccountConfig(), userRepo, oauthRepo, passwordService, tokenService, mail)
+	mfaService := services.NewMFAService(MFAConfig(), mfaRepo, userRepo, systemRepo, tokenService, signInThrottleService)
+	localAuthenticator := services.NewLocalAuthenticator(userRepo, passwordHasher)
+	ldapAuthenticator := services.NewLDAPAuthenticator(LDAPConfig(), nil, db, systemLDAPSettingsRepo, userRepo, userSystemRepo, localAuthenticator)
+	credentials := services.NewSystemAuthenticator(AuthBackend(), systemRepo, localAuthenticator, ldapAuthenticator)
+	systemTokenService := services.NewSystemTokenService(tokenService, systemRepo)
+	systemLDAPService := services.NewSystemLDAPService(AuthBackend(), LDAPConfig(), nil, systemRepo, systemLDAPSettingsRepo, userRepo)
+	userService := services.NewUserService(db, userRepo, passwordHasher, tokenService, mfaService, signInThrottleService, passwordService, credentials)
 	roleService := services.NewRoleService(roleRepo)
-	systemUserService := services.NewSystemUserService(db, userSystemRepo)
+	systemUserService := services.NewSystemUserService(db, userSystemRepo, tokenService)
+	systemClientService := services.NewSystemClientService(systemClientRepo, tokenService)
+	systemAPIKeyService := services.NewSystemAPIKeyService(systemAPIKeyRepo)
+	authzService := services.NewAuthzService(userRepo)
+	groupService := services.NewGroupService(groupRepo, userRepo, systemRepo)
+	roleAssignmentService := services.NewRoleAssignmentService(roleAssignmentRepo, userRepo)
+	impersonationService := services.NewImpersonationService(ImpersonationConfig(), impersonationRepo, adminRepo, userRepo, tokenService)
+	oauthService := services.NewOAuthService(OAuthConfig(), oauthRepo, systemRepo, userRepo, userService, systemClientService, tokenService, keyService, mfaService)
+	if !oauthService.OIDCEnabled() {
+		log.Printf("OpenID Connect deshabilitado: %v", services.ErrOIDCDisabled)
+	}
 
 	// Inicialización de handlers
 	commonHandler := common.NewCommonHandler()
-	authHandler := auth.NewAuthHandler(authService)
-	systemHandler := systems.NewSystemHandler(systemService, roleService, permissionService, systemUserService)
-	userHandler := users.NewUserHandler(userService, userPermissionService)
+	authHandler := auth.NewAuthHandler(authService, adminSessionService)
+	systemHandler := systems.NewSystemHandler(systemService, roleService, permissionService, systemUserService, oauthService, systemClientService, systemAPIKeyService, systemLDAPService, systemTokenService)
+	userHandler := users.NewUserHandler(userService, userPermissionService, accountService, mfaService, tokenService, signInThrottleService, impersonationService, groupService, roleAssignmentService, systemLDAPService)
 	roleHandler := roles.NewRoleHandler(roleService)
 	permissionHandler := permissions.NewPermissionHandler(permissionService)
+	tokenHandler := tokens.NewTokenHandler(tokenService, keyService)
+	oauthHandler := oauth.NewOAuthHandler(oauthService)
+	accountHandler := account.NewAccountHandler(accountService)
+	impersonationHandler := impersonations.NewImpersonationHandler(impersonationService)
+	authzHandler := authz.NewAuthzHandler(authzService, roleAssignmentService)
+	groupHandler := groups.NewGroupHandler(groupService, systemService)
 
 	// Registrar rutas
 	common.RegisterCommonRoutes(router, commonHandler)
 	auth.RegisterAuthRoutes(router, authHandler)
 	systems.RegisterSystemsRoutes(router, systemHandler, roleHandler, permissionHandler, userHandler)
-	users.RegisterUserRoutes(router, userHandler)
+	users.RegisterUserRoutes(router, userHandler, systemAPIKeyService)
+	tokens.RegisterTokenRoutes(router, tokenHandler, systemAPIKeyService)
+	oauth.RegisterOAuthRoutes(router, oauthHandler)
+	account.RegisterAccountRoutes(router, accountHandler, systemAPIKeyService)
+	impersonations.RegisterImpersonationRoutes(router, impersonationHandler)
+	authz.RegisterAuthzRoutes(router, authzHandler, systemAPIKeyService, systemAPIKeyService)
+	groups.RegisterGroupRoutes(router, groupHandler)
+
+	// Servidor gRPC
+	var grpcServer *grpc.Server
+	if GRPCPort() != "" {
+		accessServer := accessgrpc.NewAccessServer(userService, userPermissionService, tokenService, authzService, mfaService)
+		grpcServer = accessgrpc.NewGRPCServer(accessServer, systemAPIKeyService, GRPCServerOptions()...)
+	}
 
-	return router
+	return router, grpcServer
 }
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"log"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// GRPCPort es la dirección del servidor gRPC, p. ej. ":9090". Vacío lo
+// desactiva.
+func GRPCPort() string {
+	return GetEnv("GRPC_PORT", "")
+}
+
+// GRPCServerOptions activa TLS si se indican el certificado y la llave; sin
+// ellos el servidor escucha en texto plano, para redes internas
+func GRPCServerOptions() []grpc.ServerOption {
+	certFile := GetEnv("GRPC_TLS_CERT", "")
+	keyFile := GetEnv("GRPC_TLS_KEY", "")
+	if certFile == "" && keyFile == "" {
+		return nil
+	}
+
+	creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+	if err != nil {
+		log.Fatalf("gRPC TLS configuration failed: %v", err)
+	}
+	return []grpc.ServerOption{grpc.Creds(creds)}
+}
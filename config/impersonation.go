@@ -0,0 +1,14 @@
+package config
+
+import (
+	"accessv2/internal/services"
+	"time"
+)
+
+// ImpersonationConfig arma la configuración de las suplantaciones desde el entorno
+func ImpersonationConfig() services.ImpersonationConfig {
+	return services.ImpersonationConfig{
+		TokenTTL:        GetEnvDuration("IMPERSONATION_TOKEN_TTL", 15*time.Minute),
+		MinReasonLength: GetEnvInt("IMPERSONATION_REASON_MIN_LENGTH", 10),
+	}
+}
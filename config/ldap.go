@@ -0,0 +1,27 @@
+package config
+
+import (
+	"accessv2/pkg/ldapauth"
+	"time"
+)
+
+// AuthBackend es el backend de autenticación de los sistemas que no eligen uno (local o ldap)
+func AuthBackend() string {
+	return GetEnv("AUTH_BACKEND", "local")
+}
+
+// LDAPConfig arma el directorio global
+func LDAPConfig() ldapauth.Config {
+	return ldapauth.Config{
+		URL:                GetEnv("LDAP_URL", ""),
+		StartTLS:           GetEnvBool("LDAP_START_TLS", false),
+		InsecureSkipVerify: GetEnvBool("LDAP_INSECURE_SKIP_VERIFY", false),
+		BindDN:             GetEnv("LDAP_BIND_DN", ""),
+		BindPassword:       GetEnv("LDAP_BIND_PASSWORD", ""),
+		BaseDN:             GetEnv("LDAP_BASE_DN", ""),
+		UserFilter:         GetEnv("LDAP_USER_FILTER", "(uid=%s)"),
+		UsernameAttribute:  GetEnv("LDAP_USERNAME_ATTRIBUTE", "uid"),
+		EmailAttribute:     GetEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		Timeout:            GetEnvDuration("LDAP_TIMEOUT", 5*time.Second),
+	}
+}
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"accessv2/pkg/mailer"
+)
+
+// MailerConfig arma la configuración del envío de correos desde el entorno
+func MailerConfig() mailer.Config {
+	return mailer.Config{
+		Driver:       GetEnv("MAIL_DRIVER", mailer.DriverFile),
+		From:         GetEnv("MAIL_FROM", "PipsAuthz <no-reply@localhost>"),
+		SMTPHost:     GetEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     GetEnvInt("SMTP_PORT", 587),
+		SMTPUsername: GetEnv("SMTP_USERNAME", ""),
+		SMTPPassword: GetEnv("SMTP_PASSWORD", ""),
+		OutboxDir:    GetEnv("MAIL_OUTBOX_DIR", "./outbox"),
+	}
+}
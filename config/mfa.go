@@ -0,0 +1,16 @@
+package config
+
+import (
+	"accessv2/internal/services"
+	"time"
+)
+
+// MFAConfig arma la configuración de la verificación en dos pasos
+func MFAConfig() services.MFAConfig {
+	return services.MFAConfig{
+		Issuer:        GetEnv("APP_NAME", "PipsAuthz"),
+		ChallengeTTL:  GetEnvDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+		MaxAttempts:   GetEnvInt("MFA_MAX_ATTEMPTS", 5),
+		RecoveryCodes: GetEnvInt("MFA_RECOVERY_CODES", 10),
+	}
+}
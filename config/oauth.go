@@ -0,0 +1,17 @@
+package config
+
+import (
+	"accessv2/internal/services"
+	"strings"
+	"time"
+)
+
+// OAuthConfig arma la configuración del proveedor OpenID Connect desde el entorno
+func OAuthConfig() services.OAuthConfig {
+	issuer := GetEnv("OIDC_ISSUER", GetEnv("BASE_URL", "http://localhost:8085"))
+	return services.OAuthConfig{
+		Issuer:     strings.TrimRight(issuer, "/"),
+		CodeTTL:    GetEnvDuration("OIDC_CODE_TTL", time.Minute),
+		IDTokenTTL: GetEnvDuration("OIDC_ID_TOKEN_TTL", time.Hour),
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"accessv2/internal/services"
+	"accessv2/pkg/password"
+)
+
+// PasswordHasherConfig arma la configuración del hash de contraseñas desde el entorno
+func PasswordHasherConfig() password.Config {
+	cfg := password.DefaultConfig()
+	cfg.Algorithm = GetEnv("PASSWORD_HASH_ALGORITHM", cfg.Algorithm)
+	cfg.BcryptCost = GetEnvInt("PASSWORD_BCRYPT_COST", cfg.BcryptCost)
+	cfg.Argon2Time = uint32(GetEnvInt("PASSWORD_ARGON2_TIME", int(cfg.Argon2Time)))
+	cfg.Argon2Memory = uint32(GetEnvInt("PASSWORD_ARGON2_MEMORY_KB", int(cfg.Argon2Memory)))
+	cfg.Argon2Threads = uint8(GetEnvInt("PASSWORD_ARGON2_THREADS", int(cfg.Argon2Threads)))
+	return cfg
+}
+
+// PasswordPolicyConfig arma las reglas de las contraseñas nuevas desde el entorno
+func PasswordPolicyConfig() password.PolicyConfig {
+	return password.PolicyConfig{
+		MinLength:     GetEnvInt("PASSWORD_MIN_LENGTH", 10),
+		RequireUpper:  GetEnvBool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:  GetEnvBool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:  GetEnvBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol: GetEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		BlocklistFile: GetEnv("PASSWORD_BLOCKLIST_FILE", ""),
+	}
+}
+
+// PasswordConfig arma la configuración del historial y el vencimiento de contraseñas
+func PasswordConfig() services.PasswordConfig {
+	return services.PasswordConfig{
+		HistorySize: GetEnvInt("PASSWORD_HISTORY", 5),
+		MaxAge:      GetEnvDuration("PASSWORD_MAX_AGE", 0),
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"accessv2/internal/services"
+	"accessv2/pkg/sessionstore"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SessionStoreConfig arma los tiempos de vida de las sesiones de la consola
+func SessionStoreConfig() sessionstore.Config {
+	return sessionstore.Config{
+		Table:         "admin_sessions",
+		IdleTimeout:   GetEnvDuration("SESSION_IDLE_TIMEOUT", 30*time.Minute),
+		MaxAge:        GetEnvDuration("SESSION_MAX_AGE", 12*time.Hour),
+		TouchInterval: time.Minute,
+		OwnerKey:      "UserID",
+	}
+}
+
+// AdminSessionConfig arma la configuración del listado de sesiones
+func AdminSessionConfig() services.AdminSessionConfig {
+	return services.AdminSessionConfig{
+		IdleTimeout: SessionStoreConfig().IdleTimeout,
+	}
+}
+
+// NewSessionStore crea el almacén de sesiones de la consola en la base de datos
+func NewSessionStore(db *gorm.DB) *sessionstore.Store {
+	return sessionstore.New(db, SessionStoreConfig(), []byte(GetEnv("SESSION_SECRET", "default-secret-32-bytes-long!")))
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"accessv2/internal/services"
+	"strings"
+	"time"
+)
+
+// SignInThrottleConfig arma los umbrales de la protección contra fuerza bruta
+func SignInThrottleConfig() services.SignInThrottleConfig {
+	return services.SignInThrottleConfig{
+		MaxFailures:     GetEnvInt("SIGN_IN_MAX_FAILURES", 5),
+		IPMaxFailures:   GetEnvInt("SIGN_IN_IP_MAX_FAILURES", 20),
+		FailureWindow:   GetEnvDuration("SIGN_IN_FAILURE_WINDOW", 15*time.Minute),
+		BackoffBase:     GetEnvDuration("SIGN_IN_BACKOFF_BASE", time.Second),
+		BackoffMax:      GetEnvDuration("SIGN_IN_BACKOFF_MAX", time.Minute),
+		LockoutDuration: GetEnvDuration("SIGN_IN_LOCKOUT_DURATION", 15*time.Minute),
+	}
+}
+
+// TrustedProxies lista los proxies cuyas cabeceras X-Forwarded-For se aceptan
+// para obtener la IP del cliente. Vacío usa la IP de la conexión.
+func TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(GetEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
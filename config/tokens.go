@@ -0,0 +1,42 @@
+package config
+
+import (
+	"accessv2/internal/services"
+	"time"
+)
+
+// TokenConfig arma la configuración de emisión de tokens desde el entorno. El
+// emisor por defecto es el mismo de OpenID Connect.
+func TokenConfig() services.TokenConfig {
+	return services.TokenConfig{
+		Issuer:     OAuthConfig().Issuer,
+		AccessTTL:  GetEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTTL: GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+	}
+}
+
+// KeyConfig arma la configuración de las llaves de firma desde el entorno
+func KeyConfig() services.KeyConfig {
+	return services.KeyConfig{
+		Algorithm:      GetEnv("JWT_ALGORITHM", "HS256"),
+		Secret:         GetEnv("JWT_KEY", ""),
+		RotationPeriod: GetEnvDuration("JWT_KEY_ROTATION", 30*24*time.Hour),
+		// Una llave retirada se publica mientras puedan existir tokens firmados con ella
+		PublishGrace: longestAccessTTL() + time.Minute,
+		// Tras pasar de HS256 a un algoritmo asimétrico, acepta los tokens HS256
+		// ya emitidos durante PublishGrace
+		LegacyHS256: GetEnvBool("JWT_ACCEPT_LEGACY_HS256", false),
+	}
+}
+
+// longestAccessTTL es la mayor vigencia posible de un access token: la global,
+// la máxima que admite un sistema o la de una suplantación
+func longestAccessTTL() time.Duration {
+	ttl := GetEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+	for _, candidate := range []time.Duration{services.MaxSystemAccessTTL, ImpersonationConfig().TokenTTL} {
+		if candidate > ttl {
+			ttl = candidate
+		}
+	}
+	return ttl
+}
@@ -0,0 +1,16 @@
+-- migrate:up
+
+CREATE TABLE admins (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  username VARCHAR(20) UNIQUE NOT NULL,
+  password VARCHAR(100) NOT NULL,
+  email VARCHAR(50) UNIQUE NOT NULL,
+  activated BOOLEAN NOT NULL DEFAULT 1,
+  last_login DATETIME,
+  created DATETIME NOT NULL,
+  updated DATETIME NOT NULL
+);
+
+-- migrate:down
+
+DROP TABLE admins;
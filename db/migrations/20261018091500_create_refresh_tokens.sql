@@ -0,0 +1,22 @@
+-- migrate:up
+
+CREATE TABLE refresh_tokens (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  token_hash VARCHAR(64) UNIQUE NOT NULL,
+  family_id VARCHAR(64) NOT NULL,
+  user_id INTEGER NOT NULL,
+  system_id INTEGER NOT NULL,
+  expires_at DATETIME NOT NULL,
+  used_at DATETIME,
+  revoked_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  FOREIGN KEY(system_id) REFERENCES systems(id)
+);
+
+CREATE INDEX idx_refresh_tokens_family ON refresh_tokens(family_id);
+
+-- migrate:down
+
+DROP INDEX IF EXISTS idx_refresh_tokens_family;
+DROP TABLE refresh_tokens;
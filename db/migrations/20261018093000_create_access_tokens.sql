@@ -0,0 +1,21 @@
+-- migrate:up
+
+CREATE TABLE access_tokens (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  jti VARCHAR(64) UNIQUE NOT NULL,
+  user_id INTEGER NOT NULL,
+  system_id INTEGER NOT NULL,
+  expires_at DATETIME NOT NULL,
+  revoked_at DATETIME,
+  revoke_reason VARCHAR(50),
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  FOREIGN KEY(system_id) REFERENCES systems(id)
+);
+
+CREATE INDEX idx_access_tokens_user_system ON access_tokens(user_id, system_id);
+
+-- migrate:down
+
+DROP INDEX IF EXISTS idx_access_tokens_user_system;
+DROP TABLE access_tokens;
@@ -0,0 +1,16 @@
+-- migrate:up
+
+CREATE TABLE signing_keys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  kid VARCHAR(64) UNIQUE NOT NULL,
+  algorithm VARCHAR(10) NOT NULL,
+  private_key TEXT NOT NULL,
+  public_key TEXT NOT NULL,
+  rotated_at DATETIME,
+  expires_at DATETIME,
+  created DATETIME NOT NULL
+);
+
+-- migrate:down
+
+DROP TABLE signing_keys;
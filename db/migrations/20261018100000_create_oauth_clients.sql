@@ -0,0 +1,33 @@
+-- migrate:up
+
+CREATE TABLE system_redirect_uris (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  system_id INTEGER NOT NULL,
+  uri VARCHAR(255) NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(system_id) REFERENCES systems(id) ON DELETE CASCADE,
+  UNIQUE(system_id, uri)
+);
+
+CREATE TABLE authorization_codes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  code_hash VARCHAR(64) UNIQUE NOT NULL,
+  system_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  redirect_uri VARCHAR(255) NOT NULL,
+  scope VARCHAR(255) NOT NULL DEFAULT '',
+  nonce VARCHAR(255) NOT NULL DEFAULT '',
+  code_challenge VARCHAR(128) NOT NULL,
+  code_challenge_method VARCHAR(10) NOT NULL,
+  auth_time DATETIME NOT NULL,
+  expires_at DATETIME NOT NULL,
+  used_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  FOREIGN KEY(system_id) REFERENCES systems(id)
+);
+
+-- migrate:down
+
+DROP TABLE authorization_codes;
+DROP TABLE system_redirect_uris;
@@ -0,0 +1,79 @@
+-- migrate:up
+
+CREATE TABLE system_clients (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  system_id INTEGER NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  client_id VARCHAR(64) UNIQUE NOT NULL,
+  secret_hash VARCHAR(64) NOT NULL,
+  secret_rotated_at DATETIME NOT NULL,
+  last_used_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+
+CREATE TABLE system_client_permissions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  system_client_id INTEGER NOT NULL,
+  permission_id INTEGER NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(system_client_id) REFERENCES system_clients(id) ON DELETE CASCADE,
+  FOREIGN KEY(permission_id) REFERENCES permissions(id) ON DELETE CASCADE,
+  UNIQUE(system_client_id, permission_id)
+);
+
+-- Los tokens emitidos a un cliente no pertenecen a un usuario
+CREATE TABLE access_tokens_new (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  jti VARCHAR(64) UNIQUE NOT NULL,
+  user_id INTEGER,
+  system_client_id INTEGER,
+  system_id INTEGER NOT NULL,
+  expires_at DATETIME NOT NULL,
+  revoked_at DATETIME,
+  revoke_reason VARCHAR(50),
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  FOREIGN KEY(system_client_id) REFERENCES system_clients(id),
+  FOREIGN KEY(system_id) REFERENCES systems(id)
+);
+
+INSERT INTO access_tokens_new (id, jti, user_id, system_id, expires_at, revoked_at, revoke_reason, created)
+SELECT id, jti, user_id, system_id, expires_at, revoked_at, revoke_reason, created FROM access_tokens;
+
+DROP INDEX IF EXISTS idx_access_tokens_user_system;
+DROP TABLE access_tokens;
+ALTER TABLE access_tokens_new RENAME TO access_tokens;
+
+CREATE INDEX idx_access_tokens_user_system ON access_tokens(user_id, system_id);
+CREATE INDEX idx_access_tokens_client ON access_tokens(system_client_id);
+
+-- migrate:down
+
+DELETE FROM access_tokens WHERE user_id IS NULL;
+
+CREATE TABLE access_tokens_old (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  jti VARCHAR(64) UNIQUE NOT NULL,
+  user_id INTEGER NOT NULL,
+  system_id INTEGER NOT NULL,
+  expires_at DATETIME NOT NULL,
+  revoked_at DATETIME,
+  revoke_reason VARCHAR(50),
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  FOREIGN KEY(system_id) REFERENCES systems(id)
+);
+
+INSERT INTO access_tokens_old (id, jti, user_id, system_id, expires_at, revoked_at, revoke_reason, created)
+SELECT id, jti, user_id, system_id, expires_at, revoked_at, revoke_reason, created FROM access_tokens;
+
+DROP INDEX IF EXISTS idx_access_tokens_client;
+DROP INDEX IF EXISTS idx_access_tokens_user_system;
+DROP TABLE access_tokens;
+ALTER TABLE access_tokens_old RENAME TO access_tokens;
+
+CREATE INDEX idx_access_tokens_user_system ON access_tokens(user_id, system_id);
+
+DROP TABLE system_client_permissions;
+DROP TABLE system_clients;
@@ -0,0 +1,21 @@
+-- migrate:up
+
+CREATE TABLE system_api_keys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  system_id INTEGER NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  prefix VARCHAR(12) NOT NULL,
+  key_hash VARCHAR(64) UNIQUE NOT NULL,
+  rotated_at DATETIME NOT NULL,
+  last_used_at DATETIME,
+  revoked_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_system_api_keys_system ON system_api_keys(system_id);
+
+-- migrate:down
+
+DROP INDEX IF EXISTS idx_system_api_keys_system;
+DROP TABLE system_api_keys;
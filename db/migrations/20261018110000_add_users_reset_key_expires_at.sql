@@ -0,0 +1,7 @@
+-- migrate:up
+
+ALTER TABLE users ADD COLUMN reset_key_expires_at DATETIME;
+
+-- migrate:down
+
+ALTER TABLE users DROP COLUMN reset_key_expires_at;
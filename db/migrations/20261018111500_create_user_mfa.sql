@@ -0,0 +1,45 @@
+-- migrate:up
+
+ALTER TABLE systems ADD COLUMN mfa_required BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE TABLE user_mfa (
+  user_id INTEGER PRIMARY KEY,
+  secret VARCHAR(64) NOT NULL,
+  confirmed_at DATETIME,
+  last_used_step INTEGER NOT NULL DEFAULT 0,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_mfa_recovery_codes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  code_hash VARCHAR(64) NOT NULL,
+  used_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_mfa_recovery_codes_user ON user_mfa_recovery_codes(user_id);
+
+CREATE TABLE mfa_challenges (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  token_hash VARCHAR(64) UNIQUE NOT NULL,
+  user_id INTEGER NOT NULL,
+  system_id INTEGER NOT NULL,
+  enrollment BOOLEAN NOT NULL DEFAULT 0,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  expires_at DATETIME NOT NULL,
+  used_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY(system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+
+-- migrate:down
+
+DROP TABLE mfa_challenges;
+DROP INDEX IF EXISTS idx_user_mfa_recovery_codes_user;
+DROP TABLE user_mfa_recovery_codes;
+DROP TABLE user_mfa;
+ALTER TABLE systems DROP COLUMN mfa_required;
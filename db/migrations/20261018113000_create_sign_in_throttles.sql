@@ -0,0 +1,21 @@
+-- migrate:up
+
+-- system_id 0 corresponde a la consola de administración
+CREATE TABLE sign_in_throttles (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  scope VARCHAR(16) NOT NULL,
+  system_id INTEGER NOT NULL DEFAULT 0,
+  subject VARCHAR(255) NOT NULL,
+  failures INTEGER NOT NULL DEFAULT 0,
+  last_failure_at DATETIME NOT NULL,
+  retry_at DATETIME,
+  locked_until DATETIME,
+  UNIQUE(scope, system_id, subject)
+);
+
+CREATE INDEX idx_sign_in_throttles_subject ON sign_in_throttles(scope, subject);
+
+-- migrate:down
+
+DROP INDEX IF EXISTS idx_sign_in_throttles_subject;
+DROP TABLE sign_in_throttles;
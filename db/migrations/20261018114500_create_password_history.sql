@@ -0,0 +1,20 @@
+-- migrate:up
+
+ALTER TABLE users ADD COLUMN password_changed_at DATETIME;
+UPDATE users SET password_changed_at = updated;
+
+CREATE TABLE password_history (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  password_hash VARCHAR(255) NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_password_history_user ON password_history(user_id, created);
+
+-- migrate:down
+
+DROP INDEX IF EXISTS idx_password_history_user;
+DROP TABLE password_history;
+ALTER TABLE users DROP COLUMN password_changed_at;
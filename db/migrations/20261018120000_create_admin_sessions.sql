@@ -0,0 +1,32 @@
+-- migrate:up
+
+ALTER TABLE admins ADD COLUMN is_super_admin BOOLEAN NOT NULL DEFAULT 0;
+
+-- El primer administrador registrado pasa a ser superadministrador
+UPDATE admins SET is_super_admin = 1 WHERE id = (SELECT MIN(id) FROM admins);
+
+-- Sesiones de la consola guardadas en el servidor. La cookie solo lleva un
+-- identificador aleatorio; aquí se guarda su hash. admin_id queda en NULL
+-- mientras la sesión no tenga un administrador autenticado.
+CREATE TABLE admin_sessions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  admin_id INTEGER,
+  data TEXT NOT NULL DEFAULT '',
+  ip_address VARCHAR(45) NOT NULL DEFAULT '',
+  user_agent VARCHAR(255) NOT NULL DEFAULT '',
+  created DATETIME NOT NULL,
+  last_seen_at DATETIME NOT NULL,
+  expires_at DATETIME NOT NULL,
+  FOREIGN KEY (admin_id) REFERENCES admins(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_admin_sessions_admin_id ON admin_sessions(admin_id);
+CREATE INDEX idx_admin_sessions_expires_at ON admin_sessions(expires_at);
+
+-- migrate:down
+
+DROP INDEX IF EXISTS idx_admin_sessions_expires_at;
+DROP INDEX IF EXISTS idx_admin_sessions_admin_id;
+DROP TABLE admin_sessions;
+ALTER TABLE admins DROP COLUMN is_super_admin;
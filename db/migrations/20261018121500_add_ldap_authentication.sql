@@ -0,0 +1,35 @@
+-- migrate:up
+
+-- Origen de las credenciales del usuario: local o ldap. external_id guarda el
+-- DN de la entrada del directorio vinculada.
+ALTER TABLE users ADD COLUMN auth_source VARCHAR(10) NOT NULL DEFAULT 'local';
+ALTER TABLE users ADD COLUMN external_id VARCHAR(255);
+CREATE UNIQUE INDEX idx_users_external_id ON users(external_id);
+
+-- Backend de autenticación del sistema: vacío usa el global (AUTH_BACKEND)
+ALTER TABLE systems ADD COLUMN auth_backend VARCHAR(10) NOT NULL DEFAULT '';
+
+-- Directorio propio del sistema; sin fila se usa la configuración global
+CREATE TABLE system_ldap_settings (
+  system_id INTEGER PRIMARY KEY,
+  url VARCHAR(255) NOT NULL,
+  start_tls BOOLEAN NOT NULL DEFAULT 0,
+  insecure_skip_verify BOOLEAN NOT NULL DEFAULT 0,
+  bind_dn VARCHAR(255) NOT NULL DEFAULT '',
+  bind_password VARCHAR(255) NOT NULL DEFAULT '',
+  base_dn VARCHAR(255) NOT NULL,
+  user_filter VARCHAR(255) NOT NULL DEFAULT '(uid=%s)',
+  username_attribute VARCHAR(64) NOT NULL DEFAULT 'uid',
+  email_attribute VARCHAR(64) NOT NULL DEFAULT 'mail',
+  created DATETIME NOT NULL,
+  updated DATETIME NOT NULL,
+  FOREIGN KEY (system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+
+-- migrate:down
+
+DROP TABLE system_ldap_settings;
+ALTER TABLE systems DROP COLUMN auth_backend;
+DROP INDEX IF EXISTS idx_users_external_id;
+ALTER TABLE users DROP COLUMN external_id;
+ALTER TABLE users DROP COLUMN auth_source;
@@ -0,0 +1,10 @@
+-- migrate:up
+
+-- El inicio de sesión compara usuario y correo sin distinguir mayúsculas
+CREATE INDEX idx_users_username_lower ON users(LOWER(username));
+CREATE INDEX idx_users_email_lower ON users(LOWER(email));
+
+-- migrate:down
+
+DROP INDEX IF EXISTS idx_users_email_lower;
+DROP INDEX IF EXISTS idx_users_username_lower;
@@ -0,0 +1,34 @@
+-- migrate:up
+
+-- Además de los superadministradores, pueden suplantar usuarios los
+-- administradores con este permiso
+ALTER TABLE admins ADD COLUMN can_impersonate BOOLEAN NOT NULL DEFAULT 0;
+
+-- Registro de auditoría de las suplantaciones: quién, a quién, en qué sistema,
+-- por qué y el jti del token emitido para poder revocarlo
+CREATE TABLE impersonations (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  admin_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  system_id INTEGER NOT NULL,
+  jti VARCHAR(64) NOT NULL UNIQUE,
+  reason TEXT NOT NULL,
+  ip_address VARCHAR(45) NOT NULL DEFAULT '',
+  user_agent VARCHAR(255) NOT NULL DEFAULT '',
+  expires_at DATETIME NOT NULL,
+  ended_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY (admin_id) REFERENCES admins(id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_impersonations_admin_id ON impersonations(admin_id);
+CREATE INDEX idx_impersonations_user_system ON impersonations(user_id, system_id);
+
+-- migrate:down
+
+DROP INDEX IF EXISTS idx_impersonations_user_system;
+DROP INDEX IF EXISTS idx_impersonations_admin_id;
+DROP TABLE impersonations;
+ALTER TABLE admins DROP COLUMN can_impersonate;
@@ -0,0 +1,18 @@
+-- migrate:up
+
+-- Configuración de los tokens de cada sistema. Los valores vacíos o en 0 usan
+-- los globales: emisor OIDC_ISSUER, audiencia igual al ID del sistema,
+-- vigencia ACCESS_TOKEN_TTL y las llaves de firma globales.
+ALTER TABLE systems ADD COLUMN token_issuer VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE systems ADD COLUMN token_audience VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE systems ADD COLUMN access_token_ttl INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE systems ADD COLUMN token_clock_skew INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE systems ADD COLUMN token_secret VARCHAR(255) NOT NULL DEFAULT '';
+
+-- migrate:down
+
+ALTER TABLE systems DROP COLUMN token_secret;
+ALTER TABLE systems DROP COLUMN token_clock_skew;
+ALTER TABLE systems DROP COLUMN access_token_ttl;
+ALTER TABLE systems DROP COLUMN token_audience;
+ALTER TABLE systems DROP COLUMN token_issuer;
@@ -0,0 +1,14 @@
+-- migrate:up
+
+-- La verificación de permisos se consulta en cada petición de los sistemas
+CREATE INDEX idx_systems_users_system_user ON systems_users(system_id, user_id);
+CREATE INDEX idx_systems_users_permissions_user ON systems_users_permissions(user_id, permission_id);
+CREATE INDEX idx_permissions_role_name ON permissions(role_id, name);
+CREATE INDEX idx_roles_system ON roles(system_id);
+
+-- migrate:down
+
+DROP INDEX IF EXISTS idx_roles_system;
+DROP INDEX IF EXISTS idx_permissions_role_name;
+DROP INDEX IF EXISTS idx_systems_users_permissions_user;
+DROP INDEX IF EXISTS idx_systems_users_system_user;
@@ -0,0 +1,11 @@
+-- migrate:up
+
+-- Jerarquía de roles dentro de un sistema: un rol hereda todos los permisos de
+-- su rol padre y de los ancestros de este
+ALTER TABLE roles ADD COLUMN parent_id INTEGER REFERENCES roles(id);
+CREATE INDEX idx_roles_parent_id ON roles(parent_id);
+
+-- migrate:down
+
+DROP INDEX IF EXISTS idx_roles_parent_id;
+ALTER TABLE roles DROP COLUMN parent_id;
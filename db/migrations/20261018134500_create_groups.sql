@@ -0,0 +1,87 @@
+-- migrate:up
+
+-- Grupos de usuarios: sin system_id el grupo es global y puede recibir
+-- permisos de cualquier sistema; con system_id, solo los de ese sistema
+CREATE TABLE groups (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(50) NOT NULL,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  system_id INTEGER,
+  created DATETIME NOT NULL,
+  updated DATETIME NOT NULL,
+  FOREIGN KEY (system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_groups_system_id ON groups(system_id);
+
+-- Miembros de cada grupo
+CREATE TABLE groups_users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  group_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_groups_users_group_user ON groups_users(group_id, user_id);
+CREATE INDEX idx_groups_users_user_id ON groups_users(user_id);
+
+-- Permisos otorgados al grupo; los heredan todos sus miembros
+CREATE TABLE groups_permissions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  group_id INTEGER NOT NULL,
+  permission_id INTEGER NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+  FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_groups_permissions_group_permission ON groups_permissions(group_id, permission_id);
+CREATE INDEX idx_groups_permissions_permission_id ON groups_permissions(permission_id);
+
+-- Las llaves foráneas no se exigen en la conexión, por lo que las
+-- asociaciones se limpian con triggers, como los permisos de los roles
+CREATE TRIGGER delete_group_associations
+BEFORE DELETE ON groups
+FOR EACH ROW
+BEGIN
+    DELETE FROM groups_users WHERE group_id = OLD.id;
+    DELETE FROM groups_permissions WHERE group_id = OLD.id;
+END;
+
+CREATE TRIGGER delete_permission_groups
+AFTER DELETE ON permissions
+FOR EACH ROW
+BEGIN
+    DELETE FROM groups_permissions WHERE permission_id = OLD.id;
+END;
+
+CREATE TRIGGER delete_user_groups
+AFTER DELETE ON users
+FOR EACH ROW
+BEGIN
+    DELETE FROM groups_users WHERE user_id = OLD.id;
+END;
+
+CREATE TRIGGER delete_system_groups
+BEFORE DELETE ON systems
+FOR EACH ROW
+BEGIN
+    DELETE FROM groups WHERE system_id = OLD.id;
+END;
+
+-- migrate:down
+
+DROP TRIGGER IF EXISTS delete_system_groups;
+DROP TRIGGER IF EXISTS delete_user_groups;
+DROP TRIGGER IF EXISTS delete_permission_groups;
+DROP TRIGGER IF EXISTS delete_group_associations;
+DROP INDEX IF EXISTS idx_groups_permissions_permission_id;
+DROP INDEX IF EXISTS idx_groups_permissions_group_permission;
+DROP TABLE groups_permissions;
+DROP INDEX IF EXISTS idx_groups_users_user_id;
+DROP INDEX IF EXISTS idx_groups_users_group_user;
+DROP TABLE groups_users;
+DROP INDEX IF EXISTS idx_groups_system_id;
+DROP TABLE groups;
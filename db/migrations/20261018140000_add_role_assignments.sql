@@ -0,0 +1,107 @@
+-- migrate:up
+
+-- systems_users_roles pasa a guardar solo los roles asignados explícitamente:
+-- quien tiene un rol recibe todos sus permisos, incluidos los que se agreguen
+-- después. Hasta ahora los triggers la llenaban con los roles de los permisos
+-- asignados uno a uno; esas filas se descartan para no otorgar roles completos
+-- a quien solo tenía algunos de sus permisos.
+DROP TRIGGER IF EXISTS trg_a_insert_systems_users_permissions;
+DROP TRIGGER IF EXISTS trg_a_delete_systems_users_permissions;
+DELETE FROM systems_users_roles;
+
+CREATE UNIQUE INDEX idx_systems_users_roles_system_user_role ON systems_users_roles(system_id, user_id, role_id);
+
+-- Permisos que se le quitan a un usuario aunque le lleguen por sus roles
+-- (asignados o heredados); no anulan una asignación directa ni la de un grupo
+CREATE TABLE systems_users_excluded_permissions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  system_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  permission_id INTEGER NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY (system_id) REFERENCES systems(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_systems_users_excluded_permissions_system_user_permission ON systems_users_excluded_permissions(system_id, user_id, permission_id);
+
+CREATE TRIGGER delete_role_assignments
+BEFORE DELETE ON roles
+FOR EACH ROW
+BEGIN
+    DELETE FROM systems_users_roles WHERE role_id = OLD.id;
+END;
+
+CREATE TRIGGER delete_permission_exclusions
+AFTER DELETE ON permissions
+FOR EACH ROW
+BEGIN
+    DELETE FROM systems_users_excluded_permissions WHERE permission_id = OLD.id;
+END;
+
+CREATE TRIGGER delete_user_role_assignments
+AFTER DELETE ON users
+FOR EACH ROW
+BEGIN
+    DELETE FROM systems_users_roles WHERE user_id = OLD.id;
+    DELETE FROM systems_users_excluded_permissions WHERE user_id = OLD.id;
+END;
+
+-- migrate:down
+
+DROP TRIGGER IF EXISTS delete_user_role_assignments;
+DROP TRIGGER IF EXISTS delete_permission_exclusions;
+DROP TRIGGER IF EXISTS delete_role_assignments;
+DROP INDEX IF EXISTS idx_systems_users_excluded_permissions_system_user_permission;
+DROP TABLE systems_users_excluded_permissions;
+DROP INDEX IF EXISTS idx_systems_users_roles_system_user_role;
+
+-- Se vuelve a derivar la tabla de los permisos asignados, como antes
+DELETE FROM systems_users_roles;
+INSERT INTO systems_users_roles (system_id, user_id, role_id, created)
+SELECT sup.system_id, sup.user_id, p.role_id, MIN(sup.created)
+FROM systems_users_permissions sup
+JOIN permissions p ON sup.permission_id = p.id
+GROUP BY sup.system_id, sup.user_id, p.role_id;
+
+CREATE TRIGGER trg_a_insert_systems_users_permissions
+AFTER INSERT ON systems_users_permissions
+BEGIN
+  INSERT INTO systems_users_roles (system_id, user_id, role_id, created)
+  SELECT
+    NEW.system_id,
+    NEW.user_id,
+    p.role_id,
+    NEW.created
+  FROM permissions p
+  WHERE p.id = NEW.permission_id
+    AND NOT EXISTS (
+      SELECT 1
+      FROM systems_users_roles sur
+      WHERE sur.system_id = NEW.system_id
+        AND sur.user_id = NEW.user_id
+        AND sur.role_id = p.role_id
+    );
+END;
+
+CREATE TRIGGER trg_a_delete_systems_users_permissions
+AFTER DELETE ON systems_users_permissions
+BEGIN
+  DELETE FROM systems_users_roles
+  WHERE system_id = OLD.system_id
+    AND user_id = OLD.user_id
+    AND role_id = (
+      SELECT role_id FROM permissions WHERE id = OLD.permission_id
+    )
+    AND NOT EXISTS (
+      SELECT 1
+      FROM systems_users_permissions sup
+      JOIN permissions p ON sup.permission_id = p.id
+      WHERE sup.system_id = OLD.system_id
+        AND sup.user_id = OLD.user_id
+        AND p.role_id = (
+          SELECT role_id FROM permissions WHERE id = OLD.permission_id
+        )
+    );
+END;
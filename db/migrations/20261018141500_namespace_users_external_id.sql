@@ -0,0 +1,25 @@
+-- migrate:up
+
+-- Los usuarios locales que se vincularon solos al directorio por coincidir en
+-- nombre o correo vuelven a ser locales: el vínculo ahora lo hace un
+-- administrador desde la consola
+UPDATE users SET auth_source = 'local', external_id = NULL
+WHERE auth_source = 'ldap' AND password <> '';
+
+-- external_id pasa a llevar el directorio delante ("global:<DN>" o
+-- "system-<id>:<DN>"), porque el mismo DN puede existir en dos directorios. Se
+-- toma el directorio del primer sistema al que se asoció el usuario.
+UPDATE users SET external_id = COALESCE((
+  SELECT CASE WHEN sls.system_id IS NULL THEN 'global' ELSE 'system-' || su.system_id END
+  FROM systems_users su
+  LEFT JOIN system_ldap_settings sls ON sls.system_id = su.system_id
+  WHERE su.user_id = users.id
+  ORDER BY su.id
+  LIMIT 1
+), 'global') || ':' || external_id
+WHERE external_id IS NOT NULL;
+
+-- migrate:down
+
+UPDATE users SET external_id = substr(external_id, instr(external_id, ':') + 1)
+WHERE external_id IS NOT NULL;
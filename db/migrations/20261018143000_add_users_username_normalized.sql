@@ -0,0 +1,34 @@
+-- migrate:up
+
+-- Nombre de usuario normalizado con utils.NormalizeUsername (NFKC y
+-- minúsculas), que es con el que se compara al iniciar sesión. SQLite no sabe
+-- aplicar NFKC: aquí se completan los nombres ASCII, en los que equivale a
+-- LOWER(TRIM()), y la aplicación completa los demás al iniciar.
+ALTER TABLE users ADD COLUMN username_normalized VARCHAR(80);
+UPDATE users SET username_normalized = LOWER(TRIM(username)) WHERE username NOT GLOB '*[^ -~]*';
+
+-- Si dos usuarios solo difieren en mayúsculas la migración se detiene; para
+-- verlos:
+--   SELECT username_normalized, GROUP_CONCAT(id || ':' || username, ', ')
+--   FROM users WHERE username_normalized IS NOT NULL
+--   GROUP BY username_normalized HAVING COUNT(*) > 1;
+-- Renombrar o eliminar los repetidos y volver a migrar.
+CREATE TEMP TABLE username_duplicates (username_normalized VARCHAR(80));
+CREATE TEMP TRIGGER username_duplicates_abort BEFORE INSERT ON username_duplicates
+BEGIN
+  SELECT RAISE(ABORT, 'Hay usuarios cuyo nombre solo difiere en mayusculas; renombrelos antes de migrar (ver 20261018143000_add_users_username_normalized.sql)');
+END;
+INSERT INTO username_duplicates
+  SELECT username_normalized FROM users
+  WHERE username_normalized IS NOT NULL
+  GROUP BY username_normalized HAVING COUNT(*) > 1;
+DROP TABLE username_duplicates;
+
+CREATE UNIQUE INDEX idx_users_username_normalized ON users(username_normalized);
+DROP INDEX IF EXISTS idx_users_username_lower;
+
+-- migrate:down
+
+CREATE INDEX idx_users_username_lower ON users(LOWER(username));
+DROP INDEX IF EXISTS idx_users_username_normalized;
+ALTER TABLE users DROP COLUMN username_normalized;
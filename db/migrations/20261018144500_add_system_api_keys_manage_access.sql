@@ -0,0 +1,9 @@
+-- migrate:up
+
+-- Solo las llaves creadas con este permiso pueden asignar roles y quitar
+-- permisos por /api/v1/authz; las demás solo consultan
+ALTER TABLE system_api_keys ADD COLUMN manage_access BOOLEAN NOT NULL DEFAULT 0;
+
+-- migrate:down
+
+ALTER TABLE system_api_keys DROP COLUMN manage_access;
@@ -0,0 +1,13 @@
+-- migrate:up
+
+-- La llave de activación se guarda como su hash SHA-256, igual que los demás
+-- tokens. SQLite no puede calcularlo: los enlaces pendientes dejan de valer y
+-- se reenvían desde /users/:id/edit.
+ALTER TABLE users ADD COLUMN activation_key_hash VARCHAR(64);
+UPDATE users SET activation_key_expires_at = NULL;
+ALTER TABLE users DROP COLUMN activation_key;
+
+-- migrate:down
+
+ALTER TABLE users ADD COLUMN activation_key VARCHAR(30);
+ALTER TABLE users DROP COLUMN activation_key_hash;
@@ -0,0 +1,13 @@
+-- migrate:up
+
+-- La llave de recuperación se guarda como su hash SHA-256, igual que los demás
+-- tokens. SQLite no puede calcularlo: los enlaces pendientes dejan de valer y
+-- los usuarios deben pedir uno nuevo.
+ALTER TABLE users ADD COLUMN reset_key_hash VARCHAR(64);
+UPDATE users SET reset_key_expires_at = NULL;
+ALTER TABLE users DROP COLUMN reset_key;
+
+-- migrate:down
+
+ALTER TABLE users ADD COLUMN reset_key VARCHAR(30);
+ALTER TABLE users DROP COLUMN reset_key_hash;
@@ -3,13 +3,11 @@ CREATE TABLE users (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   username VARCHAR(20) NOT NULL,
   password VARCHAR(100) NOT NULL,
-  activation_key VARCHAR(30),
-  reset_key VARCHAR(30),
   email VARCHAR(50) UNIQUE NOT NULL,
   activated BOOLEAN NOT NULL DEFAULT 0,
   created DATETIME NOT NULL,
   updated DATETIME NOT NULL
-);
+, activation_key_expires_at DATETIME, reset_key_expires_at DATETIME, password_changed_at DATETIME, auth_source VARCHAR(10) NOT NULL DEFAULT 'local', external_id VARCHAR(255), username_normalized VARCHAR(80), activation_key_hash VARCHAR(64), reset_key_hash VARCHAR(64));
 CREATE TABLE systems (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   name VARCHAR(40) NOT NULL,
@@ -17,13 +15,13 @@ CREATE TABLE systems (
   repository VARCHAR(100),
   created DATETIME NOT NULL,
   updated DATETIME NOT NULL
-);
+, mfa_required BOOLEAN NOT NULL DEFAULT 0, auth_backend VARCHAR(10) NOT NULL DEFAULT '', token_issuer VARCHAR(255) NOT NULL DEFAULT '', token_audience VARCHAR(255) NOT NULL DEFAULT '', access_token_ttl INTEGER NOT NULL DEFAULT 0, token_clock_skew INTEGER NOT NULL DEFAULT 0, token_secret VARCHAR(255) NOT NULL DEFAULT '');
 CREATE TABLE roles (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   name VARCHAR(40) NOT NULL,
   created DATETIME NOT NULL,
   updated DATETIME NOT NULL,
-  system_id INTEGER NOT NULL,
+  system_id INTEGER NOT NULL, parent_id INTEGER REFERENCES roles(id),
   FOREIGN KEY(system_id) REFERENCES systems(id)
 );
 CREATE TABLE permissions (
@@ -73,51 +71,311 @@ CREATE TABLE systems_users_roles (
   FOREIGN KEY(user_id) REFERENCES users(id),
   FOREIGN KEY(role_id) REFERENCES roles(id)
 );
-CREATE TRIGGER trg_a_insert_systems_users_permissions
-AFTER INSERT ON systems_users_permissions
+CREATE TRIGGER delete_role_permissions
+BEFORE DELETE ON roles
+FOR EACH ROW
 BEGIN
-  INSERT INTO systems_users_roles (system_id, user_id, role_id, created)
-  SELECT
-    NEW.system_id,
-    NEW.user_id,
-    p.role_id,
-    NEW.created
-  FROM permissions p
-  WHERE p.id = NEW.permission_id
-    AND NOT EXISTS (
-      SELECT 1
-      FROM systems_users_roles sur
-      WHERE sur.system_id = NEW.system_id
-        AND sur.user_id = NEW.user_id
-        AND sur.role_id = p.role_id
-    );
+    DELETE FROM permissions WHERE role_id = OLD.id;
 END;
-CREATE TRIGGER trg_a_delete_systems_users_permissions
-AFTER DELETE ON systems_users_permissions
+CREATE TABLE admins (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  username VARCHAR(20) UNIQUE NOT NULL,
+  password VARCHAR(100) NOT NULL,
+  email VARCHAR(50) UNIQUE NOT NULL,
+  activated BOOLEAN NOT NULL DEFAULT 1,
+  last_login DATETIME,
+  created DATETIME NOT NULL,
+  updated DATETIME NOT NULL
+, is_super_admin BOOLEAN NOT NULL DEFAULT 0, can_impersonate BOOLEAN NOT NULL DEFAULT 0);
+CREATE TABLE refresh_tokens (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  token_hash VARCHAR(64) UNIQUE NOT NULL,
+  family_id VARCHAR(64) NOT NULL,
+  user_id INTEGER NOT NULL,
+  system_id INTEGER NOT NULL,
+  expires_at DATETIME NOT NULL,
+  used_at DATETIME,
+  revoked_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  FOREIGN KEY(system_id) REFERENCES systems(id)
+);
+CREATE INDEX idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE TABLE signing_keys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  kid VARCHAR(64) UNIQUE NOT NULL,
+  algorithm VARCHAR(10) NOT NULL,
+  private_key TEXT NOT NULL,
+  public_key TEXT NOT NULL,
+  rotated_at DATETIME,
+  expires_at DATETIME,
+  created DATETIME NOT NULL
+);
+CREATE TABLE system_redirect_uris (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  system_id INTEGER NOT NULL,
+  uri VARCHAR(255) NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(system_id) REFERENCES systems(id) ON DELETE CASCADE,
+  UNIQUE(system_id, uri)
+);
+CREATE TABLE authorization_codes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  code_hash VARCHAR(64) UNIQUE NOT NULL,
+  system_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  redirect_uri VARCHAR(255) NOT NULL,
+  scope VARCHAR(255) NOT NULL DEFAULT '',
+  nonce VARCHAR(255) NOT NULL DEFAULT '',
+  code_challenge VARCHAR(128) NOT NULL,
+  code_challenge_method VARCHAR(10) NOT NULL,
+  auth_time DATETIME NOT NULL,
+  expires_at DATETIME NOT NULL,
+  used_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  FOREIGN KEY(system_id) REFERENCES systems(id)
+);
+CREATE TABLE system_clients (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  system_id INTEGER NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  client_id VARCHAR(64) UNIQUE NOT NULL,
+  secret_hash VARCHAR(64) NOT NULL,
+  secret_rotated_at DATETIME NOT NULL,
+  last_used_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+CREATE TABLE system_client_permissions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  system_client_id INTEGER NOT NULL,
+  permission_id INTEGER NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(system_client_id) REFERENCES system_clients(id) ON DELETE CASCADE,
+  FOREIGN KEY(permission_id) REFERENCES permissions(id) ON DELETE CASCADE,
+  UNIQUE(system_client_id, permission_id)
+);
+CREATE TABLE IF NOT EXISTS "access_tokens" (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  jti VARCHAR(64) UNIQUE NOT NULL,
+  user_id INTEGER,
+  system_client_id INTEGER,
+  system_id INTEGER NOT NULL,
+  expires_at DATETIME NOT NULL,
+  revoked_at DATETIME,
+  revoke_reason VARCHAR(50),
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  FOREIGN KEY(system_client_id) REFERENCES system_clients(id),
+  FOREIGN KEY(system_id) REFERENCES systems(id)
+);
+CREATE INDEX idx_access_tokens_user_system ON access_tokens(user_id, system_id);
+CREATE INDEX idx_access_tokens_client ON access_tokens(system_client_id);
+CREATE TABLE system_api_keys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  system_id INTEGER NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  prefix VARCHAR(12) NOT NULL,
+  key_hash VARCHAR(64) UNIQUE NOT NULL,
+  rotated_at DATETIME NOT NULL,
+  last_used_at DATETIME,
+  revoked_at DATETIME,
+  created DATETIME NOT NULL, manage_access BOOLEAN NOT NULL DEFAULT 0,
+  FOREIGN KEY(system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_system_api_keys_system ON system_api_keys(system_id);
+CREATE TABLE user_mfa (
+  user_id INTEGER PRIMARY KEY,
+  secret VARCHAR(64) NOT NULL,
+  confirmed_at DATETIME,
+  last_used_step INTEGER NOT NULL DEFAULT 0,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE user_mfa_recovery_codes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  code_hash VARCHAR(64) NOT NULL,
+  used_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_user_mfa_recovery_codes_user ON user_mfa_recovery_codes(user_id);
+CREATE TABLE mfa_challenges (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  token_hash VARCHAR(64) UNIQUE NOT NULL,
+  user_id INTEGER NOT NULL,
+  system_id INTEGER NOT NULL,
+  enrollment BOOLEAN NOT NULL DEFAULT 0,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  expires_at DATETIME NOT NULL,
+  used_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY(system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+CREATE TABLE sign_in_throttles (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  scope VARCHAR(16) NOT NULL,
+  system_id INTEGER NOT NULL DEFAULT 0,
+  subject VARCHAR(255) NOT NULL,
+  failures INTEGER NOT NULL DEFAULT 0,
+  last_failure_at DATETIME NOT NULL,
+  retry_at DATETIME,
+  locked_until DATETIME,
+  UNIQUE(scope, system_id, subject)
+);
+CREATE INDEX idx_sign_in_throttles_subject ON sign_in_throttles(scope, subject);
+CREATE TABLE password_history (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  password_hash VARCHAR(255) NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_password_history_user ON password_history(user_id, created);
+CREATE TABLE admin_sessions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  admin_id INTEGER,
+  data TEXT NOT NULL DEFAULT '',
+  ip_address VARCHAR(45) NOT NULL DEFAULT '',
+  user_agent VARCHAR(255) NOT NULL DEFAULT '',
+  created DATETIME NOT NULL,
+  last_seen_at DATETIME NOT NULL,
+  expires_at DATETIME NOT NULL,
+  FOREIGN KEY (admin_id) REFERENCES admins(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_admin_sessions_admin_id ON admin_sessions(admin_id);
+CREATE INDEX idx_admin_sessions_expires_at ON admin_sessions(expires_at);
+CREATE UNIQUE INDEX idx_users_external_id ON users(external_id);
+CREATE TABLE system_ldap_settings (
+  system_id INTEGER PRIMARY KEY,
+  url VARCHAR(255) NOT NULL,
+  start_tls BOOLEAN NOT NULL DEFAULT 0,
+  insecure_skip_verify BOOLEAN NOT NULL DEFAULT 0,
+  bind_dn VARCHAR(255) NOT NULL DEFAULT '',
+  bind_password VARCHAR(255) NOT NULL DEFAULT '',
+  base_dn VARCHAR(255) NOT NULL,
+  user_filter VARCHAR(255) NOT NULL DEFAULT '(uid=%s)',
+  username_attribute VARCHAR(64) NOT NULL DEFAULT 'uid',
+  email_attribute VARCHAR(64) NOT NULL DEFAULT 'mail',
+  created DATETIME NOT NULL,
+  updated DATETIME NOT NULL,
+  FOREIGN KEY (system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_users_email_lower ON users(LOWER(email));
+CREATE TABLE impersonations (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  admin_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  system_id INTEGER NOT NULL,
+  jti VARCHAR(64) NOT NULL UNIQUE,
+  reason TEXT NOT NULL,
+  ip_address VARCHAR(45) NOT NULL DEFAULT '',
+  user_agent VARCHAR(255) NOT NULL DEFAULT '',
+  expires_at DATETIME NOT NULL,
+  ended_at DATETIME,
+  created DATETIME NOT NULL,
+  FOREIGN KEY (admin_id) REFERENCES admins(id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_impersonations_admin_id ON impersonations(admin_id);
+CREATE INDEX idx_impersonations_user_system ON impersonations(user_id, system_id);
+CREATE INDEX idx_systems_users_system_user ON systems_users(system_id, user_id);
+CREATE INDEX idx_systems_users_permissions_user ON systems_users_permissions(user_id, permission_id);
+CREATE INDEX idx_permissions_role_name ON permissions(role_id, name);
+CREATE INDEX idx_roles_system ON roles(system_id);
+CREATE INDEX idx_roles_parent_id ON roles(parent_id);
+CREATE TABLE groups (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(50) NOT NULL,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  system_id INTEGER,
+  created DATETIME NOT NULL,
+  updated DATETIME NOT NULL,
+  FOREIGN KEY (system_id) REFERENCES systems(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_groups_system_id ON groups(system_id);
+CREATE TABLE groups_users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  group_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_groups_users_group_user ON groups_users(group_id, user_id);
+CREATE INDEX idx_groups_users_user_id ON groups_users(user_id);
+CREATE TABLE groups_permissions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  group_id INTEGER NOT NULL,
+  permission_id INTEGER NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+  FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_groups_permissions_group_permission ON groups_permissions(group_id, permission_id);
+CREATE INDEX idx_groups_permissions_permission_id ON groups_permissions(permission_id);
+CREATE TRIGGER delete_group_associations
+BEFORE DELETE ON groups
+FOR EACH ROW
 BEGIN
-  DELETE FROM systems_users_roles
-  WHERE system_id = OLD.system_id
-    AND user_id = OLD.user_id
-    AND role_id = (
-      SELECT role_id FROM permissions WHERE id = OLD.permission_id
-    )
-    AND NOT EXISTS (
-      SELECT 1
-      FROM systems_users_permissions sup
-      JOIN permissions p ON sup.permission_id = p.id
-      WHERE sup.system_id = OLD.system_id
-        AND sup.user_id = OLD.user_id
-        AND p.role_id = (
-          SELECT role_id FROM permissions WHERE id = OLD.permission_id
-        )
-    );
+    DELETE FROM groups_users WHERE group_id = OLD.id;
+    DELETE FROM groups_permissions WHERE group_id = OLD.id;
 END;
-CREATE TRIGGER delete_role_permissions
+CREATE TRIGGER delete_permission_groups
+AFTER DELETE ON permissions
+FOR EACH ROW
+BEGIN
+    DELETE FROM groups_permissions WHERE permission_id = OLD.id;
+END;
+CREATE TRIGGER delete_user_groups
+AFTER DELETE ON users
+FOR EACH ROW
+BEGIN
+    DELETE FROM groups_users WHERE user_id = OLD.id;
+END;
+CREATE TRIGGER delete_system_groups
+BEFORE DELETE ON systems
+FOR EACH ROW
+BEGIN
+    DELETE FROM groups WHERE system_id = OLD.id;
+END;
+CREATE UNIQUE INDEX idx_systems_users_roles_system_user_role ON systems_users_roles(system_id, user_id, role_id);
+CREATE TABLE systems_users_excluded_permissions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  system_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  permission_id INTEGER NOT NULL,
+  created DATETIME NOT NULL,
+  FOREIGN KEY (system_id) REFERENCES systems(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_systems_users_excluded_permissions_system_user_permission ON systems_users_excluded_permissions(system_id, user_id, permission_id);
+CREATE TRIGGER delete_role_assignments
 BEFORE DELETE ON roles
 FOR EACH ROW
 BEGIN
-    DELETE FROM permissions WHERE role_id = OLD.id;
+    DELETE FROM systems_users_roles WHERE role_id = OLD.id;
+END;
+CREATE TRIGGER delete_permission_exclusions
+AFTER DELETE ON permissions
+FOR EACH ROW
+BEGIN
+    DELETE FROM systems_users_excluded_permissions WHERE permission_id = OLD.id;
+END;
+CREATE TRIGGER delete_user_role_assignments
+AFTER DELETE ON users
+FOR EACH ROW
+BEGIN
+    DELETE FROM systems_users_roles WHERE user_id = OLD.id;
+    DELETE FROM systems_users_excluded_permissions WHERE user_id = OLD.id;
 END;
+CREATE UNIQUE INDEX idx_users_username_normalized ON users(username_normalized);
 -- Dbmate schema migrations
 INSERT INTO "schema_migrations" (version) VALUES
   ('20250607174507'),
@@ -133,4 +391,30 @@ INSERT INTO "schema_migrations" (version) VALUES
   ('20250802045923'),
   ('20250802050229'),
   ('20250821221530'),
-  ('20250831032948');
+  ('20250831032948'),
+  ('20261018090000'),
+  ('20261018091500'),
+  ('20261018093000'),
+  ('20261018094500'),
+  ('20261018100000'),
+  ('20261018101500'),
+  ('20261018103000'),
+  ('20261018104500'),
+  ('20261018110000'),
+  ('20261018111500'),
+  ('20261018113000'),
+  ('20261018114500'),
+  ('20261018120000'),
+  ('20261018121500'),
+  ('20261018123000'),
+  ('20261018124500'),
+  ('20261018130000'),
+  ('20261018131500'),
+  ('20261018133000'),
+  ('20261018134500'),
+  ('20261018140000'),
+  ('20261018141500'),
+  ('20261018143000'),
+  ('20261018144500'),
+  ('20261018150000'),
+  ('20261018151500');
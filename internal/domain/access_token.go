@@ -0,0 +1,22 @@
+// internal/domain/access_token.go
+package domain
+
+import "time"
+
+// AccessToken registra cada JWT emitido (por su jti) para poder revocarlo. Los
+// tokens de un usuario tienen UserID; los de un cliente, SystemClientID.
+type AccessToken struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	JTI            string     `gorm:"column:jti;size:64;unique;not null" json:"jti"`
+	UserID         *uint      `json:"user_id,omitempty"`
+	SystemClientID *uint      `json:"system_client_id,omitempty"`
+	SystemID       uint       `gorm:"not null" json:"system_id"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason   string     `gorm:"size:50" json:"reason,omitempty"`
+	Created        time.Time  `gorm:"not null" json:"created"`
+}
+
+func (AccessToken) TableName() string {
+	return "access_tokens"
+}
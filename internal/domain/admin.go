@@ -0,0 +1,24 @@
+// internal/domain/admin.go
+package domain
+
+import "time"
+
+// Admin representa a un administrador de la consola de gestión
+type Admin struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username  string `gorm:"size:20;unique;not null" json:"username"`
+	Password  string `gorm:"size:100;not null" json:"-"`
+	Email     string `gorm:"size:50;unique;not null" json:"email"`
+	Activated bool   `gorm:"not null;default:true" json:"activated"`
+	// Un superadministrador puede ver y terminar las sesiones de los demás
+	IsSuperAdmin bool `gorm:"not null;default:false" json:"is_super_admin"`
+	// Puede suplantar usuarios de los sistemas (los superadministradores siempre pueden)
+	CanImpersonate bool       `gorm:"not null;default:false" json:"can_impersonate"`
+	LastLogin      *time.Time `json:"last_login,omitempty"`
+	Created        time.Time  `gorm:"not null" json:"created"`
+	Updated        time.Time  `gorm:"not null" json:"updated"`
+}
+
+func (Admin) TableName() string {
+	return "admins"
+}
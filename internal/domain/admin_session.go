@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// AdminSession es una sesión de la consola guardada en el servidor. Las filas
+// sin administrador corresponden a visitantes que aún no inician sesión.
+type AdminSession struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TokenHash  string    `gorm:"size:64;unique;not null" json:"-"`
+	AdminID    *uint     `json:"admin_id,omitempty"`
+	Data       string    `gorm:"not null" json:"-"`
+	IPAddress  string    `gorm:"size:45;not null" json:"ip_address"`
+	UserAgent  string    `gorm:"size:255;not null" json:"user_agent"`
+	Created    time.Time `gorm:"not null" json:"created"`
+	LastSeenAt time.Time `gorm:"not null" json:"last_seen_at"`
+	ExpiresAt  time.Time `gorm:"not null" json:"expires_at"`
+
+	// Solo lectura, se llena con un JOIN al listar
+	Username string `gorm:"->" json:"username,omitempty"`
+}
+
+func (AdminSession) TableName() string {
+	return "admin_sessions"
+}
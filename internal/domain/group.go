@@ -0,0 +1,80 @@
+package domain
+
+import "time"
+
+// Group reúne usuarios para otorgarles permisos en conjunto. Sin SystemID es
+// global y puede recibir permisos de cualquier sistema
+type Group struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"size:50;not null" json:"name"`
+	Description string    `gorm:"size:255;not null;default:''" json:"description"`
+	SystemID    *uint     `json:"system_id,omitempty"` // nil para un grupo global
+	Created     time.Time `gorm:"not null" json:"created"`
+	Updated     time.Time `gorm:"not null" json:"updated"`
+}
+
+// IsGlobal indica si el grupo puede recibir permisos de cualquier sistema
+func (g Group) IsGlobal() bool {
+	return g.SystemID == nil
+}
+
+type GroupUser struct {
+	ID      uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID uint      `gorm:"not null" json:"group_id"`
+	UserID  uint      `gorm:"not null" json:"user_id"`
+	Created time.Time `gorm:"not null" json:"created"`
+}
+
+func (GroupUser) TableName() string {
+	return "groups_users"
+}
+
+type GroupPermission struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID      uint      `gorm:"not null" json:"group_id"`
+	PermissionID uint      `gorm:"not null" json:"permission_id"`
+	Created      time.Time `gorm:"not null" json:"created"`
+}
+
+func (GroupPermission) TableName() string {
+	return "groups_permissions"
+}
+
+// GroupSummary es un grupo con su sistema y la cantidad de miembros y permisos
+type GroupSummary struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	SystemID    *uint  `json:"system_id,omitempty"`
+	SystemName  string `json:"system_name"`
+	Members     int    `json:"members"`
+	Permissions int    `json:"permissions"`
+}
+
+// GroupMember es un usuario del grupo con la fecha en que se agregó
+type GroupMember struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Activated bool      `json:"activated"`
+	Created   time.Time `json:"created"`
+}
+
+// GroupSystemPermissions son los roles de un sistema con los permisos que se
+// pueden otorgar al grupo, marcando los ya otorgados
+type GroupSystemPermissions struct {
+	ID    uint                  `json:"id"`
+	Name  string                `json:"name"`
+	Roles []RoleWithPermissions `json:"roles"`
+}
+
+// GroupGrantablePermission es una fila plana de GroupSystemPermissions
+type GroupGrantablePermission struct {
+	SystemID       uint
+	SystemName     string
+	RoleID         uint
+	RoleName       string
+	PermissionID   uint
+	PermissionName string
+	IsAssigned     bool
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// Impersonation registra cada vez que un administrador suplanta a un usuario
+// en un sistema; JTI identifica el access token emitido para la suplantación
+type Impersonation struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	AdminID   uint       `gorm:"not null" json:"admin_id"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	SystemID  uint       `gorm:"not null" json:"system_id"`
+	JTI       string     `gorm:"column:jti;size:64;unique;not null" json:"jti"`
+	Reason    string     `gorm:"not null" json:"reason"`
+	IPAddress string     `gorm:"size:45;not null" json:"ip_address"`
+	UserAgent string     `gorm:"size:255;not null" json:"user_agent"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Created   time.Time  `gorm:"not null" json:"created"`
+
+	// Solo lectura, se llenan con un JOIN al listar
+	AdminUsername string `gorm:"->" json:"admin_username,omitempty"`
+	Username      string `gorm:"->" json:"username,omitempty"`
+	SystemName    string `gorm:"->" json:"system_name,omitempty"`
+}
+
+func (Impersonation) TableName() string {
+	return "impersonations"
+}
+
+// IsActive indica si el token de la suplantación sigue vigente
+func (i Impersonation) IsActive(now time.Time) bool {
+	return i.EndedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,49 @@
+// internal/domain/mfa.go
+package domain
+
+import "time"
+
+// UserMFA es el segundo factor TOTP del usuario. Mientras ConfirmedAt sea nil
+// la inscripción está pendiente y no se exige al iniciar sesión.
+type UserMFA struct {
+	UserID       uint       `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Secret       string     `gorm:"size:64;not null" json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `gorm:"not null;default:0" json:"-"`
+	Created      time.Time  `gorm:"not null" json:"created"`
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// UserMFARecoveryCode es un código de recuperación de un solo uso
+type UserMFARecoveryCode struct {
+	ID       uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID   uint       `gorm:"not null" json:"user_id"`
+	CodeHash string     `gorm:"size:64;not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at,omitempty"`
+	Created  time.Time  `gorm:"not null" json:"created"`
+}
+
+func (UserMFARecoveryCode) TableName() string {
+	return "user_mfa_recovery_codes"
+}
+
+// MFAChallenge es el paso intermedio entre la contraseña y el segundo factor.
+// Con Enrollment el usuario debe inscribir su TOTP antes de completar el ingreso.
+type MFAChallenge struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	TokenHash  string     `gorm:"size:64;unique;not null" json:"-"`
+	UserID     uint       `gorm:"not null" json:"user_id"`
+	SystemID   uint       `gorm:"not null" json:"system_id"`
+	Enrollment bool       `gorm:"not null;default:false" json:"enrollment"`
+	Attempts   int        `gorm:"not null;default:0" json:"attempts"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt     *time.Time `json:"used_at,omitempty"`
+	Created    time.Time  `gorm:"not null" json:"created"`
+}
+
+func (MFAChallenge) TableName() string {
+	return "mfa_challenges"
+}
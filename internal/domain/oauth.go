@@ -0,0 +1,39 @@
+// internal/domain/oauth.go
+package domain
+
+import "time"
+
+// SystemRedirectURI es una URI de retorno registrada para un sistema que actúa
+// como cliente OpenID Connect
+type SystemRedirectURI struct {
+	ID       uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SystemID uint      `gorm:"not null" json:"system_id"`
+	URI      string    `gorm:"column:uri;size:255;not null" json:"uri"`
+	Created  time.Time `gorm:"not null" json:"created"`
+}
+
+func (SystemRedirectURI) TableName() string {
+	return "system_redirect_uris"
+}
+
+// AuthorizationCode es un código de autorización de un solo uso emitido tras
+// el inicio de sesión del usuario, ligado al desafío PKCE del cliente
+type AuthorizationCode struct {
+	ID                  uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	CodeHash            string     `gorm:"size:64;unique;not null" json:"-"`
+	SystemID            uint       `gorm:"not null" json:"system_id"`
+	UserID              uint       `gorm:"not null" json:"user_id"`
+	RedirectURI         string     `gorm:"column:redirect_uri;size:255;not null" json:"redirect_uri"`
+	Scope               string     `gorm:"size:255;not null" json:"scope"`
+	Nonce               string     `gorm:"size:255;not null" json:"-"`
+	CodeChallenge       string     `gorm:"size:128;not null" json:"-"`
+	CodeChallengeMethod string     `gorm:"size:10;not null" json:"-"`
+	AuthTime            time.Time  `gorm:"not null" json:"auth_time"`
+	ExpiresAt           time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt              *time.Time `json:"used_at,omitempty"`
+	Created             time.Time  `gorm:"not null" json:"created"`
+}
+
+func (AuthorizationCode) TableName() string {
+	return "authorization_codes"
+}
@@ -0,0 +1,17 @@
+// internal/domain/password_history.go
+package domain
+
+import "time"
+
+// PasswordHistory guarda los hashes de las últimas contraseñas del usuario
+// para impedir que se reutilicen
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       uint      `gorm:"not null" json:"user_id"`
+	PasswordHash string    `gorm:"size:255;not null" json:"-"`
+	Created      time.Time `gorm:"not null" json:"created"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
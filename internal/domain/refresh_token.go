@@ -0,0 +1,22 @@
+// internal/domain/refresh_token.go
+package domain
+
+import "time"
+
+// RefreshToken es un token de renovación de un solo uso. Todos los tokens que
+// nacen de un mismo inicio de sesión comparten FamilyID.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	TokenHash string     `gorm:"size:64;unique;not null" json:"-"`
+	FamilyID  string     `gorm:"size:64;not null" json:"family_id"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	SystemID  uint       `gorm:"not null" json:"system_id"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	Created   time.Time  `gorm:"not null" json:"created"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
@@ -9,6 +9,13 @@ type Role struct {
 	Created     time.Time    `gorm:"not null" json:"created"`
 	Updated     time.Time    `gorm:"not null" json:"updated"`
 	SystemID    uint         `gorm:"not null" json:"system_id"`                   // Hace referencia a System.ID
+	ParentID    *uint        `json:"parent_id,omitempty"`                         // Rol del que hereda los permisos
 	System      System       `gorm:"foreignKey:SystemID" json:"system,omitempty"` // Relación con System
 	Permissions []Permission `json:"permissions"`
 }
+
+// RoleNode es un rol con los roles que heredan de él, para mostrar la jerarquía
+type RoleNode struct {
+	Role
+	Children []*RoleNode
+}
@@ -0,0 +1,34 @@
+// internal/domain/sign_in_throttle.go
+package domain
+
+import "time"
+
+// Alcances de los contadores de intentos fallidos
+const (
+	ThrottleScopeUsername = "username"
+	ThrottleScopeIP       = "ip"
+)
+
+// SignInThrottle cuenta los intentos fallidos de inicio de sesión de un usuario
+// o de una IP dentro de un sistema (SystemID 0 es la consola). RetryAt es la
+// espera exponencial entre intentos y LockedUntil el bloqueo temporal.
+type SignInThrottle struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Scope         string     `gorm:"size:16;not null" json:"scope"`
+	SystemID      uint       `gorm:"not null;default:0" json:"system_id"`
+	Subject       string     `gorm:"size:255;not null" json:"subject"`
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt time.Time  `gorm:"not null" json:"last_failure_at"`
+	RetryAt       *time.Time `json:"retry_at,omitempty"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	SystemName    string     `gorm:"->" json:"system_name,omitempty"`
+}
+
+func (SignInThrottle) TableName() string {
+	return "sign_in_throttles"
+}
+
+// IsLocked indica si el bloqueo temporal sigue vigente
+func (t SignInThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
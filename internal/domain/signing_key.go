@@ -0,0 +1,21 @@
+// internal/domain/signing_key.go
+package domain
+
+import "time"
+
+// SigningKey es una llave asimétrica para firmar tokens. La llave vigente no
+// tiene RotatedAt; las retiradas se siguen publicando hasta ExpiresAt.
+type SigningKey struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Kid        string     `gorm:"size:64;unique;not null" json:"kid"`
+	Algorithm  string     `gorm:"size:10;not null" json:"algorithm"`
+	PrivateKey string     `gorm:"type:text;not null" json:"-"`
+	PublicKey  string     `gorm:"type:text;not null" json:"public_key"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Created    time.Time  `gorm:"not null" json:"created"`
+}
+
+func (SigningKey) TableName() string {
+	return "signing_keys"
+}
@@ -1,21 +1,42 @@
 package domain
 
-import "time"
+import (
+	"strconv"
+	"time"
+)
 
 type System struct {
-	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name        string    `gorm:"size:40;not null" json:"name"`
-	Description string    `gorm:"type:text" json:"description"`
-	Repository  string    `gorm:"size:100" json:"repository"`
-	Created     time.Time `gorm:"not null" json:"created"`
-	Updated     time.Time `gorm:"not null" json:"updated"`
-	Roles       []*Role   `json:"roles"`
+	ID          uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string `gorm:"size:40;not null" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+	Repository  string `gorm:"size:100" json:"repository"`
+	MFARequired bool   `gorm:"column:mfa_required;not null;default:false" json:"mfa_required"`
+	// Backend de autenticación (local o ldap); vacío usa el global
+	AuthBackend string `gorm:"size:10;not null;default:''" json:"auth_backend"`
+	// Configuración de sus tokens; vacío o 0 usa el valor global
+	TokenIssuer    string    `gorm:"size:255;not null;default:''" json:"token_issuer"`
+	TokenAudience  string    `gorm:"size:255;not null;default:''" json:"token_audience"`
+	AccessTokenTTL int       `gorm:"column:access_token_ttl;not null;default:0" json:"access_token_ttl"` // segundos
+	TokenClockSkew int       `gorm:"not null;default:0" json:"token_clock_skew"`                         // segundos
+	TokenSecret    string    `gorm:"size:255;not null;default:''" json:"-"`                              // firma HS256 propia
+	Created        time.Time `gorm:"not null" json:"created"`
+	Updated        time.Time `gorm:"not null" json:"updated"`
+	Roles          []*Role   `json:"roles"`
 }
 
 func (System) TableName() string {
 	return "systems"
 }
 
+// Audience devuelve el aud de los tokens del sistema: el configurado o su ID,
+// igual que en el ID token de OpenID Connect
+func (s System) Audience() string {
+	if s.TokenAudience != "" {
+		return s.TokenAudience
+	}
+	return strconv.FormatUint(uint64(s.ID), 10)
+}
+
 type SystemListResponse struct {
 	Systems     []System `json:"systems"`
 	Total       int64    `json:"total"`
@@ -0,0 +1,25 @@
+// internal/domain/system_api_key.go
+package domain
+
+import "time"
+
+// SystemAPIKey es una credencial con la que un sistema llama a las APIs de
+// autenticación. Solo se guarda el hash; Prefix permite identificarla en la consola.
+type SystemAPIKey struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	SystemID   uint       `gorm:"not null" json:"system_id"`
+	Name       string     `gorm:"size:50;not null" json:"name"`
+	Prefix     string     `gorm:"size:12;not null" json:"prefix"`
+	KeyHash    string     `gorm:"size:64;unique;not null" json:"-"`
+	RotatedAt  time.Time  `gorm:"not null" json:"rotated_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Created    time.Time  `gorm:"not null" json:"created"`
+	// La llave puede asignar roles y quitar permisos a los usuarios del
+	// sistema; sin esto solo consulta
+	ManageAccess bool `gorm:"not null;default:false" json:"manage_access"`
+}
+
+func (SystemAPIKey) TableName() string {
+	return "system_api_keys"
+}
@@ -0,0 +1,52 @@
+// internal/domain/system_client.go
+package domain
+
+import "time"
+
+// SystemClient es un par client_id/secret de un sistema para llamadas entre
+// sistemas (grant client_credentials). Solo se guarda el hash del secreto.
+type SystemClient struct {
+	ID              uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	SystemID        uint       `gorm:"not null" json:"system_id"`
+	Name            string     `gorm:"size:50;not null" json:"name"`
+	ClientID        string     `gorm:"column:client_id;size:64;unique;not null" json:"client_id"`
+	SecretHash      string     `gorm:"size:64;not null" json:"-"`
+	SecretRotatedAt time.Time  `gorm:"not null" json:"secret_rotated_at"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+	Created         time.Time  `gorm:"not null" json:"created"`
+}
+
+func (SystemClient) TableName() string {
+	return "system_clients"
+}
+
+// SystemClientPermission es un permiso otorgado a un cliente
+type SystemClientPermission struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SystemClientID uint      `gorm:"not null" json:"system_client_id"`
+	PermissionID   uint      `gorm:"not null" json:"permission_id"`
+	Created        time.Time `gorm:"not null" json:"created"`
+}
+
+func (SystemClientPermission) TableName() string {
+	return "system_client_permissions"
+}
+
+// ClientPermissionRow es una fila plana permiso/rol/sistema con la marca de
+// si está otorgado al cliente
+type ClientPermissionRow struct {
+	SystemID       uint   `json:"system_id"`
+	SystemName     string `json:"system_name"`
+	RoleID         uint   `json:"role_id"`
+	RoleName       string `json:"role_name"`
+	PermissionID   uint   `json:"permission_id"`
+	PermissionName string `json:"permission_name"`
+	IsAssigned     bool   `json:"is_assigned"`
+}
+
+// SystemWithRolePermissions agrupa los roles de un sistema con sus permisos
+type SystemWithRolePermissions struct {
+	ID    uint                  `json:"id"`
+	Name  string                `json:"name"`
+	Roles []RoleWithPermissions `json:"roles"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// SystemLDAPSettings es el directorio propio de un sistema. Sin fila el sistema
+// usa la configuración LDAP global.
+type SystemLDAPSettings struct {
+	SystemID           uint      `gorm:"primaryKey;autoIncrement:false" json:"system_id"`
+	URL                string    `gorm:"size:255;not null" json:"url"`
+	StartTLS           bool      `gorm:"column:start_tls;not null;default:false" json:"start_tls"`
+	InsecureSkipVerify bool      `gorm:"not null;default:false" json:"insecure_skip_verify"`
+	BindDN             string    `gorm:"column:bind_dn;size:255;not null" json:"bind_dn"`
+	BindPassword       string    `gorm:"size:255;not null" json:"-"`
+	BaseDN             string    `gorm:"column:base_dn;size:255;not null" json:"base_dn"`
+	UserFilter         string    `gorm:"size:255;not null" json:"user_filter"`
+	UsernameAttribute  string    `gorm:"size:64;not null" json:"username_attribute"`
+	EmailAttribute     string    `gorm:"size:64;not null" json:"email_attribute"`
+	Created            time.Time `gorm:"not null" json:"created"`
+	Updated            time.Time `gorm:"not null" json:"updated"`
+}
+
+func (SystemLDAPSettings) TableName() string {
+	return "system_ldap_settings"
+}
+
+// LDAPDirectory es un directorio disponible para vincular usuarios: el global
+// o el propio de un sistema
+type LDAPDirectory struct {
+	Key  string
+	Name string
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// SystemUserRole es un rol asignado al usuario en el sistema: le otorga todos
+// los permisos del rol, incluidos los que se agreguen después
+type SystemUserRole struct {
+	ID       uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SystemID uint      `gorm:"not null" json:"system_id"`
+	UserID   uint      `gorm:"not null" json:"user_id"`
+	RoleID   uint      `gorm:"not null" json:"role_id"`
+	Created  time.Time `gorm:"not null" json:"created"`
+}
+
+func (SystemUserRole) TableName() string {
+	return "systems_users_roles"
+}
+
+// SystemUserExcludedPermission es un permiso que se le quita al usuario aunque
+// le llegue por sus roles
+type SystemUserExcludedPermission struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SystemID     uint      `gorm:"not null" json:"system_id"`
+	UserID       uint      `gorm:"not null" json:"user_id"`
+	PermissionID uint      `gorm:"not null" json:"permission_id"`
+	Created      time.Time `gorm:"not null" json:"created"`
+}
+
+func (SystemUserExcludedPermission) TableName() string {
+	return "systems_users_excluded_permissions"
+}
@@ -6,15 +6,40 @@ import (
 )
 
 type User struct {
-	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Username      string    `gorm:"size:20;not null" json:"username"`
-	Password      string    `gorm:"size:100;not null" json:"password"`
-	ActivationKey string    `gorm:"size:30" json:"activation_key,omitempty"`
-	ResetKey      string    `gorm:"size:30" json:"reset_key,omitempty"`
-	Email         string    `gorm:"size:50;unique;not null" json:"email"`
-	Activated     bool      `gorm:"not null;default:false" json:"activated"`
-	Created       time.Time `gorm:"not null" json:"created"`
-	Updated       time.Time `gorm:"not null" json:"updated"`
+	ID       uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username string `gorm:"size:20;not null" json:"username"`
+	// Nombre de usuario normalizado con utils.NormalizeUsername; único, es el
+	// que se compara al iniciar sesión
+	UsernameNormalized *string   `gorm:"size:80;unique" json:"-"`
+	Password           string    `gorm:"size:100;not null" json:"password"`
+	ActivationKeyHash  string    `gorm:"size:64" json:"-"` // hash de la llave del enlace de activación
+	ResetKeyHash       string    `gorm:"size:64" json:"-"` // hash de la llave del enlace de recuperación
+	Email              string    `gorm:"size:50;unique;not null" json:"email"`
+	Activated          bool      `gorm:"not null;default:false" json:"activated"`
+	Created            time.Time `gorm:"not null" json:"created"`
+	Updated            time.Time `gorm:"not null" json:"updated"`
+	// Vencimiento del enlace de activación enviado por correo
+	ActivationKeyExpiresAt *time.Time `json:"activation_key_expires_at,omitempty"`
+	// Vencimiento del enlace de recuperación de contraseña
+	ResetKeyExpiresAt *time.Time `json:"reset_key_expires_at,omitempty"`
+	// Último cambio de contraseña, usado para su vencimiento
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"`
+	// Origen de las credenciales: ldap para los usuarios creados desde el
+	// directorio, que no tienen contraseña local
+	AuthSource string `gorm:"size:10;not null;default:local" json:"auth_source"`
+	// Entrada del directorio vinculada, como "<directorio>:<DN>"
+	ExternalID *string `gorm:"size:255;unique" json:"external_id,omitempty"`
+}
+
+// Orígenes de las credenciales de un usuario
+const (
+	AuthSourceLocal = "local"
+	AuthSourceLDAP  = "ldap"
+)
+
+// IsExternal indica si la contraseña del usuario la administra un directorio externo
+func (u User) IsExternal() bool {
+	return u.AuthSource != "" && u.AuthSource != AuthSourceLocal
 }
 
 type UserSummary struct {
@@ -33,6 +58,9 @@ type SystemUserRolesPermissions struct {
 	RoleID         uint   `json:"role_id"`
 	RoleName       string `json:"role_name"`
 	IsAssigned     bool   `json:"is_assigned"`
+	GroupNames     string `json:"group_names"` // grupos del usuario que otorgan el permiso
+	RoleAssigned   bool   `json:"role_assigned"`
+	IsExcluded     bool   `json:"is_excluded"`
 }
 
 // Permission represents a permission within a role.
@@ -40,6 +68,7 @@ type UserPermission struct {
 	ID         uint   `json:"id"`
 	Name       string `json:"name"`
 	IsAssigned bool   `json:"is_assigned"`
+	Groups     string `json:"groups,omitempty"` // grupos por los que el usuario tiene el permiso
 }
 
 // RoleWithPermissions represents a role and its associated permissions.
@@ -47,5 +76,6 @@ type RoleWithPermissions struct {
 	ID          uint             `json:"id"`
 	Name        string           `json:"name"`
 	RoleID      string           `json:"role_id"`
+	Assigned    bool             `json:"-"` // rol asignado completo al usuario
 	Permissions []UserPermission `json:"permissions"`
 }
@@ -0,0 +1,20 @@
+package forms
+
+// ForgotPasswordRequest solicita el enlace de recuperación de contraseña.
+// ResetURL solo se acepta en la API, para enlazar a una página del sistema.
+type ForgotPasswordRequest struct {
+	Identifier string `form:"identifier" json:"identifier" binding:"required"`
+	ResetURL   string `form:"-" json:"reset_url"`
+}
+
+// ResetPasswordForm es el formulario de la página de recuperación
+type ResetPasswordForm struct {
+	Password        string `form:"password" binding:"required"`
+	PasswordConfirm string `form:"password_confirm" binding:"required"`
+}
+
+// ResetPasswordRequest es la variante JSON de la recuperación de contraseña
+type ResetPasswordRequest struct {
+	Key      string `json:"key" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
@@ -0,0 +1,48 @@
+package forms
+
+// AuthzCheckRequest pide verificar si un usuario tiene uno o varios permisos
+// en el sistema de la llave de API. El usuario se indica por user_id o por
+// username; permission y permissions se pueden combinar.
+type AuthzCheckRequest struct {
+	SystemID    uint64   `json:"system_id"`
+	UserID      uint     `json:"user_id"`
+	Username    string   `json:"username"`
+	Permission  string   `json:"permission"`
+	Permissions []string `json:"permissions"`
+	Match       string   `json:"match"` // all (por defecto) o any
+}
+
+// AuthzBatchCheckRequest verifica cada permiso por separado en una sola llamada
+type AuthzBatchCheckRequest struct {
+	SystemID    uint64   `json:"system_id"`
+	UserID      uint     `json:"user_id"`
+	Username    string   `json:"username"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// RoleAssignmentsRequest pide los roles asignados a un usuario
+type RoleAssignmentsRequest struct {
+	SystemID uint64 `json:"system_id"`
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// RoleAssignmentRequest asigna o quita un rol, indicado por role_id o por su
+// nombre en role
+type RoleAssignmentRequest struct {
+	SystemID uint64 `json:"system_id"`
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	RoleID   uint   `json:"role_id"`
+	Role     string `json:"role"`
+}
+
+// PermissionExclusionRequest quita o devuelve un permiso, indicado por
+// permission_id o por su nombre en permission
+type PermissionExclusionRequest struct {
+	SystemID     uint64 `json:"system_id"`
+	UserID       uint   `json:"user_id"`
+	Username     string `json:"username"`
+	PermissionID uint   `json:"permission_id"`
+	Permission   string `json:"permission"`
+}
@@ -0,0 +1,19 @@
+package forms
+
+type GroupCreateInput struct {
+	Name        string `form:"name" binding:"required"`
+	Description string `form:"description"`
+	SystemID    uint   `form:"system_id"` // 0 para un grupo global
+}
+
+// GroupEditInput no incluye el sistema: el alcance del grupo se fija al crearlo
+type GroupEditInput struct {
+	Name        string `form:"name" binding:"required"`
+	Description string `form:"description"`
+}
+
+// GroupMembersInput recibe nombres de usuario o correos separados por comas,
+// espacios o saltos de línea
+type GroupMembersInput struct {
+	Users string `form:"users" binding:"required"`
+}
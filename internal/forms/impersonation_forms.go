@@ -0,0 +1,7 @@
+package forms
+
+// ImpersonationForm es el motivo que el administrador registra al suplantar a
+// un usuario; queda en la auditoría
+type ImpersonationForm struct {
+	Reason string `form:"reason" binding:"required"`
+}
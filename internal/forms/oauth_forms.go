@@ -0,0 +1,52 @@
+package forms
+
+// AuthorizeRequest son los parámetros de /oauth/authorize (OpenID Connect Core 3.1.2.1)
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	Nonce               string `form:"nonce"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+// AuthorizeLoginForm es el formulario de inicio de sesión del usuario final
+type AuthorizeLoginForm struct {
+	AuthorizeRequest
+	Username string `form:"username" binding:"required"`
+	Password string `form:"password" binding:"required"`
+}
+
+// AuthorizeMFAForm es el paso de segundo factor del inicio de sesión
+type AuthorizeMFAForm struct {
+	AuthorizeRequest
+	Code         string `form:"code"`
+	RecoveryCode string `form:"recovery_code"`
+}
+
+// OAuthTokenRequest son los parámetros del endpoint de token (RFC 6749 4.1.3, 4.4 y 6)
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Audience     string `form:"audience"` // sistema destino en client_credentials
+}
+
+type RedirectURIForm struct {
+	URI string `form:"uri" binding:"required"`
+}
+
+type SystemClientForm struct {
+	Name string `form:"name" binding:"required"`
+}
+
+type SystemAPIKeyForm struct {
+	Name         string `form:"name" binding:"required"`
+	ManageAccess bool   `form:"manage_access"`
+}
@@ -1,9 +1,16 @@
 package forms
 
 type RoleCreateInput struct {
-	Name string `form:"name" binding:"required"`
+	Name     string `form:"name" binding:"required"`
+	ParentID uint   `form:"parent_id"` // 0 sin rol padre
 }
 
 type RoleEditInput struct {
-	Name string `form:"name" binding:"required"`
+	Name     string `form:"name" binding:"required"`
+	ParentID uint   `form:"parent_id"`
+}
+
+// RoleDeleteInput indica a qué rol pasan los hijos del rol que se elimina
+type RoleDeleteInput struct {
+	ParentID uint `form:"parent_id"`
 }
@@ -8,6 +8,7 @@ type SystemCreateInput struct {
 	Name        string    `form:"name" binding:"required"`
 	Description string    `form:"description"`
 	Repository  string    `form:"repository"`
+	MFARequired bool      `form:"mfa_required"`
 	Created     time.Time `form:"created" time_format:"2006-01-02"`
 	Updated     time.Time `form:"updated" time_format:"2006-01-02"`
 }
@@ -16,4 +17,30 @@ type SystemEditInput struct {
 	Name        string `form:"name" binding:"required"`
 	Description string `form:"description"`
 	Repository  string `form:"repository"`
+	MFARequired bool   `form:"mfa_required"`
+}
+
+// SystemLDAPForm configura el backend de autenticación y el directorio propio
+// del sistema. Sin URL el sistema usa el directorio global; la contraseña de
+// bind vacía conserva la guardada.
+type SystemLDAPForm struct {
+	AuthBackend        string `form:"auth_backend"`
+	URL                string `form:"ldap_url"`
+	StartTLS           bool   `form:"ldap_start_tls"`
+	InsecureSkipVerify bool   `form:"ldap_insecure_skip_verify"`
+	BindDN             string `form:"ldap_bind_dn"`
+	BindPassword       string `form:"ldap_bind_password"`
+	BaseDN             string `form:"ldap_base_dn"`
+	UserFilter         string `form:"ldap_user_filter"`
+	UsernameAttribute  string `form:"ldap_username_attribute"`
+	EmailAttribute     string `form:"ldap_email_attribute"`
+}
+
+// SystemTokenForm configura los tokens del sistema. Los campos vacíos o en 0
+// usan los valores globales; las duraciones van en segundos.
+type SystemTokenForm struct {
+	Issuer         string `form:"token_issuer"`
+	Audience       string `form:"token_audience"`
+	AccessTokenTTL int    `form:"access_token_ttl"`
+	ClockSkew      int    `form:"token_clock_skew"`
 }
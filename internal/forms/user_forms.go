@@ -16,7 +16,53 @@ type UserEditInput struct {
 
 // LoginRequest representa la estructura del JSON de entrada
 type SignInRequest struct {
-	SystemID uint64 `json:"system_id" binding:"required"`
+	SystemID uint64 `json:"system_id"` // opcional: por defecto, el sistema de la llave de API
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
+
+// SignInByEmailRequest inicia sesión con el correo del usuario
+type SignInByEmailRequest struct {
+	SystemID uint64 `json:"system_id"` // opcional: por defecto, el sistema de la llave de API
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// SignInIdentifierRequest inicia sesión con el usuario o el correo
+type SignInIdentifierRequest struct {
+	SystemID   uint64 `json:"system_id"` // opcional: por defecto, el sistema de la llave de API
+	Identifier string `json:"identifier" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+}
+
+// ChangePasswordRequest cambia la contraseña con la actual, aunque esté vencida
+type ChangePasswordRequest struct {
+	SystemID        uint64 `json:"system_id"` // opcional: por defecto, el sistema de la llave de API
+	Username        string `json:"username" binding:"required"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// MFASignInRequest completa el desafío de segundo factor con un código TOTP o
+// con un código de recuperación
+type MFASignInRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// MFAEnrollRequest inicia la inscripción; sin challenge_token se usa el access
+// token de la cabecera Authorization
+type MFAEnrollRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+type MFAConfirmRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// UserDirectoryLinkForm vincula un usuario con una entrada del directorio
+type UserDirectoryLinkForm struct {
+	Directory string `form:"directory" binding:"required"`
+	Login     string `form:"login" binding:"required"`
+}
@@ -0,0 +1,50 @@
+package accessgrpc
+
+import (
+	"accessv2/pkg/accesspb"
+	"accessv2/pkg/middleware"
+	"context"
+	"log"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type systemIDKey struct{}
+
+// NewGRPCServer registra el servicio y exige en cada llamada una llave de API
+// vigente en los metadatos x-api-key, como APIKeyRequired en la API HTTP
+func NewGRPCServer(server *AccessServer, apiKeys middleware.APIKeyValidator, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.UnaryInterceptor(apiKeyInterceptor(apiKeys)))
+	grpcServer := grpc.NewServer(opts...)
+	accesspb.RegisterAccessServiceServer(grpcServer, server)
+	return grpcServer
+}
+
+func apiKeyInterceptor(apiKeys middleware.APIKeyValidator) grpc.UnaryServerInterceptor {
+	header := strings.ToLower(middleware.APIKeyHeader)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(header)
+		if len(values) == 0 || values[0] == "" {
+			return nil, status.Error(codes.Unauthenticated, "Missing "+header)
+		}
+
+		id, err := apiKeys.ValidateAPIKey(values[0])
+		if err != nil {
+			log.Printf("Unauthorized gRPC access attempt from %s: %v", clientIP(ctx), err)
+			return nil, status.Error(codes.Unauthenticated, "Invalid or revoked "+header)
+		}
+
+		return handler(context.WithValue(ctx, systemIDKey{}, uint64(id)), req)
+	}
+}
+
+// systemID devuelve el sistema autenticado por la llave de API
+func systemID(ctx context.Context) uint64 {
+	id, _ := ctx.Value(systemIDKey{}).(uint64)
+	return id
+}
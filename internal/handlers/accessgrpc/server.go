@@ -0,0 +1,340 @@
+// Package accessgrpc expone sobre gRPC el inicio de sesión, la validación de
+// tokens y la verificación de permisos, con los mismos servicios que /api/v1
+package accessgrpc
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/responses"
+	"accessv2/internal/services"
+	"accessv2/pkg/accesspb"
+	"accessv2/pkg/middleware"
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type AccessServer struct {
+	accesspb.UnimplementedAccessServiceServer
+	userService           *services.UserService
+	userPermissionService *services.UserPermissionService
+	tokenService          *services.TokenService
+	authzService          *services.AuthzService
+	mfaService            *services.MFAService
+}
+
+func NewAccessServer(userService *services.UserService, userPermissionService *services.UserPermissionService, tokenService *services.TokenService, authzService *services.AuthzService, mfaService *services.MFAService) *AccessServer {
+	return &AccessServer{
+		userService:           userService,
+		userPermissionService: userPermissionService,
+		tokenService:          tokenService,
+		authzService:          authzService,
+		mfaService:            mfaService,
+	}
+}
+
+// SignIn responde igual que POST /api/v1/users/sign-in; los errores se
+// traducen a códigos de gRPC
+func (s *AccessServer) SignIn(ctx context.Context, req *accesspb.SignInRequest) (*accesspb.SignInResponse, error) {
+	if strings.TrimSpace(req.GetIdentifier()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "identifier es requerido")
+	}
+	if strings.TrimSpace(req.GetPassword()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "password es requerido")
+	}
+
+	kind := services.IdentifierAny
+	switch req.GetIdentifierType() {
+	case accesspb.IdentifierType_IDENTIFIER_TYPE_USERNAME:
+		kind = services.IdentifierUsername
+	case accesspb.IdentifierType_IDENTIFIER_TYPE_EMAIL:
+		kind = services.IdentifierEmail
+	}
+
+	userWithAccess, challenge, err := s.userService.ValidateBySystemIdentifier(systemID(ctx), kind, req.GetIdentifier(), req.GetPassword(), endUserIP(ctx))
+	if err != nil {
+		return nil, signInError(ctx, err)
+	}
+
+	if challenge != nil {
+		return &accesspb.SignInResponse{Mfa: &accesspb.MFAChallenge{
+			ChallengeToken:     challenge.ChallengeToken,
+			ExpiresIn:          challenge.ExpiresIn,
+			EnrollmentRequired: challenge.EnrollmentRequired,
+		}}, nil
+	}
+
+	return toSignInResponse(userWithAccess, nil), nil
+}
+
+// CompleteMFA responde igual que POST /api/v1/users/sign-in/mfa: los códigos
+// incorrectos cuentan como intentos fallidos del usuario
+func (s *AccessServer) CompleteMFA(ctx context.Context, req *accesspb.CompleteMFARequest) (*accesspb.SignInResponse, error) {
+	if strings.TrimSpace(req.GetChallengeToken()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "challenge_token es requerido")
+	}
+	if strings.TrimSpace(req.GetCode()) == "" && strings.TrimSpace(req.GetRecoveryCode()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "code o recovery_code es requerido")
+	}
+
+	userWithAccess, recoveryCodes, err := s.mfaService.CompleteSignIn(req.GetChallengeToken(), systemID(ctx), req.GetCode(), req.GetRecoveryCode())
+	if err != nil {
+		return nil, mfaError(ctx, err)
+	}
+	return toSignInResponse(userWithAccess, recoveryCodes), nil
+}
+
+// ValidateToken responde igual que POST /api/v1/token/introspect
+func (s *AccessServer) ValidateToken(ctx context.Context, req *accesspb.ValidateTokenRequest) (*accesspb.ValidateTokenResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token es requerido")
+	}
+
+	result, err := s.tokenService.Introspect(req.GetToken(), req.GetTokenTypeHint())
+	if err != nil {
+		log.Printf("Error al validar el token por gRPC: %v", err)
+		return nil, status.Error(codes.Internal, "Error al validar el token")
+	}
+	// Los tokens de otros sistemas se reportan como inactivos
+	if !result.Active || result.SystemID != systemID(ctx) {
+		return &accesspb.ValidateTokenResponse{Active: false}, nil
+	}
+
+	response := &accesspb.ValidateTokenResponse{
+		Active:    true,
+		TokenType: result.TokenType,
+		Sub:       result.Sub,
+		Username:  result.Username,
+		ClientId:  result.ClientID,
+		SystemId:  result.SystemID,
+		Exp:       result.Exp,
+		Iat:       result.Iat,
+		Iss:       result.Iss,
+		Aud:       result.Aud,
+		Jti:       result.JTI,
+		Roles:     toRoles(result.Roles),
+	}
+	if result.Act != nil {
+		response.Act = &accesspb.Actor{
+			Sub:             result.Act.Sub,
+			Username:        result.Act.Username,
+			ImpersonationId: uint64(result.Act.ImpersonationID),
+		}
+	}
+	return response, nil
+}
+
+// CheckPermission responde igual que POST /api/v1/authz/check
+func (s *AccessServer) CheckPermission(ctx context.Context, req *accesspb.CheckPermissionRequest) (*accesspb.CheckPermissionResponse, error) {
+	match := services.AuthzMatchAll
+	if req.GetMatch() == accesspb.Match_MATCH_ANY {
+		match = services.AuthzMatchAny
+	}
+
+	result, err := s.authzService.Check(systemID(ctx), uint(req.GetUserId()), req.GetUsername(), req.GetPermissions(), match)
+	if err != nil {
+		return nil, authzError(err)
+	}
+
+	return &accesspb.CheckPermissionResponse{
+		UserId:  uint64(result.UserID),
+		Allowed: result.Allowed,
+		Reason:  result.Reason,
+		Roles:   toRoleRefs(result.Roles),
+		Missing: result.Missing,
+	}, nil
+}
+
+// CheckPermissions responde igual que POST /api/v1/authz/check/batch
+func (s *AccessServer) CheckPermissions(ctx context.Context, req *accesspb.CheckPermissionsRequest) (*accesspb.CheckPermissionsResponse, error) {
+	result, err := s.authzService.CheckBatch(systemID(ctx), uint(req.GetUserId()), req.GetUsername(), req.GetPermissions())
+	if err != nil {
+		return nil, authzError(err)
+	}
+
+	decisions := make([]*accesspb.PermissionDecision, 0, len(result.Results))
+	for _, decision := range result.Results {
+		decisions = append(decisions, &accesspb.PermissionDecision{
+			Permission: decision.Permission,
+			Allowed:    decision.Allowed,
+			Roles:      toRoleRefs(decision.Roles),
+		})
+	}
+
+	return &accesspb.CheckPermissionsResponse{
+		UserId:  uint64(result.UserID),
+		Reason:  result.Reason,
+		Results: decisions,
+	}, nil
+}
+
+// ListEffectivePermissions devuelve los roles con permisos asignados al
+// usuario. Un usuario inactivo no tiene permisos vigentes.
+func (s *AccessServer) ListEffectivePermissions(ctx context.Context, req *accesspb.ListEffectivePermissionsRequest) (*accesspb.ListEffectivePermissionsResponse, error) {
+	sysID := systemID(ctx)
+	user, err := s.authzService.FindUser(sysID, uint(req.GetUserId()), req.GetUsername())
+	if err != nil {
+		return nil, authzError(err)
+	}
+
+	response := &accesspb.ListEffectivePermissionsResponse{
+		UserId:      uint64(user.ID),
+		Roles:       []*accesspb.Role{},
+		Permissions: []string{},
+	}
+	if !user.Activated {
+		response.Reason = services.AuthzReasonUserInactive
+		return response, nil
+	}
+
+	roles, err := s.userPermissionService.GetEffectivePermissions(sysID, uint64(user.ID))
+	if err != nil {
+		log.Printf("Error al listar los permisos del usuario %d por gRPC: %v", user.ID, err)
+		return nil, status.Error(codes.Internal, "Error al listar los permisos")
+	}
+
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		response.Roles = append(response.Roles, toDomainRole(role))
+		for _, permission := range role.Permissions {
+			if !seen[permission.Name] {
+				seen[permission.Name] = true
+				response.Permissions = append(response.Permissions, permission.Name)
+			}
+		}
+	}
+	return response, nil
+}
+
+// signInError traduce los errores del inicio de sesión como lo hace la API
+// HTTP. Un ingreso bloqueado lleva los segundos de espera en retry-after.
+func signInError(ctx context.Context, err error) error {
+	var blocked *services.SignInBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		retryAfter := int64(math.Ceil(blocked.RetryAfter.Seconds()))
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.FormatInt(retryAfter, 10), "sign-in-code", blocked.Code))
+		return status.Error(codes.ResourceExhausted, blocked.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound),
+		errors.Is(err, services.ErrInvalidUserCredentials),
+		strings.Contains(err.Error(), "credenciales inválidas"),
+		strings.Contains(err.Error(), "no encontrado"):
+		return status.Error(codes.Unauthenticated, "Credenciales inválidas")
+	case strings.Contains(err.Error(), "no activo"):
+		return status.Error(codes.PermissionDenied, "Usuario no activo")
+	case errors.Is(err, services.ErrPasswordExpired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	log.Printf("Error en el inicio de sesión por gRPC: %v", err)
+	return status.Error(codes.Internal, "Error al iniciar sesión")
+}
+
+// mfaError traduce los errores del segundo factor como lo hace la API HTTP
+func mfaError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode),
+		errors.Is(err, services.ErrInvalidMFAChallenge),
+		errors.Is(err, services.ErrMFANotPending):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, services.ErrUserNotActive):
+		return status.Error(codes.PermissionDenied, "Usuario no activo")
+	}
+	var blocked *services.SignInBlockedError
+	if errors.As(err, &blocked) {
+		return signInError(ctx, err)
+	}
+	log.Printf("Error al completar el segundo factor por gRPC: %v", err)
+	return status.Error(codes.Internal, "Error al verificar el código")
+}
+
+func authzError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrAuthzUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrAuthzUserRequired),
+		errors.Is(err, services.ErrAuthzPermissionRequired),
+		errors.Is(err, services.ErrAuthzTooManyPermissions),
+		errors.Is(err, services.ErrAuthzInvalidMatch):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	log.Printf("Error al verificar permisos por gRPC: %v", err)
+	return status.Error(codes.Internal, "Error al verificar los permisos")
+}
+
+func toSignInResponse(userWithAccess responses.UserWithAccess, recoveryCodes []string) *accesspb.SignInResponse {
+	return &accesspb.SignInResponse{
+		User: &accesspb.User{
+			Id:       uint64(userWithAccess.User.ID),
+			Username: userWithAccess.User.Username,
+			Email:    userWithAccess.User.Email,
+		},
+		AccessToken:   userWithAccess.Token,
+		RefreshToken:  userWithAccess.RefreshToken,
+		ExpiresIn:     userWithAccess.ExpiresIn,
+		Roles:         toRoles(userWithAccess.Roles),
+		RecoveryCodes: recoveryCodes,
+	}
+}
+
+func toRoles(roles []*responses.RoleAccess) []*accesspb.Role {
+	result := make([]*accesspb.Role, 0, len(roles))
+	for _, role := range roles {
+		permissions := make([]*accesspb.Permission, 0, len(role.Permissions))
+		for _, permission := range role.Permissions {
+			permissions = append(permissions, &accesspb.Permission{Id: uint64(permission.ID), Name: permission.Name})
+		}
+		result = append(result, &accesspb.Role{Id: uint64(role.ID), Name: role.Name, Permissions: permissions})
+	}
+	return result
+}
+
+func toDomainRole(role domain.RoleWithPermissions) *accesspb.Role {
+	permissions := make([]*accesspb.Permission, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		permissions = append(permissions, &accesspb.Permission{Id: uint64(permission.ID), Name: permission.Name})
+	}
+	return &accesspb.Role{Id: uint64(role.ID), Name: role.Name, Permissions: permissions}
+}
+
+func toRoleRefs(roles []responses.AuthzRole) []*accesspb.RoleRef {
+	result := make([]*accesspb.RoleRef, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, &accesspb.RoleRef{Id: uint64(role.ID), Name: role.Name})
+	}
+	return result
+}
+
+// endUserIP es la IP del usuario final que el sistema informa en los metadatos
+// x-end-user-ip. La IP de la conexión es la del backend del sistema, así que
+// sin ese dato no se cuentan intentos fallidos por IP.
+func endUserIP(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(strings.ToLower(middleware.EndUserIPHeader))
+	if len(values) == 0 {
+		return ""
+	}
+	return middleware.ParseEndUserIP(values[0])
+}
+
+// clientIP es la IP de la conexión, para los registros
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
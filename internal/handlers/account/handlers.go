@@ -0,0 +1,251 @@
+// internal/handlers/account/handlers.go
+package account
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"accessv2/internal/forms"
+	"accessv2/internal/responses"
+	"accessv2/internal/services"
+	"accessv2/pkg/middleware"
+	"accessv2/pkg/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Mensaje único de la solicitud de recuperación, exista o no la cuenta
+const forgotPasswordMessage = "Si la cuenta existe, enviamos un enlace para restablecer la contraseña a su correo"
+
+type AccountHandler struct {
+	service *services.AccountService
+}
+
+func NewAccountHandler(service *services.AccountService) *AccountHandler {
+	return &AccountHandler{service: service}
+}
+
+// ActivateHandler muestra (GET) la confirmación de activación y activa la
+// cuenta (POST). La activación no ocurre en el GET para que los clientes de
+// correo que abren los enlaces por adelantado no consuman la llave.
+func (h *AccountHandler) ActivateHandler(c *gin.Context) {
+	key := c.Param("key")
+
+	if c.Request.Method == http.MethodPost {
+		user, err := h.service.Activate(key)
+		if err != nil {
+			h.renderActivationError(c, err)
+			return
+		}
+		h.renderResult(c, http.StatusOK, "Cuenta activada", "La cuenta "+user.Username+" ya está activa. Puedes iniciar sesión en tus sistemas.")
+		return
+	}
+
+	user, err := h.service.GetPendingActivation(key)
+	if err != nil {
+		h.renderActivationError(c, err)
+		return
+	}
+
+	globals, _ := c.Get("globals")
+	csrfToken, _ := c.Get("csrf_token")
+	c.HTML(http.StatusOK, "account/activate", gin.H{
+		"title":     "Activar Cuenta",
+		"globals":   globals,
+		"csrfToken": csrfToken,
+		"user":      user,
+		"key":       key,
+		"styles":    []string{"css/auth"},
+		"scripts":   []string{},
+	})
+}
+
+// ForgotPasswordHandler muestra (GET) y procesa (POST) la solicitud del
+// enlace de recuperación de contraseña
+func (h *AccountHandler) ForgotPasswordHandler(c *gin.Context) {
+	session := sessions.Default(c)
+
+	if c.Request.Method == http.MethodPost {
+		var form forms.ForgotPasswordRequest
+		if err := c.ShouldBind(&form); err != nil {
+			session.AddFlash("Ingresa tu usuario o correo electrónico", "account_error")
+			session.Save()
+			c.Redirect(http.StatusFound, "/forgot-password")
+			return
+		}
+
+		if err := h.service.RequestPasswordReset(0, form.Identifier, ""); err != nil {
+			log.Printf("Error al solicitar la recuperación de contraseña: %v", err)
+		}
+
+		session.AddFlash(forgotPasswordMessage, "account_success")
+		session.Save()
+		c.Redirect(http.StatusFound, "/forgot-password")
+		return
+	}
+
+	flashError := utils.FirstFlashOrEmpty(session.Flashes("account_error"))
+	flashSuccess := utils.FirstFlashOrEmpty(session.Flashes("account_success"))
+	session.Save()
+
+	globals, _ := c.Get("globals")
+	csrfToken, _ := c.Get("csrf_token")
+	c.HTML(http.StatusOK, "account/forgot-password", gin.H{
+		"title":         "Recuperar Contraseña",
+		"globals":       globals,
+		"csrfToken":     csrfToken,
+		"flash_error":   flashError,
+		"flash_success": flashSuccess,
+		"styles":        []string{"css/auth"},
+		"scripts":       []string{},
+	})
+}
+
+// ResetPasswordHandler muestra (GET) y procesa (POST) el formulario para
+// elegir una nueva contraseña desde el enlace de recuperación
+func (h *AccountHandler) ResetPasswordHandler(c *gin.Context) {
+	key := c.Param("key")
+	session := sessions.Default(c)
+
+	if c.Request.Method == http.MethodPost {
+		var form forms.ResetPasswordForm
+		if err := c.ShouldBind(&form); err != nil {
+			h.flashResetError(c, key, "Ingresa y confirma la nueva contraseña")
+			return
+		}
+		if form.Password != form.PasswordConfirm {
+			h.flashResetError(c, key, "Las contraseñas no coinciden")
+			return
+		}
+
+		user, err := h.service.ResetPassword(key, form.Password)
+		if err != nil {
+			if services.IsPasswordRejected(err) {
+				h.flashResetError(c, key, err.Error())
+				return
+			}
+			h.renderResetError(c, err)
+			return
+		}
+
+		h.renderResult(c, http.StatusOK, "Contraseña actualizada", "La contraseña de "+user.Username+" fue actualizada y se cerraron sus sesiones abiertas. Ya puedes iniciar sesión.")
+		return
+	}
+
+	user, err := h.service.GetPendingReset(key)
+	if err != nil {
+		h.renderResetError(c, err)
+		return
+	}
+
+	flashError := utils.FirstFlashOrEmpty(session.Flashes("account_error"))
+	session.Save()
+
+	globals, _ := c.Get("globals")
+	csrfToken, _ := c.Get("csrf_token")
+	c.HTML(http.StatusOK, "account/reset-password", gin.H{
+		"title":       "Restablecer Contraseña",
+		"globals":     globals,
+		"csrfToken":   csrfToken,
+		"user":        user,
+		"key":         key,
+		"flash_error": flashError,
+		"styles":      []string{"css/auth"},
+		"scripts":     []string{},
+	})
+}
+
+// APIForgotPasswordHandler solicita el enlace de recuperación para un usuario
+// del sistema de la llave de API. Responde igual exista o no la cuenta.
+func (h *AccountHandler) APIForgotPasswordHandler(c *gin.Context) {
+	var req forms.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.AccountResponse{
+			Success: false,
+			Error:   "Datos de entrada inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(middleware.APISystemID(c), req.Identifier, req.ResetURL); err != nil {
+		if errors.Is(err, services.ErrInvalidResetURL) {
+			c.JSON(http.StatusBadRequest, responses.AccountResponse{Success: false, Error: err.Error()})
+			return
+		}
+		log.Printf("Error al solicitar la recuperación de contraseña: %v", err)
+	}
+
+	c.JSON(http.StatusAccepted, responses.AccountResponse{
+		Success: true,
+		Message: forgotPasswordMessage,
+	})
+}
+
+// APIResetPasswordHandler establece la nueva contraseña con la llave recibida por correo
+func (h *AccountHandler) APIResetPasswordHandler(c *gin.Context) {
+	var req forms.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.AccountResponse{
+			Success: false,
+			Error:   "Datos de entrada inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	if _, err := h.service.ResetPassword(req.Key, req.Password); err != nil {
+		statusCode := http.StatusInternalServerError
+		errorMsg := "Error al restablecer la contraseña"
+		if errors.Is(err, services.ErrInvalidResetKey) || errors.Is(err, services.ErrResetKeyExpired) {
+			statusCode = http.StatusBadRequest
+			errorMsg = err.Error()
+		} else if services.IsPasswordRejected(err) {
+			statusCode = http.StatusUnprocessableEntity
+			errorMsg = err.Error()
+		} else {
+			log.Printf("Error al restablecer la contraseña: %v", err)
+		}
+		c.JSON(statusCode, responses.AccountResponse{Success: false, Error: errorMsg})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.AccountResponse{
+		Success: true,
+		Message: "Contraseña actualizada; se revocaron las sesiones abiertas del usuario",
+	})
+}
+
+func (h *AccountHandler) flashResetError(c *gin.Context, key, message string) {
+	session := sessions.Default(c)
+	session.AddFlash(message, "account_error")
+	session.Save()
+	c.Redirect(http.StatusFound, "/reset-password/"+key)
+}
+
+func (h *AccountHandler) renderResetError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidResetKey) || errors.Is(err, services.ErrResetKeyExpired) {
+		h.renderResult(c, http.StatusBadRequest, "No se pudo restablecer la contraseña", err.Error()+". Puedes solicitar un nuevo enlace.")
+		return
+	}
+	log.Printf("Error al restablecer la contraseña: %v", err)
+	h.renderResult(c, http.StatusInternalServerError, "No se pudo restablecer la contraseña", "Ocurrió un error inesperado, inténtalo nuevamente.")
+}
+
+func (h *AccountHandler) renderActivationError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidActivationKey) || errors.Is(err, services.ErrActivationKeyExpired) {
+		h.renderResult(c, http.StatusBadRequest, "No se pudo activar la cuenta", err.Error()+". Solicita un nuevo enlace al administrador.")
+		return
+	}
+	log.Printf("Error al activar la cuenta: %v", err)
+	h.renderResult(c, http.StatusInternalServerError, "No se pudo activar la cuenta", "Ocurrió un error inesperado, inténtalo nuevamente.")
+}
+
+func (h *AccountHandler) renderResult(c *gin.Context, status int, title, message string) {
+	c.HTML(status, "account/result", gin.H{
+		"title":   title,
+		"globals": c.MustGet("globals"),
+		"message": message,
+		"styles":  []string{"css/common"},
+	})
+}
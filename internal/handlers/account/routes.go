@@ -0,0 +1,24 @@
+package account
+
+import (
+	"accessv2/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAccountRoutes(r *gin.Engine, handler *AccountHandler, apiKeys middleware.APIKeyValidator) {
+	// páginas públicas del usuario final
+	r.GET("/activate/:key", handler.ActivateHandler)
+	r.POST("/activate/:key", handler.ActivateHandler)
+	r.GET("/forgot-password", handler.ForgotPasswordHandler)
+	r.POST("/forgot-password", handler.ForgotPasswordHandler)
+	r.GET("/reset-password/:key", handler.ResetPasswordHandler)
+	r.POST("/reset-password/:key", handler.ResetPasswordHandler)
+
+	// apis para que los sistemas integren la recuperación en sus propias páginas
+	passwordGroup := r.Group("/api/v1/users/password", middleware.APIKeyRequired(apiKeys))
+	{
+		passwordGroup.POST("/forgot", handler.APIForgotPasswordHandler)
+		passwordGroup.POST("/reset", handler.APIResetPasswordHandler)
+	}
+}
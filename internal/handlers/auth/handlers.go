@@ -2,12 +2,19 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 
+	"accessv2/internal/domain"
 	"accessv2/internal/forms"
 	"accessv2/internal/services"
 
 	"accessv2/pkg/middleware"
+	"accessv2/pkg/sessionstore"
 	"accessv2/pkg/utils"
 
 	"github.com/gin-contrib/sessions"
@@ -15,11 +22,12 @@ import (
 )
 
 type AuthHandler struct {
-	authService *services.AuthService
+	authService    *services.AuthService
+	sessionService *services.AdminSessionService
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *services.AuthService, sessionService *services.AdminSessionService) *AuthHandler {
+	return &AuthHandler{authService: authService, sessionService: sessionService}
 }
 
 func (h *AuthHandler) SignIn(c *gin.Context) {
@@ -53,9 +61,14 @@ func (h *AuthHandler) SignIn(c *gin.Context) {
 		return
 	}
 
-	isValid, err := h.authService.Authenticate(form.Username, form.Password)
-	if err != nil || !isValid {
-		session.AddFlash("Usuario o contraseña incorrectos", "error")
+	admin, err := h.authService.Authenticate(form.Username, form.Password, c.ClientIP())
+	if err != nil {
+		message := "Usuario o contraseña incorrectos"
+		var blocked *services.SignInBlockedError
+		if errors.As(err, &blocked) {
+			message = blocked.Error()
+		}
+		session.AddFlash(message, "error")
 		session.Save()
 		c.Redirect(http.StatusFound, "/sign-in") // Redirige en lugar de renderizar
 		return
@@ -63,9 +76,16 @@ func (h *AuthHandler) SignIn(c *gin.Context) {
 
 	// Login exitoso
 	session.Set("IsAuthenticated", true)
-	session.Set("Username", form.Username)
-	session.Set("UserID", "1")
-	session.Save()
+	session.Set("Username", admin.Username)
+	session.Set("UserID", int(admin.ID))
+	if err := session.Save(); err != nil {
+		log.Printf("No se pudo guardar la sesión del administrador %d: %v", admin.ID, err)
+		session.AddFlash("No se pudo iniciar la sesión, intenta de nuevo", "error")
+		session.Save()
+		c.Redirect(http.StatusFound, "/sign-in")
+		return
+	}
+	h.sessionService.AttachClient(session.ID(), c.ClientIP(), c.Request.UserAgent())
 	c.Redirect(http.StatusFound, "/")
 }
 
@@ -112,3 +132,98 @@ func (h *AuthHandler) Session(c *gin.Context) {
 		// ... otros campos si los necesitas
 	})
 }
+
+// ListSessionsHandler muestra las sesiones abiertas del administrador y, a un
+// superadministrador, las de todos los administradores
+func (h *AuthHandler) ListSessionsHandler(c *gin.Context) {
+	globals, _ := c.Get("globals")
+	sessionData := c.MustGet("sessionData").(middleware.SessionData)
+	adminID := uint(sessionData.UserID)
+
+	message := utils.Message{
+		Content: c.Query("message"),
+		Type:    c.Query("type"),
+	}
+
+	mySessions, err := h.sessionService.GetAdminSessions(adminID)
+	if err != nil {
+		message = utils.Message{Content: "Error al obtener las sesiones", Type: "danger"}
+	}
+
+	isSuperAdmin, err := h.sessionService.IsSuperAdmin(adminID)
+	if err != nil {
+		log.Printf("No se pudo consultar si el administrador %d es superadministrador: %v", adminID, err)
+	}
+
+	var allSessions []domain.AdminSession
+	if isSuperAdmin {
+		if allSessions, err = h.sessionService.GetAllSessions(); err != nil {
+			message = utils.Message{Content: "Error al obtener las sesiones de los administradores", Type: "danger"}
+		}
+	}
+
+	c.HTML(http.StatusOK, "auth/sessions", gin.H{
+		"title":            "Sesiones Activas",
+		"globals":          globals,
+		"session":          sessionData,
+		"navLink":          "sessions",
+		"styles":           []string{},
+		"scripts":          []string{},
+		"message":          message,
+		"mySessions":       mySessions,
+		"allSessions":      allSessions,
+		"isSuperAdmin":     isSuperAdmin,
+		"currentTokenHash": sessionstore.HashToken(sessions.Default(c).ID()),
+	})
+}
+
+// TerminateSessionHandler cierra una sesión propia o, siendo superadministrador,
+// la de otro administrador
+func (h *AuthHandler) TerminateSessionHandler(c *gin.Context) {
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		message := "ID de sesión inválido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/sessions?message=%s&type=danger", url.QueryEscape(message)))
+		return
+	}
+
+	sessionData := c.MustGet("sessionData").(middleware.SessionData)
+	current := sessions.Default(c)
+
+	terminated, err := h.sessionService.Terminate(uint(sessionData.UserID), uint(sessionID))
+	if err != nil {
+		message := "Error al terminar la sesión"
+		if errors.Is(err, services.ErrAdminSessionNotFound) || errors.Is(err, services.ErrAdminSessionForbidden) {
+			message = err.Error()
+		}
+		c.Redirect(http.StatusFound, fmt.Sprintf("/sessions?message=%s&type=danger", url.QueryEscape(message)))
+		return
+	}
+
+	// Terminar la sesión actual equivale a cerrar sesión
+	if terminated.TokenHash == sessionstore.HashToken(current.ID()) {
+		current.Clear()
+		current.Options(sessions.Options{MaxAge: -1})
+		current.Save()
+		c.Redirect(http.StatusFound, "/sign-in")
+		return
+	}
+
+	message := "Sesión terminada exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/sessions?message=%s&type=success", url.QueryEscape(message)))
+}
+
+// TerminateOtherSessionsHandler cierra todas las sesiones del administrador salvo la actual
+func (h *AuthHandler) TerminateOtherSessionsHandler(c *gin.Context) {
+	sessionData := c.MustGet("sessionData").(middleware.SessionData)
+
+	count, err := h.sessionService.TerminateOthers(uint(sessionData.UserID), sessions.Default(c).ID())
+	if err != nil {
+		message := "Error al terminar las sesiones"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/sessions?message=%s&type=danger", url.QueryEscape(message)))
+		return
+	}
+
+	message := fmt.Sprintf("Se terminaron %d sesiones", count)
+	c.Redirect(http.StatusFound, fmt.Sprintf("/sessions?message=%s&type=success", url.QueryEscape(message)))
+}
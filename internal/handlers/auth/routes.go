@@ -11,4 +11,12 @@ func RegisterAuthRoutes(r *gin.Engine, handler *AuthHandler) {
 	r.POST("/sign-in", middleware.AuthRequiredInverse(), handler.SignIn)
 	r.GET("/sign-out", handler.SignOut)
 	r.GET("/session", middleware.AuthRequired(), handler.Session)
+
+	// Sesiones abiertas de la consola
+	sessionsGroup := r.Group("/sessions", middleware.AuthRequired())
+	{
+		sessionsGroup.GET("/", handler.ListSessionsHandler)
+		sessionsGroup.GET("/terminate-others", handler.TerminateOtherSessionsHandler)
+		sessionsGroup.GET("/:id/terminate", handler.TerminateSessionHandler)
+	}
 }
@@ -0,0 +1,276 @@
+package authz
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/forms"
+	"accessv2/internal/responses"
+	"accessv2/internal/services"
+	"accessv2/pkg/middleware"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthzHandler struct {
+	service           *services.AuthzService
+	assignmentService *services.RoleAssignmentService
+}
+
+func NewAuthzHandler(service *services.AuthzService, assignmentService *services.RoleAssignmentService) *AuthzHandler {
+	return &AuthzHandler{service: service, assignmentService: assignmentService}
+}
+
+// APICheckHandler responde si el usuario tiene el permiso, o los permisos,
+// pedidos y con qué roles
+func (h *AuthzHandler) APICheckHandler(c *gin.Context) {
+	var req forms.AuthzCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.AuthzCheckResponse{
+			Success: false,
+			Error:   "Datos de entrada inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	systemID, ok := resolveSystemID(c, req.SystemID)
+	if !ok {
+		c.JSON(http.StatusForbidden, responses.AuthzCheckResponse{
+			Success: false,
+			Error:   "system_id no corresponde a la llave de API",
+		})
+		return
+	}
+
+	permissions := req.Permissions
+	if req.Permission != "" {
+		permissions = append([]string{req.Permission}, permissions...)
+	}
+
+	result, err := h.service.Check(systemID, req.UserID, req.Username, permissions, req.Match)
+	if err != nil {
+		statusCode, message := checkError(err)
+		c.JSON(statusCode, responses.AuthzCheckResponse{
+			Success: false,
+			Message: message,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, responses.AuthzCheckResponse{
+		Success: true,
+		Data:    &result,
+	})
+}
+
+// APICheckBatchHandler verifica varios permisos de un usuario en una llamada
+// y devuelve una decisión por cada uno
+func (h *AuthzHandler) APICheckBatchHandler(c *gin.Context) {
+	var req forms.AuthzBatchCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.AuthzBatchResponse{
+			Success: false,
+			Error:   "Datos de entrada inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	systemID, ok := resolveSystemID(c, req.SystemID)
+	if !ok {
+		c.JSON(http.StatusForbidden, responses.AuthzBatchResponse{
+			Success: false,
+			Error:   "system_id no corresponde a la llave de API",
+		})
+		return
+	}
+
+	result, err := h.service.CheckBatch(systemID, req.UserID, req.Username, req.Permissions)
+	if err != nil {
+		statusCode, message := checkError(err)
+		c.JSON(statusCode, responses.AuthzBatchResponse{
+			Success: false,
+			Message: message,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, responses.AuthzBatchResponse{
+		Success: true,
+		Data:    &result,
+	})
+}
+
+// APIRoleAssignmentsHandler devuelve los roles asignados al usuario y los
+// permisos que se le quitaron
+func (h *AuthzHandler) APIRoleAssignmentsHandler(c *gin.Context) {
+	var req forms.RoleAssignmentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		assignmentResponse(c, http.StatusBadRequest, "Datos de entrada inválidos: "+err.Error())
+		return
+	}
+
+	systemID, userID, ok := h.resolveUser(c, req.SystemID, req.UserID, req.Username)
+	if !ok {
+		return
+	}
+
+	h.respondAssignments(c, systemID, userID)
+}
+
+// APIAssignRoleHandler asigna un rol completo al usuario
+func (h *AuthzHandler) APIAssignRoleHandler(c *gin.Context) {
+	h.changeRole(c, h.assignmentService.AssignRole)
+}
+
+// APIUnassignRoleHandler quita un rol asignado al usuario
+func (h *AuthzHandler) APIUnassignRoleHandler(c *gin.Context) {
+	h.changeRole(c, h.assignmentService.UnassignRole)
+}
+
+// APIExcludePermissionHandler le quita al usuario un permiso que recibe por
+// sus roles
+func (h *AuthzHandler) APIExcludePermissionHandler(c *gin.Context) {
+	h.changeExclusion(c, h.assignmentService.ExcludePermission)
+}
+
+// APIIncludePermissionHandler devuelve al usuario un permiso quitado
+func (h *AuthzHandler) APIIncludePermissionHandler(c *gin.Context) {
+	h.changeExclusion(c, h.assignmentService.IncludePermission)
+}
+
+func (h *AuthzHandler) changeRole(c *gin.Context, change func(uint64, uint, domain.Role) error) {
+	var req forms.RoleAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		assignmentResponse(c, http.StatusBadRequest, "Datos de entrada inválidos: "+err.Error())
+		return
+	}
+
+	systemID, userID, ok := h.resolveUser(c, req.SystemID, req.UserID, req.Username)
+	if !ok {
+		return
+	}
+
+	role, err := h.assignmentService.FindRole(systemID, req.RoleID, req.Role)
+	if err == nil {
+		err = change(systemID, userID, role)
+	}
+	if err != nil {
+		assignmentError(c, err)
+		return
+	}
+
+	h.respondAssignments(c, systemID, userID)
+}
+
+func (h *AuthzHandler) changeExclusion(c *gin.Context, change func(uint64, uint, domain.Permission) error) {
+	var req forms.PermissionExclusionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		assignmentResponse(c, http.StatusBadRequest, "Datos de entrada inválidos: "+err.Error())
+		return
+	}
+
+	systemID, userID, ok := h.resolveUser(c, req.SystemID, req.UserID, req.Username)
+	if !ok {
+		return
+	}
+
+	permission, err := h.assignmentService.FindPermission(systemID, req.PermissionID, req.Permission)
+	if err == nil {
+		err = change(systemID, userID, permission)
+	}
+	if err != nil {
+		assignmentError(c, err)
+		return
+	}
+
+	h.respondAssignments(c, systemID, userID)
+}
+
+// resolveUser valida el sistema de la llave y busca al usuario asociado a él
+func (h *AuthzHandler) resolveUser(c *gin.Context, requestedSystemID uint64, userID uint, username string) (uint64, uint, bool) {
+	systemID, ok := resolveSystemID(c, requestedSystemID)
+	if !ok {
+		assignmentResponse(c, http.StatusForbidden, "system_id no corresponde a la llave de API")
+		return 0, 0, false
+	}
+
+	user, err := h.service.FindUser(systemID, userID, username)
+	if err != nil {
+		assignmentError(c, err)
+		return 0, 0, false
+	}
+	return systemID, user.ID, true
+}
+
+func (h *AuthzHandler) respondAssignments(c *gin.Context, systemID uint64, userID uint) {
+	assignments, err := h.assignmentService.GetAssignments(systemID, userID)
+	if err != nil {
+		assignmentError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, responses.RoleAssignmentsResponse{
+		Success: true,
+		Data:    &assignments,
+	})
+}
+
+func assignmentResponse(c *gin.Context, statusCode int, message string) {
+	c.JSON(statusCode, responses.RoleAssignmentsResponse{
+		Success: false,
+		Error:   message,
+	})
+}
+
+func assignmentError(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	message := "Error al actualizar los roles del usuario"
+	switch {
+	case errors.Is(err, services.ErrAuthzUserNotFound):
+		statusCode, message = http.StatusNotFound, "Usuario no encontrado"
+	case errors.Is(err, services.ErrRoleAssignmentRoleNotFound):
+		statusCode, message = http.StatusNotFound, "Rol no encontrado"
+	case errors.Is(err, services.ErrRoleAssignmentPermissionNotFound):
+		statusCode, message = http.StatusNotFound, "Permiso no encontrado"
+	case errors.Is(err, services.ErrAuthzUserRequired),
+		errors.Is(err, services.ErrRoleAssignmentRoleRequired),
+		errors.Is(err, services.ErrRoleAssignmentPermissionRequired):
+		statusCode, message = http.StatusBadRequest, "Solicitud inválida"
+	default:
+		log.Printf("No se pudieron actualizar los roles del usuario: %v", err)
+	}
+	c.JSON(statusCode, responses.RoleAssignmentsResponse{
+		Success: false,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
+
+// resolveSystemID usa el sistema de la llave de API; el system_id del cuerpo
+// es opcional y, si viene, debe coincidir
+func resolveSystemID(c *gin.Context, systemID uint64) (uint64, bool) {
+	apiSystemID := middleware.APISystemID(c)
+	if systemID != 0 && systemID != apiSystemID {
+		return 0, false
+	}
+	return apiSystemID, true
+}
+
+func checkError(err error) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrAuthzUserNotFound):
+		return http.StatusNotFound, "Usuario no encontrado"
+	case errors.Is(err, services.ErrAuthzUserRequired),
+		errors.Is(err, services.ErrAuthzPermissionRequired),
+		errors.Is(err, services.ErrAuthzTooManyPermissions),
+		errors.Is(err, services.ErrAuthzInvalidMatch):
+		return http.StatusBadRequest, "Solicitud inválida"
+	}
+	return http.StatusInternalServerError, "Error al verificar los permisos"
+}
@@ -0,0 +1,28 @@
+package authz
+
+import (
+	"accessv2/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAuthzRoutes(r *gin.Engine, handler *AuthzHandler, apiKeys middleware.APIKeyValidator, managerKeys middleware.AccessManagerKeyValidator) {
+	// apis
+	authzGroup := r.Group("/api/v1/authz", middleware.APIKeyRequired(apiKeys))
+	{
+		authzGroup.POST("/check", handler.APICheckHandler)
+		authzGroup.POST("/check/batch", handler.APICheckBatchHandler)
+
+		// roles asignados y permisos quitados a cada usuario
+		authzGroup.POST("/roles", handler.APIRoleAssignmentsHandler)
+
+		// cambios: solo con una llave creada para administrar accesos
+		manageGroup := authzGroup.Group("", middleware.AccessManagerKeyRequired(managerKeys))
+		{
+			manageGroup.POST("/roles/assign", handler.APIAssignRoleHandler)
+			manageGroup.POST("/roles/unassign", handler.APIUnassignRoleHandler)
+			manageGroup.POST("/permissions/exclude", handler.APIExcludePermissionHandler)
+			manageGroup.POST("/permissions/include", handler.APIIncludePermissionHandler)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// internal/handlers/groups/handlers.go
+package groups
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"accessv2/internal/domain"
+	"accessv2/internal/forms"
+	"accessv2/internal/services"
+
+	"accessv2/pkg/middleware"
+	"accessv2/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type GroupHandler struct {
+	service       *services.GroupService
+	systemService *services.SystemService
+}
+
+func NewGroupHandler(service *services.GroupService, systemService *services.SystemService) *GroupHandler {
+	return &GroupHandler{service: service, systemService: systemService}
+}
+
+// ListGroupsHandler muestra los grupos globales y los de cada sistema
+func (h *GroupHandler) ListGroupsHandler(c *gin.Context) {
+	globals, _ := c.Get("globals")
+	sessionData := c.MustGet("sessionData").(middleware.SessionData)
+
+	message := utils.Message{
+		Content: c.Query("message"),
+		Type:    c.Query("type"),
+	}
+
+	groups, err := h.service.GetAllGroups()
+	if err != nil {
+		message = utils.Message{Content: "Error al obtener los grupos", Type: "danger"}
+	}
+
+	c.HTML(http.StatusOK, "groups/list", gin.H{
+		"title":   "Grupos de Usuarios",
+		"globals": globals,
+		"session": sessionData,
+		"navLink": "groups",
+		"styles":  []string{},
+		"scripts": []string{},
+		"message": message,
+		"groups":  groups,
+	})
+}
+
+func (h *GroupHandler) CreateGroupHandler(c *gin.Context) {
+	if c.Request.Method == http.MethodPost {
+		var input forms.GroupCreateInput
+		if err := c.ShouldBind(&input); err != nil {
+			message := "El nombre del grupo es requerido"
+			c.Redirect(http.StatusFound, fmt.Sprintf("/groups/create?message=%s&type=danger", url.QueryEscape(message)))
+			return
+		}
+
+		group, err := h.service.CreateGroup(&input)
+		if err != nil {
+			message := "Error al crear el grupo"
+			switch {
+			case errors.Is(err, services.ErrGroupNameRequired),
+				errors.Is(err, services.ErrGroupNameInUse),
+				errors.Is(err, services.ErrGroupSystemNotFound):
+				message = err.Error()
+			default:
+				log.Printf("No se pudo crear el grupo: %v", err)
+			}
+			c.Redirect(http.StatusFound, fmt.Sprintf("/groups/create?message=%s&type=danger", url.QueryEscape(message)))
+			return
+		}
+
+		message := "Grupo creado exitosamente"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/groups/%d/edit?message=%s&type=success", group.ID, url.QueryEscape(message)))
+		return
+	}
+
+	systems, err := h.systemService.GetAllSystems()
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/groups?message=%s&type=danger", url.QueryEscape("Error al buscar los sistemas")))
+		return
+	}
+
+	globals, _ := c.Get("globals")
+	sessionData := c.MustGet("sessionData").(middleware.SessionData)
+	csrfToken, _ := c.Get("csrf_token")
+
+	message := utils.Message{
+		Content: c.Query("message"),
+		Type:    c.Query("type"),
+	}
+
+	c.HTML(http.StatusOK, "groups/create", gin.H{
+		"title":     "Crear Grupo",
+		"globals":   globals,
+		"session":   sessionData,
+		"navLink":   "groups",
+		"styles":    []string{},
+		"scripts":   []string{},
+		"message":   message,
+		"csrfToken": csrfToken,
+		"systems":   systems,
+	})
+}
+
+func (h *GroupHandler) EditGroupHandler(c *gin.Context) {
+	group, ok := h.groupParam(c)
+	if !ok {
+		return
+	}
+
+	if c.Request.Method == http.MethodPost {
+		var input forms.GroupEditInput
+		if err := c.ShouldBind(&input); err != nil {
+			h.redirectToGroup(c, group.ID, "El nombre del grupo es requerido", "danger")
+			return
+		}
+
+		group.Name = input.Name
+		group.Description = input.Description
+		if err := h.service.UpdateGroup(&group); err != nil {
+			message := "Error al actualizar el grupo"
+			switch {
+			case errors.Is(err, services.ErrGroupNameRequired),
+				errors.Is(err, services.ErrGroupNameInUse):
+				message = err.Error()
+			default:
+				log.Printf("No se pudo actualizar el grupo %d: %v", group.ID, err)
+			}
+			h.redirectToGroup(c, group.ID, message, "danger")
+			return
+		}
+
+		h.redirectToGroup(c, group.ID, "Grupo actualizado exitosamente", "success")
+		return
+	}
+
+	members, err := h.service.GetMembers(uint64(group.ID))
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/groups?message=%s&type=danger", url.QueryEscape("Error al buscar los miembros del grupo")))
+		return
+	}
+
+	permissions, err := h.service.GetGrantablePermissions(group)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/groups?message=%s&type=danger", url.QueryEscape("Error al buscar los permisos del grupo")))
+		return
+	}
+
+	// Nombre del sistema para los grupos que no son globales
+	var system domain.System
+	if group.SystemID != nil {
+		if err := h.systemService.FetchSystem(uint64(*group.SystemID), &system); err != nil {
+			c.Redirect(http.StatusFound, fmt.Sprintf("/groups?message=%s&type=danger", url.QueryEscape("Error al buscar el sistema del grupo")))
+			return
+		}
+	}
+
+	globals, _ := c.Get("globals")
+	sessionData := c.MustGet("sessionData").(middleware.SessionData)
+	csrfToken, _ := c.Get("csrf_token")
+
+	message := utils.Message{
+		Content: c.Query("message"),
+		Type:    c.Query("type"),
+	}
+
+	c.HTML(http.StatusOK, "groups/edit", gin.H{
+		"title":       "Editar Grupo",
+		"globals":     globals,
+		"session":     sessionData,
+		"navLink":     "groups",
+		"styles":      []string{},
+		"scripts":     []string{},
+		"message":     message,
+		"csrfToken":   csrfToken,
+		"group":       group,
+		"system":      system,
+		"members":     members,
+		"permissions": permissions,
+	})
+}
+
+func (h *GroupHandler) DeleteGroupHandler(c *gin.Context) {
+	group, ok := h.groupParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteGroup(uint64(group.ID)); err != nil {
+		log.Printf("No se pudo eliminar el grupo %d: %v", group.ID, err)
+		c.Redirect(http.StatusFound, fmt.Sprintf("/groups?message=%s&type=danger", url.QueryEscape("Error al eliminar el grupo")))
+		return
+	}
+
+	message := "Grupo eliminado exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/groups?message=%s&type=success", url.QueryEscape(message)))
+}
+
+// AddMembersHandler agrega varios usuarios a la vez por nombre de usuario o correo
+func (h *GroupHandler) AddMembersHandler(c *gin.Context) {
+	group, ok := h.groupParam(c)
+	if !ok {
+		return
+	}
+
+	var input forms.GroupMembersInput
+	if err := c.ShouldBind(&input); err != nil {
+		h.redirectToGroup(c, group.ID, services.ErrGroupMembersRequired.Error(), "danger")
+		return
+	}
+
+	added, notFound, err := h.service.AddMembers(uint64(group.ID), input.Users)
+	if err != nil {
+		message := "Error al agregar los miembros"
+		if errors.Is(err, services.ErrGroupMembersRequired) {
+			message = err.Error()
+		} else {
+			log.Printf("No se pudieron agregar miembros al grupo %d: %v", group.ID, err)
+		}
+		h.redirectToGroup(c, group.ID, message, "danger")
+		return
+	}
+
+	if len(notFound) > 0 {
+		message := fmt.Sprintf("Usuarios agregados: %d; no se encontraron: %s", added, strings.Join(notFound, ", "))
+		h.redirectToGroup(c, group.ID, message, "warning")
+		return
+	}
+
+	h.redirectToGroup(c, group.ID, fmt.Sprintf("Usuarios agregados al grupo: %d", added), "success")
+}
+
+func (h *GroupHandler) RemoveMemberHandler(c *gin.Context) {
+	group, ok := h.groupParam(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		h.redirectToGroup(c, group.ID, "ID de usuario inválido", "danger")
+		return
+	}
+
+	if err := h.service.RemoveMember(uint64(group.ID), userID); err != nil {
+		log.Printf("No se pudo quitar al usuario %d del grupo %d: %v", userID, group.ID, err)
+		h.redirectToGroup(c, group.ID, "Error al quitar al usuario del grupo", "danger")
+		return
+	}
+
+	h.redirectToGroup(c, group.ID, "Usuario quitado del grupo", "success")
+}
+
+// PermissionsHandler reemplaza los permisos otorgados al grupo con los marcados
+func (h *GroupHandler) PermissionsHandler(c *gin.Context) {
+	group, ok := h.groupParam(c)
+	if !ok {
+		return
+	}
+
+	var permissionIDs []uint
+	for permIDStr := range c.PostFormMap("permissions") {
+		permID, err := strconv.ParseUint(permIDStr, 10, 32)
+		if err != nil {
+			h.redirectToGroup(c, group.ID, "Error al procesar los permisos", "danger")
+			return
+		}
+		permissionIDs = append(permissionIDs, uint(permID))
+	}
+
+	if err := h.service.SetPermissions(group, permissionIDs); err != nil {
+		message := "Error al guardar los permisos del grupo"
+		if errors.Is(err, services.ErrGroupPermissionScope) {
+			message = err.Error()
+		} else {
+			log.Printf("No se pudieron guardar los permisos del grupo %d: %v", group.ID, err)
+		}
+		h.redirectToGroup(c, group.ID, message, "danger")
+		return
+	}
+
+	h.redirectToGroup(c, group.ID, "Permisos del grupo actualizados", "success")
+}
+
+// groupParam carga el grupo de la URL; si no existe redirige al listado
+func (h *GroupHandler) groupParam(c *gin.Context) (domain.Group, bool) {
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/groups?message=%s&type=danger", url.QueryEscape("ID de grupo inválido")))
+		return domain.Group{}, false
+	}
+
+	var group domain.Group
+	if err := h.service.FetchGroup(groupID, &group); err != nil {
+		message := "Error al cargar el grupo"
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			message = "Grupo no encontrado"
+		}
+		c.Redirect(http.StatusFound, fmt.Sprintf("/groups?message=%s&type=danger", url.QueryEscape(message)))
+		return domain.Group{}, false
+	}
+	return group, true
+}
+
+func (h *GroupHandler) redirectToGroup(c *gin.Context, groupID uint, message, kind string) {
+	c.Redirect(http.StatusFound, fmt.Sprintf("/groups/%d/edit?message=%s&type=%s", groupID, url.QueryEscape(message), kind))
+}
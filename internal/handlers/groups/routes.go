@@ -0,0 +1,30 @@
+package groups
+
+import (
+	"accessv2/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterGroupRoutes(r *gin.Engine, handler *GroupHandler) {
+	groupsGroup := r.Group("/groups", middleware.AuthRequired())
+	{
+		groupsGroup.GET("/", handler.ListGroupsHandler)
+		groupsGroup.GET("/create", handler.CreateGroupHandler)
+		groupsGroup.POST("/create", handler.CreateGroupHandler)
+
+		groupByIDGroup := groupsGroup.Group("/:id")
+		{
+			groupByIDGroup.GET("/edit", handler.EditGroupHandler)
+			groupByIDGroup.POST("/edit", handler.EditGroupHandler)
+			groupByIDGroup.GET("/delete", handler.DeleteGroupHandler)
+
+			// miembros
+			groupByIDGroup.POST("/members", handler.AddMembersHandler)
+			groupByIDGroup.GET("/members/:user_id/delete", handler.RemoveMemberHandler)
+
+			// permisos otorgados al grupo
+			groupByIDGroup.POST("/permissions", handler.PermissionsHandler)
+		}
+	}
+}
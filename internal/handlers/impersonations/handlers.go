@@ -0,0 +1,158 @@
+// internal/handlers/impersonations/handlers.go
+package impersonations
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"accessv2/internal/forms"
+	"accessv2/internal/services"
+
+	"accessv2/pkg/middleware"
+	"accessv2/pkg/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// recentLimit es la cantidad de suplantaciones que se muestran en la auditoría
+const recentLimit = 100
+
+type ImpersonationHandler struct {
+	service *services.ImpersonationService
+}
+
+func NewImpersonationHandler(service *services.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{service: service}
+}
+
+// StartHandler registra la suplantación con su motivo y muestra el token una
+// sola vez en la auditoría
+func (h *ImpersonationHandler) StartHandler(c *gin.Context) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/users?message=%s&type=danger", systemID, url.QueryEscape("ID de usuario inválido")))
+		return
+	}
+
+	back := fmt.Sprintf("/systems/%d/users/%d", systemID, userID)
+	if origin := c.Query("origin"); origin != "" {
+		back += "?origin=" + url.QueryEscape(origin) + "&"
+	} else {
+		back += "?"
+	}
+
+	var form forms.ImpersonationForm
+	if err := c.ShouldBind(&form); err != nil {
+		message := "Indica el motivo de la suplantación"
+		c.Redirect(http.StatusFound, fmt.Sprintf("%smessage=%s&type=danger", back, url.QueryEscape(message)))
+		return
+	}
+
+	sessionData := c.MustGet("sessionData").(middleware.SessionData)
+	impersonation, token, err := h.service.Start(uint(sessionData.UserID), systemID, uint(userID), form.Reason, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		message := "Error al suplantar al usuario"
+		switch {
+		case errors.Is(err, services.ErrImpersonationForbidden),
+			errors.Is(err, services.ErrImpersonationUserUnavailable),
+			errors.Is(err, services.ErrImpersonationReasonTooShort):
+			message = err.Error()
+		default:
+			log.Printf("No se pudo suplantar al usuario %d en el sistema %d: %v", userID, systemID, err)
+		}
+		c.Redirect(http.StatusFound, fmt.Sprintf("%smessage=%s&type=danger", back, url.QueryEscape(message)))
+		return
+	}
+
+	// El token se muestra una sola vez; la barra indica la suplantación en curso
+	session := sessions.Default(c)
+	session.AddFlash(token, "impersonation_token")
+	session.Set("ImpersonationID", int(impersonation.ID))
+	session.Set("ImpersonatedUser", impersonation.Username)
+	session.Set("ImpersonatedUntil", impersonation.ExpiresAt.Unix())
+	session.Save()
+
+	message := fmt.Sprintf("Suplantando a %s hasta las %s", impersonation.Username, impersonation.ExpiresAt.Format("03:04:05 PM"))
+	c.Redirect(http.StatusFound, fmt.Sprintf("/impersonations?message=%s&type=warning", url.QueryEscape(message)))
+}
+
+// ListHandler muestra la auditoría de suplantaciones y el token recién emitido
+func (h *ImpersonationHandler) ListHandler(c *gin.Context) {
+	globals, _ := c.Get("globals")
+	sessionData := c.MustGet("sessionData").(middleware.SessionData)
+
+	message := utils.Message{
+		Content: c.Query("message"),
+		Type:    c.Query("type"),
+	}
+
+	session := sessions.Default(c)
+	newToken := utils.FirstFlashOrEmpty(session.Flashes("impersonation_token"))
+	session.Save()
+
+	impersonations, err := h.service.GetRecent(uint(sessionData.UserID), recentLimit)
+	if err != nil {
+		message = utils.Message{Content: "Error al obtener las suplantaciones", Type: "danger"}
+	}
+
+	c.HTML(http.StatusOK, "impersonations/list", gin.H{
+		"title":          "Suplantaciones",
+		"globals":        globals,
+		"session":        sessionData,
+		"navLink":        "impersonations",
+		"styles":         []string{},
+		"scripts":        []string{},
+		"message":        message,
+		"impersonations": impersonations,
+		"newToken":       newToken,
+		"now":            time.Now(),
+	})
+}
+
+// EndHandler termina la suplantación y revoca su token
+func (h *ImpersonationHandler) EndHandler(c *gin.Context) {
+	impersonationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		message := "ID de suplantación inválido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/impersonations?message=%s&type=danger", url.QueryEscape(message)))
+		return
+	}
+
+	sessionData := c.MustGet("sessionData").(middleware.SessionData)
+	ended, err := h.service.End(uint(sessionData.UserID), uint(impersonationID))
+	if err != nil {
+		message := "Error al terminar la suplantación"
+		if errors.Is(err, services.ErrImpersonationNotFound) || errors.Is(err, services.ErrImpersonationEndForbidden) {
+			message = err.Error()
+		}
+		c.Redirect(http.StatusFound, fmt.Sprintf("/impersonations?message=%s&type=danger", url.QueryEscape(message)))
+		return
+	}
+
+	if sessionData.ImpersonationID == int(ended.ID) {
+		clearImpersonation(sessions.Default(c))
+	}
+
+	message := "Suplantación terminada exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/impersonations?message=%s&type=success", url.QueryEscape(message)))
+}
+
+// clearImpersonation quita el indicador de la barra
+func clearImpersonation(session sessions.Session) {
+	session.Delete("ImpersonationID")
+	session.Delete("ImpersonatedUser")
+	session.Delete("ImpersonatedUntil")
+	session.Save()
+}
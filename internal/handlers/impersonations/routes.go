@@ -0,0 +1,19 @@
+package impersonations
+
+import (
+	"accessv2/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterImpersonationRoutes(r *gin.Engine, handler *ImpersonationHandler) {
+	// Suplantar a un usuario desde la pantalla de sus accesos en el sistema
+	r.POST("/systems/:id/users/:user_id/impersonate", middleware.AuthRequired(), handler.StartHandler)
+
+	// Auditoría de suplantaciones
+	impersonationsGroup := r.Group("/impersonations", middleware.AuthRequired())
+	{
+		impersonationsGroup.GET("/", handler.ListHandler)
+		impersonationsGroup.GET("/:id/end", handler.EndHandler)
+	}
+}
@@ -0,0 +1,395 @@
+// internal/handlers/oauth/handlers.go
+package oauth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"accessv2/internal/domain"
+	"accessv2/internal/forms"
+	"accessv2/internal/responses"
+	"accessv2/internal/services"
+	"accessv2/pkg/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthHandler struct {
+	service *services.OAuthService
+}
+
+func NewOAuthHandler(service *services.OAuthService) *OAuthHandler {
+	return &OAuthHandler{service: service}
+}
+
+// DiscoveryHandler publica el documento de descubrimiento; sin un algoritmo
+// asimétrico OpenID Connect está deshabilitado y responde 404
+func (h *OAuthHandler) DiscoveryHandler(c *gin.Context) {
+	discovery, err := h.service.Discovery()
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.OAuthErrorResponse{
+			Error:            services.OAuthInvalidRequest,
+			ErrorDescription: err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, discovery)
+}
+
+// Claves de sesión del desafío de segundo factor pendiente
+const (
+	sessionMFAChallenge  = "oauth_mfa_challenge"
+	sessionMFAEnrollment = "oauth_mfa_enrollment"
+)
+
+// Authorize muestra el inicio de sesión del usuario final (GET) y, con
+// credenciales válidas, redirige al cliente con el código de autorización (POST)
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	system, req, ok := h.bindAuthorizationRequest(c)
+	if !ok {
+		return
+	}
+
+	session := sessions.Default(c)
+
+	if c.Request.Method == http.MethodGet {
+		// Recuperar flashes al mostrar el formulario
+		flashes := session.Flashes("oauth_error")
+		session.Save()
+
+		h.renderSignIn(c, system, req, utils.FirstFlashOrEmpty(flashes))
+		return
+	}
+
+	var form forms.AuthorizeLoginForm
+	if err := c.ShouldBind(&form); err != nil {
+		session.AddFlash("Por favor completa todos los campos", "oauth_error")
+		session.Save()
+		c.Redirect(http.StatusFound, authorizeURL(req))
+		return
+	}
+
+	redirectURL, challenge, err := h.service.Authorize(system, req, form.Username, form.Password, c.ClientIP())
+	if err != nil {
+		message := "Error al iniciar sesión"
+		var blocked *services.SignInBlockedError
+		switch {
+		case errors.As(err, &blocked):
+			message = blocked.Error()
+		case errors.Is(err, services.ErrInvalidUserCredentials):
+			message = "Usuario o contraseña incorrectos"
+		case errors.Is(err, services.ErrUserNotActive):
+			message = "Usuario no activo"
+		case errors.Is(err, services.ErrPasswordExpired):
+			message = "Tu contraseña venció; restablécela con «¿Olvidaste tu contraseña?» para continuar"
+		default:
+			log.Printf("Error al autorizar en el sistema %d: %v", system.ID, err)
+		}
+		session.AddFlash(message, "oauth_error")
+		session.Save()
+		c.Redirect(http.StatusFound, authorizeURL(req)) // Redirige en lugar de renderizar
+		return
+	}
+
+	// Falta el segundo factor: el desafío queda en la sesión hasta completarlo
+	if challenge != nil {
+		session.Set(sessionMFAChallenge, challenge.ChallengeToken)
+		session.Set(sessionMFAEnrollment, challenge.EnrollmentRequired)
+		session.Save()
+		c.Redirect(http.StatusFound, authorizeMFAURL(req))
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// AuthorizeMFA pide el código de la aplicación autenticadora (o la inscribe si
+// el sistema lo exige) y, verificado, redirige al cliente con el código de
+// autorización
+func (h *OAuthHandler) AuthorizeMFA(c *gin.Context) {
+	system, req, ok := h.bindAuthorizationRequest(c)
+	if !ok {
+		return
+	}
+
+	session := sessions.Default(c)
+	challengeToken, _ := session.Get(sessionMFAChallenge).(string)
+	enrollment, _ := session.Get(sessionMFAEnrollment).(bool)
+	if challengeToken == "" {
+		session.AddFlash("La verificación expiró, vuelve a iniciar sesión", "oauth_error")
+		session.Save()
+		c.Redirect(http.StatusFound, authorizeURL(req))
+		return
+	}
+
+	if c.Request.Method == http.MethodGet {
+		flashes := session.Flashes("oauth_error")
+		session.Save()
+
+		var setup *responses.MFAEnrollment
+		if enrollment {
+			data, err := h.service.StartMFAEnrollment(system, challengeToken)
+			if err != nil {
+				h.restartSignIn(c, req, err)
+				return
+			}
+			setup = &data
+		}
+
+		h.renderMFA(c, system, req, setup, utils.FirstFlashOrEmpty(flashes))
+		return
+	}
+
+	var form forms.AuthorizeMFAForm
+	if err := c.ShouldBind(&form); err != nil || (form.Code == "" && form.RecoveryCode == "") {
+		session.AddFlash("Ingresa el código de verificación", "oauth_error")
+		session.Save()
+		c.Redirect(http.StatusFound, authorizeMFAURL(req))
+		return
+	}
+
+	redirectURL, recoveryCodes, err := h.service.AuthorizeMFA(system, req, challengeToken, form.Code, form.RecoveryCode)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMFACode) {
+			session.AddFlash("Código de verificación incorrecto", "oauth_error")
+			session.Save()
+			c.Redirect(http.StatusFound, authorizeMFAURL(req))
+			return
+		}
+		h.restartSignIn(c, req, err)
+		return
+	}
+
+	session.Delete(sessionMFAChallenge)
+	session.Delete(sessionMFAEnrollment)
+	session.Save()
+
+	// Recién inscrito: se muestran los códigos de recuperación una única vez
+	if len(recoveryCodes) > 0 {
+		c.HTML(http.StatusOK, "oauth/recovery-codes", gin.H{
+			"title":         "Códigos de recuperación - " + system.Name,
+			"globals":       c.MustGet("globals"),
+			"system":        system,
+			"recoveryCodes": recoveryCodes,
+			"continueURL":   redirectURL,
+			"styles":        []string{"css/auth"},
+			"scripts":       []string{},
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// bindAuthorizationRequest valida el cliente y la petición de autorización.
+// Si no son válidos ya respondió con el error correspondiente.
+func (h *OAuthHandler) bindAuthorizationRequest(c *gin.Context) (domain.System, forms.AuthorizeRequest, bool) {
+	var req forms.AuthorizeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.renderError(c, "Petición de autorización inválida")
+		return domain.System{}, req, false
+	}
+
+	// Sin un cliente y redirect_uri válidos no se redirige (RFC 6749 4.1.2.1)
+	system, err := h.service.ValidateClient(req.ClientID, req.RedirectURI)
+	if err != nil {
+		var oauthErr *services.OAuthError
+		if errors.As(err, &oauthErr) {
+			h.renderError(c, oauthErr.Description)
+			return domain.System{}, req, false
+		}
+		log.Printf("Error al validar el cliente %s: %v", req.ClientID, err)
+		h.renderError(c, "Error al validar el cliente")
+		return domain.System{}, req, false
+	}
+
+	if err := h.service.ValidateAuthorizationRequest(req); err != nil {
+		h.redirectError(c, req, err)
+		return domain.System{}, req, false
+	}
+
+	return system, req, true
+}
+
+// restartSignIn descarta el desafío pendiente y vuelve al formulario de ingreso
+func (h *OAuthHandler) restartSignIn(c *gin.Context, req forms.AuthorizeRequest, err error) {
+	message := "Error al verificar el código"
+	var blocked *services.SignInBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		message = blocked.Error()
+	case errors.Is(err, services.ErrInvalidMFAChallenge):
+		message = "La verificación expiró, vuelve a iniciar sesión"
+	case errors.Is(err, services.ErrUserNotActive):
+		message = "Usuario no activo"
+	default:
+		log.Printf("Error en la verificación en dos pasos: %v", err)
+	}
+
+	session := sessions.Default(c)
+	session.Delete(sessionMFAChallenge)
+	session.Delete(sessionMFAEnrollment)
+	session.AddFlash(message, "oauth_error")
+	session.Save()
+	c.Redirect(http.StatusFound, authorizeURL(req))
+}
+
+// APITokenHandler canjea códigos de autorización y refresh tokens (RFC 6749 3.2)
+func (h *OAuthHandler) APITokenHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req forms.OAuthTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.OAuthErrorResponse{
+			Error:            services.OAuthInvalidRequest,
+			ErrorDescription: "grant_type es requerido",
+		})
+		return
+	}
+
+	// client_secret_basic: las credenciales van codificadas en la cabecera (RFC 6749 2.3.1)
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, _ = url.QueryUnescape(clientID)
+		req.ClientSecret, _ = url.QueryUnescape(clientSecret)
+	}
+
+	result, err := h.service.Exchange(req)
+	if err != nil {
+		h.apiError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// APIUserInfoHandler devuelve los claims del usuario dueño del access token
+func (h *OAuthHandler) APIUserInfoHandler(c *gin.Context) {
+	accessToken := utils.BearerToken(c.GetHeader("Authorization"))
+	if accessToken == "" {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, responses.OAuthErrorResponse{
+			Error:            services.OAuthInvalidToken,
+			ErrorDescription: "Token de acceso requerido",
+		})
+		return
+	}
+
+	info, err := h.service.UserInfo(accessToken)
+	if err != nil {
+		h.apiError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+func (h *OAuthHandler) apiError(c *gin.Context, err error) {
+	var oauthErr *services.OAuthError
+	if !errors.As(err, &oauthErr) {
+		log.Printf("Error en el endpoint OAuth %s: %v", c.Request.URL.Path, err)
+		c.JSON(http.StatusInternalServerError, responses.OAuthErrorResponse{
+			Error:            "server_error",
+			ErrorDescription: "Error interno del servidor",
+		})
+		return
+	}
+
+	statusCode := http.StatusBadRequest
+	switch oauthErr.Code {
+	case services.OAuthInvalidClient:
+		statusCode = http.StatusUnauthorized
+		if _, _, ok := c.Request.BasicAuth(); ok {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+	case services.OAuthInvalidToken:
+		statusCode = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+
+	c.JSON(statusCode, responses.OAuthErrorResponse{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	})
+}
+
+// redirectError informa el error al cliente a través de su redirect_uri
+func (h *OAuthHandler) redirectError(c *gin.Context, req forms.AuthorizeRequest, err error) {
+	params := url.Values{"error": {services.OAuthInvalidRequest}, "state": {req.State}}
+	var oauthErr *services.OAuthError
+	if errors.As(err, &oauthErr) {
+		params.Set("error", oauthErr.Code)
+		params.Set("error_description", oauthErr.Description)
+	}
+	c.Redirect(http.StatusFound, services.RedirectWithParams(req.RedirectURI, params))
+}
+
+func (h *OAuthHandler) renderSignIn(c *gin.Context, system domain.System, req forms.AuthorizeRequest, flashError string) {
+	globals, _ := c.Get("globals")
+	csrfToken, _ := c.Get("csrf_token")
+
+	c.HTML(http.StatusOK, "oauth/sign-in", gin.H{
+		"title":       "Iniciar Sesión - " + system.Name,
+		"globals":     globals,
+		"csrfToken":   csrfToken,
+		"system":      system,
+		"request":     req,
+		"flash_error": flashError,
+		"styles":      []string{"css/auth"},
+		"scripts":     []string{},
+	})
+}
+
+func (h *OAuthHandler) renderMFA(c *gin.Context, system domain.System, req forms.AuthorizeRequest, setup *responses.MFAEnrollment, flashError string) {
+	globals, _ := c.Get("globals")
+	csrfToken, _ := c.Get("csrf_token")
+
+	c.HTML(http.StatusOK, "oauth/mfa", gin.H{
+		"title":       "Verificación en dos pasos - " + system.Name,
+		"globals":     globals,
+		"csrfToken":   csrfToken,
+		"system":      system,
+		"request":     req,
+		"setup":       setup,
+		"flash_error": flashError,
+		"styles":      []string{"css/auth"},
+		"scripts":     []string{},
+	})
+}
+
+// authorizeURL reconstruye la petición de autorización para volver a mostrar el formulario
+func authorizeURL(req forms.AuthorizeRequest) string {
+	return "/oauth/authorize?" + authorizeParams(req).Encode()
+}
+
+// authorizeMFAURL lleva al paso de segundo factor conservando la petición
+func authorizeMFAURL(req forms.AuthorizeRequest) string {
+	return "/oauth/authorize/mfa?" + authorizeParams(req).Encode()
+}
+
+func authorizeParams(req forms.AuthorizeRequest) url.Values {
+	return url.Values{
+		"response_type":         {req.ResponseType},
+		"client_id":             {req.ClientID},
+		"redirect_uri":          {req.RedirectURI},
+		"scope":                 {req.Scope},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {req.CodeChallenge},
+		"code_challenge_method": {req.CodeChallengeMethod},
+	}
+}
+
+func (h *OAuthHandler) renderError(c *gin.Context, message string) {
+	c.HTML(http.StatusBadRequest, "oauth/error", gin.H{
+		"title":   "Error de autorización",
+		"globals": c.MustGet("globals"),
+		"message": message,
+		"styles":  []string{"css/common"},
+	})
+}
@@ -0,0 +1,24 @@
+package oauth
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterOAuthRoutes(r *gin.Engine, handler *OAuthHandler) {
+	// descubrimiento
+	r.GET("/.well-known/openid-configuration", handler.DiscoveryHandler)
+
+	// inicio de sesión del usuario final
+	r.GET("/oauth/authorize", handler.Authorize)
+	r.POST("/oauth/authorize", handler.Authorize)
+	r.GET("/oauth/authorize/mfa", handler.AuthorizeMFA)
+	r.POST("/oauth/authorize/mfa", handler.AuthorizeMFA)
+
+	// apis (autenticadas por el propio protocolo, sin X-API-Key)
+	oauthGroup := r.Group("/api/v1/oauth")
+	{
+		oauthGroup.POST("/token", handler.APITokenHandler)
+		oauthGroup.GET("/userinfo", handler.APIUserInfoHandler)
+		oauthGroup.POST("/userinfo", handler.APIUserInfoHandler)
+	}
+}
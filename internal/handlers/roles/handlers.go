@@ -111,18 +111,25 @@ func (h *RoleHandler) CreateRoleHandler(c *gin.Context) {
 		return
 	}
 	// Manejar método GET (muestra el formulario)
+	parentRoles, err := h.service.GetParentCandidates(int(systemID), 0)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape("Error al buscar los roles del sistema")))
+		return
+	}
+
 	message := utils.Message{
 		Content: c.Query("message"),
 		Type:    c.Query("type"),
 	}
 	c.HTML(http.StatusOK, "roles/create", gin.H{
-		"title":     "Crear Nuevo Usuario",
-		"systemID":  systemID,
-		"globals":   globals,
-		"message":   message,
-		"session":   sessionData.(middleware.SessionData),
-		"navLink":   "users",
-		"csrfToken": csrfToken,
+		"title":       "Crear Nuevo Usuario",
+		"systemID":    systemID,
+		"parentRoles": parentRoles,
+		"globals":     globals,
+		"message":     message,
+		"session":     sessionData.(middleware.SessionData),
+		"navLink":     "users",
+		"csrfToken":   csrfToken,
 	})
 }
 
@@ -184,6 +191,10 @@ func (h *RoleHandler) handleEditRolePost(c *gin.Context, systemID uint64, roleID
 
 	// Actualizar datos
 	role.Name = form.Name
+	role.ParentID = nil
+	if form.ParentID != 0 {
+		role.ParentID = &form.ParentID
+	}
 	role.Updated = time.Now()
 
 	// Guardar cambios
@@ -213,6 +224,22 @@ func (h *RoleHandler) handleEditRoleGet(c *gin.Context, systemID uint64, roleID
 		return
 	}
 
+	// Roles que pueden ser padre y roles que heredan de este
+	parentRoles, err := h.service.GetParentCandidates(int(role.SystemID), role.ID)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape("Error al buscar los roles del sistema")))
+		return
+	}
+	children, err := h.service.GetChildren(roleID)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape("Error al buscar los roles hijos")))
+		return
+	}
+	var parentID uint
+	if role.ParentID != nil {
+		parentID = *role.ParentID
+	}
+
 	// Obtener token CSRF
 	csrfToken, _ := c.Get("csrf_token")
 
@@ -226,16 +253,19 @@ func (h *RoleHandler) handleEditRoleGet(c *gin.Context, systemID uint64, roleID
 	}
 
 	c.HTML(http.StatusOK, "roles/edit", gin.H{
-		"title":     "Editar Sistema",
-		"csrfToken": csrfToken,
-		"globals":   globals,
-		"role":      role,
-		"systemID":  systemID,
-		"session":   sessionData.(middleware.SessionData),
-		"navLink":   "systems",
-		"message":   message,
-		"styles":    []string{},
-		"scripts":   []string{},
+		"title":       "Editar Sistema",
+		"csrfToken":   csrfToken,
+		"globals":     globals,
+		"role":        role,
+		"parentID":    parentID,
+		"parentRoles": parentRoles,
+		"children":    children,
+		"systemID":    systemID,
+		"session":     sessionData.(middleware.SessionData),
+		"navLink":     "systems",
+		"message":     message,
+		"styles":      []string{},
+		"scripts":     []string{},
 	})
 }
 
@@ -273,8 +303,25 @@ func (h *RoleHandler) DeleteRoleHandler(c *gin.Context) {
 		return
 	}
 
-	// Eliminar el rol
-	if err := h.service.DeleteRole(roleID); err != nil {
+	// Con POST se eliminan reasignando los hijos al rol elegido
+	if c.Request.Method == http.MethodPost {
+		var form forms.RoleDeleteInput
+		if err := c.ShouldBind(&form); err != nil {
+			message := "Datos del formulario inválidos"
+			c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/roles/%d/edit?message=%s&type=danger", systemID, roleID, url.QueryEscape(message)))
+			return
+		}
+		err = h.service.DeleteRoleReassigning(role, form.ParentID)
+	} else {
+		err = h.service.DeleteRole(roleID)
+	}
+
+	if err != nil {
+		// Los hijos deben reasignarse desde la edición del rol
+		if errors.Is(err, services.ErrRoleHasChildren) || errors.Is(err, services.ErrRoleParentCycle) || errors.Is(err, services.ErrRoleParentInvalid) {
+			c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/roles/%d/edit?message=%s&type=warning", systemID, roleID, url.QueryEscape(err.Error())))
+			return
+		}
 		message := "Error al eliminar el rol"
 		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
 		return
@@ -14,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -23,14 +24,24 @@ type SystemHandler struct {
 	roleService       *services.RoleService
 	permissionService *services.PermissionService
 	systemUserService *services.SystemUserService
+	oauthService      *services.OAuthService
+	clientService     *services.SystemClientService
+	apiKeyService     *services.SystemAPIKeyService
+	ldapService       *services.SystemLDAPService
+	tokenService      *services.SystemTokenService
 }
 
-func NewSystemHandler(service *services.SystemService, roleService *services.RoleService, permissionService *services.PermissionService, systemUserService *services.SystemUserService) *SystemHandler {
+func NewSystemHandler(service *services.SystemService, roleService *services.RoleService, permissionService *services.PermissionService, systemUserService *services.SystemUserService, oauthService *services.OAuthService, clientService *services.SystemClientService, apiKeyService *services.SystemAPIKeyService, ldapService *services.SystemLDAPService, tokenService *services.SystemTokenService) *SystemHandler {
 	return &SystemHandler{
 		service:           service,
 		roleService:       roleService,
 		permissionService: permissionService,
 		systemUserService: systemUserService,
+		oauthService:      oauthService,
+		clientService:     clientService,
+		apiKeyService:     apiKeyService,
+		ldapService:       ldapService,
+		tokenService:      tokenService,
 	}
 }
 
@@ -234,6 +245,7 @@ func (h *SystemHandler) handleEditSystemPost(c *gin.Context, systemID uint64) {
 	system.Name = form.Name
 	system.Description = form.Description
 	system.Repository = form.Repository
+	system.MFARequired = form.MFARequired
 	system.Updated = time.Now()
 
 	// Guardar cambios
@@ -287,6 +299,51 @@ func (h *SystemHandler) handleEditSystemGet(c *gin.Context, systemID uint64) {
 		endRecordRoles = int(totalRoles)
 	}
 
+	// Jerarquía completa de roles del sistema
+	roleTree, err := h.roleService.GetRoleTree(int(systemID))
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("Error al buscar los roles del sistema")))
+		return
+	}
+
+	// URIs de retorno del cliente OpenID Connect
+	redirectURIs, err := h.oauthService.GetRedirectURIs(systemID)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("Error al buscar las URIs de retorno del sistema")))
+		return
+	}
+
+	// Credenciales client_credentials del sistema
+	clients, err := h.clientService.GetSystemClients(systemID)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("Error al buscar los clientes del sistema")))
+		return
+	}
+
+	// Llaves de API con las que el sistema llama a las APIs de autenticación
+	apiKeys, err := h.apiKeyService.GetSystemAPIKeys(systemID)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("Error al buscar las llaves de API del sistema")))
+		return
+	}
+
+	// Directorio LDAP propio del sistema, nil si usa el global
+	ldapSettings, err := h.ldapService.GetSettings(systemID)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("Error al buscar el directorio LDAP del sistema")))
+		return
+	}
+
+	// El secreto y la llave recién generados se muestran una sola vez
+	session := sessions.Default(c)
+	newClientID := utils.FirstFlashOrEmpty(session.Flashes("client_id"))
+	clientSecret := utils.FirstFlashOrEmpty(session.Flashes("client_secret"))
+	newAPIKey := utils.FirstFlashOrEmpty(session.Flashes("api_key"))
+	tokenSecret := utils.FirstFlashOrEmpty(session.Flashes("token_secret"))
+	session.Save()
+
+	defaultIssuer, defaultAccessTTL := h.tokenService.Defaults()
+
 	// Obtener token CSRF
 	csrfToken, _ := c.Get("csrf_token")
 	globals, _ := c.Get("globals")
@@ -314,6 +371,21 @@ func (h *SystemHandler) handleEditSystemGet(c *gin.Context, systemID uint64) {
 		"startRecordRoles": startRecordRoles,
 		"endRecordRoles":   endRecordRoles,
 		"totalRoles":       totalRoles,
+		"roleTree":         roleTree,
+		"redirectURIs":     redirectURIs,
+		"clients":          clients,
+		"newClientID":      newClientID,
+		"clientSecret":     clientSecret,
+		"apiKeys":          apiKeys,
+		"newAPIKey":        newAPIKey,
+		"ldapSettings":     ldapSettings,
+		"defaultBackend":   h.ldapService.DefaultBackend(),
+		"globalLDAPURL":    h.ldapService.GlobalURL(),
+		"tokenSecret":      tokenSecret,
+		"defaultIssuer":    defaultIssuer,
+		"defaultAccessTTL": int(defaultAccessTTL.Seconds()),
+		"maxAccessTTL":     int(services.MaxSystemAccessTTL.Seconds()),
+		"maxClockSkew":     int(services.MaxSystemClockSkew.Seconds()),
 		"styles":           []string{},
 		"scripts":          []string{},
 	})
@@ -355,6 +427,385 @@ func (h *SystemHandler) DeleteSystemHandler(c *gin.Context) {
 	c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=success", url.QueryEscape(message)))
 }
 
+// AddRedirectURIHandler registra una URI de retorno para el cliente OpenID Connect
+func (h *SystemHandler) AddRedirectURIHandler(c *gin.Context) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return
+	}
+
+	var form forms.RedirectURIForm
+	if err := c.ShouldBind(&form); err != nil {
+		message := "La URI de retorno es requerida"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	if err := h.oauthService.AddRedirectURI(systemID, form.URI); err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(err.Error())))
+		return
+	}
+
+	message := "URI de retorno agregada exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+// SaveLDAPHandler guarda el backend de autenticación y el directorio LDAP del sistema
+func (h *SystemHandler) SaveLDAPHandler(c *gin.Context) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return
+	}
+
+	var form forms.SystemLDAPForm
+	if err := c.ShouldBind(&form); err != nil {
+		message := "Datos del directorio inválidos"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	if err := h.ldapService.Save(systemID, form); err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(err.Error())))
+		return
+	}
+
+	message := "Autenticación del sistema actualizada exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+// SaveTokenSettingsHandler guarda el emisor, la audiencia, la vigencia y la
+// tolerancia de reloj de los tokens del sistema
+func (h *SystemHandler) SaveTokenSettingsHandler(c *gin.Context) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return
+	}
+
+	var form forms.SystemTokenForm
+	if err := c.ShouldBind(&form); err != nil {
+		message := "Datos de los tokens inválidos"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	if err := h.tokenService.Save(systemID, form); err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(err.Error())))
+		return
+	}
+
+	message := "Configuración de tokens actualizada exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+// RotateTokenSecretHandler genera el secreto con el que se firman los tokens del sistema
+func (h *SystemHandler) RotateTokenSecretHandler(c *gin.Context) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return
+	}
+
+	secret, err := h.tokenService.RotateSecret(systemID)
+	if err != nil {
+		message := "Error al generar el secreto de firma"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	session := sessions.Default(c)
+	session.AddFlash(secret, "token_secret")
+	session.Save()
+
+	message := "Secreto de firma generado exitosamente. Cópialo, no se volverá a mostrar"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+// DeleteTokenSecretHandler vuelve a firmar los tokens del sistema con las llaves globales
+func (h *SystemHandler) DeleteTokenSecretHandler(c *gin.Context) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return
+	}
+
+	if err := h.tokenService.DeleteSecret(systemID); err != nil {
+		message := "Error al quitar el secreto de firma"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	message := "Secreto de firma eliminado, los tokens se firman con las llaves globales"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+// DeleteRedirectURIHandler elimina una URI de retorno del sistema
+func (h *SystemHandler) DeleteRedirectURIHandler(c *gin.Context) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return
+	}
+
+	uriID, err := strconv.ParseUint(c.Param("uri_id"), 10, 32)
+	if err != nil {
+		message := "ID de URI inválido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	if err := h.oauthService.DeleteRedirectURI(systemID, uriID); err != nil {
+		message := "Error al eliminar la URI de retorno"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	message := "URI de retorno eliminada exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+// CreateClientHandler genera un nuevo par client_id/secret para el sistema
+func (h *SystemHandler) CreateClientHandler(c *gin.Context) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return
+	}
+
+	var form forms.SystemClientForm
+	if err := c.ShouldBind(&form); err != nil {
+		message := "El nombre del cliente es requerido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	client, secret, err := h.clientService.CreateClient(systemID, form.Name)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(err.Error())))
+		return
+	}
+
+	h.flashClientSecret(c, client, secret)
+	message := "Cliente creado exitosamente. Copia el secreto, no se volverá a mostrar"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+// RotateClientSecretHandler genera un nuevo secreto para el cliente
+func (h *SystemHandler) RotateClientSecretHandler(c *gin.Context) {
+	systemID, clientID, ok := h.clientParams(c)
+	if !ok {
+		return
+	}
+
+	client, secret, err := h.clientService.RotateSecret(systemID, clientID)
+	if err != nil {
+		message := "Error al rotar el secreto del cliente"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	h.flashClientSecret(c, client, secret)
+	message := "Secreto rotado exitosamente. Copia el nuevo secreto, no se volverá a mostrar"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+// DeleteClientHandler elimina el cliente y revoca sus tokens
+func (h *SystemHandler) DeleteClientHandler(c *gin.Context) {
+	systemID, clientID, ok := h.clientParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.clientService.DeleteClient(systemID, clientID); err != nil {
+		message := "Error al eliminar el cliente"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	message := "Cliente eliminado exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+// ClientPermissionsHandler muestra (GET) y guarda (POST) los permisos otorgados al cliente
+func (h *SystemHandler) ClientPermissionsHandler(c *gin.Context) {
+	systemID, clientID, ok := h.clientParams(c)
+	if !ok {
+		return
+	}
+
+	client, err := h.clientService.GetSystemClient(systemID, clientID)
+	if err != nil {
+		message := "Cliente no encontrado"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	if c.Request.Method == http.MethodPost {
+		var permissionIDs []uint64
+		for permIDStr := range c.PostFormMap("permissions") {
+			permID, err := strconv.ParseUint(permIDStr, 10, 64)
+			if err != nil {
+				message := "Error al procesar los permisos"
+				c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/clients/%d/permissions?message=%s&type=danger", systemID, clientID, url.QueryEscape(message)))
+				return
+			}
+			permissionIDs = append(permissionIDs, permID)
+		}
+
+		if err := h.clientService.SetClientPermissions(client, permissionIDs); err != nil {
+			message := "Error al asociar los permisos"
+			c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/clients/%d/permissions?message=%s&type=danger", systemID, clientID, url.QueryEscape(message)))
+			return
+		}
+
+		message := "Permisos actualizados con éxito"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/clients/%d/permissions?message=%s&type=success", systemID, clientID, url.QueryEscape(message)))
+		return
+	}
+
+	systems, err := h.clientService.GetClientPermissions(client)
+	if err != nil {
+		message := "Error al cargar los permisos"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	csrfToken, _ := c.Get("csrf_token")
+	globals, _ := c.Get("globals")
+	sessionData, _ := c.Get("sessionData")
+
+	// mensajes por URL, si lo hubiere
+	message := utils.Message{
+		Content: c.Query("message"),
+		Type:    c.Query("type"),
+	}
+
+	c.HTML(http.StatusOK, "systems/client-permissions", gin.H{
+		"title":     "Permisos del Cliente - " + client.Name,
+		"csrfToken": csrfToken,
+		"globals":   globals,
+		"session":   sessionData.(middleware.SessionData),
+		"navLink":   "systems",
+		"message":   message,
+		"systemID":  systemID,
+		"client":    client,
+		"systems":   systems,
+		"styles":    []string{},
+		"scripts":   []string{},
+	})
+}
+
+// CreateAPIKeyHandler genera una nueva llave de API para el sistema
+func (h *SystemHandler) CreateAPIKeyHandler(c *gin.Context) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return
+	}
+
+	var form forms.SystemAPIKeyForm
+	if err := c.ShouldBind(&form); err != nil {
+		message := "El nombre de la llave es requerido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	key, err := h.apiKeyService.CreateAPIKey(systemID, form.Name, form.ManageAccess)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(err.Error())))
+		return
+	}
+
+	h.flashAPIKey(c, key)
+	message := "Llave de API creada exitosamente. Cópiala, no se volverá a mostrar"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+// RotateAPIKeyHandler reemplaza el valor de la llave de API
+func (h *SystemHandler) RotateAPIKeyHandler(c *gin.Context) {
+	systemID, keyID, ok := h.apiKeyParams(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.apiKeyService.RotateAPIKey(systemID, keyID)
+	if err != nil {
+		message := "Error al rotar la llave de API"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	h.flashAPIKey(c, key)
+	message := "Llave de API rotada exitosamente. Copia la nueva llave, no se volverá a mostrar"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+// RevokeAPIKeyHandler revoca la llave de API
+func (h *SystemHandler) RevokeAPIKeyHandler(c *gin.Context) {
+	systemID, keyID, ok := h.apiKeyParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(systemID, keyID); err != nil {
+		message := "Error al revocar la llave de API"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return
+	}
+
+	message := "Llave de API revocada exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=success", systemID, url.QueryEscape(message)))
+}
+
+func (h *SystemHandler) apiKeyParams(c *gin.Context) (uint64, uint64, bool) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return 0, 0, false
+	}
+
+	keyID, err := strconv.ParseUint(c.Param("key_id"), 10, 32)
+	if err != nil {
+		message := "ID de llave inválido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return 0, 0, false
+	}
+
+	return systemID, keyID, true
+}
+
+func (h *SystemHandler) flashAPIKey(c *gin.Context, key string) {
+	session := sessions.Default(c)
+	session.AddFlash(key, "api_key")
+	session.Save()
+}
+
+func (h *SystemHandler) clientParams(c *gin.Context) (uint64, uint64, bool) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return 0, 0, false
+	}
+
+	clientID, err := strconv.ParseUint(c.Param("client_id"), 10, 32)
+	if err != nil {
+		message := "ID de cliente inválido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/edit?message=%s&type=danger", systemID, url.QueryEscape(message)))
+		return 0, 0, false
+	}
+
+	return systemID, clientID, true
+}
+
+func (h *SystemHandler) flashClientSecret(c *gin.Context, client domain.SystemClient, secret string) {
+	session := sessions.Default(c)
+	session.AddFlash(client.ClientID, "client_id")
+	session.AddFlash(secret, "client_secret")
+	session.Save()
+}
+
 func (h *SystemHandler) handleSystemRolesPermissions(c *gin.Context, systemID uint64, roleID uint64) {
 	// Obtener el sistema de la base de datos
 	var system domain.System
@@ -28,12 +28,37 @@ func RegisterSystemsRoutes(r *gin.Engine, handler *SystemHandler, roleHandler *r
 			systemByIDGroup.GET("/edit", handler.EditSystemHandler)
 			systemByIDGroup.GET("/delete", handler.DeleteSystemHandler)
 
+			// OpenID Connect redirect URIs
+			systemByIDGroup.POST("/redirect-uris", handler.AddRedirectURIHandler)
+			systemByIDGroup.GET("/redirect-uris/:uri_id/delete", handler.DeleteRedirectURIHandler)
+
+			// authentication backend (local / LDAP)
+			systemByIDGroup.POST("/ldap", handler.SaveLDAPHandler)
+
+			// access tokens: issuer, audience, lifetime, clock skew and signing secret
+			systemByIDGroup.POST("/tokens", handler.SaveTokenSettingsHandler)
+			systemByIDGroup.GET("/token-secret/rotate", handler.RotateTokenSecretHandler)
+			systemByIDGroup.GET("/token-secret/delete", handler.DeleteTokenSecretHandler)
+
+			// client credentials
+			systemByIDGroup.POST("/clients", handler.CreateClientHandler)
+			systemByIDGroup.GET("/clients/:client_id/rotate", handler.RotateClientSecretHandler)
+			systemByIDGroup.GET("/clients/:client_id/delete", handler.DeleteClientHandler)
+			systemByIDGroup.GET("/clients/:client_id/permissions", handler.ClientPermissionsHandler)
+			systemByIDGroup.POST("/clients/:client_id/permissions", handler.ClientPermissionsHandler)
+
+			// API keys
+			systemByIDGroup.POST("/api-keys", handler.CreateAPIKeyHandler)
+			systemByIDGroup.GET("/api-keys/:key_id/rotate", handler.RotateAPIKeyHandler)
+			systemByIDGroup.GET("/api-keys/:key_id/revoke", handler.RevokeAPIKeyHandler)
+
 			// Routes for roles, now nested correctly under the specific system group
 			systemByIDGroup.POST("/roles", roleHandler.CreateRoleHandler)
 			systemByIDGroup.GET("/roles", roleHandler.CreateRoleHandler)
 			systemByIDGroup.POST("/roles/:role_id/edit", roleHandler.EditRoleHandler)
 			systemByIDGroup.GET("/roles/:role_id/edit", roleHandler.EditRoleHandler)
 			systemByIDGroup.GET("/roles/:role_id/delete", roleHandler.DeleteRoleHandler)
+			systemByIDGroup.POST("/roles/:role_id/delete", roleHandler.DeleteRoleHandler)
 
 			// permissions
 			// Routes for roles, now nested correctly under the specific system group
@@ -51,6 +76,8 @@ func RegisterSystemsRoutes(r *gin.Engine, handler *SystemHandler, roleHandler *r
 			//users roles/permissions
 			systemByIDGroup.GET("/users/:user_id", userHandler.GetUserRolesAndPermissions)
 			systemByIDGroup.POST("/users/:user_id/permissions", userHandler.AssociatePermissionsHandler)
+			systemByIDGroup.GET("/users/:user_id/roles/:role_id/assign", userHandler.AssignRoleHandler)
+			systemByIDGroup.GET("/users/:user_id/roles/:role_id/unassign", userHandler.UnassignRoleHandler)
 		}
 	}
 }
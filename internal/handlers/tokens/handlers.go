@@ -0,0 +1,220 @@
+package tokens
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/forms"
+	"accessv2/internal/responses"
+	"accessv2/internal/services"
+	"accessv2/pkg/middleware"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TokenHandler struct {
+	service    *services.TokenService
+	keyService *services.KeyService
+}
+
+func NewTokenHandler(service *services.TokenService, keyService *services.KeyService) *TokenHandler {
+	return &TokenHandler{service: service, keyService: keyService}
+}
+
+// JWKSHandler publica las llaves públicas para verificar los tokens emitidos
+func (h *TokenHandler) JWKSHandler(c *gin.Context) {
+	set, err := h.keyService.JWKS()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "jwks_unavailable",
+			"message": "No se pudieron obtener las llaves públicas",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
+
+func (h *TokenHandler) APIRefreshHandler(c *gin.Context) {
+	var req forms.RefreshTokenRequest
+
+	// Bind JSON request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.SignResponse{
+			Success: false,
+			Error:   "Datos de entrada inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	// Llamar al servicio
+	userWithAccess, err := h.service.Refresh(middleware.APISystemID(c), req.RefreshToken)
+
+	// Manejar errores
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		errorMsg := "Error al renovar el token"
+
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			statusCode = http.StatusUnauthorized
+			errorMsg = err.Error()
+		} else if errors.Is(err, services.ErrUserNotActive) {
+			statusCode = http.StatusForbidden
+			errorMsg = "Usuario no activo"
+		}
+
+		c.JSON(statusCode, responses.SignResponse{
+			Success: false,
+			Message: errorMsg,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Respuesta exitosa
+	c.JSON(http.StatusOK, responses.SignResponse{
+		Success: true,
+		Message: "Token renovado exitosamente",
+		Data:    userWithAccess,
+	})
+}
+
+func (h *TokenHandler) APIRevokeHandler(c *gin.Context) {
+	var req forms.RevokeTokenRequest
+
+	// Acepta JSON o application/x-www-form-urlencoded (RFC 7009)
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.TokenStatusResponse{
+			Success: false,
+			Error:   "Datos de entrada inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.Revoke(middleware.APISystemID(c), req.Token, req.TokenTypeHint); err != nil {
+		c.JSON(http.StatusInternalServerError, responses.TokenStatusResponse{
+			Success: false,
+			Message: "Error al revocar el token",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.TokenStatusResponse{
+		Success: true,
+		Message: "Token revocado",
+	})
+}
+
+func (h *TokenHandler) APIIntrospectHandler(c *gin.Context) {
+	var req forms.IntrospectTokenRequest
+
+	// Acepta JSON o application/x-www-form-urlencoded (RFC 7662)
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": "Datos de entrada inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := h.service.Introspect(req.Token, req.TokenTypeHint)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             "server_error",
+			"error_description": "Error al validar el token",
+		})
+		return
+	}
+	// Los tokens de otros sistemas se reportan como inactivos
+	if result.Active && result.SystemID != middleware.APISystemID(c) {
+		result = responses.IntrospectionResponse{Active: false}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *TokenHandler) APITokenStatusHandler(c *gin.Context) {
+	token, err := h.service.GetTokenStatus(c.Param("jti"))
+	if err == nil && uint64(token.SystemID) != middleware.APISystemID(c) {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, responses.TokenStatusResponse{
+			Success: false,
+			Message: "Token no encontrado",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	status := toTokenStatus(token)
+	c.JSON(http.StatusOK, responses.TokenStatusResponse{
+		Success: true,
+		Data:    &status,
+	})
+}
+
+func (h *TokenHandler) APIRevocationsHandler(c *gin.Context) {
+	// Cada sistema solo consulta sus propias revocaciones
+	systemID := middleware.APISystemID(c)
+	if systemIDStr := c.Query("system_id"); systemIDStr != "" {
+		id, err := strconv.ParseUint(systemIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.RevocationListResponse{Success: false, Error: "system_id inválido"})
+			return
+		}
+		if id != systemID {
+			c.JSON(http.StatusForbidden, responses.RevocationListResponse{Success: false, Error: "system_id no corresponde a la llave de API"})
+			return
+		}
+	}
+
+	var since time.Time
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		parsed, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.RevocationListResponse{Success: false, Error: "since debe tener formato RFC3339"})
+			return
+		}
+		since = parsed
+	}
+
+	tokens, err := h.service.GetRevokedTokens(systemID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.RevocationListResponse{Success: false, Error: err.Error()})
+		return
+	}
+
+	data := make([]responses.TokenStatus, 0, len(tokens))
+	for _, token := range tokens {
+		data = append(data, toTokenStatus(token))
+	}
+
+	c.JSON(http.StatusOK, responses.RevocationListResponse{
+		Success: true,
+		Data:    data,
+	})
+}
+
+func toTokenStatus(token domain.AccessToken) responses.TokenStatus {
+	return responses.TokenStatus{
+		JTI:            token.JTI,
+		UserID:         token.UserID,
+		SystemClientID: token.SystemClientID,
+		SystemID:       token.SystemID,
+		Revoked:        token.RevokedAt != nil,
+		RevokedAt:      token.RevokedAt,
+		Reason:         token.RevokeReason,
+		ExpiresAt:      token.ExpiresAt,
+	}
+}
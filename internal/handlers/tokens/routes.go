@@ -0,0 +1,22 @@
+package tokens
+
+import (
+	"accessv2/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterTokenRoutes(r *gin.Engine, handler *TokenHandler, apiKeys middleware.APIKeyValidator) {
+	// llaves públicas
+	r.GET("/.well-known/jwks.json", handler.JWKSHandler)
+
+	// apis
+	tokenGroup := r.Group("/api/v1/token", middleware.APIKeyRequired(apiKeys))
+	{
+		tokenGroup.POST("/refresh", handler.APIRefreshHandler)
+		tokenGroup.POST("/revoke", handler.APIRevokeHandler)
+		tokenGroup.POST("/introspect", handler.APIIntrospectHandler)
+		tokenGroup.GET("/revocations", handler.APIRevocationsHandler)
+		tokenGroup.GET("/revocations/:jti", handler.APITokenStatusHandler)
+	}
+}
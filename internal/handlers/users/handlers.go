@@ -10,6 +10,8 @@ import (
 	"accessv2/pkg/utils"
 	"errors"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -23,10 +25,29 @@ import (
 type UserHandler struct {
 	service               *services.UserService
 	userPermissionService *services.UserPermissionService
+	accountService        *services.AccountService
+	mfaService            *services.MFAService
+	tokenService          *services.TokenService
+	throttleService       *services.SignInThrottleService
+	impersonationService  *services.ImpersonationService
+	groupService          *services.GroupService
+	roleAssignmentService *services.RoleAssignmentService
+	ldapService           *services.SystemLDAPService
 }
 
-func NewUserHandler(service *services.UserService, userPermissionService *services.UserPermissionService) *UserHandler {
-	return &UserHandler{service: service, userPermissionService: userPermissionService}
+func NewUserHandler(service *services.UserService, userPermissionService *services.UserPermissionService, accountService *services.AccountService, mfaService *services.MFAService, tokenService *services.TokenService, throttleService *services.SignInThrottleService, impersonationService *services.ImpersonationService, groupService *services.GroupService, roleAssignmentService *services.RoleAssignmentService, ldapService *services.SystemLDAPService) *UserHandler {
+	return &UserHandler{
+		service:               service,
+		userPermissionService: userPermissionService,
+		accountService:        accountService,
+		mfaService:            mfaService,
+		tokenService:          tokenService,
+		throttleService:       throttleService,
+		impersonationService:  impersonationService,
+		groupService:          groupService,
+		roleAssignmentService: roleAssignmentService,
+		ldapService:           ldapService,
+	}
 }
 
 func (h *UserHandler) ListUsers(c *gin.Context) {
@@ -111,17 +132,27 @@ func (h *UserHandler) CreateUserHandler(c *gin.Context) {
 		var input forms.UserCreateInput
 		// Parsear formulario
 		if err := c.ShouldBind(&input); err != nil {
-			c.Redirect(http.StatusFound, fmt.Sprintf("/users/create?message=%s&type=danger", err.Error()))
+			c.Redirect(http.StatusFound, fmt.Sprintf("/users/create?message=%s&type=danger", url.QueryEscape(err.Error())))
 			return
 		}
 
 		// Crear usuario a través del servicio
 		user, err := h.service.CreateUser(&input)
 		if err != nil {
-			c.Redirect(http.StatusFound, fmt.Sprintf("/users/create?message=%s&type=danger", err.Error()))
+			c.Redirect(http.StatusFound, fmt.Sprintf("/users/create?message=%s&type=danger", url.QueryEscape(err.Error())))
 			return
 		}
 
+		// Los usuarios inactivos reciben el enlace de activación por correo
+		if !user.Activated {
+			if err := h.accountService.SendActivation(*user); err != nil {
+				log.Printf("No se pudo enviar la activación del usuario %d: %v", user.ID, err)
+				message := "Usuario creado, pero no se pudo enviar el correo de activación"
+				c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=warning", user.ID, url.QueryEscape(message)))
+				return
+			}
+		}
+
 		// Redirigir a editar usuario
 		message := "Usuario creado exitosamente"
 		c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=success", user.ID, message))
@@ -247,6 +278,32 @@ func (h *UserHandler) handleEditUserGet(c *gin.Context, userID uint64) {
 		return
 	}
 
+	userGroups, err := h.groupService.GetUserGroups(uint(userID))
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users?message=%s&type=danger", url.QueryEscape("Error al buscar los grupos del usuario")))
+		return
+	}
+
+	mfa, recoveryCodesLeft, err := h.mfaService.GetUserMFA(uint(userID))
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users?message=%s&type=danger", url.QueryEscape("Error al buscar la verificación en dos pasos del usuario")))
+		return
+	}
+
+	signInThrottles, err := h.throttleService.GetUserThrottles(user.Username)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users?message=%s&type=danger", url.QueryEscape("Error al buscar los intentos fallidos del usuario")))
+		return
+	}
+
+	directories, err := h.ldapService.Directories()
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users?message=%s&type=danger", url.QueryEscape("Error al buscar los directorios")))
+		return
+	}
+
+	passwordExpiresAt := h.service.PasswordExpiresAt(user)
+
 	fmt.Println(systemRolesPermissions)
 	// cambiar contraseña
 	user.Password = "1234567890"
@@ -260,11 +317,138 @@ func (h *UserHandler) handleEditUserGet(c *gin.Context, userID uint64) {
 		"navLink":                "users",
 		"message":                message,
 		"systemRolesPermissions": systemRolesPermissions,
+		"userGroups":             userGroups,
+		"now":                    time.Now(),
+		"mfa":                    mfa,
+		"recoveryCodesLeft":      recoveryCodesLeft,
+		"signInThrottles":        signInThrottles,
+		"passwordExpiresAt":      passwordExpiresAt,
+		"directories":            directories,
 		"styles":                 []string{},
 		"scripts":                []string{},
 	})
 }
 
+// ResendActivationHandler envía un nuevo enlace de activación al usuario
+func (h *UserHandler) ResendActivationHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		message := "ID de usuario inválido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users?message=%s&type=danger", url.QueryEscape(message)))
+		return
+	}
+
+	if err := h.accountService.ResendActivation(userID); err != nil {
+		message := "Error al enviar el correo de activación"
+		if errors.Is(err, services.ErrUserAlreadyActive) {
+			message = err.Error()
+		} else {
+			log.Printf("No se pudo reenviar la activación del usuario %d: %v", userID, err)
+		}
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=danger", userID, url.QueryEscape(message)))
+		return
+	}
+
+	message := "Enlace de activación enviado exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=success", userID, url.QueryEscape(message)))
+}
+
+// ResetMFAHandler elimina la verificación en dos pasos del usuario
+func (h *UserHandler) ResetMFAHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		message := "ID de usuario inválido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users?message=%s&type=danger", url.QueryEscape(message)))
+		return
+	}
+
+	if err := h.mfaService.Reset(uint(userID)); err != nil {
+		message := "Error al restablecer la verificación en dos pasos"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=danger", userID, url.QueryEscape(message)))
+		return
+	}
+
+	message := "Verificación en dos pasos restablecida exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=success", userID, url.QueryEscape(message)))
+}
+
+// LinkDirectoryHandler vincula al usuario con su entrada del directorio
+func (h *UserHandler) LinkDirectoryHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		message := "ID de usuario inválido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users?message=%s&type=danger", url.QueryEscape(message)))
+		return
+	}
+
+	var form forms.UserDirectoryLinkForm
+	if err := c.ShouldBind(&form); err != nil {
+		message := "Debe indicar el directorio y el usuario en el directorio"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=danger", userID, url.QueryEscape(message)))
+		return
+	}
+
+	if err := h.ldapService.LinkUser(uint(userID), form.Directory, form.Login); err != nil {
+		message := "Error al vincular el usuario con el directorio"
+		switch {
+		case errors.Is(err, services.ErrLDAPDirectoryNotFound), errors.Is(err, services.ErrLDAPEntryNotFound), errors.Is(err, services.ErrLDAPEntryLinked):
+			message = err.Error()
+		default:
+			log.Printf("No se pudo vincular el usuario %d con el directorio %s: %v", userID, form.Directory, err)
+		}
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=danger", userID, url.QueryEscape(message)))
+		return
+	}
+
+	message := "Usuario vinculado con el directorio exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=success", userID, url.QueryEscape(message)))
+}
+
+// UnlinkDirectoryHandler quita el vínculo del usuario con el directorio
+func (h *UserHandler) UnlinkDirectoryHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		message := "ID de usuario inválido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users?message=%s&type=danger", url.QueryEscape(message)))
+		return
+	}
+
+	if err := h.ldapService.UnlinkUser(uint(userID)); err != nil {
+		message := "Error al desvincular el usuario del directorio"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=danger", userID, url.QueryEscape(message)))
+		return
+	}
+
+	message := "Usuario desvinculado del directorio exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=success", userID, url.QueryEscape(message)))
+}
+
+// ClearSignInLocksHandler reinicia los intentos fallidos del usuario en todos los sistemas
+func (h *UserHandler) ClearSignInLocksHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		message := "ID de usuario inválido"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users?message=%s&type=danger", url.QueryEscape(message)))
+		return
+	}
+
+	var user domain.User
+	if err := h.service.FetchUser(userID, &user); err != nil {
+		message := "Usuario no encontrado"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users?message=%s&type=danger", url.QueryEscape(message)))
+		return
+	}
+
+	if err := h.throttleService.ClearUser(user.Username); err != nil {
+		message := "Error al desbloquear el usuario"
+		c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=danger", userID, url.QueryEscape(message)))
+		return
+	}
+
+	message := "Usuario desbloqueado exitosamente"
+	c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/edit?message=%s&type=success", userID, url.QueryEscape(message)))
+}
+
 func (h *UserHandler) DeleteUserHandler(c *gin.Context) {
 	// Obtener parámetros
 	userIDStr := c.Param("id")
@@ -346,20 +530,28 @@ func (h *UserHandler) GetUserRolesAndPermissions(c *gin.Context) {
 		Type:    c.Query("type"),
 	}
 
+	// Solo se ofrece suplantar al usuario a quien tiene el permiso
+	canImpersonate, err := h.impersonationService.CanImpersonate(uint(sessionData.(middleware.SessionData).UserID))
+	if err != nil {
+		log.Printf("No se pudo consultar si el administrador puede suplantar usuarios: %v", err)
+	}
+
 	// Renderizar vista
 	c.HTML(http.StatusOK, "users/roles-permissions", gin.H{
-		"title":       "Permisos de los Roles del Usuario",
-		"systemID":    systemID,
-		"userID":      userID,
-		"permissions": permissions,
-		"csrfToken":   csrfToken,
-		"globals":     globals,
-		"origin":      origin,
-		"session":     sessionData.(middleware.SessionData),
-		"navLink":     navLink,
-		"styles":      styles,  // Pasar array de estilos
-		"scripts":     scripts, // Pasar array de scripts
-		"message":     message,
+		"title":           "Permisos de los Roles del Usuario",
+		"systemID":        systemID,
+		"userID":          userID,
+		"permissions":     permissions,
+		"csrfToken":       csrfToken,
+		"globals":         globals,
+		"origin":          origin,
+		"session":         sessionData.(middleware.SessionData),
+		"navLink":         navLink,
+		"styles":          styles,  // Pasar array de estilos
+		"scripts":         scripts, // Pasar array de scripts
+		"message":         message,
+		"canImpersonate":  canImpersonate,
+		"minReasonLength": h.impersonationService.MinReasonLength(),
 	})
 }
 
@@ -405,8 +597,14 @@ func (h *UserHandler) AssociatePermissionsHandler(c *gin.Context) {
 		permissionIDs = append(permissionIDs, permID)
 	}
 
-	// Llamar a un servicio o repositorio para asociar los permisos al usuario
-	err = h.userPermissionService.AssociatePermissions(uint(systemID), uint(userID), uint(roleID), permissionIDs)
+	// Con el rol asignado, los permisos desmarcados se le quitan al usuario;
+	// si no, se asocian uno a uno
+	assigned, err := h.roleAssignmentService.HasRole(systemID, uint(userID), uint(roleID))
+	if err == nil && assigned {
+		err = h.roleAssignmentService.SetRolePermissions(systemID, uint(userID), domain.Role{ID: uint(roleID), SystemID: uint(systemID)}, permissionIDs)
+	} else if err == nil {
+		err = h.userPermissionService.AssociatePermissions(uint(systemID), uint(userID), uint(roleID), permissionIDs)
+	}
 	if err != nil {
 		// Redirigir a la URL base con un mensaje de error y el origen
 		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/users/%d?origin=%s&message=%s&type=danger", systemID, userID, origin, url.QueryEscape("Error al asociar los permisos")))
@@ -417,6 +615,59 @@ func (h *UserHandler) AssociatePermissionsHandler(c *gin.Context) {
 	c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/users/%d?origin=%s&message=%s&type=success", systemID, userID, origin, url.QueryEscape("Permisos actualizados con éxito")))
 }
 
+// AssignRoleHandler asigna el rol completo al usuario en el sistema
+func (h *UserHandler) AssignRoleHandler(c *gin.Context) {
+	h.changeRole(c, h.roleAssignmentService.AssignRole, "Rol asignado al usuario")
+}
+
+// UnassignRoleHandler quita el rol asignado; los permisos asociados uno a uno
+// y los de sus grupos no cambian
+func (h *UserHandler) UnassignRoleHandler(c *gin.Context) {
+	h.changeRole(c, h.roleAssignmentService.UnassignRole, "Rol quitado al usuario")
+}
+
+func (h *UserHandler) changeRole(c *gin.Context, change func(uint64, uint, domain.Role) error, success string) {
+	systemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems?message=%s&type=danger", url.QueryEscape("ID de sistema inválido")))
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/systems/%d/users?message=%s&type=danger", systemID, url.QueryEscape("ID de usuario inválido")))
+		return
+	}
+
+	back := fmt.Sprintf("/systems/%d/users/%d?", systemID, userID)
+	if origin := c.Query("origin"); origin != "" {
+		back += "origin=" + url.QueryEscape(origin) + "&"
+	}
+
+	roleID, err := strconv.ParseUint(c.Param("role_id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("%smessage=%s&type=danger", back, url.QueryEscape("ID de rol inválido")))
+		return
+	}
+
+	role, err := h.roleAssignmentService.FindRole(systemID, uint(roleID), "")
+	if err == nil {
+		err = change(systemID, uint(userID), role)
+	}
+	if err != nil {
+		message := "Error al actualizar los roles del usuario"
+		if errors.Is(err, services.ErrRoleAssignmentRoleNotFound) || errors.Is(err, services.ErrAuthzUserNotFound) {
+			message = err.Error()
+		} else {
+			log.Printf("No se pudo cambiar el rol %d del usuario %d en el sistema %d: %v", roleID, userID, systemID, err)
+		}
+		c.Redirect(http.StatusFound, fmt.Sprintf("%smessage=%s&type=danger", back, url.QueryEscape(message)))
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("%smessage=%s&type=success", back, url.QueryEscape(success)))
+}
+
 func (h *UserHandler) APISignInHandler(c *gin.Context) {
 	var loginReq forms.SignInRequest
 
@@ -429,24 +680,62 @@ func (h *UserHandler) APISignInHandler(c *gin.Context) {
 		return
 	}
 
-	// Validar campos requeridos
-	if loginReq.SystemID == 0 {
+	h.apiSignIn(c, loginReq.SystemID, services.IdentifierUsername, "username", loginReq.Username, loginReq.Password)
+}
+
+// APISignInByEmailHandler inicia sesión con el correo del usuario
+func (h *UserHandler) APISignInByEmailHandler(c *gin.Context) {
+	var loginReq forms.SignInByEmailRequest
+	if err := c.ShouldBindJSON(&loginReq); err != nil {
 		c.JSON(http.StatusBadRequest, responses.SignResponse{
 			Success: false,
-			Error:   "system_id es requerido",
+			Error:   "Datos de entrada inválidos: " + err.Error(),
 		})
 		return
 	}
 
-	if strings.TrimSpace(loginReq.Username) == "" {
+	h.apiSignIn(c, loginReq.SystemID, services.IdentifierEmail, "email", loginReq.Email, loginReq.Password)
+}
+
+// APISignInIdentifierHandler inicia sesión con el usuario o el correo
+func (h *UserHandler) APISignInIdentifierHandler(c *gin.Context) {
+	var loginReq forms.SignInIdentifierRequest
+	if err := c.ShouldBindJSON(&loginReq); err != nil {
 		c.JSON(http.StatusBadRequest, responses.SignResponse{
 			Success: false,
-			Error:   "username es requerido",
+			Error:   "Datos de entrada inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	h.apiSignIn(c, loginReq.SystemID, services.IdentifierAny, "identifier", loginReq.Identifier, loginReq.Password)
+}
+
+// apiSignIn valida las credenciales y responde igual sin importar cómo se
+// identificó al usuario. field es el nombre del campo para los mensajes.
+func (h *UserHandler) apiSignIn(c *gin.Context, systemID uint64, kind, field, identifier, password string) {
+	// El system_id es opcional; debe coincidir con el sistema de la llave de API
+	apiSystemID := middleware.APISystemID(c)
+	if systemID == 0 {
+		systemID = apiSystemID
+	}
+	if systemID != apiSystemID {
+		c.JSON(http.StatusForbidden, responses.SignResponse{
+			Success: false,
+			Error:   "system_id no corresponde a la llave de API",
 		})
 		return
 	}
 
-	if strings.TrimSpace(loginReq.Password) == "" {
+	if strings.TrimSpace(identifier) == "" {
+		c.JSON(http.StatusBadRequest, responses.SignResponse{
+			Success: false,
+			Error:   field + " es requerido",
+		})
+		return
+	}
+
+	if strings.TrimSpace(password) == "" {
 		c.JSON(http.StatusBadRequest, responses.SignResponse{
 			Success: false,
 			Error:   "password es requerido",
@@ -455,12 +744,21 @@ func (h *UserHandler) APISignInHandler(c *gin.Context) {
 	}
 
 	// Llamar al servicio
-	userWithAccess, err := h.service.ValidateBySystemUsernamePassword(
-		loginReq.SystemID,
-		loginReq.Username,
-		loginReq.Password,
+	userWithAccess, challenge, err := h.service.ValidateBySystemIdentifier(
+		systemID,
+		kind,
+		identifier,
+		password,
+		middleware.EndUserIP(c),
 	)
 
+	// Demasiados intentos fallidos: no se llegó a validar la contraseña
+	var blocked *services.SignInBlockedError
+	if errors.As(err, &blocked) {
+		signInBlocked(c, blocked)
+		return
+	}
+
 	// Manejar errores
 	if err != nil {
 		statusCode := http.StatusInternalServerError
@@ -468,6 +766,7 @@ func (h *UserHandler) APISignInHandler(c *gin.Context) {
 
 		// Determinar el código de estado apropiado
 		if errors.Is(err, gorm.ErrRecordNotFound) ||
+			errors.Is(err, services.ErrInvalidUserCredentials) ||
 			strings.Contains(err.Error(), "credenciales inválidas") ||
 			strings.Contains(err.Error(), "no encontrado") {
 			statusCode = http.StatusUnauthorized
@@ -475,6 +774,15 @@ func (h *UserHandler) APISignInHandler(c *gin.Context) {
 		} else if strings.Contains(err.Error(), "no activo") {
 			statusCode = http.StatusForbidden
 			errorMsg = "Usuario no activo"
+		} else if errors.Is(err, services.ErrPasswordExpired) {
+			// El cliente debe llevar al usuario a /api/v1/users/password/change
+			c.JSON(http.StatusForbidden, responses.SignResponse{
+				Success: false,
+				Message: "Contraseña vencida, debe cambiarla",
+				Error:   err.Error(),
+				Code:    services.SignInPasswordExpired,
+			})
+			return
 		}
 
 		c.JSON(statusCode, responses.SignResponse{
@@ -485,6 +793,20 @@ func (h *UserHandler) APISignInHandler(c *gin.Context) {
 		return
 	}
 
+	// Falta el segundo factor: se devuelve el desafío en lugar de los tokens
+	if challenge != nil {
+		message := "Ingresa el código de verificación"
+		if challenge.EnrollmentRequired {
+			message = "El sistema exige verificación en dos pasos; inscribe tu aplicación autenticadora"
+		}
+		c.JSON(http.StatusAccepted, responses.SignResponse{
+			Success: true,
+			Message: message,
+			MFA:     challenge,
+		})
+		return
+	}
+
 	// Respuesta exitosa
 	c.JSON(http.StatusOK, responses.SignResponse{
 		Success: true,
@@ -492,3 +814,269 @@ func (h *UserHandler) APISignInHandler(c *gin.Context) {
 		Data:    userWithAccess,
 	})
 }
+
+// APIChangePasswordHandler cambia la contraseña validando la actual. Es el
+// camino para los usuarios cuya contraseña venció.
+func (h *UserHandler) APIChangePasswordHandler(c *gin.Context) {
+	var req forms.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.SignResponse{
+			Success: false,
+			Error:   "Datos de entrada inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	apiSystemID := middleware.APISystemID(c)
+	if req.SystemID == 0 {
+		req.SystemID = apiSystemID
+	}
+	if req.SystemID != apiSystemID {
+		c.JSON(http.StatusForbidden, responses.SignResponse{
+			Success: false,
+			Error:   "system_id no corresponde a la llave de API",
+		})
+		return
+	}
+
+	err := h.service.ChangePassword(req.SystemID, req.Username, req.CurrentPassword, req.NewPassword, middleware.EndUserIP(c))
+	if err != nil {
+		var blocked *services.SignInBlockedError
+		statusCode := http.StatusInternalServerError
+		errorMsg := "Error al cambiar la contraseña"
+		switch {
+		case errors.As(err, &blocked):
+			signInBlocked(c, blocked)
+			return
+		case errors.Is(err, services.ErrInvalidUserCredentials):
+			statusCode = http.StatusUnauthorized
+			errorMsg = "Credenciales inválidas"
+		case errors.Is(err, services.ErrUserNotActive):
+			statusCode = http.StatusForbidden
+			errorMsg = "Usuario no activo"
+		case services.IsPasswordRejected(err):
+			statusCode = http.StatusUnprocessableEntity
+			errorMsg = err.Error()
+		default:
+			log.Printf("Error al cambiar la contraseña de %q: %v", req.Username, err)
+		}
+		c.JSON(statusCode, responses.SignResponse{
+			Success: false,
+			Message: errorMsg,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.SignResponse{
+		Success: true,
+		Message: "Contraseña actualizada; inicia sesión con la nueva contraseña",
+	})
+}
+
+// signInBlocked responde a un ingreso rechazado por exceso de intentos fallidos
+func signInBlocked(c *gin.Context, blocked *services.SignInBlockedError) {
+	statusCode := http.StatusTooManyRequests
+	errorMsg := "Demasiados intentos fallidos"
+	if blocked.Code == services.SignInAccountLocked {
+		statusCode = http.StatusLocked
+		errorMsg = "Cuenta bloqueada temporalmente"
+	}
+	retryAfter := int64(math.Ceil(blocked.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	c.JSON(statusCode, responses.SignResponse{
+		Success:    false,
+		Message:    errorMsg,
+		Error:      blocked.Error(),
+		Code:       blocked.Code,
+		RetryAfter: retryAfter,
+	})
+}
+
+// APISignInMFAHandler completa el inicio de sesión con el segundo factor
+func (h *UserHandler) APISignInMFAHandler(c *gin.Context) {
+	var req forms.MFASignInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.SignResponse{
+			Success: false,
+			Error:   "Datos de entrada inválidos: " + err.Error(),
+		})
+		return
+	}
+	if strings.TrimSpace(req.Code) == "" && strings.TrimSpace(req.RecoveryCode) == "" {
+		c.JSON(http.StatusBadRequest, responses.SignResponse{
+			Success: false,
+			Error:   "code o recovery_code es requerido",
+		})
+		return
+	}
+
+	userWithAccess, recoveryCodes, err := h.mfaService.CompleteSignIn(req.ChallengeToken, middleware.APISystemID(c), req.Code, req.RecoveryCode)
+	var blocked *services.SignInBlockedError
+	if errors.As(err, &blocked) {
+		signInBlocked(c, blocked)
+		return
+	}
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		errorMsg := "Error al verificar el código"
+		switch {
+		case errors.Is(err, services.ErrInvalidMFACode), errors.Is(err, services.ErrInvalidMFAChallenge), errors.Is(err, services.ErrMFANotPending):
+			statusCode = http.StatusUnauthorized
+			errorMsg = err.Error()
+		case errors.Is(err, services.ErrUserNotActive):
+			statusCode = http.StatusForbidden
+			errorMsg = "Usuario no activo"
+		default:
+			log.Printf("Error al completar el segundo factor: %v", err)
+		}
+		c.JSON(statusCode, responses.SignResponse{
+			Success: false,
+			Message: errorMsg,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.SignResponse{
+		Success:       true,
+		Message:       "Autenticación exitosa",
+		Data:          userWithAccess,
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+// APIMFAEnrollHandler entrega el secreto TOTP para inscribir la aplicación
+// autenticadora. Se autentica con el challenge_token de un ingreso que exige
+// inscripción o con el access token del usuario.
+func (h *UserHandler) APIMFAEnrollHandler(c *gin.Context) {
+	var req forms.MFAEnrollRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, responses.MFAEnrollmentResponse{
+				Success: false,
+				Error:   "Datos de entrada inválidos: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	systemID := middleware.APISystemID(c)
+	var (
+		enrollment responses.MFAEnrollment
+		err        error
+	)
+	if req.ChallengeToken != "" {
+		enrollment, err = h.mfaService.StartChallengeEnrollment(req.ChallengeToken, systemID)
+	} else {
+		var userID uint
+		userID, err = h.tokenService.AuthenticatedUser(systemID, utils.BearerToken(c.GetHeader("Authorization")))
+		if err == nil {
+			enrollment, err = h.mfaService.StartEnrollment(userID)
+		}
+	}
+
+	if err != nil {
+		h.mfaAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.MFAEnrollmentResponse{
+		Success: true,
+		Message: "Registra el secreto en tu aplicación autenticadora y confirma con un código",
+		Data:    &enrollment,
+	})
+}
+
+// APIMFAConfirmHandler activa la inscripción del usuario del access token
+func (h *UserHandler) APIMFAConfirmHandler(c *gin.Context) {
+	var req forms.MFAConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, responses.MFAEnrollmentResponse{
+			Success: false,
+			Error:   "Datos de entrada inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	userID, err := h.tokenService.AuthenticatedUser(middleware.APISystemID(c), utils.BearerToken(c.GetHeader("Authorization")))
+	if err != nil {
+		h.mfaAPIError(c, err)
+		return
+	}
+
+	recoveryCodes, err := h.mfaService.ConfirmEnrollment(userID, req.Code)
+	if err != nil {
+		h.mfaAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.MFAEnrollmentResponse{
+		Success:       true,
+		Message:       "Verificación en dos pasos activada. Guarda los códigos de recuperación, no se volverán a mostrar",
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+func (h *UserHandler) mfaAPIError(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	errorMsg := "Error en la verificación en dos pasos"
+	switch {
+	case errors.Is(err, services.ErrInvalidAccessToken), errors.Is(err, services.ErrInvalidMFAChallenge), errors.Is(err, services.ErrInvalidMFACode):
+		statusCode = http.StatusUnauthorized
+		errorMsg = err.Error()
+	case errors.Is(err, services.ErrImpersonationTokenNotAllowed):
+		statusCode = http.StatusForbidden
+		errorMsg = err.Error()
+	case errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFANotPending):
+		statusCode = http.StatusConflict
+		errorMsg = err.Error()
+	default:
+		log.Printf("Error en la verificación en dos pasos: %v", err)
+	}
+	c.JSON(statusCode, responses.MFAEnrollmentResponse{
+		Success: false,
+		Error:   errorMsg,
+	})
+}
+
+func (h *UserHandler) APISignOutHandler(c *gin.Context) {
+	accessToken := utils.BearerToken(c.GetHeader("Authorization"))
+	if accessToken == "" {
+		c.JSON(http.StatusUnauthorized, responses.SignResponse{
+			Success: false,
+			Error:   "Se requiere la cabecera Authorization: Bearer <token>",
+		})
+		return
+	}
+
+	// El refresh token es opcional
+	var req forms.SignOutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, responses.SignResponse{
+				Success: false,
+				Error:   "Datos de entrada inválidos: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := h.service.SignOut(middleware.APISystemID(c), accessToken, req.RefreshToken); err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidAccessToken) {
+			statusCode = http.StatusUnauthorized
+		}
+		c.JSON(statusCode, responses.SignResponse{
+			Success: false,
+			Message: "No se pudo cerrar la sesión",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.SignResponse{
+		Success: true,
+		Message: "Sesión cerrada exitosamente",
+	})
+}
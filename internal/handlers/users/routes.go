@@ -6,7 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterUserRoutes(r *gin.Engine, handler *UserHandler) {
+func RegisterUserRoutes(r *gin.Engine, handler *UserHandler, apiKeys middleware.APIKeyValidator) {
 	// views
 	usersGroup := r.Group("/users", middleware.AuthRequired())
 	{
@@ -16,11 +16,23 @@ func RegisterUserRoutes(r *gin.Engine, handler *UserHandler) {
 		usersGroup.POST("/:id/edit", handler.EditUserHandler)
 		usersGroup.GET("/:id/edit", handler.EditUserHandler)
 		usersGroup.GET("/:id/delete", handler.DeleteUserHandler)
+		usersGroup.GET("/:id/activation/resend", handler.ResendActivationHandler)
+		usersGroup.GET("/:id/mfa/reset", handler.ResetMFAHandler)
+		usersGroup.GET("/:id/sign-in-locks/clear", handler.ClearSignInLocksHandler)
+		usersGroup.POST("/:id/directory/link", handler.LinkDirectoryHandler)
+		usersGroup.GET("/:id/directory/unlink", handler.UnlinkDirectoryHandler)
 	}
 	// auth
-	authGroup := r.Group("/api/v1/users", middleware.XAuthTriggerRequired())
+	authGroup := r.Group("/api/v1/users", middleware.APIKeyRequired(apiKeys))
 	{
+		authGroup.POST("/sign-in", handler.APISignInIdentifierHandler)
 		authGroup.POST("/sign-in/by-username", handler.APISignInHandler)
+		authGroup.POST("/sign-in/by-email", handler.APISignInByEmailHandler)
+		authGroup.POST("/sign-in/mfa", handler.APISignInMFAHandler)
+		authGroup.POST("/password/change", handler.APIChangePasswordHandler)
+		authGroup.POST("/mfa/enroll", handler.APIMFAEnrollHandler)
+		authGroup.POST("/mfa/confirm", handler.APIMFAConfirmHandler)
+		authGroup.POST("/sign-out", handler.APISignOutHandler)
 	}
 	// apis
 
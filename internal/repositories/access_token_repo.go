@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AccessTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAccessTokenRepository(db *gorm.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{db: db}
+}
+
+func (r *AccessTokenRepository) Create(token *domain.AccessToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *AccessTokenRepository) GetByJTI(jti string) (domain.AccessToken, error) {
+	var token domain.AccessToken
+	result := r.db.Where("jti = ?", jti).First(&token)
+	if result.Error != nil {
+		return domain.AccessToken{}, result.Error
+	}
+	return token, nil
+}
+
+func (r *AccessTokenRepository) Revoke(jti, reason string, when time.Time) error {
+	return r.db.Model(&domain.AccessToken{}).
+		Where("jti = ? AND revoked_at IS NULL", jti).
+		Updates(map[string]interface{}{"revoked_at": when, "revoke_reason": reason}).Error
+}
+
+// RevokeByUser revoca los tokens vigentes del usuario. Con systemID igual a 0
+// se revocan los de todos los sistemas.
+func (r *AccessTokenRepository) RevokeByUser(userID uint, systemID uint64, reason string, when time.Time) error {
+	query := r.db.Model(&domain.AccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, when)
+	if systemID > 0 {
+		query = query.Where("system_id = ?", systemID)
+	}
+	return query.Updates(map[string]interface{}{"revoked_at": when, "revoke_reason": reason}).Error
+}
+
+// RevokeByClient revoca los tokens vigentes emitidos al cliente
+func (r *AccessTokenRepository) RevokeByClient(systemClientID uint, reason string, when time.Time) error {
+	return r.db.Model(&domain.AccessToken{}).
+		Where("system_client_id = ? AND revoked_at IS NULL AND expires_at > ?", systemClientID, when).
+		Updates(map[string]interface{}{"revoked_at": when, "revoke_reason": reason}).Error
+}
+
+// GetRevoked lista los tokens revocados que aún no expiran, opcionalmente
+// filtrados por sistema y por fecha de revocación
+func (r *AccessTokenRepository) GetRevoked(systemID uint64, since time.Time) ([]domain.AccessToken, error) {
+	var tokens []domain.AccessToken
+	query := r.db.Where("revoked_at IS NOT NULL AND expires_at > ?", time.Now())
+	if systemID > 0 {
+		query = query.Where("system_id = ?", systemID)
+	}
+	if !since.IsZero() {
+		query = query.Where("revoked_at >= ?", since)
+	}
+	err := query.Order("revoked_at").Find(&tokens).Error
+	return tokens, err
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminRepository(db *gorm.DB) *AdminRepository {
+	return &AdminRepository{db: db}
+}
+
+func (r *AdminRepository) GetByID(id uint) (domain.Admin, error) {
+	var admin domain.Admin
+	result := r.db.First(&admin, id)
+	if result.Error != nil {
+		return domain.Admin{}, result.Error
+	}
+	return admin, nil
+}
+
+func (r *AdminRepository) GetByUsername(username string) (domain.Admin, error) {
+	var admin domain.Admin
+	result := r.db.Where("username = ?", username).First(&admin)
+	if result.Error != nil {
+		return domain.Admin{}, result.Error
+	}
+	return admin, nil
+}
+
+func (r *AdminRepository) CheckAdminExists(username, email string) error {
+	var existing domain.Admin
+	result := r.db.Where("username = ? OR email = ?", username, email).First(&existing)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil // No existe, todo bien
+		}
+		return result.Error // Error de base de datos
+	}
+	return errors.New("El usuario y/o correo del administrador ya están en uso")
+}
+
+func (r *AdminRepository) CountSuperAdmins() (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.Admin{}).Where("is_super_admin = ?", true).Count(&count).Error
+	return count, err
+}
+
+func (r *AdminRepository) Create(admin *domain.Admin) error {
+	return r.db.Create(admin).Error
+}
+
+func (r *AdminRepository) UpdatePassword(id uint, hash string) error {
+	return r.db.Model(&domain.Admin{}).Where("id = ?", id).Update("password", hash).Error
+}
+
+func (r *AdminRepository) UpdateLastLogin(id uint, when time.Time) error {
+	return r.db.Model(&domain.Admin{}).Where("id = ?", id).Update("last_login", when).Error
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminSessionRepository(db *gorm.DB) *AdminSessionRepository {
+	return &AdminSessionRepository{db: db}
+}
+
+// activeAdminSessions filtra las sesiones de administradores que siguen vigentes
+func activeAdminSessions(idleSince, now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("admin_sessions.admin_id IS NOT NULL AND admin_sessions.expires_at > ? AND admin_sessions.last_seen_at > ?", now, idleSince)
+	}
+}
+
+func (r *AdminSessionRepository) GetByID(id uint) (domain.AdminSession, error) {
+	var session domain.AdminSession
+	err := r.db.First(&session, id).Error
+	return session, err
+}
+
+// GetActiveByAdmin devuelve las sesiones vigentes de un administrador
+func (r *AdminSessionRepository) GetActiveByAdmin(adminID uint, idleSince, now time.Time) ([]domain.AdminSession, error) {
+	var sessions []domain.AdminSession
+	err := r.db.Scopes(activeAdminSessions(idleSince, now)).
+		Where("admin_sessions.admin_id = ?", adminID).
+		Order("admin_sessions.last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// GetActive devuelve las sesiones vigentes de todos los administradores
+func (r *AdminSessionRepository) GetActive(idleSince, now time.Time) ([]domain.AdminSession, error) {
+	var sessions []domain.AdminSession
+	err := r.db.Table("admin_sessions").
+		Select("admin_sessions.*, admins.username AS username").
+		Joins("JOIN admins ON admins.id = admin_sessions.admin_id").
+		Scopes(activeAdminSessions(idleSince, now)).
+		Order("admins.username, admin_sessions.last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// UpdateClient registra la IP y el navegador desde los que se inició la sesión
+func (r *AdminSessionRepository) UpdateClient(tokenHash, ipAddress, userAgent string) error {
+	return r.db.Model(&domain.AdminSession{}).Where("token_hash = ?", tokenHash).Updates(map[string]interface{}{
+		"ip_address": ipAddress,
+		"user_agent": userAgent,
+	}).Error
+}
+
+func (r *AdminSessionRepository) Delete(id uint) error {
+	return r.db.Delete(&domain.AdminSession{}, id).Error
+}
+
+// DeleteOthersByAdmin borra las sesiones del administrador salvo la indicada
+func (r *AdminSessionRepository) DeleteOthersByAdmin(adminID uint, keepTokenHash string) (int64, error) {
+	result := r.db.Where("admin_id = ? AND token_hash <> ?", adminID, keepTokenHash).Delete(&domain.AdminSession{})
+	return result.RowsAffected, result.Error
+}
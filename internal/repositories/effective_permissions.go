@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// effectivePermissionsQuery arma los permisos vigentes del usuario en el
+// sistema: los asignados en systems_users_permissions, los otorgados a los
+// grupos globales o del sistema de los que es miembro, todos los de los roles
+// asignados en systems_users_roles y, por cada rol que tiene o en el que tiene
+// algún permiso, los de sus roles ancestros. Los heredados se informan bajo el
+// rol que tiene el usuario. Los permisos de systems_users_excluded_permissions
+// se descuentan de los que llegan por roles, no de los asignados directamente
+// ni por grupos. El UNION de ancestors evita recorrer un ciclo más de una vez.
+const effectivePermissionsQuery = `
+        WITH RECURSIVE granted (permission_id) AS (
+            SELECT SUP.permission_id
+            FROM systems_users_permissions AS SUP
+            WHERE SUP.user_id = @user
+            UNION
+            SELECT GP.permission_id
+            FROM groups_users AS GU
+            INNER JOIN groups AS G ON GU.group_id = G.id
+            INNER JOIN groups_permissions AS GP ON GP.group_id = G.id
+            WHERE GU.user_id = @user AND (G.system_id IS NULL OR G.system_id = @system)
+        ),
+        assigned_roles (role_id) AS (
+            SELECT SUR.role_id
+            FROM systems_users_roles AS SUR
+            WHERE SUR.user_id = @user AND SUR.system_id = @system
+        ),
+        excluded (permission_id) AS (
+            SELECT SUEP.permission_id
+            FROM systems_users_excluded_permissions AS SUEP
+            WHERE SUEP.user_id = @user AND SUEP.system_id = @system
+        ),
+        held_roles (role_id, parent_id) AS (
+            SELECT R.id, R.parent_id
+            FROM granted AS GR
+            INNER JOIN permissions AS P ON GR.permission_id = P.id
+            INNER JOIN roles AS R ON P.role_id = R.id
+            WHERE R.system_id = @system
+            UNION
+            SELECT R.id, R.parent_id
+            FROM assigned_roles AS AR
+            INNER JOIN roles AS R ON AR.role_id = R.id
+            WHERE R.system_id = @system
+        ),
+        ancestors (role_id, ancestor_id) AS (
+            SELECT role_id, parent_id FROM held_roles WHERE parent_id IS NOT NULL
+            UNION
+            SELECT A.role_id, R.parent_id
+            FROM ancestors AS A
+            INNER JOIN roles AS R ON R.id = A.ancestor_id
+            WHERE R.parent_id IS NOT NULL
+        ),
+        user_permissions (role_id, permission_id) AS (
+            SELECT P.role_id, P.id
+            FROM granted AS GR
+            INNER JOIN permissions AS P ON GR.permission_id = P.id
+            UNION
+            SELECT RP.role_id, RP.permission_id
+            FROM (
+                SELECT AR.role_id, P.id AS permission_id
+                FROM assigned_roles AS AR
+                INNER JOIN permissions AS P ON P.role_id = AR.role_id
+                UNION
+                SELECT A.role_id, P.id
+                FROM ancestors AS A
+                INNER JOIN permissions AS P ON P.role_id = A.ancestor_id
+            ) AS RP
+            WHERE RP.permission_id NOT IN (SELECT permission_id FROM excluded)
+        )
+        SELECT
+            S.id AS system_id,
+            S.name AS system_name,
+            R.id AS role_id,
+            R.name AS role_name,
+            P.id AS permission_id,
+            P.name AS permission_name
+        FROM user_permissions AS UP
+        INNER JOIN roles AS R ON UP.role_id = R.id
+        INNER JOIN permissions AS P ON UP.permission_id = P.id
+        INNER JOIN systems AS S ON R.system_id = S.id
+        WHERE S.id = @system %s
+        ORDER BY R.id, P.id;
+    `
+
+// effectivePermissions ejecuta effectivePermissionsQuery; con names solo
+// devuelve esos permisos
+func effectivePermissions(db *gorm.DB, userID uint, systemID uint64, names []string) ([]domain.UserSystemPermission, error) {
+	var flatPermissions []domain.UserSystemPermission
+
+	filter := ""
+	args := map[string]interface{}{"user": userID, "system": systemID}
+	if names != nil {
+		filter = "AND P.name IN @names"
+		args["names"] = names
+	}
+
+	query := fmt.Sprintf(effectivePermissionsQuery, filter)
+	if err := db.Raw(query, args).Scan(&flatPermissions).Error; err != nil {
+		return nil, err
+	}
+	return flatPermissions, nil
+}
@@ -0,0 +1,304 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/testutil"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// hierarchyRole es un rol de la jerarquía de prueba con sus permisos por ID
+type hierarchyRole struct {
+	id          uint
+	name        string
+	systemID    uint
+	parentID    uint
+	permissions map[uint]string
+}
+
+// testHierarchy es, en el sistema Uno, Admin <- Manager <- Operator, Auditor
+// sin padre y Loop1 <-> Loop2, un ciclo ya guardado; en el sistema Dos, Externo
+var testHierarchy = []hierarchyRole{
+	{1, "Admin", 1, 0, map[uint]string{11: "admin.all"}},
+	{2, "Manager", 1, 1, map[uint]string{21: "reports.view", 22: "reports.export"}},
+	{3, "Operator", 1, 2, map[uint]string{31: "orders.view"}},
+	{4, "Auditor", 1, 0, map[uint]string{41: "audit.view"}},
+	{5, "Externo", 2, 0, map[uint]string{51: "external.view"}},
+	{6, "Loop1", 1, 7, map[uint]string{61: "loop1.view"}},
+	{7, "Loop2", 1, 6, map[uint]string{71: "loop2.view"}},
+}
+
+// newHierarchyDB crea testHierarchy, los grupos 1 (del sistema Uno), 2 (global)
+// y 3 (del sistema Dos) y al usuario 1, asociado a ambos sistemas
+func newHierarchyDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := testutil.NewDB(t)
+	now := time.Now()
+
+	create := func(value interface{}) {
+		t.Helper()
+		if err := db.Create(value).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	create(&[]domain.System{{ID: 1, Name: "Uno", Created: now, Updated: now}, {ID: 2, Name: "Dos", Created: now, Updated: now}})
+	create(&domain.User{ID: 1, Username: "jdoe", Email: "jdoe@example.com", Activated: true, Created: now, Updated: now})
+	create(&[]domain.SystemUser{{SystemID: 1, UserID: 1, Created: now}, {SystemID: 2, UserID: 1, Created: now}})
+
+	// Los padres se enlazan después de crear todos los roles por el ciclo
+	for _, role := range testHierarchy {
+		create(&domain.Role{ID: role.id, Name: role.name, SystemID: role.systemID, Created: now, Updated: now})
+		for id, name := range role.permissions {
+			create(&domain.Permission{ID: id, Name: name, RoleID: role.id, Created: now, Updated: now})
+		}
+	}
+	for _, role := range testHierarchy {
+		if role.parentID != 0 {
+			if err := db.Model(&domain.Role{ID: role.id}).Update("parent_id", role.parentID).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	systemOne, systemTwo := uint(1), uint(2)
+	create(&[]domain.Group{
+		{ID: 1, Name: "Sistema uno", SystemID: &systemOne, Created: now, Updated: now},
+		{ID: 2, Name: "Global", Created: now, Updated: now},
+		{ID: 3, Name: "Sistema dos", SystemID: &systemTwo, Created: now, Updated: now},
+	})
+	return db
+}
+
+// grantPermissions asigna permisos sueltos al usuario 1 en el sistema
+func grantPermissions(t *testing.T, db *gorm.DB, systemID uint, permissionIDs ...uint) {
+	t.Helper()
+	for _, id := range permissionIDs {
+		grant := domain.SystemUserPermission{SystemID: systemID, UserID: 1, PermissionID: id, Created: time.Now()}
+		if err := db.Create(&grant).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// assignRoles asigna roles completos al usuario 1 en el sistema
+func assignRoles(t *testing.T, db *gorm.DB, systemID uint, roleIDs ...uint) {
+	t.Helper()
+	for _, id := range roleIDs {
+		assignment := domain.SystemUserRole{SystemID: systemID, UserID: 1, RoleID: id, Created: time.Now()}
+		if err := db.Create(&assignment).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// excludePermissions quita permisos de los roles del usuario 1 en el sistema
+func excludePermissions(t *testing.T, db *gorm.DB, systemID uint, permissionIDs ...uint) {
+	t.Helper()
+	for _, id := range permissionIDs {
+		exclusion := domain.SystemUserExcludedPermission{SystemID: systemID, UserID: 1, PermissionID: id, Created: time.Now()}
+		if err := db.Create(&exclusion).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// grantGroups hace al usuario 1 miembro de los grupos y otorga a cada grupo sus
+// permisos
+func grantGroups(t *testing.T, db *gorm.DB, memberOf []uint, grants map[uint][]uint) {
+	t.Helper()
+	now := time.Now()
+	for _, groupID := range memberOf {
+		if err := db.Create(&domain.GroupUser{GroupID: groupID, UserID: 1, Created: now}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for groupID, permissionIDs := range grants {
+		for _, id := range permissionIDs {
+			if err := db.Create(&domain.GroupPermission{GroupID: groupID, PermissionID: id, Created: now}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// rolePermissions resume las filas como "rol:permiso", ordenadas
+func rolePermissions(rows []domain.UserSystemPermission) []string {
+	got := []string{}
+	for _, p := range rows {
+		got = append(got, fmt.Sprintf("%s:%s", p.RoleName, p.PermissionName))
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestEffectivePermissions(t *testing.T) {
+	tests := []struct {
+		name          string
+		direct        []uint // permisos asignados en el sistema Uno
+		otherSystem   []uint // permisos asignados en el sistema Dos
+		roles         []uint // roles asignados en el sistema Uno
+		excluded      []uint // permisos excluidos en el sistema Uno
+		excludedOther []uint // permisos excluidos en el sistema Dos
+		memberOf      []uint // grupos del usuario
+		groupGrants   map[uint][]uint
+		want          []string
+	}{
+		{
+			name: "sin asignaciones",
+			want: []string{},
+		},
+		{
+			name:   "permiso directo con sus ancestros",
+			direct: []uint{31},
+			want:   []string{"Operator:admin.all", "Operator:orders.view", "Operator:reports.export", "Operator:reports.view"},
+		},
+		{
+			name:   "del rol propio solo lo asignado",
+			direct: []uint{21},
+			want:   []string{"Manager:admin.all", "Manager:reports.view"},
+		},
+		{
+			name:   "roles sin relación",
+			direct: []uint{21, 41},
+			want:   []string{"Auditor:audit.view", "Manager:admin.all", "Manager:reports.view"},
+		},
+		{
+			name:        "permisos de otro sistema",
+			otherSystem: []uint{51},
+			want:        []string{},
+		},
+		{
+			name:  "rol asignado con sus ancestros",
+			roles: []uint{2},
+			want:  []string{"Manager:admin.all", "Manager:reports.export", "Manager:reports.view"},
+		},
+		{
+			name:     "rol asignado sin un permiso propio",
+			roles:    []uint{2},
+			excluded: []uint{22},
+			want:     []string{"Manager:admin.all", "Manager:reports.view"},
+		},
+		{
+			name:     "rol asignado sin un permiso heredado",
+			roles:    []uint{2},
+			excluded: []uint{11},
+			want:     []string{"Manager:reports.export", "Manager:reports.view"},
+		},
+		{
+			name:     "la exclusión no quita un permiso directo",
+			direct:   []uint{21},
+			excluded: []uint{21},
+			want:     []string{"Manager:admin.all", "Manager:reports.view"},
+		},
+		{
+			name:          "la exclusión de otro sistema no aplica",
+			roles:         []uint{2},
+			excludedOther: []uint{22},
+			want:          []string{"Manager:admin.all", "Manager:reports.export", "Manager:reports.view"},
+		},
+		{
+			name:  "rol de otro sistema",
+			roles: []uint{5},
+			want:  []string{},
+		},
+		{
+			name:  "rol asignado en un ciclo guardado",
+			roles: []uint{6},
+			want:  []string{"Loop1:loop1.view", "Loop1:loop2.view"},
+		},
+		{
+			name:        "grupo del sistema",
+			memberOf:    []uint{1},
+			groupGrants: map[uint][]uint{1: {41}},
+			want:        []string{"Auditor:audit.view"},
+		},
+		{
+			name:        "grupo global",
+			memberOf:    []uint{2},
+			groupGrants: map[uint][]uint{2: {41}},
+			want:        []string{"Auditor:audit.view"},
+		},
+		{
+			name:        "grupo de otro sistema",
+			memberOf:    []uint{3},
+			groupGrants: map[uint][]uint{3: {41}},
+			want:        []string{},
+		},
+		{
+			name:        "grupo sin ser miembro",
+			groupGrants: map[uint][]uint{1: {41}},
+			want:        []string{},
+		},
+		{
+			name:        "grupo con un permiso que hereda",
+			memberOf:    []uint{2},
+			groupGrants: map[uint][]uint{2: {21}},
+			want:        []string{"Manager:admin.all", "Manager:reports.view"},
+		},
+		{
+			name:        "el mismo permiso directo y por grupo",
+			direct:      []uint{41},
+			memberOf:    []uint{1},
+			groupGrants: map[uint][]uint{1: {41}},
+			want:        []string{"Auditor:audit.view"},
+		},
+		{
+			name:        "la exclusión no quita un permiso de grupo",
+			memberOf:    []uint{1},
+			groupGrants: map[uint][]uint{1: {41}},
+			excluded:    []uint{41},
+			want:        []string{"Auditor:audit.view"},
+		},
+		{
+			name:        "la exclusión quita lo heredado por un permiso de grupo",
+			memberOf:    []uint{2},
+			groupGrants: map[uint][]uint{2: {21}},
+			excluded:    []uint{11},
+			want:        []string{"Manager:reports.view"},
+		},
+		{
+			name:   "ciclo guardado",
+			direct: []uint{61},
+			want:   []string{"Loop1:loop1.view", "Loop1:loop2.view"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newHierarchyDB(t)
+			grantPermissions(t, db, 1, tt.direct...)
+			grantPermissions(t, db, 2, tt.otherSystem...)
+			assignRoles(t, db, 1, tt.roles...)
+			excludePermissions(t, db, 1, tt.excluded...)
+			excludePermissions(t, db, 2, tt.excludedOther...)
+			grantGroups(t, db, tt.memberOf, tt.groupGrants)
+
+			rows, err := effectivePermissions(db, 1, 1, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rolePermissions(rows); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("permisos = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEffectivePermissionsByName(t *testing.T) {
+	db := newHierarchyDB(t)
+	grantPermissions(t, db, 1, 31, 21)
+
+	rows, err := effectivePermissions(db, 1, 1, []string{"reports.view", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Un permiso aparece una vez por cada rol que lo otorga
+	want := []string{"Manager:reports.view", "Operator:reports.view"}
+	if got := rolePermissions(rows); !reflect.DeepEqual(got, want) {
+		t.Fatalf("permisos = %v, se esperaba %v", got, want)
+	}
+}
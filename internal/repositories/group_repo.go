@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GroupRepository struct {
+	db *gorm.DB
+}
+
+func NewGroupRepository(db *gorm.DB) *GroupRepository {
+	return &GroupRepository{db: db}
+}
+
+// groupSummaries agrega a cada grupo el nombre de su sistema y la cantidad de
+// miembros y permisos
+func groupSummaries(db *gorm.DB) *gorm.DB {
+	return db.Table("groups").
+		Select(`groups.id, groups.name, groups.description, groups.system_id,
+			COALESCE(systems.name, '') AS system_name,
+			(SELECT COUNT(*) FROM groups_users WHERE groups_users.group_id = groups.id) AS members,
+			(SELECT COUNT(*) FROM groups_permissions WHERE groups_permissions.group_id = groups.id) AS permissions`).
+		Joins("LEFT JOIN systems ON systems.id = groups.system_id")
+}
+
+// GetAll devuelve los grupos globales primero y luego los de cada sistema
+func (r *GroupRepository) GetAll() ([]domain.GroupSummary, error) {
+	var groups []domain.GroupSummary
+	err := r.db.Scopes(groupSummaries).
+		Order("groups.system_id IS NOT NULL, systems.name, groups.name").
+		Scan(&groups).Error
+	return groups, err
+}
+
+// GetByUser devuelve los grupos de los que el usuario es miembro
+func (r *GroupRepository) GetByUser(userID uint) ([]domain.GroupSummary, error) {
+	var groups []domain.GroupSummary
+	err := r.db.Scopes(groupSummaries).
+		Joins("JOIN groups_users AS GU ON GU.group_id = groups.id").
+		Where("GU.user_id = ?", userID).
+		Order("groups.system_id IS NOT NULL, systems.name, groups.name").
+		Scan(&groups).Error
+	return groups, err
+}
+
+func (r *GroupRepository) GetByID(id uint64) (domain.Group, error) {
+	var group domain.Group
+	result := r.db.First(&group, id)
+	if result.Error != nil {
+		return domain.Group{}, result.Error
+	}
+	return group, nil
+}
+
+// CheckNameExists indica si ya hay otro grupo con el nombre en el mismo
+// alcance (global o el sistema)
+func (r *GroupRepository) CheckNameExists(name string, systemID *uint, exceptID uint) (bool, error) {
+	var count int64
+	query := r.db.Model(&domain.Group{}).Where("LOWER(name) = LOWER(?) AND id <> ?", name, exceptID)
+	if systemID == nil {
+		query = query.Where("system_id IS NULL")
+	} else {
+		query = query.Where("system_id = ?", *systemID)
+	}
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+func (r *GroupRepository) Create(group *domain.Group) error {
+	return r.db.Create(group).Error
+}
+
+func (r *GroupRepository) Update(group *domain.Group) error {
+	return r.db.Save(group).Error
+}
+
+func (r *GroupRepository) Delete(id uint64) error {
+	return r.db.Delete(&domain.Group{}, id).Error
+}
+
+// GetMembers devuelve los usuarios del grupo ordenados por nombre de usuario
+func (r *GroupRepository) GetMembers(groupID uint64) ([]domain.GroupMember, error) {
+	var members []domain.GroupMember
+	err := r.db.Table("groups_users").
+		Select("users.id, users.username, users.email, users.activated, groups_users.created").
+		Joins("JOIN users ON users.id = groups_users.user_id").
+		Where("groups_users.group_id = ?", groupID).
+		Order("users.username").
+		Scan(&members).Error
+	return members, err
+}
+
+// AddMembers agrega los usuarios al grupo; los que ya son miembros se ignoran
+func (r *GroupRepository) AddMembers(groupID uint64, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	members := make([]domain.GroupUser, 0, len(userIDs))
+	for _, userID := range userIDs {
+		members = append(members, domain.GroupUser{GroupID: uint(groupID), UserID: userID, Created: now})
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error
+}
+
+func (r *GroupRepository) RemoveMember(groupID, userID uint64) error {
+	return r.db.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&domain.GroupUser{}).Error
+}
+
+// GetGrantablePermissions devuelve los permisos que se pueden otorgar al
+// grupo: los de su sistema o, si es global, los de todos los sistemas
+func (r *GroupRepository) GetGrantablePermissions(group domain.Group) ([]domain.GroupGrantablePermission, error) {
+	var permissions []domain.GroupGrantablePermission
+
+	query := r.db.Table("permissions AS P").
+		Select(`S.id AS system_id, S.name AS system_name, R.id AS role_id, R.name AS role_name,
+			P.id AS permission_id, P.name AS permission_name,
+			CASE WHEN GP.id IS NOT NULL THEN 1 ELSE 0 END AS is_assigned`).
+		Joins("JOIN roles AS R ON R.id = P.role_id").
+		Joins("JOIN systems AS S ON S.id = R.system_id").
+		Joins("LEFT JOIN groups_permissions AS GP ON GP.permission_id = P.id AND GP.group_id = ?", group.ID)
+	if group.SystemID != nil {
+		query = query.Where("S.id = ?", *group.SystemID)
+	}
+
+	err := query.Order("S.name, R.name, P.name").Scan(&permissions).Error
+	return permissions, err
+}
+
+// SetPermissions reemplaza los permisos otorgados al grupo
+func (r *GroupRepository) SetPermissions(groupID uint64, permissionIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", groupID).Delete(&domain.GroupPermission{}).Error; err != nil {
+			return err
+		}
+		if len(permissionIDs) == 0 {
+			return nil
+		}
+		now := time.Now()
+		permissions := make([]domain.GroupPermission, 0, len(permissionIDs))
+		for _, permissionID := range permissionIDs {
+			permissions = append(permissions, domain.GroupPermission{GroupID: uint(groupID), PermissionID: permissionID, Created: now})
+		}
+		return tx.Create(&permissions).Error
+	})
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ImpersonationRepository struct {
+	db *gorm.DB
+}
+
+func NewImpersonationRepository(db *gorm.DB) *ImpersonationRepository {
+	return &ImpersonationRepository{db: db}
+}
+
+// impersonationsWithNames agrega el administrador, el usuario y el sistema de cada registro
+func impersonationsWithNames(db *gorm.DB) *gorm.DB {
+	return db.Table("impersonations").
+		Select("impersonations.*, admins.username AS admin_username, users.username AS username, systems.name AS system_name").
+		Joins("JOIN admins ON admins.id = impersonations.admin_id").
+		Joins("JOIN users ON users.id = impersonations.user_id").
+		Joins("JOIN systems ON systems.id = impersonations.system_id")
+}
+
+func (r *ImpersonationRepository) Create(impersonation *domain.Impersonation) error {
+	return r.db.Create(impersonation).Error
+}
+
+func (r *ImpersonationRepository) GetByID(id uint) (domain.Impersonation, error) {
+	var impersonation domain.Impersonation
+	err := r.db.Scopes(impersonationsWithNames).Where("impersonations.id = ?", id).First(&impersonation).Error
+	return impersonation, err
+}
+
+// GetRecent devuelve las últimas suplantaciones, las más nuevas primero. Con
+// adminID distinto de 0 solo las de ese administrador.
+func (r *ImpersonationRepository) GetRecent(adminID uint, limit int) ([]domain.Impersonation, error) {
+	var impersonations []domain.Impersonation
+	query := r.db.Scopes(impersonationsWithNames)
+	if adminID > 0 {
+		query = query.Where("impersonations.admin_id = ?", adminID)
+	}
+	err := query.Order("impersonations.created DESC").Limit(limit).Find(&impersonations).Error
+	return impersonations, err
+}
+
+// End marca la suplantación como terminada si aún no lo estaba
+func (r *ImpersonationRepository) End(id uint, when time.Time) error {
+	return r.db.Model(&domain.Impersonation{}).
+		Where("id = ? AND ended_at IS NULL", id).
+		Update("ended_at", when).Error
+}
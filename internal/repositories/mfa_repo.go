@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MFARepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+func (r *MFARepository) GetByUser(userID uint) (domain.UserMFA, error) {
+	var mfa domain.UserMFA
+	result := r.db.Where("user_id = ?", userID).First(&mfa)
+	if result.Error != nil {
+		return domain.UserMFA{}, result.Error
+	}
+	return mfa, nil
+}
+
+// SavePending guarda una inscripción sin confirmar, reemplazando la anterior
+func (r *MFARepository) SavePending(mfa *domain.UserMFA) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "confirmed_at", "last_used_step", "created"}),
+	}).Create(mfa).Error
+}
+
+// Confirm activa la inscripción y reemplaza sus códigos de recuperación
+func (r *MFARepository) Confirm(userID uint, step int64, codeHashes []string, when time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.UserMFA{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"confirmed_at": when, "last_used_step": step}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.UserMFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]domain.UserMFARecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, domain.UserMFARecoveryCode{UserID: userID, CodeHash: hash, Created: when})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseStep registra el paso TOTP usado solo si es posterior al último, para
+// que un mismo código no sirva dos veces
+func (r *MFARepository) UseStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&domain.UserMFA{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UseRecoveryCode consume un código de recuperación vigente
+func (r *MFARepository) UseRecoveryCode(userID uint, codeHash string, when time.Time) (bool, error) {
+	result := r.db.Model(&domain.UserMFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", when)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CountRecoveryCodes cuenta los códigos de recuperación sin usar
+func (r *MFARepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.UserMFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// Delete elimina la inscripción, sus códigos y los desafíos pendientes del usuario
+func (r *MFARepository) Delete(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.MFAChallenge{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.UserMFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&domain.UserMFA{}).Error
+	})
+}
+
+func (r *MFARepository) CreateChallenge(challenge *domain.MFAChallenge) error {
+	return r.db.Create(challenge).Error
+}
+
+func (r *MFARepository) GetChallengeByHash(tokenHash string) (domain.MFAChallenge, error) {
+	var challenge domain.MFAChallenge
+	result := r.db.Where("token_hash = ?", tokenHash).First(&challenge)
+	if result.Error != nil {
+		return domain.MFAChallenge{}, result.Error
+	}
+	return challenge, nil
+}
+
+func (r *MFARepository) IncrementChallengeAttempts(id uint) error {
+	return r.db.Model(&domain.MFAChallenge{}).Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// MarkChallengeUsed consume el desafío solo si aún no lo estaba
+func (r *MFARepository) MarkChallengeUsed(id uint, when time.Time) (bool, error) {
+	result := r.db.Model(&domain.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", when)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OAuthRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthRepository(db *gorm.DB) *OAuthRepository {
+	return &OAuthRepository{db: db}
+}
+
+func (r *OAuthRepository) GetRedirectURIs(systemID uint64) ([]domain.SystemRedirectURI, error) {
+	var uris []domain.SystemRedirectURI
+	result := r.db.Where("system_id = ?", systemID).Order("uri").Find(&uris)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return uris, nil
+}
+
+// HasRedirectURI indica si la URI está registrada exactamente para el sistema
+func (r *OAuthRepository) HasRedirectURI(systemID uint64, uri string) (bool, error) {
+	var count int64
+	err := r.db.Model(&domain.SystemRedirectURI{}).
+		Where("system_id = ? AND uri = ?", systemID, uri).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *OAuthRepository) CreateRedirectURI(uri *domain.SystemRedirectURI) error {
+	return r.db.Create(uri).Error
+}
+
+func (r *OAuthRepository) DeleteRedirectURI(systemID uint64, id uint64) error {
+	return r.db.Where("system_id = ? AND id = ?", systemID, id).Delete(&domain.SystemRedirectURI{}).Error
+}
+
+func (r *OAuthRepository) CreateAuthorizationCode(code *domain.AuthorizationCode) error {
+	return r.db.Create(code).Error
+}
+
+func (r *OAuthRepository) GetAuthorizationCode(codeHash string) (domain.AuthorizationCode, error) {
+	var code domain.AuthorizationCode
+	result := r.db.Where("code_hash = ?", codeHash).First(&code)
+	if result.Error != nil {
+		return domain.AuthorizationCode{}, result.Error
+	}
+	return code, nil
+}
+
+// MarkAuthorizationCodeUsed consume el código solo si aún no se había usado.
+// Devuelve false cuando otra petición lo canjeó primero.
+func (r *OAuthRepository) MarkAuthorizationCodeUsed(id uint, when time.Time) (bool, error) {
+	result := r.db.Model(&domain.AuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", when)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
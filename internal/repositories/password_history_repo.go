@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{db: db}
+}
+
+// GetRecent devuelve las últimas contraseñas del usuario, de la más reciente a la más antigua
+func (r *PasswordHistoryRepository) GetRecent(userID uint, limit int) ([]domain.PasswordHistory, error) {
+	var history []domain.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).
+		Order("created DESC, id DESC").
+		Limit(limit).
+		Find(&history).Error
+	return history, err
+}
+
+// Add registra la contraseña y conserva solo las últimas keep del usuario
+func (r *PasswordHistoryRepository) Add(entry *domain.PasswordHistory, keep int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		recent := tx.Model(&domain.PasswordHistory{}).Select("id").
+			Where("user_id = ?", entry.UserID).
+			Order("created DESC, id DESC").
+			Limit(keep)
+		return tx.Where("user_id = ? AND id NOT IN (?)", entry.UserID, recent).
+			Delete(&domain.PasswordHistory{}).Error
+	})
+}
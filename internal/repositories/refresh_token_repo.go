@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+func (r *RefreshTokenRepository) Create(token *domain.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *RefreshTokenRepository) GetByHash(tokenHash string) (domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	result := r.db.Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		return domain.RefreshToken{}, result.Error
+	}
+	return token, nil
+}
+
+// MarkUsed marca el token como usado solo si aún no lo estaba. Devuelve false
+// cuando otra petición lo consumió primero.
+func (r *RefreshTokenRepository) MarkUsed(id uint, when time.Time) (bool, error) {
+	result := r.db.Model(&domain.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", when)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeFamily revoca todos los tokens de una misma familia
+func (r *RefreshTokenRepository) RevokeFamily(familyID string, when time.Time) error {
+	return r.db.Model(&domain.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", when).Error
+}
+
+// RevokeByUser revoca los refresh tokens vigentes del usuario. Con systemID
+// igual a 0 se revocan los de todos los sistemas.
+func (r *RefreshTokenRepository) RevokeByUser(userID uint, systemID uint64, when time.Time) error {
+	query := r.db.Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID)
+	if systemID > 0 {
+		query = query.Where("system_id = ?", systemID)
+	}
+	return query.Update("revoked_at", when).Error
+}
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoleAssignmentRepository guarda los roles asignados a los usuarios en
+// systems_users_roles y los permisos que se les quitan
+type RoleAssignmentRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleAssignmentRepository(db *gorm.DB) *RoleAssignmentRepository {
+	return &RoleAssignmentRepository{db: db}
+}
+
+// FindRole busca un rol del sistema por ID o, si roleID es 0, por nombre
+func (r *RoleAssignmentRepository) FindRole(systemID uint64, roleID uint, name string) (domain.Role, error) {
+	var role domain.Role
+	query := r.db.Where("system_id = ?", systemID)
+	if roleID != 0 {
+		query = query.Where("id = ?", roleID)
+	} else {
+		query = query.Where("name = ?", name)
+	}
+	err := query.First(&role).Error
+	return role, err
+}
+
+// FindPermission busca un permiso de los roles del sistema por ID o, si
+// permissionID es 0, por nombre
+func (r *RoleAssignmentRepository) FindPermission(systemID uint64, permissionID uint, name string) (domain.Permission, error) {
+	var permission domain.Permission
+	query := r.db.Select("permissions.*").Joins("JOIN roles ON roles.id = permissions.role_id").
+		Where("roles.system_id = ?", systemID)
+	if permissionID != 0 {
+		query = query.Where("permissions.id = ?", permissionID)
+	} else {
+		query = query.Where("permissions.name = ?", name)
+	}
+	err := query.Order("permissions.id").First(&permission).Error
+	return permission, err
+}
+
+// GetRoles devuelve los roles asignados al usuario en el sistema
+func (r *RoleAssignmentRepository) GetRoles(systemID uint64, userID uint) ([]domain.Role, error) {
+	var roles []domain.Role
+	err := r.db.Select("roles.*").Joins("JOIN systems_users_roles AS SUR ON SUR.role_id = roles.id").
+		Where("SUR.system_id = ? AND SUR.user_id = ?", systemID, userID).
+		Order("roles.name").
+		Find(&roles).Error
+	return roles, err
+}
+
+func (r *RoleAssignmentRepository) HasRole(systemID uint64, userID, roleID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&domain.SystemUserRole{}).
+		Where("system_id = ? AND user_id = ? AND role_id = ?", systemID, userID, roleID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Assign asigna el rol al usuario; si ya lo tenía no hace nada
+func (r *RoleAssignmentRepository) Assign(systemID uint64, userID, roleID uint) error {
+	assignment := domain.SystemUserRole{SystemID: uint(systemID), UserID: userID, RoleID: roleID, Created: time.Now()}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&assignment).Error
+}
+
+// Unassign quita el rol al usuario junto con las exclusiones de sus permisos,
+// que solo tenían sentido mientras lo tuviera
+func (r *RoleAssignmentRepository) Unassign(systemID uint64, userID, roleID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("system_id = ? AND user_id = ? AND role_id = ?", systemID, userID, roleID).
+			Delete(&domain.SystemUserRole{}).Error; err != nil {
+			return err
+		}
+		rolePermissions := tx.Model(&domain.Permission{}).Select("id").Where("role_id = ?", roleID)
+		return tx.Where("system_id = ? AND user_id = ? AND permission_id IN (?)", systemID, userID, rolePermissions).
+			Delete(&domain.SystemUserExcludedPermission{}).Error
+	})
+}
+
+// GetExcludedPermissions devuelve los permisos quitados al usuario en el sistema
+func (r *RoleAssignmentRepository) GetExcludedPermissions(systemID uint64, userID uint) ([]domain.Permission, error) {
+	var permissions []domain.Permission
+	err := r.db.Select("permissions.*").Joins("JOIN systems_users_excluded_permissions AS SUEP ON SUEP.permission_id = permissions.id").
+		Where("SUEP.system_id = ? AND SUEP.user_id = ?", systemID, userID).
+		Order("permissions.name").
+		Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *RoleAssignmentRepository) Exclude(systemID uint64, userID, permissionID uint) error {
+	exclusion := domain.SystemUserExcludedPermission{SystemID: uint(systemID), UserID: userID, PermissionID: permissionID, Created: time.Now()}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&exclusion).Error
+}
+
+func (r *RoleAssignmentRepository) Include(systemID uint64, userID, permissionID uint) error {
+	return r.db.Where("system_id = ? AND user_id = ? AND permission_id = ?", systemID, userID, permissionID).
+		Delete(&domain.SystemUserExcludedPermission{}).Error
+}
+
+// GetRolePermissionIDs devuelve los IDs de los permisos propios del rol
+func (r *RoleAssignmentRepository) GetRolePermissionIDs(roleID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&domain.Permission{}).Where("role_id = ?", roleID).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// SetRoleExclusions deja excluidos del rol asignado exactamente los permisos
+// indicados. Como la exclusión no anula una asignación directa, también se
+// eliminan las asignaciones directas de esos permisos.
+func (r *RoleAssignmentRepository) SetRoleExclusions(systemID uint64, userID, roleID uint, permissionIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		rolePermissions := tx.Model(&domain.Permission{}).Select("id").Where("role_id = ?", roleID)
+		if err := tx.Where("system_id = ? AND user_id = ? AND permission_id IN (?)", systemID, userID, rolePermissions).
+			Delete(&domain.SystemUserExcludedPermission{}).Error; err != nil {
+			return err
+		}
+		if len(permissionIDs) == 0 {
+			return nil
+		}
+		if err := tx.Where("system_id = ? AND user_id = ? AND permission_id IN ?", systemID, userID, permissionIDs).
+			Delete(&domain.SystemUserPermission{}).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		exclusions := make([]domain.SystemUserExcludedPermission, 0, len(permissionIDs))
+		for _, permissionID := range permissionIDs {
+			exclusions = append(exclusions, domain.SystemUserExcludedPermission{SystemID: uint(systemID), UserID: userID, PermissionID: permissionID, Created: now})
+		}
+		return tx.Create(&exclusions).Error
+	})
+}
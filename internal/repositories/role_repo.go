@@ -96,3 +96,21 @@ func (r *RoleRepository) Update(role *domain.Role) error {
 func (r *RoleRepository) Delete(id uint64) error {
 	return r.db.Delete(&domain.Role{}, id).Error
 }
+
+// GetChildren devuelve los roles que heredan directamente del rol
+func (r *RoleRepository) GetChildren(id uint64) ([]domain.Role, error) {
+	var roles []domain.Role
+	err := r.db.Where("parent_id = ?", id).Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// DeleteReassigningChildren asigna a los hijos del rol el nuevo padre (nil los
+// deja sin padre) y elimina el rol, en una sola transacción
+func (r *RoleRepository) DeleteReassigningChildren(id uint64, newParentID *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Role{}).Where("parent_id = ?", id).Update("parent_id", newParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Role{}, id).Error
+	})
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SignInThrottleRepository struct {
+	db *gorm.DB
+}
+
+func NewSignInThrottleRepository(db *gorm.DB) *SignInThrottleRepository {
+	return &SignInThrottleRepository{db: db}
+}
+
+func (r *SignInThrottleRepository) Get(scope string, systemID uint64, subject string) (domain.SignInThrottle, error) {
+	var throttle domain.SignInThrottle
+	result := r.db.Where("scope = ? AND system_id = ? AND subject = ?", scope, systemID, subject).First(&throttle)
+	if result.Error != nil {
+		return domain.SignInThrottle{}, result.Error
+	}
+	return throttle, nil
+}
+
+// IncrementFailures suma un intento fallido de forma atómica. Si el último
+// fallo es anterior a windowStart el contador vuelve a empezar.
+func (r *SignInThrottleRepository) IncrementFailures(scope string, systemID uint64, subject string, when, windowStart time.Time) (domain.SignInThrottle, error) {
+	var throttle domain.SignInThrottle
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		row := domain.SignInThrottle{
+			Scope:         scope,
+			SystemID:      uint(systemID),
+			Subject:       subject,
+			Failures:      1,
+			LastFailureAt: when,
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "scope"}, {Name: "system_id"}, {Name: "subject"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":        gorm.Expr("CASE WHEN sign_in_throttles.last_failure_at < ? THEN 1 ELSE sign_in_throttles.failures + 1 END", windowStart),
+				"last_failure_at": when,
+			}),
+		}).Create(&row).Error; err != nil {
+			return err
+		}
+		return tx.Where("scope = ? AND system_id = ? AND subject = ?", scope, systemID, subject).First(&throttle).Error
+	})
+	return throttle, err
+}
+
+// UpdateBlock guarda la espera y el bloqueo calculados tras un fallo
+func (r *SignInThrottleRepository) UpdateBlock(id uint, retryAt, lockedUntil *time.Time) error {
+	return r.db.Model(&domain.SignInThrottle{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"retry_at": retryAt, "locked_until": lockedUntil}).Error
+}
+
+func (r *SignInThrottleRepository) Delete(scope string, systemID uint64, subject string) error {
+	return r.db.Where("scope = ? AND system_id = ? AND subject = ?", scope, systemID, subject).
+		Delete(&domain.SignInThrottle{}).Error
+}
+
+// GetBySubject devuelve los contadores del sujeto en todos los sistemas
+func (r *SignInThrottleRepository) GetBySubject(scope, subject string) ([]domain.SignInThrottle, error) {
+	var throttles []domain.SignInThrottle
+	err := r.db.Table("sign_in_throttles").
+		Select("sign_in_throttles.*, systems.name AS system_name").
+		Joins("LEFT JOIN systems ON systems.id = sign_in_throttles.system_id").
+		Where("sign_in_throttles.scope = ? AND sign_in_throttles.subject = ?", scope, subject).
+		Order("sign_in_throttles.last_failure_at DESC").
+		Find(&throttles).Error
+	return throttles, err
+}
+
+func (r *SignInThrottleRepository) DeleteBySubject(scope, subject string) error {
+	return r.db.Where("scope = ? AND subject = ?", scope, subject).Delete(&domain.SignInThrottle{}).Error
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SigningKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{db: db}
+}
+
+// GetActive devuelve la llave vigente (sin rotar) más reciente del algoritmo
+func (r *SigningKeyRepository) GetActive(algorithm string) (domain.SigningKey, error) {
+	var key domain.SigningKey
+	result := r.db.Where("algorithm = ? AND rotated_at IS NULL", algorithm).
+		Order("created DESC").
+		First(&key)
+	if result.Error != nil {
+		return domain.SigningKey{}, result.Error
+	}
+	return key, nil
+}
+
+// GetFirst devuelve la primera llave generada, que marca el paso de HS256 a un
+// algoritmo asimétrico
+func (r *SigningKeyRepository) GetFirst() (domain.SigningKey, error) {
+	var key domain.SigningKey
+	result := r.db.Order("created ASC").First(&key)
+	if result.Error != nil {
+		return domain.SigningKey{}, result.Error
+	}
+	return key, nil
+}
+
+func (r *SigningKeyRepository) GetByKid(kid string) (domain.SigningKey, error) {
+	var key domain.SigningKey
+	result := r.db.Where("kid = ?", kid).First(&key)
+	if result.Error != nil {
+		return domain.SigningKey{}, result.Error
+	}
+	return key, nil
+}
+
+// GetPublished devuelve las llaves que aún deben publicarse en el JWKS
+func (r *SigningKeyRepository) GetPublished(now time.Time) ([]domain.SigningKey, error) {
+	var keys []domain.SigningKey
+	err := r.db.Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("created DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+// Rotate retira las llaves vigentes y guarda la nueva dentro de una transacción
+func (r *SigningKeyRepository) Rotate(newKey *domain.SigningKey, retiredExpiresAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.SigningKey{}).
+			Where("rotated_at IS NULL").
+			Updates(map[string]interface{}{"rotated_at": newKey.Created, "expires_at": retiredExpiresAt}).Error; err != nil {
+			return err
+		}
+		return tx.Create(newKey).Error
+	})
+}
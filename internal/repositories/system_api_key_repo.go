@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SystemAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewSystemAPIKeyRepository(db *gorm.DB) *SystemAPIKeyRepository {
+	return &SystemAPIKeyRepository{db: db}
+}
+
+func (r *SystemAPIKeyRepository) GetBySystem(systemID uint64) ([]domain.SystemAPIKey, error) {
+	var keys []domain.SystemAPIKey
+	result := r.db.Where("system_id = ?", systemID).Order("revoked_at IS NOT NULL, name").Find(&keys)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return keys, nil
+}
+
+// GetBySystemAndID obtiene la llave solo si pertenece al sistema
+func (r *SystemAPIKeyRepository) GetBySystemAndID(systemID, id uint64) (domain.SystemAPIKey, error) {
+	var key domain.SystemAPIKey
+	result := r.db.Where("system_id = ? AND id = ?", systemID, id).First(&key)
+	if result.Error != nil {
+		return domain.SystemAPIKey{}, result.Error
+	}
+	return key, nil
+}
+
+func (r *SystemAPIKeyRepository) GetByHash(keyHash string) (domain.SystemAPIKey, error) {
+	var key domain.SystemAPIKey
+	result := r.db.Where("key_hash = ?", keyHash).First(&key)
+	if result.Error != nil {
+		return domain.SystemAPIKey{}, result.Error
+	}
+	return key, nil
+}
+
+func (r *SystemAPIKeyRepository) Create(key *domain.SystemAPIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *SystemAPIKeyRepository) UpdateKey(id uint, prefix, keyHash string, when time.Time) error {
+	return r.db.Model(&domain.SystemAPIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"prefix": prefix, "key_hash": keyHash, "rotated_at": when}).Error
+}
+
+func (r *SystemAPIKeyRepository) UpdateLastUsed(id uint, when time.Time) error {
+	return r.db.Model(&domain.SystemAPIKey{}).Where("id = ?", id).Update("last_used_at", when).Error
+}
+
+func (r *SystemAPIKeyRepository) Revoke(id uint, when time.Time) error {
+	return r.db.Model(&domain.SystemAPIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", when).Error
+}
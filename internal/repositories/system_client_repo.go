@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/responses"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SystemClientRepository struct {
+	db *gorm.DB
+}
+
+func NewSystemClientRepository(db *gorm.DB) *SystemClientRepository {
+	return &SystemClientRepository{db: db}
+}
+
+func (r *SystemClientRepository) GetBySystem(systemID uint64) ([]domain.SystemClient, error) {
+	var clients []domain.SystemClient
+	result := r.db.Where("system_id = ?", systemID).Order("name").Find(&clients)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return clients, nil
+}
+
+// GetBySystemAndID obtiene el cliente solo si pertenece al sistema
+func (r *SystemClientRepository) GetBySystemAndID(systemID, id uint64) (domain.SystemClient, error) {
+	var client domain.SystemClient
+	result := r.db.Where("system_id = ? AND id = ?", systemID, id).First(&client)
+	if result.Error != nil {
+		return domain.SystemClient{}, result.Error
+	}
+	return client, nil
+}
+
+func (r *SystemClientRepository) GetByClientID(clientID string) (domain.SystemClient, error) {
+	var client domain.SystemClient
+	result := r.db.Where("client_id = ?", clientID).First(&client)
+	if result.Error != nil {
+		return domain.SystemClient{}, result.Error
+	}
+	return client, nil
+}
+
+func (r *SystemClientRepository) Create(client *domain.SystemClient) error {
+	return r.db.Create(client).Error
+}
+
+func (r *SystemClientRepository) UpdateSecret(id uint, secretHash string, when time.Time) error {
+	return r.db.Model(&domain.SystemClient{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"secret_hash": secretHash, "secret_rotated_at": when}).Error
+}
+
+func (r *SystemClientRepository) UpdateLastUsed(id uint, when time.Time) error {
+	return r.db.Model(&domain.SystemClient{}).Where("id = ?", id).Update("last_used_at", when).Error
+}
+
+// Delete elimina el cliente junto con sus permisos
+func (r *SystemClientRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("system_client_id = ?", id).Delete(&domain.SystemClientPermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.SystemClient{}, id).Error
+	})
+}
+
+// GetPermissionMatrix lista todos los permisos de todos los sistemas marcando
+// los otorgados al cliente
+func (r *SystemClientRepository) GetPermissionMatrix(id uint) ([]domain.ClientPermissionRow, error) {
+	var rows []domain.ClientPermissionRow
+
+	query := `
+        SELECT
+            S.id AS system_id,
+            S.name AS system_name,
+            R.id AS role_id,
+            R.name AS role_name,
+            P.id AS permission_id,
+            P.name AS permission_name,
+            CASE
+                WHEN SCP.id IS NOT NULL THEN 1
+                ELSE 0
+            END AS is_assigned
+        FROM permissions AS P
+        INNER JOIN roles AS R ON P.role_id = R.id
+        INNER JOIN systems AS S ON R.system_id = S.id
+        LEFT JOIN system_client_permissions AS SCP
+            ON SCP.permission_id = P.id
+            AND SCP.system_client_id = ?
+        ORDER BY S.name, R.name, P.name;
+    `
+
+	if err := r.db.Raw(query, id).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ReplacePermissions reemplaza los permisos otorgados al cliente
+func (r *SystemClientRepository) ReplacePermissions(id uint, permissionIDs []uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("system_client_id = ?", id).Delete(&domain.SystemClientPermission{}).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, permissionID := range permissionIDs {
+			if err := tx.Create(&domain.SystemClientPermission{
+				SystemClientID: id,
+				PermissionID:   uint(permissionID),
+				Created:        now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetNestedPermissionsBySystem devuelve los roles y permisos otorgados al
+// cliente en el sistema indicado, con la misma forma que los de un usuario
+func (r *SystemClientRepository) GetNestedPermissionsBySystem(id uint, systemID uint64) (responses.SystemAccess, error) {
+	var flatPermissions []domain.UserSystemPermission
+
+	query := `
+        SELECT
+            S.id AS system_id,
+            S.name AS system_name,
+            R.id AS role_id,
+            R.name AS role_name,
+            P.id AS permission_id,
+            P.name AS permission_name
+        FROM system_client_permissions AS SCP
+        INNER JOIN permissions AS P ON SCP.permission_id = P.id
+        INNER JOIN roles AS R ON P.role_id = R.id
+        INNER JOIN systems AS S ON R.system_id = S.id
+        WHERE SCP.system_client_id = ? AND S.id = ?;
+    `
+
+	if err := r.db.Raw(query, id, systemID).Scan(&flatPermissions).Error; err != nil {
+		return responses.SystemAccess{}, err
+	}
+
+	rolesMap := make(map[uint64]*responses.RoleAccess)
+	roles := []*responses.RoleAccess{}
+
+	for _, p := range flatPermissions {
+		role, roleExists := rolesMap[p.RoleID]
+		if !roleExists {
+			role = &responses.RoleAccess{
+				ID:          uint(p.RoleID),
+				Name:        p.RoleName,
+				Permissions: []responses.PermissionAccess{},
+			}
+			rolesMap[p.RoleID] = role
+			roles = append(roles, role)
+		}
+
+		role.Permissions = append(role.Permissions, responses.PermissionAccess{
+			ID:   uint(p.PermissionID),
+			Name: p.PermissionName,
+		})
+	}
+
+	return responses.SystemAccess{Roles: roles}, nil
+}
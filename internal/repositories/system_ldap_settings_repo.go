@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type SystemLDAPSettingsRepository struct {
+	db *gorm.DB
+}
+
+func NewSystemLDAPSettingsRepository(db *gorm.DB) *SystemLDAPSettingsRepository {
+	return &SystemLDAPSettingsRepository{db: db}
+}
+
+func (r *SystemLDAPSettingsRepository) GetBySystem(systemID uint) (domain.SystemLDAPSettings, error) {
+	var settings domain.SystemLDAPSettings
+	err := r.db.Where("system_id = ?", systemID).First(&settings).Error
+	return settings, err
+}
+
+// Save crea o reemplaza el directorio del sistema
+func (r *SystemLDAPSettingsRepository) Save(settings *domain.SystemLDAPSettings) error {
+	return r.db.Save(settings).Error
+}
+
+// GetSystems devuelve los sistemas con directorio propio, con su nombre
+func (r *SystemLDAPSettingsRepository) GetSystems() ([]domain.System, error) {
+	var systems []domain.System
+	err := r.db.Select("systems.*").Joins("JOIN system_ldap_settings ON system_ldap_settings.system_id = systems.id").
+		Order("systems.name").
+		Find(&systems).Error
+	return systems, err
+}
+
+func (r *SystemLDAPSettingsRepository) Delete(systemID uint) error {
+	return r.db.Where("system_id = ?", systemID).Delete(&domain.SystemLDAPSettings{}).Error
+}
@@ -66,6 +66,33 @@ func (r *SystemRepository) Update(system *domain.System) error {
 	return r.db.Save(system).Error
 }
 
+// UpdateAuthBackend cambia el backend de autenticación del sistema
+func (r *SystemRepository) UpdateAuthBackend(id uint64, backend string) error {
+	return r.db.Model(&domain.System{}).Where("id = ?", id).Update("auth_backend", backend).Error
+}
+
+// UpdateTokenSettings cambia la configuración de los tokens del sistema
+func (r *SystemRepository) UpdateTokenSettings(id uint64, issuer, audience string, accessTTL, clockSkew int) error {
+	return r.db.Model(&domain.System{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"token_issuer":     issuer,
+		"token_audience":   audience,
+		"access_token_ttl": accessTTL,
+		"token_clock_skew": clockSkew,
+	}).Error
+}
+
+// UpdateTokenSecret cambia el secreto con el que se firman los tokens del sistema
+func (r *SystemRepository) UpdateTokenSecret(id uint64, secret string) error {
+	return r.db.Model(&domain.System{}).Where("id = ?", id).Update("token_secret", secret).Error
+}
+
+// TokenAudienceInUse indica si otro sistema ya usa la audiencia
+func (r *SystemRepository) TokenAudienceInUse(audience string, exceptID uint64) (bool, error) {
+	var count int64
+	err := r.db.Model(&domain.System{}).Where("token_audience = ? AND id <> ?", audience, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
 func (r *SystemRepository) Delete(id uint64) error {
 	return r.db.Delete(&domain.System{}, id).Error
 }
@@ -68,7 +68,24 @@ func (r *UserPermissionRepository) GetSystemUserRolesPermissions(systemID, userI
             CASE
                 WHEN sup.id IS NOT NULL THEN 1
                 ELSE 0
-            END AS is_assigned
+            END AS is_assigned,
+            (
+                SELECT GROUP_CONCAT(g.name, ', ')
+                FROM groups_permissions gp
+                JOIN groups g ON g.id = gp.group_id
+                JOIN groups_users gu ON gu.group_id = g.id
+                WHERE gp.permission_id = p.id
+                    AND gu.user_id = su.user_id
+                    AND (g.system_id IS NULL OR g.system_id = su.system_id)
+            ) AS group_names,
+            CASE
+                WHEN sur.id IS NOT NULL THEN 1
+                ELSE 0
+            END AS role_assigned,
+            CASE
+                WHEN suep.id IS NOT NULL THEN 1
+                ELSE 0
+            END AS is_excluded
         FROM systems_users su
         JOIN roles r ON r.system_id = su.system_id
         JOIN permissions p ON p.role_id = r.id
@@ -76,6 +93,14 @@ func (r *UserPermissionRepository) GetSystemUserRolesPermissions(systemID, userI
             ON sup.system_id = su.system_id
             AND sup.user_id = su.user_id
             AND sup.permission_id = p.id
+        LEFT JOIN systems_users_roles sur
+            ON sur.system_id = su.system_id
+            AND sur.user_id = su.user_id
+            AND sur.role_id = r.id
+        LEFT JOIN systems_users_excluded_permissions suep
+            ON suep.system_id = su.system_id
+            AND suep.user_id = su.user_id
+            AND suep.permission_id = p.id
         WHERE su.system_id = ? AND su.user_id = ?;
     `
 
@@ -90,6 +115,12 @@ func (r *UserPermissionRepository) GetSystemUserRolesPermissions(systemID, userI
 	return permissions, nil
 }
 
+// GetEffectivePermissions devuelve los permisos vigentes del usuario en el
+// sistema, incluidos los heredados de los roles ancestros
+func (r *UserPermissionRepository) GetEffectivePermissions(systemID, userID uint64) ([]domain.UserSystemPermission, error) {
+	return effectivePermissions(r.db, uint(userID), systemID, nil)
+}
+
 // GetUserNestedPermissions fetches and structures a user's permissions hierarchically.
 func (r *UserPermissionRepository) GetUserNestedPermissions(userID uint) ([]domain.System, error) {
 	var flatPermissions []domain.UserSystemPermission
@@ -4,7 +4,10 @@ package repositories
 import (
 	"accessv2/internal/domain"
 	"accessv2/internal/responses"
+	"accessv2/pkg/utils"
 	"errors"
+	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -29,7 +32,7 @@ func (r *UserRepository) GetAll() ([]domain.User, error) {
 func (r *UserRepository) CheckUserExists(username, email string, excludeID uint) error {
 	var existingUser domain.User
 	query := r.db.Model(&domain.User{}).
-		Where("username = ? OR email = ?", username, email)
+		Where("username_normalized = ? OR LOWER(email) = ?", utils.NormalizeUsername(username), utils.NormalizeEmail(email))
 
 	if excludeID > 0 {
 		query = query.Where("id != ?", excludeID)
@@ -44,10 +47,10 @@ func (r *UserRepository) CheckUserExists(username, email string, excludeID uint)
 	}
 
 	// Determinar qué campo causó el conflicto
-	if existingUser.Username == username {
+	if utils.NormalizeUsername(existingUser.Username) == utils.NormalizeUsername(username) {
 		return errors.New("username already exists")
 	}
-	if existingUser.Email == email {
+	if utils.NormalizeEmail(existingUser.Email) == utils.NormalizeEmail(email) {
 		return errors.New("email already exists")
 	}
 
@@ -60,7 +63,7 @@ func (r *UserRepository) CheckUserExistsForUpdate(username string, email string,
 	// La consulta busca un usuario cuyo 'username' o 'email' coincida con los valores proporcionados,
 	// pero que su 'id' sea diferente al del usuario actual.
 	query := r.db.Model(&domain.User{}).
-		Where("(username = ? OR email = ?) AND id != ?", username, email, id)
+		Where("(username_normalized = ? OR LOWER(email) = ?) AND id != ?", utils.NormalizeUsername(username), utils.NormalizeEmail(email), id)
 
 	result := query.First(&existingUser)
 
@@ -75,10 +78,10 @@ func (r *UserRepository) CheckUserExistsForUpdate(username string, email string,
 
 	// Si el resultado no es un error, GORM encontró un registro.
 	// Esto significa que ya existe un usuario con el mismo nombre de usuario o correo.
-	if existingUser.Username == username {
+	if utils.NormalizeUsername(existingUser.Username) == utils.NormalizeUsername(username) {
 		return errors.New("El nombre de usuario ya está en uso por otro usuario.")
 	}
-	if existingUser.Email == email {
+	if utils.NormalizeEmail(existingUser.Email) == utils.NormalizeEmail(email) {
 		return errors.New("El correo electrónico ya está en uso por otro usuario.")
 	}
 
@@ -130,23 +133,91 @@ func (r *UserRepository) GetByID(id uint64) (domain.User, error) {
 }
 
 func (r *UserRepository) Create(user *domain.User) error {
+	setUsernameNormalized(user)
 	return r.db.Create(user).Error
 }
 
 func (r *UserRepository) Update(user *domain.User) error {
+	setUsernameNormalized(user)
 	return r.db.Save(user).Error
 }
 
+func setUsernameNormalized(user *domain.User) {
+	normalized := utils.NormalizeUsername(user.Username)
+	user.UsernameNormalized = &normalized
+}
+
+// NormalizeUsernames completa username_normalized de los usuarios que la
+// migración no pudo normalizar (SQLite no aplica NFKC). Falla si el nombre
+// normalizado de uno ya lo tiene otro usuario.
+func (r *UserRepository) NormalizeUsernames() error {
+	var users []domain.User
+	if err := r.db.Where("username_normalized IS NULL").Order("id").Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		normalized := utils.NormalizeUsername(user.Username)
+		var existing domain.User
+		err := r.db.Where("username_normalized = ?", normalized).First(&existing).Error
+		if err == nil {
+			return fmt.Errorf("los usuarios %d (%q) y %d (%q) solo difieren en mayúsculas; renombre uno de ellos", existing.ID, existing.Username, user.ID, user.Username)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := r.db.Model(&domain.User{}).Where("id = ?", user.ID).Update("username_normalized", normalized).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *UserRepository) Delete(id uint64) error {
 	return r.db.Delete(&domain.User{}, id).Error
 }
 
-func (r *UserRepository) GetBySystemUsernamePassword(systemID uint64, username, password string) (domain.User, error) {
+// GetBySystemUsername busca al usuario asociado al sistema sin distinguir
+// mayúsculas en el nombre de usuario
+func (r *UserRepository) GetBySystemUsername(systemID uint64, username string) (domain.User, error) {
+	var user domain.User
+
+	result := r.db.
+		Joins("JOIN systems_users ON systems_users.user_id = users.id").
+		Where("systems_users.system_id = ? AND users.username_normalized = ?", systemID, utils.NormalizeUsername(username)).
+		First(&user)
+
+	if result.Error != nil {
+		return domain.User{}, result.Error
+	}
+
+	return user, nil
+}
+
+// GetBySystemEmail busca al usuario asociado al sistema por su correo, sin
+// distinguir mayúsculas
+func (r *UserRepository) GetBySystemEmail(systemID uint64, email string) (domain.User, error) {
+	var user domain.User
+
+	result := r.db.
+		Joins("JOIN systems_users ON systems_users.user_id = users.id").
+		Where("systems_users.system_id = ? AND LOWER(users.email) = ?", systemID, utils.NormalizeEmail(email)).
+		First(&user)
+
+	if result.Error != nil {
+		return domain.User{}, result.Error
+	}
+
+	return user, nil
+}
+
+// GetBySystemAndID obtiene al usuario solo si sigue asociado al sistema
+func (r *UserRepository) GetBySystemAndID(systemID uint64, userID uint) (domain.User, error) {
 	var user domain.User
 
 	result := r.db.
 		Joins("JOIN systems_users ON systems_users.user_id = users.id").
-		Where("systems_users.system_id = ? AND users.username = ? AND users.password = ?", systemID, username, password).
+		Where("systems_users.system_id = ? AND users.id = ?", systemID, userID).
 		First(&user)
 
 	if result.Error != nil {
@@ -156,26 +227,128 @@ func (r *UserRepository) GetBySystemUsernamePassword(systemID uint64, username,
 	return user, nil
 }
 
+// UpdatePassword reemplaza únicamente el hash de la contraseña del usuario
+func (r *UserRepository) UpdatePassword(id uint, hash string) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", id).Update("password", hash).Error
+}
+
+// ChangePassword guarda una contraseña nueva elegida por el usuario
+func (r *UserRepository) ChangePassword(id uint, hash string, when time.Time) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"password": hash, "password_changed_at": when, "updated": when}).Error
+}
+
+// GetByActivationKeyHash busca al usuario dueño de un enlace de activación
+func (r *UserRepository) GetByActivationKeyHash(keyHash string) (domain.User, error) {
+	var user domain.User
+	result := r.db.Where("activation_key_hash = ?", keyHash).First(&user)
+	if result.Error != nil {
+		return domain.User{}, result.Error
+	}
+	return user, nil
+}
+
+// UpdateActivationKeyHash reemplaza la llave de activación y su vencimiento
+func (r *UserRepository) UpdateActivationKeyHash(id uint, keyHash string, expiresAt time.Time) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"activation_key_hash": keyHash, "activation_key_expires_at": expiresAt}).Error
+}
+
+// Activate marca al usuario como activo y consume la llave de activación
+func (r *UserRepository) Activate(id uint, when time.Time) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"activated": true, "activation_key_hash": nil, "activation_key_expires_at": nil, "updated": when}).Error
+}
+
+// GetByIdentifier busca al usuario por nombre de usuario o correo (sin
+// distinguir mayúsculas). Con systemID distinto de 0 solo
+// considera a los usuarios asociados a ese sistema.
+func (r *UserRepository) GetByIdentifier(systemID uint64, identifier string) (domain.User, error) {
+	var user domain.User
+
+	query := r.db.Where("users.username_normalized = ? OR LOWER(users.email) = ?", utils.NormalizeUsername(identifier), utils.NormalizeEmail(identifier))
+	if systemID != 0 {
+		query = query.
+			Joins("JOIN systems_users ON systems_users.user_id = users.id").
+			Where("systems_users.system_id = ?", systemID)
+	}
+
+	result := query.First(&user)
+	if result.Error != nil {
+		return domain.User{}, result.Error
+	}
+	return user, nil
+}
+
+// GetByExternalID busca al usuario vinculado a una entrada del directorio
+func (r *UserRepository) GetByExternalID(externalID string) (domain.User, error) {
+	var user domain.User
+	result := r.db.Where("external_id = ?", externalID).First(&user)
+	if result.Error != nil {
+		return domain.User{}, result.Error
+	}
+	return user, nil
+}
+
+// GetByIdentity busca al usuario local con el mismo nombre de usuario o correo
+// que una entrada del directorio, sin distinguir mayúsculas, esté vinculado o no
+func (r *UserRepository) GetByIdentity(username, email string) (domain.User, error) {
+	var user domain.User
+	query := r.db.Where(
+		r.db.Where("username_normalized = ?", utils.NormalizeUsername(username)).Or("? <> '' AND LOWER(email) = ?", email, utils.NormalizeEmail(email)),
+	)
+	result := query.Order("id").First(&user)
+	if result.Error != nil {
+		return domain.User{}, result.Error
+	}
+	return user, nil
+}
+
+// LinkExternal vincula al usuario con una entrada del directorio. Su contraseña
+// local, si tiene, se sigue usando en los sistemas con backend local.
+func (r *UserRepository) LinkExternal(id uint, externalID string) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"external_id": externalID, "updated": time.Now()}).Error
+}
+
+// UnlinkExternal quita el vínculo con el directorio; un usuario creado desde el
+// directorio pasa a ser local y debe recuperar su contraseña para ingresar
+func (r *UserRepository) UnlinkExternal(id uint) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"auth_source": domain.AuthSourceLocal, "external_id": nil, "updated": time.Now()}).Error
+}
+
+// GetByResetKeyHash busca al usuario dueño de un enlace de recuperación
+func (r *UserRepository) GetByResetKeyHash(keyHash string) (domain.User, error) {
+	var user domain.User
+	result := r.db.Where("reset_key_hash = ?", keyHash).First(&user)
+	if result.Error != nil {
+		return domain.User{}, result.Error
+	}
+	return user, nil
+}
+
+// UpdateResetKeyHash reemplaza la llave de recuperación y su vencimiento
+func (r *UserRepository) UpdateResetKeyHash(id uint, keyHash string, expiresAt time.Time) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"reset_key_hash": keyHash, "reset_key_expires_at": expiresAt}).Error
+}
+
+// ResetPassword guarda la nueva contraseña y consume la llave de recuperación.
+// Solo actualiza si la llave sigue siendo la indicada, para que sea de un solo uso.
+func (r *UserRepository) ResetPassword(id uint, keyHash, hash string, when time.Time) (bool, error) {
+	result := r.db.Model(&domain.User{}).Where("id = ? AND reset_key_hash = ?", id, keyHash).
+		Updates(map[string]interface{}{"password": hash, "password_changed_at": when, "reset_key_hash": nil, "reset_key_expires_at": nil, "updated": when})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 func (r *UserRepository) GetUserNestedPermissionsBySystem(userID uint, systemID uint64) (responses.SystemAccess, error) {
-	var flatPermissions []domain.UserSystemPermission
-
-	query := `
-        SELECT
-            S.id AS system_id,
-            S.name AS system_name,
-            R.id AS role_id,
-            R.name AS role_name,
-            P.id AS permission_id,
-            P.name AS permission_name
-        FROM systems_users_permissions AS SUP
-        INNER JOIN users AS U ON SUP.user_id = U.id
-        INNER JOIN permissions AS P ON SUP.permission_id = P.id
-        INNER JOIN roles AS R ON P.role_id = R.id
-        INNER JOIN systems AS S ON R.system_id = S.id
-        WHERE SUP.user_id = ? AND S.id = ?;
-    `
-
-	if err := r.db.Raw(query, userID, systemID).Scan(&flatPermissions).Error; err != nil {
+	// Incluye los permisos heredados de los roles ancestros
+	flatPermissions, err := effectivePermissions(r.db, userID, systemID, nil)
+	if err != nil {
 		return responses.SystemAccess{}, err
 	}
 
@@ -215,3 +388,10 @@ func (r *UserRepository) GetUserNestedPermissionsBySystem(userID uint, systemID
 
 	return systemAccess, nil
 }
+
+// GetUserPermissionRolesBySystem devuelve, con la misma consulta que
+// GetUserNestedPermissionsBySystem, solo las filas de los permisos pedidos,
+// incluidos los heredados. Un permiso aparece una vez por cada rol que lo otorga.
+func (r *UserRepository) GetUserPermissionRolesBySystem(userID uint, systemID uint64, names []string) ([]domain.UserSystemPermission, error) {
+	return effectivePermissions(r.db, userID, systemID, names)
+}
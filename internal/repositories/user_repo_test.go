@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/testutil"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// addUnnormalizedUser crea un usuario sin username_normalized, como los que
+// deja la migración cuando SQLite no puede normalizar el nombre
+func addUnnormalizedUser(t *testing.T, db *gorm.DB, username, email string) domain.User {
+	t.Helper()
+	now := time.Now()
+	user := domain.User{Username: username, Email: email, Created: now, Updated: now}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestUserRepositoryNormalizeUsernames(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := NewUserRepository(db)
+
+	user := addUnnormalizedUser(t, db, "Ñandú", "a@example.com")
+	if err := repo.NormalizeUsernames(); err != nil {
+		t.Fatalf("normalizar: %v", err)
+	}
+	var stored domain.User
+	if err := db.First(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.UsernameNormalized == nil || *stored.UsernameNormalized != "ñandú" {
+		t.Fatalf("username_normalized = %v, se esperaba ñandú", stored.UsernameNormalized)
+	}
+
+	addUnnormalizedUser(t, db, "ÑANDÚ", "b@example.com")
+	if err := repo.NormalizeUsernames(); err == nil {
+		t.Fatal("se esperaba un error por los nombres que solo difieren en mayúsculas")
+	}
+}
@@ -0,0 +1,8 @@
+// internal/responses/account_responses.go
+package responses
+
+type AccountResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
@@ -0,0 +1,64 @@
+// internal/responses/authz_responses.go
+package responses
+
+// AuthzRole es un rol del usuario que otorga el permiso consultado
+type AuthzRole struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// AuthzDecision es el resultado de verificar un permiso
+type AuthzDecision struct {
+	Permission string      `json:"permission"`
+	Allowed    bool        `json:"allowed"`
+	Roles      []AuthzRole `json:"roles"`
+}
+
+// AuthzCheckResult resume la verificación de uno o varios permisos. Reason
+// explica una denegación que no depende de los permisos, como user_inactive.
+type AuthzCheckResult struct {
+	UserID   uint        `json:"user_id"`
+	SystemID uint64      `json:"system_id"`
+	Allowed  bool        `json:"allowed"`
+	Reason   string      `json:"reason,omitempty"`
+	Roles    []AuthzRole `json:"roles"`
+	Missing  []string    `json:"missing"`
+}
+
+// AuthzBatchResult tiene una decisión por cada permiso, en el orden pedido
+type AuthzBatchResult struct {
+	UserID   uint            `json:"user_id"`
+	SystemID uint64          `json:"system_id"`
+	Reason   string          `json:"reason,omitempty"`
+	Results  []AuthzDecision `json:"results"`
+}
+
+// RoleAssignments son los roles asignados al usuario en el sistema y los
+// permisos que se le quitaron aunque le lleguen por esos roles
+type RoleAssignments struct {
+	UserID              uint        `json:"user_id"`
+	SystemID            uint64      `json:"system_id"`
+	Roles               []AuthzRole `json:"roles"`
+	ExcludedPermissions []string    `json:"excluded_permissions"`
+}
+
+type AuthzCheckResponse struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message,omitempty"`
+	Data    *AuthzCheckResult `json:"data,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+type AuthzBatchResponse struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message,omitempty"`
+	Data    *AuthzBatchResult `json:"data,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+type RoleAssignmentsResponse struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message,omitempty"`
+	Data    *RoleAssignments `json:"data,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
@@ -0,0 +1,55 @@
+// internal/responses/oauth_responses.go
+package responses
+
+import "github.com/golang-jwt/jwt/v5"
+
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type UserInfoResponse struct {
+	Sub               string        `json:"sub"`
+	PreferredUsername string        `json:"preferred_username"`
+	Email             string        `json:"email,omitempty"`
+	SystemID          uint64        `json:"system_id"`
+	Roles             []*RoleAccess `json:"roles"`
+}
+
+// IDTokenClaims son los claims del ID token; incluyen los roles del usuario en
+// el sistema que actúa como cliente
+type IDTokenClaims struct {
+	Nonce             string        `json:"nonce,omitempty"`
+	AuthTime          int64         `json:"auth_time,omitempty"`
+	PreferredUsername string        `json:"preferred_username"`
+	Email             string        `json:"email,omitempty"`
+	SystemID          uint64        `json:"system_id"`
+	Roles             []*RoleAccess `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// OpenIDConfiguration es el documento de descubrimiento de OpenID Connect
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
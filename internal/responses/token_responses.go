@@ -0,0 +1,46 @@
+// internal/responses/token_responses.go
+package responses
+
+import "time"
+
+type TokenStatus struct {
+	JTI            string     `json:"jti"`
+	UserID         *uint      `json:"user_id,omitempty"`
+	SystemClientID *uint      `json:"system_client_id,omitempty"`
+	SystemID       uint       `json:"system_id"`
+	Revoked        bool       `json:"revoked"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	Reason         string     `json:"reason,omitempty"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+}
+
+type TokenStatusResponse struct {
+	Success bool         `json:"success"`
+	Message string       `json:"message,omitempty"`
+	Data    *TokenStatus `json:"data,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+type RevocationListResponse struct {
+	Success bool          `json:"success"`
+	Data    []TokenStatus `json:"data"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// IntrospectionResponse sigue el formato de RFC 7662: si el token no está
+// activo solo se devuelve active en false
+type IntrospectionResponse struct {
+	Active    bool          `json:"active"`
+	TokenType string        `json:"token_type,omitempty"`
+	Sub       string        `json:"sub,omitempty"`
+	Username  string        `json:"username,omitempty"`
+	ClientID  string        `json:"client_id,omitempty"`
+	SystemID  uint64        `json:"system_id,omitempty"`
+	Exp       int64         `json:"exp,omitempty"`
+	Iat       int64         `json:"iat,omitempty"`
+	Iss       string        `json:"iss,omitempty"`
+	Aud       string        `json:"aud,omitempty"`
+	JTI       string        `json:"jti,omitempty"`
+	Roles     []*RoleAccess `json:"roles,omitempty"`
+	Act       *ActorClaim   `json:"act,omitempty"`
+}
@@ -22,9 +22,11 @@ type PermissionAccess struct {
 }
 
 type UserWithAccess struct {
-	User  UserAccess    `json:"user"`
-	Token string        `json:"token,omitempty"`
-	Roles []*RoleAccess `json:"roles"`
+	User         UserAccess    `json:"user"`
+	Token        string        `json:"token,omitempty"`
+	RefreshToken string        `json:"refresh_token,omitempty"`
+	ExpiresIn    int64         `json:"expires_in,omitempty"` // segundos de vida del token
+	Roles        []*RoleAccess `json:"roles"`
 }
 
 type UserAccess struct {
@@ -39,12 +41,53 @@ type SignResponse struct {
 	Message string         `json:"message,omitempty"`
 	Data    UserWithAccess `json:"data,omitempty"`
 	Error   string         `json:"error,omitempty"`
+	// Code y RetryAfter describen un ingreso bloqueado por intentos fallidos
+	// (account_locked o too_many_attempts) y los segundos a esperar
+	Code       string `json:"code,omitempty"`
+	RetryAfter int64  `json:"retry_after,omitempty"`
+	// MFA indica que falta el segundo factor; en ese caso no se emiten tokens
+	MFA *MFAChallenge `json:"mfa,omitempty"`
+	// RecoveryCodes se entregan una sola vez, al completar la inscripción
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
 }
 
 type CustomClaims struct {
-	UserID   uint64        `json:"user_id"`
-	Username string        `json:"username"`
-	Email    string        `json:"email"`
+	UserID   uint64        `json:"user_id,omitempty"`
+	Username string        `json:"username,omitempty"`
+	Email    string        `json:"email,omitempty"`
+	SystemID uint64        `json:"system_id"`
+	ClientID string        `json:"client_id,omitempty"` // solo en tokens client_credentials
 	Roles    []*RoleAccess `json:"roles"`
+	Act      *ActorClaim   `json:"act,omitempty"` // solo en tokens de suplantación
 	jwt.RegisteredClaims
 }
+
+// ActorClaim identifica al administrador que actúa en nombre del usuario del
+// token, como el claim act de RFC 8693
+type ActorClaim struct {
+	Sub             string `json:"sub"` // "admin:<id>"
+	Username        string `json:"username"`
+	ImpersonationID uint   `json:"impersonation_id"`
+}
+
+// MFAChallenge se devuelve en lugar de los tokens cuando el ingreso requiere
+// segundo factor; se completa en /api/v1/users/sign-in/mfa
+type MFAChallenge struct {
+	ChallengeToken     string `json:"challenge_token"`
+	ExpiresIn          int64  `json:"expires_in"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+}
+
+// MFAEnrollment contiene el secreto TOTP para registrar en la aplicación autenticadora
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type MFAEnrollmentResponse struct {
+	Success       bool           `json:"success"`
+	Message       string         `json:"message,omitempty"`
+	Data          *MFAEnrollment `json:"data,omitempty"`
+	RecoveryCodes []string       `json:"recovery_codes,omitempty"`
+	Error         string         `json:"error,omitempty"`
+}
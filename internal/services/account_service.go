@@ -0,0 +1,268 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/mailer"
+	"accessv2/pkg/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidActivationKey = errors.New("El enlace de activación no es válido o ya fue utilizado")
+	ErrActivationKeyExpired = errors.New("El enlace de activación expiró")
+	ErrUserAlreadyActive    = errors.New("El usuario ya está activo")
+	ErrInvalidResetKey      = errors.New("El enlace de recuperación no es válido o ya fue utilizado")
+	ErrResetKeyExpired      = errors.New("El enlace de recuperación expiró")
+	ErrInvalidResetURL      = errors.New("reset_url no está registrada como URI de retorno del sistema")
+	ErrPasswordRequired     = errors.New("La contraseña es requerida")
+)
+
+// AccountConfig agrupa la configuración de los flujos de cuenta por correo
+type AccountConfig struct {
+	AppName       string
+	BaseURL       string        // URL pública con la que se arman los enlaces
+	ActivationTTL time.Duration // vigencia del enlace de activación
+	ResetTTL      time.Duration // vigencia del enlace de recuperación de contraseña
+}
+
+// AccountService administra los flujos de cuenta del usuario final que se
+// completan desde un enlace enviado por correo
+type AccountService struct {
+	cfg          AccountConfig
+	repo         *repositories.UserRepository
+	oauthRepo    *repositories.OAuthRepository
+	passwords    *PasswordService
+	tokenService *TokenService
+	mailer       mailer.Mailer
+}
+
+func NewAccountService(cfg AccountConfig, repo *repositories.UserRepository, oauthRepo *repositories.OAuthRepository, passwords *PasswordService, tokenService *TokenService, mailer mailer.Mailer) *AccountService {
+	return &AccountService{
+		cfg:          cfg,
+		repo:         repo,
+		oauthRepo:    oauthRepo,
+		passwords:    passwords,
+		tokenService: tokenService,
+		mailer:       mailer,
+	}
+}
+
+// SendActivation genera una nueva llave de activación y envía el enlace al
+// correo del usuario. Se guarda solo el hash de la llave. Un envío anterior
+// deja de ser válido.
+func (s *AccountService) SendActivation(user domain.User) error {
+	if user.Activated {
+		return ErrUserAlreadyActive
+	}
+
+	key, err := utils.SecureToken(22)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.cfg.ActivationTTL)
+	if err := s.repo.UpdateActivationKeyHash(user.ID, utils.HashToken(key), expiresAt); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hola %s,\n\n"+
+		"Se creó una cuenta para ti en %s. Para activarla abre el siguiente enlace:\n\n"+
+		"%s/activate/%s\n\n"+
+		"El enlace vence el %s. Si no esperabas este correo puedes ignorarlo.\n",
+		user.Username, s.cfg.AppName, s.cfg.BaseURL, key, expiresAt.Format("02/01/2006 15:04"))
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Activa tu cuenta de " + s.cfg.AppName,
+		Body:    body,
+	})
+}
+
+// ResendActivation vuelve a enviar el enlace de activación al usuario
+func (s *AccountService) ResendActivation(userID uint64) error {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	return s.SendActivation(user)
+}
+
+// GetPendingActivation devuelve al usuario de un enlace de activación vigente
+func (s *AccountService) GetPendingActivation(key string) (domain.User, error) {
+	if key == "" {
+		return domain.User{}, ErrInvalidActivationKey
+	}
+
+	user, err := s.repo.GetByActivationKeyHash(utils.HashToken(key))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.User{}, ErrInvalidActivationKey
+		}
+		return domain.User{}, err
+	}
+	if user.Activated {
+		return domain.User{}, ErrInvalidActivationKey
+	}
+	// Las llaves generadas antes de este flujo no tienen vencimiento y no se aceptan
+	if user.ActivationKeyExpiresAt == nil || time.Now().After(*user.ActivationKeyExpiresAt) {
+		return domain.User{}, ErrActivationKeyExpired
+	}
+
+	return user, nil
+}
+
+// Activate activa la cuenta del enlace y consume la llave
+func (s *AccountService) Activate(key string) (domain.User, error) {
+	user, err := s.GetPendingActivation(key)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if err := s.repo.Activate(user.ID, time.Now()); err != nil {
+		return domain.User{}, err
+	}
+	user.Activated = true
+	return user, nil
+}
+
+// RequestPasswordReset envía un enlace de recuperación al usuario con el
+// nombre de usuario o correo indicado. Para no revelar qué cuentas existen no
+// informa si el usuario no existe o está inactivo, y el correo se envía en
+// segundo plano.
+//
+// Con systemID distinto de 0 solo se buscan usuarios de ese sistema, y resetURL
+// permite que el enlace apunte a una página propia del sistema, que debe estar
+// registrada como URI de retorno; la llave se agrega en el parámetro key. Se
+// guarda solo el hash de la llave.
+func (s *AccountService) RequestPasswordReset(systemID uint64, identifier, resetURL string) error {
+	identifier = strings.TrimSpace(identifier)
+	if identifier == "" {
+		return errors.New("Ingresa tu usuario o correo electrónico")
+	}
+
+	if resetURL != "" {
+		registered := false
+		if systemID != 0 {
+			var err error
+			registered, err = s.oauthRepo.HasRedirectURI(systemID, resetURL)
+			if err != nil {
+				return err
+			}
+		}
+		if !registered {
+			return ErrInvalidResetURL
+		}
+	}
+
+	user, err := s.repo.GetByIdentifier(systemID, identifier)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	// Sin aviso para no revelar qué cuentas existen ni cuáles son del directorio
+	if !user.Activated || user.Email == "" || user.IsExternal() {
+		return nil
+	}
+
+	key, err := utils.SecureToken(22)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.cfg.ResetTTL)
+	if err := s.repo.UpdateResetKeyHash(user.ID, utils.HashToken(key), expiresAt); err != nil {
+		return err
+	}
+
+	link := s.cfg.BaseURL + "/reset-password/" + key
+	if resetURL != "" {
+		link = RedirectWithParams(resetURL, url.Values{"key": {key}})
+	}
+
+	body := fmt.Sprintf("Hola %s,\n\n"+
+		"Recibimos una solicitud para restablecer tu contraseña de %s. Para elegir una nueva abre el siguiente enlace:\n\n"+
+		"%s\n\n"+
+		"El enlace vence el %s y solo puede usarse una vez. Si no solicitaste el cambio puedes ignorar este correo.\n",
+		user.Username, s.cfg.AppName, link, expiresAt.Format("02/01/2006 15:04"))
+
+	go func() {
+		if err := s.mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: "Restablece tu contraseña de " + s.cfg.AppName,
+			Body:    body,
+		}); err != nil {
+			log.Printf("No se pudo enviar la recuperación de contraseña del usuario %d: %v", user.ID, err)
+		}
+	}()
+
+	return nil
+}
+
+// GetPendingReset devuelve al usuario de un enlace de recuperación vigente
+func (s *AccountService) GetPendingReset(key string) (domain.User, error) {
+	if key == "" {
+		return domain.User{}, ErrInvalidResetKey
+	}
+
+	user, err := s.repo.GetByResetKeyHash(utils.HashToken(key))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.User{}, ErrInvalidResetKey
+		}
+		return domain.User{}, err
+	}
+	// Las llaves generadas al crear el usuario no tienen vencimiento y no se aceptan
+	if user.ResetKeyExpiresAt == nil || time.Now().After(*user.ResetKeyExpiresAt) {
+		return domain.User{}, ErrResetKeyExpired
+	}
+
+	return user, nil
+}
+
+// ResetPassword reemplaza la contraseña del enlace de recuperación, consume la
+// llave y revoca los tokens vigentes del usuario en todos los sistemas. La
+// contraseña nueva debe cumplir la política y no estar en el historial.
+func (s *AccountService) ResetPassword(key, plainPassword string) (domain.User, error) {
+	user, err := s.GetPendingReset(key)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if plainPassword == "" {
+		return domain.User{}, ErrPasswordRequired
+	}
+	if user.IsExternal() {
+		return domain.User{}, ErrExternalPassword
+	}
+
+	hash, err := s.passwords.Prepare(user, plainPassword)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	now := time.Now()
+	updated, err := s.repo.ResetPassword(user.ID, utils.HashToken(key), hash, now)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !updated {
+		// Otra petición usó la llave al mismo tiempo
+		return domain.User{}, ErrInvalidResetKey
+	}
+
+	if err := s.passwords.Record(user.ID, hash, now); err != nil {
+		log.Printf("No se pudo registrar el historial de contraseñas del usuario %d: %v", user.ID, err)
+	}
+
+	if err := s.tokenService.RevokeUserTokens(user.ID, 0, RevokeReasonPasswordReset); err != nil {
+		return domain.User{}, fmt.Errorf("Contraseña actualizada, pero no se pudieron revocar los tokens: %w", err)
+	}
+
+	return user, nil
+}
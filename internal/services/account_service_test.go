@@ -0,0 +1,143 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/mailer"
+	"accessv2/pkg/password"
+	"accessv2/pkg/utils"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// outbox guarda los correos enviados; RequestPasswordReset envía en segundo
+// plano, por eso es un canal
+type outbox chan mailer.Message
+
+func (o outbox) Send(msg mailer.Message) error {
+	o <- msg
+	return nil
+}
+
+// accountFixture es el servicio que envía los enlaces de cuenta al usuario de
+// tokenFixture
+type accountFixture struct {
+	*tokenFixture
+	account *AccountService
+	outbox  outbox
+}
+
+func newAccountFixture(t *testing.T) *accountFixture {
+	t.Helper()
+	f := newTokenFixture(t)
+
+	policy, err := password.NewPolicy(password.PolicyConfig{MinLength: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasher, err := password.NewHasher(password.Config{Algorithm: password.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwords := NewPasswordService(PasswordConfig{}, policy, hasher, repositories.NewPasswordHistoryRepository(f.db))
+
+	mails := make(outbox, 1)
+	account := NewAccountService(
+		AccountConfig{AppName: "Access", BaseURL: "http://localhost", ActivationTTL: time.Hour, ResetTTL: time.Hour},
+		repositories.NewUserRepository(f.db), repositories.NewOAuthRepository(f.db), passwords, f.service, mails,
+	)
+	return &accountFixture{tokenFixture: f, account: account, outbox: mails}
+}
+
+// deactivate deja al usuario pendiente de activación
+func (f *accountFixture) deactivate(t *testing.T) {
+	t.Helper()
+	if err := f.db.Model(&domain.User{}).Where("id = ?", f.user.ID).Update("activated", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.user.Activated = false
+}
+
+// linkKey devuelve la llave del enlace del último correo enviado
+func (f *accountFixture) linkKey(t *testing.T, path string) string {
+	t.Helper()
+	var msg mailer.Message
+	select {
+	case msg = <-f.outbox:
+	case <-time.After(time.Second):
+		t.Fatal("no se envió el correo")
+	}
+	match := regexp.MustCompile(regexp.QuoteMeta(path) + `([A-Za-z0-9_-]+)`).FindStringSubmatch(msg.Body)
+	if match == nil {
+		t.Fatalf("el correo no tiene el enlace %s: %q", path, msg.Body)
+	}
+	return match[1]
+}
+
+func (f *accountFixture) stored(t *testing.T) domain.User {
+	t.Helper()
+	var user domain.User
+	if err := f.db.First(&user, f.user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestAccountServiceActivationKeyIsHashed(t *testing.T) {
+	f := newAccountFixture(t)
+	f.deactivate(t)
+	if err := f.account.SendActivation(f.user); err != nil {
+		t.Fatal(err)
+	}
+	key := f.linkKey(t, "/activate/")
+
+	stored := f.stored(t)
+	if stored.ActivationKeyHash != utils.HashToken(key) {
+		t.Fatalf("activation_key_hash = %q, se esperaba el hash de la llave del enlace", stored.ActivationKeyHash)
+	}
+
+	// El valor guardado no sirve como enlace
+	if _, err := f.account.Activate(stored.ActivationKeyHash); !errors.Is(err, ErrInvalidActivationKey) {
+		t.Fatalf("activar con el hash: err = %v, se esperaba ErrInvalidActivationKey", err)
+	}
+	if _, err := f.account.Activate(key); err != nil {
+		t.Fatalf("activar: %v", err)
+	}
+	if stored := f.stored(t); !stored.Activated || stored.ActivationKeyHash != "" {
+		t.Fatalf("la activación debe consumir la llave: %+v", stored)
+	}
+	if _, err := f.account.Activate(key); !errors.Is(err, ErrInvalidActivationKey) {
+		t.Fatalf("segunda activación: err = %v, se esperaba ErrInvalidActivationKey", err)
+	}
+}
+
+func TestAccountServiceResetKeyIsHashed(t *testing.T) {
+	f := newAccountFixture(t)
+	if err := f.account.RequestPasswordReset(0, "jdoe", ""); err != nil {
+		t.Fatal(err)
+	}
+	key := f.linkKey(t, "/reset-password/")
+
+	stored := f.stored(t)
+	if stored.ResetKeyHash != utils.HashToken(key) {
+		t.Fatalf("reset_key_hash = %q, se esperaba el hash de la llave del enlace", stored.ResetKeyHash)
+	}
+
+	// El valor guardado no sirve como enlace
+	if _, err := f.account.ResetPassword(stored.ResetKeyHash, "Nueva-clave-9"); !errors.Is(err, ErrInvalidResetKey) {
+		t.Fatalf("restablecer con el hash: err = %v, se esperaba ErrInvalidResetKey", err)
+	}
+	if _, err := f.account.ResetPassword(key, "Nueva-clave-9"); err != nil {
+		t.Fatalf("restablecer: %v", err)
+	}
+	if stored := f.stored(t); stored.ResetKeyHash != "" || stored.ResetKeyExpiresAt != nil {
+		t.Fatalf("el restablecimiento debe consumir la llave: %+v", stored)
+	}
+	if _, err := f.account.ResetPassword(key, "Otra-clave-9"); !errors.Is(err, ErrInvalidResetKey) {
+		t.Fatalf("segundo restablecimiento: err = %v, se esperaba ErrInvalidResetKey", err)
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/sessionstore"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAdminSessionNotFound  = errors.New("La sesión no existe o ya terminó")
+	ErrAdminSessionForbidden = errors.New("Solo un superadministrador puede terminar sesiones de otros administradores")
+)
+
+// AdminSessionConfig indica cuándo una sesión de la consola deja de estar
+// vigente por inactividad; la edad máxima queda en la columna expires_at
+type AdminSessionConfig struct {
+	IdleTimeout time.Duration // 0 desactiva el vencimiento por inactividad
+}
+
+// AdminSessionService lista y termina las sesiones de la consola que guarda
+// sessionstore en la base de datos
+type AdminSessionService struct {
+	cfg       AdminSessionConfig
+	repo      *repositories.AdminSessionRepository
+	adminRepo *repositories.AdminRepository
+}
+
+func NewAdminSessionService(cfg AdminSessionConfig, repo *repositories.AdminSessionRepository, adminRepo *repositories.AdminRepository) *AdminSessionService {
+	return &AdminSessionService{cfg: cfg, repo: repo, adminRepo: adminRepo}
+}
+
+// AttachClient registra la IP y el navegador de la sesión recién iniciada
+func (s *AdminSessionService) AttachClient(sessionToken, clientIP, userAgent string) {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	if err := s.repo.UpdateClient(sessionstore.HashToken(sessionToken), clientIP, userAgent); err != nil {
+		log.Printf("No se pudo registrar el cliente de la sesión: %v", err)
+	}
+}
+
+// GetAdminSessions devuelve las sesiones vigentes del administrador
+func (s *AdminSessionService) GetAdminSessions(adminID uint) ([]domain.AdminSession, error) {
+	idleSince, now := s.window()
+	return s.repo.GetActiveByAdmin(adminID, idleSince, now)
+}
+
+// GetAllSessions devuelve las sesiones vigentes de todos los administradores
+func (s *AdminSessionService) GetAllSessions() ([]domain.AdminSession, error) {
+	idleSince, now := s.window()
+	return s.repo.GetActive(idleSince, now)
+}
+
+// IsSuperAdmin se consulta en cada petición para que quitar el rol tenga efecto inmediato
+func (s *AdminSessionService) IsSuperAdmin(adminID uint) (bool, error) {
+	admin, err := s.adminRepo.GetByID(adminID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return admin.IsSuperAdmin && admin.Activated, nil
+}
+
+// Terminate cierra una sesión. Un administrador puede cerrar las propias; las
+// de otros solo un superadministrador.
+func (s *AdminSessionService) Terminate(actorID, sessionID uint) (*domain.AdminSession, error) {
+	session, err := s.repo.GetByID(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAdminSessionNotFound
+		}
+		return nil, err
+	}
+	if session.AdminID == nil {
+		return nil, ErrAdminSessionNotFound
+	}
+
+	if *session.AdminID != actorID {
+		isSuper, err := s.IsSuperAdmin(actorID)
+		if err != nil {
+			return nil, err
+		}
+		if !isSuper {
+			return nil, ErrAdminSessionForbidden
+		}
+	}
+
+	if err := s.repo.Delete(session.ID); err != nil {
+		return nil, err
+	}
+	log.Printf("Sesión %d del administrador %d terminada por el administrador %d", session.ID, *session.AdminID, actorID)
+	return &session, nil
+}
+
+// TerminateOthers cierra todas las sesiones del administrador salvo la actual
+func (s *AdminSessionService) TerminateOthers(adminID uint, currentToken string) (int64, error) {
+	return s.repo.DeleteOthersByAdmin(adminID, sessionstore.HashToken(currentToken))
+}
+
+// window devuelve los límites de actividad y edad de una sesión vigente
+func (s *AdminSessionService) window() (time.Time, time.Time) {
+	now := time.Now()
+	idleSince := time.Time{}
+	if s.cfg.IdleTimeout > 0 {
+		idleSince = now.Add(-s.cfg.IdleTimeout)
+	}
+	return idleSince, now
+}
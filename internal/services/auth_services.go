@@ -2,25 +2,130 @@
 package services
 
 import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/password"
 	"errors"
-	"os"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
 )
 
+var ErrInvalidAdminCredentials = errors.New("Usuario o contraseña incorrectos")
+
+// consoleSystemID identifica a la consola en los contadores de intentos fallidos
+const consoleSystemID = 0
+
 type AuthService struct {
-	// Puedes agregar dependencias como userRepo si necesitas
+	repo     *repositories.AdminRepository
+	hasher   *password.Hasher
+	throttle *SignInThrottleService
+}
+
+func NewAuthService(repo *repositories.AdminRepository, hasher *password.Hasher, throttle *SignInThrottleService) *AuthService {
+	return &AuthService{repo: repo, hasher: hasher, throttle: throttle}
+}
+
+// Authenticate valida las credenciales de un administrador de la consola y lo
+// devuelve. Con demasiados intentos fallidos devuelve *SignInBlockedError.
+func (s *AuthService) Authenticate(username, plainPassword, clientIP string) (*domain.Admin, error) {
+	if err := s.throttle.Check(consoleSystemID, username, clientIP); err != nil {
+		return nil, err
+	}
+
+	admin, err := s.repo.GetByUsername(strings.TrimSpace(username))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.recordFailure(username, clientIP)
+			return nil, ErrInvalidAdminCredentials
+		}
+		return nil, err
+	}
+
+	match, needsRehash, err := s.hasher.Verify(admin.Password, plainPassword)
+	if err != nil {
+		return nil, err
+	}
+	if !match || !admin.Activated {
+		s.recordFailure(username, clientIP)
+		return nil, ErrInvalidAdminCredentials
+	}
+
+	if err := s.throttle.RecordSuccess(consoleSystemID, username); err != nil {
+		log.Printf("No se pudo reiniciar los intentos fallidos del administrador %q: %v", username, err)
+	}
+
+	if needsRehash {
+		if passwordHash, err := s.hasher.Hash(plainPassword); err == nil {
+			if err := s.repo.UpdatePassword(admin.ID, passwordHash); err != nil {
+				log.Printf("No se pudo actualizar el hash del administrador %d: %v", admin.ID, err)
+			}
+		}
+	}
+
+	now := time.Now()
+	if err := s.repo.UpdateLastLogin(admin.ID, now); err != nil {
+		log.Printf("No se pudo registrar el último ingreso del administrador %d: %v", admin.ID, err)
+	}
+	admin.LastLogin = &now
+
+	return &admin, nil
 }
 
-func NewAuthService() *AuthService {
-	return &AuthService{}
+func (s *AuthService) recordFailure(username, clientIP string) {
+	if err := s.throttle.RecordFailure(consoleSystemID, username, clientIP); err != nil {
+		log.Printf("No se pudo registrar el intento fallido del administrador %q: %v", username, err)
+	}
 }
 
-func (s *AuthService) Authenticate(username, password string) (bool, error) {
-	envUser := os.Getenv("ADMIN_USERNAME")
-	envPass := os.Getenv("ADMIN_PASSWORD")
+// CreateAdmin registra un nuevo administrador con la contraseña hasheada. Si
+// todavía no hay un superadministrador, el nuevo lo será. canImpersonate le
+// permite suplantar usuarios sin ser superadministrador.
+func (s *AuthService) CreateAdmin(username, email, plainPassword string, superAdmin, canImpersonate bool) (*domain.Admin, error) {
+	username = strings.TrimSpace(username)
+	email = strings.TrimSpace(email)
+
+	if username == "" || email == "" {
+		return nil, errors.New("El usuario y el correo del administrador son requeridos")
+	}
+	if plainPassword == "" {
+		return nil, errors.New("La contraseña del administrador es requerida")
+	}
+
+	if err := s.repo.CheckAdminExists(username, email); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := s.hasher.Hash(plainPassword)
+	if err != nil {
+		return nil, fmt.Errorf("Error al generar el hash de la contraseña: %w", err)
+	}
+
+	if !superAdmin {
+		count, err := s.repo.CountSuperAdmins()
+		if err != nil {
+			return nil, err
+		}
+		superAdmin = count == 0
+	}
+
+	admin := &domain.Admin{
+		Username:       username,
+		Email:          email,
+		Password:       passwordHash,
+		Activated:      true,
+		IsSuperAdmin:   superAdmin,
+		CanImpersonate: canImpersonate,
+		Created:        time.Now(),
+	}
+	admin.Updated = admin.Created
 
-	if envUser == "" || envPass == "" {
-		return false, errors.New("credenciales de administrador no configuradas")
+	if err := s.repo.Create(admin); err != nil {
+		return nil, err
 	}
 
-	return username == envUser && password == envPass, nil
+	return admin, nil
 }
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/password"
+	"errors"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// Backends de autenticación de un sistema
+const (
+	AuthBackendLocal = "local"
+	AuthBackendLDAP  = "ldap"
+)
+
+// CredentialAuthenticator verifica el usuario y la contraseña de un ingreso a
+// un sistema y devuelve el usuario local asociado. La autorización siempre
+// sale de las tablas locales; el autenticador solo decide si la contraseña es
+// correcta. Devuelve ErrInvalidUserCredentials si no lo es.
+type CredentialAuthenticator interface {
+	Authenticate(systemID uint64, username, plainPassword string) (domain.User, error)
+}
+
+// LocalAuthenticator valida la contraseña guardada en la tabla users
+type LocalAuthenticator struct {
+	repo   *repositories.UserRepository
+	hasher *password.Hasher
+}
+
+func NewLocalAuthenticator(repo *repositories.UserRepository, hasher *password.Hasher) *LocalAuthenticator {
+	return &LocalAuthenticator{repo: repo, hasher: hasher}
+}
+
+func (a *LocalAuthenticator) Authenticate(systemID uint64, username, plainPassword string) (domain.User, error) {
+	user, err := a.repo.GetBySystemUsername(systemID, username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.User{}, ErrInvalidUserCredentials
+		}
+		return domain.User{}, fmt.Errorf("Error al validar usuario: %w", err)
+	}
+
+	// La contraseña de un usuario vinculado al directorio no vale localmente
+	if user.IsExternal() {
+		return domain.User{}, ErrInvalidUserCredentials
+	}
+
+	match, needsRehash, err := a.hasher.Verify(user.Password, plainPassword)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("Error al validar usuario: %w", err)
+	}
+	if !match {
+		return domain.User{}, ErrInvalidUserCredentials
+	}
+
+	// Actualizar filas en texto plano o con un costo débil tras un ingreso exitoso
+	if needsRehash {
+		if err := a.rehashPassword(&user, plainPassword); err != nil {
+			log.Printf("No se pudo actualizar el hash de la contraseña del usuario %d: %v", user.ID, err)
+		}
+	}
+
+	return user, nil
+}
+
+// rehashPassword regenera el hash de la contraseña con la configuración vigente
+func (a *LocalAuthenticator) rehashPassword(user *domain.User, plainPassword string) error {
+	passwordHash, err := a.hasher.Hash(plainPassword)
+	if err != nil {
+		return err
+	}
+	if err := a.repo.UpdatePassword(user.ID, passwordHash); err != nil {
+		return err
+	}
+	user.Password = passwordHash
+	return nil
+}
+
+// SystemAuthenticator elige el autenticador según el backend del sistema; los
+// sistemas sin backend propio usan el global
+type SystemAuthenticator struct {
+	defaultBackend string
+	systemRepo     *repositories.SystemRepository
+	local          CredentialAuthenticator
+	ldap           CredentialAuthenticator
+}
+
+func NewSystemAuthenticator(defaultBackend string, systemRepo *repositories.SystemRepository, local, ldap CredentialAuthenticator) *SystemAuthenticator {
+	return &SystemAuthenticator{defaultBackend: defaultBackend, systemRepo: systemRepo, local: local, ldap: ldap}
+}
+
+func (a *SystemAuthenticator) Authenticate(systemID uint64, username, plainPassword string) (domain.User, error) {
+	backend, err := a.Backend(systemID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.User{}, ErrInvalidUserCredentials
+		}
+		return domain.User{}, err
+	}
+
+	if backend == AuthBackendLDAP {
+		return a.ldap.Authenticate(systemID, username, plainPassword)
+	}
+	return a.local.Authenticate(systemID, username, plainPassword)
+}
+
+// Backend devuelve el backend efectivo del sistema
+func (a *SystemAuthenticator) Backend(systemID uint64) (string, error) {
+	system, err := a.systemRepo.GetByID(systemID)
+	if err != nil {
+		return "", err
+	}
+	if system.AuthBackend != "" {
+		return system.AuthBackend, nil
+	}
+	return a.defaultBackend, nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/testutil"
+	"accessv2/pkg/password"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLocalAuthenticatorNormalizesUsername(t *testing.T) {
+	db := testutil.NewDB(t)
+	now := time.Now()
+	system := domain.System{Name: "Uno", Created: now, Updated: now}
+	if err := db.Create(&system).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	hasher, err := password.NewHasher(password.Config{Algorithm: password.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := hasher.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := repositories.NewUserRepository(db)
+	user := domain.User{Username: "JPérez", Password: hash, Email: "jperez@example.com", Activated: true, Created: now, Updated: now}
+	if err := repo.Create(&user); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&domain.SystemUser{SystemID: system.ID, UserID: user.ID, Created: now}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator := NewLocalAuthenticator(repo, hasher)
+	tests := []struct {
+		name     string
+		username string
+		wantErr  error
+	}{
+		{name: "tal cual", username: "JPérez"},
+		{name: "minúsculas", username: "jpérez"},
+		{name: "mayúsculas no ASCII", username: "JPÉREZ"},
+		{name: "espacios alrededor", username: "  jpérez "},
+		{name: "ancho completo", username: "ＪＰéｒｅｚ"},
+		{name: "acento combinado", username: "jpe\u0301rez"},
+		{name: "otro usuario", username: "jperez", wantErr: ErrInvalidUserCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authenticator.Authenticate(uint64(system.ID), tt.username, "secret")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, se esperaba %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.ID != user.ID {
+				t.Fatalf("se obtuvo el usuario %d, se esperaba %d", got.ID, user.ID)
+			}
+		})
+	}
+
+	duplicate := domain.User{Username: "jpÉrez", Password: hash, Email: "otro@example.com", Created: now, Updated: now}
+	if err := repo.Create(&duplicate); err == nil {
+		t.Fatal("se creó un usuario que solo difiere en mayúsculas")
+	}
+}
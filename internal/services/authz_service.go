@@ -0,0 +1,207 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/responses"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MaxAuthzPermissions limita los permisos que se verifican en una llamada
+const MaxAuthzPermissions = 100
+
+// Modos de combinar varios permisos en una verificación
+const (
+	AuthzMatchAll = "all"
+	AuthzMatchAny = "any"
+)
+
+// Motivo de una denegación que no depende de los permisos
+const AuthzReasonUserInactive = "user_inactive"
+
+var (
+	ErrAuthzUserRequired       = errors.New("Debe indicar user_id o username")
+	ErrAuthzUserNotFound       = errors.New("El usuario no existe en el sistema")
+	ErrAuthzPermissionRequired = errors.New("Debe indicar al menos un permiso")
+	ErrAuthzTooManyPermissions = fmt.Errorf("Se pueden verificar hasta %d permisos por llamada", MaxAuthzPermissions)
+	ErrAuthzInvalidMatch       = errors.New("match debe ser all o any")
+)
+
+// AuthzService responde si un usuario tiene permisos del sistema, con los
+// mismos datos que se publican en el claim roles de los tokens
+type AuthzService struct {
+	userRepo *repositories.UserRepository
+}
+
+func NewAuthzService(userRepo *repositories.UserRepository) *AuthzService {
+	return &AuthzService{userRepo: userRepo}
+}
+
+// Check verifica los permisos en conjunto: con match all el usuario debe
+// tenerlos todos y con any basta uno. Roles son los que otorgan alguno de los
+// permisos pedidos.
+func (s *AuthzService) Check(systemID uint64, userID uint, username string, permissions []string, match string) (responses.AuthzCheckResult, error) {
+	match = strings.ToLower(strings.TrimSpace(match))
+	if match == "" {
+		match = AuthzMatchAll
+	}
+	if match != AuthzMatchAll && match != AuthzMatchAny {
+		return responses.AuthzCheckResult{}, ErrAuthzInvalidMatch
+	}
+
+	user, names, decisions, err := s.decide(systemID, userID, username, permissions)
+	if err != nil {
+		return responses.AuthzCheckResult{}, err
+	}
+
+	result := responses.AuthzCheckResult{
+		UserID:   user.ID,
+		SystemID: systemID,
+		Roles:    []responses.AuthzRole{},
+		Missing:  []string{},
+	}
+	if !user.Activated {
+		result.Reason = AuthzReasonUserInactive
+		result.Missing = names
+		return result, nil
+	}
+
+	granted := 0
+	seen := make(map[uint]bool)
+	for _, decision := range decisions {
+		if !decision.Allowed {
+			result.Missing = append(result.Missing, decision.Permission)
+			continue
+		}
+		granted++
+		for _, role := range decision.Roles {
+			if !seen[role.ID] {
+				seen[role.ID] = true
+				result.Roles = append(result.Roles, role)
+			}
+		}
+	}
+
+	if match == AuthzMatchAny {
+		result.Allowed = granted > 0
+	} else {
+		result.Allowed = granted == len(decisions)
+	}
+	return result, nil
+}
+
+// CheckBatch devuelve una decisión por cada permiso, en el orden pedido
+func (s *AuthzService) CheckBatch(systemID uint64, userID uint, username string, permissions []string) (responses.AuthzBatchResult, error) {
+	user, _, decisions, err := s.decide(systemID, userID, username, permissions)
+	if err != nil {
+		return responses.AuthzBatchResult{}, err
+	}
+
+	result := responses.AuthzBatchResult{
+		UserID:   user.ID,
+		SystemID: systemID,
+		Results:  decisions,
+	}
+	if !user.Activated {
+		result.Reason = AuthzReasonUserInactive
+	}
+	return result, nil
+}
+
+// decide resuelve al usuario y arma la decisión de cada permiso con una sola
+// consulta. Un usuario inactivo no tiene ningún permiso.
+func (s *AuthzService) decide(systemID uint64, userID uint, username string, permissions []string) (domain.User, []string, []responses.AuthzDecision, error) {
+	names := normalizePermissionNames(permissions)
+	if len(names) == 0 {
+		return domain.User{}, nil, nil, ErrAuthzPermissionRequired
+	}
+	if len(names) > MaxAuthzPermissions {
+		return domain.User{}, nil, nil, ErrAuthzTooManyPermissions
+	}
+
+	user, err := s.FindUser(systemID, userID, username)
+	if err != nil {
+		return domain.User{}, nil, nil, err
+	}
+
+	decisions := make([]responses.AuthzDecision, len(names))
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		decisions[i] = responses.AuthzDecision{Permission: name, Roles: []responses.AuthzRole{}}
+		index[name] = i
+	}
+	if !user.Activated {
+		return user, names, decisions, nil
+	}
+
+	rows, err := s.userRepo.GetUserPermissionRolesBySystem(user.ID, systemID, names)
+	if err != nil {
+		return domain.User{}, nil, nil, err
+	}
+	for _, row := range rows {
+		i, ok := index[row.PermissionName]
+		if !ok {
+			continue
+		}
+		decision := &decisions[i]
+		decision.Allowed = true
+		if !hasAuthzRole(decision.Roles, uint(row.RoleID)) {
+			decision.Roles = append(decision.Roles, responses.AuthzRole{ID: uint(row.RoleID), Name: row.RoleName})
+		}
+	}
+
+	return user, names, decisions, nil
+}
+
+// FindUser busca al usuario entre los asociados al sistema por su ID o, si no
+// se indica, por su nombre de usuario
+func (s *AuthzService) FindUser(systemID uint64, userID uint, username string) (domain.User, error) {
+	var (
+		user domain.User
+		err  error
+	)
+	switch {
+	case userID != 0:
+		user, err = s.userRepo.GetBySystemAndID(systemID, userID)
+	case strings.TrimSpace(username) != "":
+		user, err = s.userRepo.GetBySystemUsername(systemID, username)
+	default:
+		return domain.User{}, ErrAuthzUserRequired
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.User{}, ErrAuthzUserNotFound
+		}
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+// normalizePermissionNames quita espacios, vacíos y repetidos conservando el
+// orden
+func normalizePermissionNames(permissions []string) []string {
+	names := make([]string, 0, len(permissions))
+	seen := make(map[string]bool, len(permissions))
+	for _, name := range permissions {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+func hasAuthzRole(roles []responses.AuthzRole, id uint) bool {
+	for _, role := range roles {
+		if role.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/responses"
+	"accessv2/internal/testutil"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestAuthzService arma el sistema Uno con los roles Operator (reports.view,
+// reports.export) y Viewer (reports.view). jdoe tiene todos esos permisos,
+// mlopez está inactivo con los de Operator y otro solo está en el sistema Dos.
+func newTestAuthzService(t *testing.T) *AuthzService {
+	t.Helper()
+	db := testutil.NewDB(t)
+	now := time.Now()
+
+	create := func(value interface{}) {
+		t.Helper()
+		if err := db.Create(value).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	create(&[]domain.System{{ID: 1, Name: "Uno", Created: now, Updated: now}, {ID: 2, Name: "Dos", Created: now, Updated: now}})
+
+	userRepo := repositories.NewUserRepository(db)
+	for _, user := range []domain.User{
+		{ID: 1, Username: "jdoe", Email: "jdoe@example.com", Activated: true},
+		{ID: 2, Username: "mlopez", Email: "mlopez@example.com"},
+		{ID: 3, Username: "otro", Email: "otro@example.com", Activated: true},
+	} {
+		user.Created, user.Updated = now, now
+		if err := userRepo.Create(&user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	create(&[]domain.SystemUser{{SystemID: 1, UserID: 1, Created: now}, {SystemID: 1, UserID: 2, Created: now}, {SystemID: 2, UserID: 3, Created: now}})
+
+	create(&domain.Role{ID: 1, Name: "Operator", SystemID: 1, Created: now, Updated: now, Permissions: []domain.Permission{
+		{ID: 11, Name: "reports.view", Created: now, Updated: now},
+		{ID: 12, Name: "reports.export", Created: now, Updated: now},
+	}})
+	create(&domain.Role{ID: 2, Name: "Viewer", SystemID: 1, Created: now, Updated: now, Permissions: []domain.Permission{
+		{ID: 21, Name: "reports.view", Created: now, Updated: now},
+	}})
+
+	var grants []domain.SystemUserPermission
+	for _, grant := range []struct{ user, permission uint }{{1, 11}, {1, 12}, {1, 21}, {2, 11}, {2, 12}} {
+		grants = append(grants, domain.SystemUserPermission{SystemID: 1, UserID: grant.user, PermissionID: grant.permission, Created: now})
+	}
+	create(&grants)
+
+	return NewAuthzService(userRepo)
+}
+
+func TestAuthzServiceCheck(t *testing.T) {
+	s := newTestAuthzService(t)
+	tooMany := make([]string, MaxAuthzPermissions+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("perm.%d", i)
+	}
+	operator := responses.AuthzRole{ID: 1, Name: "Operator"}
+	viewer := responses.AuthzRole{ID: 2, Name: "Viewer"}
+
+	tests := []struct {
+		name        string
+		userID      uint
+		username    string
+		permissions []string
+		match       string
+		wantErr     error
+		wantAllowed bool
+		wantRoles   []responses.AuthzRole
+		wantMissing []string
+		wantReason  string
+	}{
+		{
+			name:        "permiso otorgado por dos roles",
+			userID:      1,
+			permissions: []string{"reports.view"},
+			wantAllowed: true,
+			wantRoles:   []responses.AuthzRole{operator, viewer},
+			wantMissing: []string{},
+		},
+		{
+			name:        "all con uno faltante",
+			userID:      1,
+			permissions: []string{"reports.export", "users.manage"},
+			match:       "all",
+			wantRoles:   []responses.AuthzRole{operator},
+			wantMissing: []string{"users.manage"},
+		},
+		{
+			name:        "any con uno faltante",
+			userID:      1,
+			permissions: []string{"reports.export", "users.manage"},
+			match:       " ANY ",
+			wantAllowed: true,
+			wantRoles:   []responses.AuthzRole{operator},
+			wantMissing: []string{"users.manage"},
+		},
+		{
+			name:        "any sin ninguno",
+			userID:      1,
+			permissions: []string{"users.manage", "users.delete"},
+			match:       "any",
+			wantRoles:   []responses.AuthzRole{},
+			wantMissing: []string{"users.manage", "users.delete"},
+		},
+		{
+			name:        "por nombre de usuario sin distinguir mayúsculas",
+			username:    " JDoe ",
+			permissions: []string{"reports.export"},
+			wantAllowed: true,
+			wantRoles:   []responses.AuthzRole{operator},
+			wantMissing: []string{},
+		},
+		{
+			name:        "permisos repetidos y con espacios",
+			userID:      1,
+			permissions: []string{" reports.export ", "reports.export", ""},
+			wantAllowed: true,
+			wantRoles:   []responses.AuthzRole{operator},
+			wantMissing: []string{},
+		},
+		{
+			name:        "el nombre del permiso distingue mayúsculas",
+			userID:      1,
+			permissions: []string{"Reports.View"},
+			wantRoles:   []responses.AuthzRole{},
+			wantMissing: []string{"Reports.View"},
+		},
+		{
+			name:        "usuario inactivo",
+			userID:      2,
+			permissions: []string{"reports.view", "reports.export"},
+			wantRoles:   []responses.AuthzRole{},
+			wantMissing: []string{"reports.view", "reports.export"},
+			wantReason:  AuthzReasonUserInactive,
+		},
+		{name: "usuario de otro sistema", userID: 3, permissions: []string{"reports.view"}, wantErr: ErrAuthzUserNotFound},
+		{name: "usuario inexistente", username: "nadie", permissions: []string{"reports.view"}, wantErr: ErrAuthzUserNotFound},
+		{name: "sin usuario", permissions: []string{"reports.view"}, wantErr: ErrAuthzUserRequired},
+		{name: "sin permisos", userID: 1, permissions: []string{" ", ""}, wantErr: ErrAuthzPermissionRequired},
+		{name: "demasiados permisos", userID: 1, permissions: tooMany, wantErr: ErrAuthzTooManyPermissions},
+		{name: "match inválido", userID: 1, permissions: []string{"reports.view"}, match: "some", wantErr: ErrAuthzInvalidMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Check(1, tt.userID, tt.username, tt.permissions, tt.match)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, se esperaba %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got.Allowed != tt.wantAllowed || got.Reason != tt.wantReason {
+				t.Fatalf("allowed = %v, reason = %q; se esperaba %v, %q", got.Allowed, got.Reason, tt.wantAllowed, tt.wantReason)
+			}
+			if !reflect.DeepEqual(got.Roles, tt.wantRoles) {
+				t.Fatalf("roles = %v, se esperaba %v", got.Roles, tt.wantRoles)
+			}
+			if !reflect.DeepEqual(got.Missing, tt.wantMissing) {
+				t.Fatalf("missing = %v, se esperaba %v", got.Missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestAuthzServiceCheckBatch(t *testing.T) {
+	s := newTestAuthzService(t)
+
+	got, err := s.CheckBatch(1, 1, "", []string{"users.manage", "reports.view", "reports.export", "reports.view"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []responses.AuthzDecision{
+		{Permission: "users.manage", Roles: []responses.AuthzRole{}},
+		{Permission: "reports.view", Allowed: true, Roles: []responses.AuthzRole{{ID: 1, Name: "Operator"}, {ID: 2, Name: "Viewer"}}},
+		{Permission: "reports.export", Allowed: true, Roles: []responses.AuthzRole{{ID: 1, Name: "Operator"}}},
+	}
+	if !reflect.DeepEqual(got.Results, want) {
+		t.Fatalf("decisiones = %+v, se esperaba %+v", got.Results, want)
+	}
+
+	inactive, err := s.CheckBatch(1, 2, "", []string{"reports.view"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inactive.Reason != AuthzReasonUserInactive || inactive.Results[0].Allowed {
+		t.Fatalf("usuario inactivo: %+v", inactive)
+	}
+}
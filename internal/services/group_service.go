@@ -0,0 +1,207 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/forms"
+	"accessv2/internal/repositories"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrGroupNameRequired    = errors.New("El nombre del grupo es requerido")
+	ErrGroupNameInUse       = errors.New("Nombre de grupo ya en uso")
+	ErrGroupSystemNotFound  = errors.New("El sistema del grupo no existe")
+	ErrGroupPermissionScope = errors.New("Un grupo de sistema solo puede recibir permisos de ese sistema")
+	ErrGroupMembersRequired = errors.New("Indica al menos un usuario")
+)
+
+type GroupService struct {
+	repo       *repositories.GroupRepository
+	userRepo   *repositories.UserRepository
+	systemRepo *repositories.SystemRepository
+}
+
+func NewGroupService(repo *repositories.GroupRepository, userRepo *repositories.UserRepository, systemRepo *repositories.SystemRepository) *GroupService {
+	return &GroupService{repo: repo, userRepo: userRepo, systemRepo: systemRepo}
+}
+
+func (s *GroupService) GetAllGroups() ([]domain.GroupSummary, error) {
+	return s.repo.GetAll()
+}
+
+// GetUserGroups devuelve los grupos de los que el usuario es miembro
+func (s *GroupService) GetUserGroups(userID uint) ([]domain.GroupSummary, error) {
+	return s.repo.GetByUser(userID)
+}
+
+func (s *GroupService) FetchGroup(id uint64, group *domain.Group) error {
+	tempGroup, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	*group = tempGroup
+	return nil
+}
+
+// CreateGroup crea un grupo global (SystemID 0) o de un sistema
+func (s *GroupService) CreateGroup(input *forms.GroupCreateInput) (*domain.Group, error) {
+	group := &domain.Group{
+		Name:        strings.TrimSpace(input.Name),
+		Description: strings.TrimSpace(input.Description),
+	}
+	if input.SystemID != 0 {
+		if _, err := s.systemRepo.GetByID(uint64(input.SystemID)); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrGroupSystemNotFound
+			}
+			return nil, err
+		}
+		systemID := input.SystemID
+		group.SystemID = &systemID
+	}
+	if err := s.validateName(group); err != nil {
+		return nil, err
+	}
+
+	group.Created = time.Now()
+	group.Updated = group.Created
+	if err := s.repo.Create(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// UpdateGroup guarda el nombre y la descripción; el sistema no cambia
+func (s *GroupService) UpdateGroup(group *domain.Group) error {
+	group.Name = strings.TrimSpace(group.Name)
+	group.Description = strings.TrimSpace(group.Description)
+	if err := s.validateName(group); err != nil {
+		return err
+	}
+
+	group.Updated = time.Now()
+	return s.repo.Update(group)
+}
+
+// DeleteGroup elimina el grupo; sus miembros pierden los permisos otorgados por él
+func (s *GroupService) DeleteGroup(id uint64) error {
+	return s.repo.Delete(id)
+}
+
+func (s *GroupService) GetMembers(groupID uint64) ([]domain.GroupMember, error) {
+	return s.repo.GetMembers(groupID)
+}
+
+// AddMembers agrega al grupo los usuarios indicados por nombre de usuario o
+// correo y devuelve los identificadores que no corresponden a ningún usuario
+func (s *GroupService) AddMembers(groupID uint64, users string) (int, []string, error) {
+	identifiers := strings.FieldsFunc(users, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	if len(identifiers) == 0 {
+		return 0, nil, ErrGroupMembersRequired
+	}
+
+	var userIDs []uint
+	var notFound []string
+	seen := make(map[uint]bool)
+	for _, identifier := range identifiers {
+		user, err := s.userRepo.GetByIdentifier(0, identifier)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				notFound = append(notFound, identifier)
+				continue
+			}
+			return 0, nil, err
+		}
+		if !seen[user.ID] {
+			seen[user.ID] = true
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+
+	if err := s.repo.AddMembers(groupID, userIDs); err != nil {
+		return 0, nil, err
+	}
+	return len(userIDs), notFound, nil
+}
+
+func (s *GroupService) RemoveMember(groupID, userID uint64) error {
+	return s.repo.RemoveMember(groupID, userID)
+}
+
+// GetGrantablePermissions agrupa por sistema y rol los permisos que se pueden
+// otorgar al grupo, marcando los ya otorgados
+func (s *GroupService) GetGrantablePermissions(group domain.Group) ([]domain.GroupSystemPermissions, error) {
+	flatPermissions, err := s.repo.GetGrantablePermissions(group)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []domain.GroupSystemPermissions{}
+	systemIndex := make(map[uint]int)
+	roleIndex := make(map[uint]int)
+	for _, p := range flatPermissions {
+		si, exists := systemIndex[p.SystemID]
+		if !exists {
+			si = len(result)
+			systemIndex[p.SystemID] = si
+			result = append(result, domain.GroupSystemPermissions{ID: p.SystemID, Name: p.SystemName})
+		}
+
+		system := &result[si]
+		ri, exists := roleIndex[p.RoleID]
+		if !exists {
+			ri = len(system.Roles)
+			roleIndex[p.RoleID] = ri
+			system.Roles = append(system.Roles, domain.RoleWithPermissions{ID: p.RoleID, Name: p.RoleName})
+		}
+
+		system.Roles[ri].Permissions = append(system.Roles[ri].Permissions, domain.UserPermission{
+			ID:         p.PermissionID,
+			Name:       p.PermissionName,
+			IsAssigned: p.IsAssigned,
+		})
+	}
+
+	return result, nil
+}
+
+// SetPermissions reemplaza los permisos del grupo. Un grupo de sistema solo
+// puede recibir permisos de ese sistema.
+func (s *GroupService) SetPermissions(group domain.Group, permissionIDs []uint) error {
+	grantable, err := s.repo.GetGrantablePermissions(group)
+	if err != nil {
+		return err
+	}
+	allowed := make(map[uint]bool, len(grantable))
+	for _, p := range grantable {
+		allowed[p.PermissionID] = true
+	}
+	for _, id := range permissionIDs {
+		if !allowed[id] {
+			return ErrGroupPermissionScope
+		}
+	}
+
+	return s.repo.SetPermissions(uint64(group.ID), permissionIDs)
+}
+
+// validateName exige un nombre único dentro del alcance del grupo
+func (s *GroupService) validateName(group *domain.Group) error {
+	if group.Name == "" {
+		return ErrGroupNameRequired
+	}
+	exists, err := s.repo.CheckNameExists(group.Name, group.SystemID, group.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrGroupNameInUse
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/responses"
+	"accessv2/pkg/utils"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrImpersonationForbidden       = errors.New("No tienes permiso para suplantar usuarios")
+	ErrImpersonationNotFound        = errors.New("La suplantación no existe")
+	ErrImpersonationEndForbidden    = errors.New("Solo un superadministrador puede terminar suplantaciones de otros administradores")
+	ErrImpersonationUserUnavailable = errors.New("El usuario no está activo o no pertenece al sistema")
+	ErrImpersonationReasonTooShort  = errors.New("El motivo de la suplantación es demasiado corto")
+)
+
+// ImpersonationConfig fija la vigencia de los tokens de suplantación y el
+// largo mínimo del motivo que queda en la auditoría
+type ImpersonationConfig struct {
+	TokenTTL        time.Duration
+	MinReasonLength int
+}
+
+// ImpersonationService permite a un administrador autorizado obtener un token
+// de un usuario para ver lo mismo que el sistema consumidor. Cada suplantación
+// queda registrada con su motivo y el token lleva el claim act.
+type ImpersonationService struct {
+	cfg          ImpersonationConfig
+	repo         *repositories.ImpersonationRepository
+	adminRepo    *repositories.AdminRepository
+	userRepo     *repositories.UserRepository
+	tokenService *TokenService
+}
+
+func NewImpersonationService(cfg ImpersonationConfig, repo *repositories.ImpersonationRepository, adminRepo *repositories.AdminRepository, userRepo *repositories.UserRepository, tokenService *TokenService) *ImpersonationService {
+	return &ImpersonationService{
+		cfg:          cfg,
+		repo:         repo,
+		adminRepo:    adminRepo,
+		userRepo:     userRepo,
+		tokenService: tokenService,
+	}
+}
+
+// MinReasonLength devuelve el largo mínimo del motivo
+func (s *ImpersonationService) MinReasonLength() int {
+	return s.cfg.MinReasonLength
+}
+
+// CanImpersonate se consulta en cada petición para que quitar el permiso tenga
+// efecto inmediato
+func (s *ImpersonationService) CanImpersonate(adminID uint) (bool, error) {
+	admin, err := s.activeAdmin(adminID)
+	if err != nil || admin == nil {
+		return false, err
+	}
+	return admin.IsSuperAdmin || admin.CanImpersonate, nil
+}
+
+// Start registra la suplantación y emite un access token del usuario en el
+// sistema con el claim act del administrador
+func (s *ImpersonationService) Start(adminID uint, systemID uint64, userID uint, reason, clientIP, userAgent string) (domain.Impersonation, string, error) {
+	admin, err := s.activeAdmin(adminID)
+	if err != nil {
+		return domain.Impersonation{}, "", err
+	}
+	if admin == nil || !(admin.IsSuperAdmin || admin.CanImpersonate) {
+		return domain.Impersonation{}, "", ErrImpersonationForbidden
+	}
+
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) < s.cfg.MinReasonLength {
+		return domain.Impersonation{}, "", fmt.Errorf("%w, escribe al menos %d caracteres", ErrImpersonationReasonTooShort, s.cfg.MinReasonLength)
+	}
+
+	user, err := s.userRepo.GetBySystemAndID(systemID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Impersonation{}, "", ErrImpersonationUserUnavailable
+		}
+		return domain.Impersonation{}, "", err
+	}
+	if !user.Activated {
+		return domain.Impersonation{}, "", ErrImpersonationUserUnavailable
+	}
+
+	access, err := s.userRepo.GetUserNestedPermissionsBySystem(user.ID, systemID)
+	if err != nil {
+		return domain.Impersonation{}, "", err
+	}
+
+	jti, err := utils.SecureToken(16)
+	if err != nil {
+		return domain.Impersonation{}, "", err
+	}
+
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	now := time.Now()
+	impersonation := domain.Impersonation{
+		AdminID:   admin.ID,
+		UserID:    user.ID,
+		SystemID:  uint(systemID),
+		JTI:       jti,
+		Reason:    reason,
+		IPAddress: clientIP,
+		UserAgent: userAgent,
+		ExpiresAt: now.Add(s.cfg.TokenTTL),
+		Created:   now,
+	}
+	if err := s.repo.Create(&impersonation); err != nil {
+		return domain.Impersonation{}, "", err
+	}
+
+	actor := responses.ActorClaim{
+		Sub:             "admin:" + strconv.FormatUint(uint64(admin.ID), 10),
+		Username:        admin.Username,
+		ImpersonationID: impersonation.ID,
+	}
+	token, expiresAt, err := s.tokenService.IssueImpersonationToken(jti, user, systemID, access.Roles, s.cfg.TokenTTL, actor)
+	if err != nil {
+		// El registro queda en la auditoría, pero terminado
+		if endErr := s.repo.End(impersonation.ID, time.Now()); endErr != nil {
+			log.Printf("No se pudo cerrar la suplantación %d: %v", impersonation.ID, endErr)
+		}
+		return domain.Impersonation{}, "", err
+	}
+
+	log.Printf("El administrador %q suplanta al usuario %q en el sistema %d: %s", admin.Username, user.Username, systemID, reason)
+
+	impersonation.ExpiresAt = expiresAt
+	impersonation.AdminUsername = admin.Username
+	impersonation.Username = user.Username
+	return impersonation, token, nil
+}
+
+// End termina la suplantación y revoca su token. Un administrador puede
+// terminar las propias; las de otros solo un superadministrador.
+func (s *ImpersonationService) End(adminID, impersonationID uint) (domain.Impersonation, error) {
+	impersonation, err := s.repo.GetByID(impersonationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Impersonation{}, ErrImpersonationNotFound
+		}
+		return domain.Impersonation{}, err
+	}
+
+	if impersonation.AdminID != adminID {
+		admin, err := s.activeAdmin(adminID)
+		if err != nil {
+			return domain.Impersonation{}, err
+		}
+		if admin == nil || !admin.IsSuperAdmin {
+			return domain.Impersonation{}, ErrImpersonationEndForbidden
+		}
+	}
+
+	if impersonation.EndedAt != nil {
+		return impersonation, nil
+	}
+
+	now := time.Now()
+	if err := s.repo.End(impersonation.ID, now); err != nil {
+		return domain.Impersonation{}, err
+	}
+	if err := s.tokenService.RevokeToken(impersonation.JTI, RevokeReasonImpersonationEnd); err != nil {
+		return domain.Impersonation{}, err
+	}
+
+	impersonation.EndedAt = &now
+	return impersonation, nil
+}
+
+// GetRecent devuelve la auditoría de suplantaciones: todas para un
+// superadministrador, las propias para los demás
+func (s *ImpersonationService) GetRecent(adminID uint, limit int) ([]domain.Impersonation, error) {
+	admin, err := s.activeAdmin(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin != nil && admin.IsSuperAdmin {
+		return s.repo.GetRecent(0, limit)
+	}
+	return s.repo.GetRecent(adminID, limit)
+}
+
+// activeAdmin devuelve nil si el administrador no existe o está desactivado
+func (s *ImpersonationService) activeAdmin(adminID uint) (*domain.Admin, error) {
+	admin, err := s.adminRepo.GetByID(adminID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !admin.Activated {
+		return nil, nil
+	}
+	return &admin, nil
+}
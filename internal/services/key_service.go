@@ -0,0 +1,299 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/jwks"
+	"accessv2/pkg/utils"
+	"crypto"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// KeyConfig agrupa la configuración de las llaves de firma
+type KeyConfig struct {
+	Algorithm      string        // HS256, RS256, ES256 o EdDSA
+	Secret         string        // JWT_KEY, usado con HS256
+	RotationPeriod time.Duration // cada cuánto se genera una nueva llave asimétrica
+	PublishGrace   time.Duration // cuánto se sigue publicando una llave retirada
+	// LegacyHS256 acepta, con un algoritmo asimétrico, los tokens HS256 firmados
+	// con Secret antes del cambio; solo hasta PublishGrace después de la primera
+	// llave asimétrica
+	LegacyHS256 bool
+}
+
+// KeyService firma y verifica tokens con la llave configurada. Con algoritmos
+// asimétricos las llaves se guardan en signing_keys y se rotan periódicamente.
+type KeyService struct {
+	cfg  KeyConfig
+	repo *repositories.SigningKeyRepository
+
+	mu         sync.Mutex
+	current    *domain.SigningKey
+	signer     crypto.Signer
+	publicKeys map[string]cachedPublicKey
+	legacyEnd  *time.Time // fin de la aceptación de HS256 ya calculado
+}
+
+type cachedPublicKey struct {
+	key       crypto.PublicKey
+	expiresAt *time.Time
+}
+
+func NewKeyService(cfg KeyConfig, repo *repositories.SigningKeyRepository) (*KeyService, error) {
+	if _, err := jwks.SigningMethod(cfg.Algorithm); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, cfg.Algorithm)
+	}
+	return &KeyService{
+		cfg:        cfg,
+		repo:       repo,
+		publicKeys: make(map[string]cachedPublicKey),
+	}, nil
+}
+
+// Algorithm devuelve el algoritmo con el que se firman los tokens
+func (s *KeyService) Algorithm() string {
+	return s.cfg.Algorithm
+}
+
+// Sign firma los claims con la llave vigente, agregando el kid en la cabecera
+func (s *KeyService) Sign(claims jwt.Claims) (string, error) {
+	method, _ := jwks.SigningMethod(s.cfg.Algorithm)
+
+	if !jwks.IsAsymmetric(s.cfg.Algorithm) {
+		if s.cfg.Secret == "" {
+			return "", errors.New("clave JWT no configurada")
+		}
+		return jwt.NewWithClaims(method, claims).SignedString([]byte(s.cfg.Secret))
+	}
+
+	key, signer, err := s.currentKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.Kid
+	return token.SignedString(signer)
+}
+
+// Keyfunc resuelve la llave de verificación de un token según su algoritmo y kid
+func (s *KeyService) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() == jwks.AlgHS256 {
+		if s.cfg.Secret == "" {
+			return nil, errors.New("clave JWT no configurada")
+		}
+		if s.cfg.Algorithm != jwks.AlgHS256 && !s.acceptsLegacyHS256(time.Now()) {
+			return nil, errors.New("los tokens HS256 ya no se aceptan")
+		}
+		return []byte(s.cfg.Secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("el token no incluye kid")
+	}
+	return s.publicKey(kid)
+}
+
+// ValidMethods lista los algoritmos aceptados al verificar. Tras pasar a un
+// algoritmo asimétrico, HS256 solo se acepta con LegacyHS256 y hasta que
+// expiran los tokens emitidos antes del cambio.
+func (s *KeyService) ValidMethods() []string {
+	methods := []string{s.cfg.Algorithm}
+	if s.cfg.Algorithm != jwks.AlgHS256 && s.cfg.Secret != "" && s.acceptsLegacyHS256(time.Now()) {
+		methods = append(methods, jwks.AlgHS256)
+	}
+	return methods
+}
+
+// acceptsLegacyHS256 indica si todavía se aceptan los tokens HS256 emitidos
+// antes del cambio de algoritmo. El plazo corre desde la primera llave
+// asimétrica; si aún no existe, no se firmó ningún token con ella.
+func (s *KeyService) acceptsLegacyHS256(now time.Time) bool {
+	if !s.cfg.LegacyHS256 {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.legacyEnd == nil {
+		first, err := s.repo.GetFirst()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true
+		}
+		if err != nil {
+			log.Printf("Error al consultar la primera llave de firma: %v", err)
+			return false
+		}
+		end := first.Created.Add(s.cfg.PublishGrace)
+		s.legacyEnd = &end
+	}
+	return now.Before(*s.legacyEnd)
+}
+
+// JWKS devuelve las llaves públicas vigentes y las retiradas que aún no expiran
+func (s *KeyService) JWKS() (jwks.Set, error) {
+	set := jwks.Set{Keys: []jwks.JWK{}}
+	if !jwks.IsAsymmetric(s.cfg.Algorithm) {
+		return set, nil
+	}
+
+	// Garantiza que exista una llave vigente antes de publicar
+	if _, _, err := s.currentKey(); err != nil {
+		return set, err
+	}
+
+	keys, err := s.repo.GetPublished(time.Now())
+	if err != nil {
+		return set, err
+	}
+
+	for _, key := range keys {
+		pub, err := jwks.DecodePublicKey(key.PublicKey)
+		if err != nil {
+			return set, err
+		}
+		jwk, err := jwks.NewJWK(key.Kid, key.Algorithm, pub)
+		if err != nil {
+			return set, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set, nil
+}
+
+// Rotate genera una nueva llave y retira la vigente, que se sigue publicando
+// durante PublishGrace para validar los tokens que firmó
+func (s *KeyService) Rotate() error {
+	if !jwks.IsAsymmetric(s.cfg.Algorithm) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rotateLocked()
+}
+
+// StartRotation revisa periódicamente si la llave vigente debe rotarse
+func (s *KeyService) StartRotation(interval time.Duration) {
+	if !jwks.IsAsymmetric(s.cfg.Algorithm) {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, _, err := s.currentKey(); err != nil {
+				log.Printf("Error al rotar la llave de firma: %v", err)
+			}
+		}
+	}()
+}
+
+// currentKey devuelve la llave vigente, cargándola o rotándola si hace falta
+func (s *KeyService) currentKey() (*domain.SigningKey, crypto.Signer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		key, err := s.repo.GetActive(s.cfg.Algorithm)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, err
+		}
+		if err == nil {
+			signer, err := jwks.DecodePrivateKey(key.PrivateKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			s.current = &key
+			s.signer = signer
+		}
+	}
+
+	if s.current == nil || time.Since(s.current.Created) >= s.cfg.RotationPeriod {
+		if err := s.rotateLocked(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return s.current, s.signer, nil
+}
+
+func (s *KeyService) rotateLocked() error {
+	signer, err := jwks.GenerateKey(s.cfg.Algorithm)
+	if err != nil {
+		return err
+	}
+	privatePEM, err := jwks.EncodePrivateKey(signer)
+	if err != nil {
+		return err
+	}
+	publicPEM, err := jwks.EncodePublicKey(signer.Public())
+	if err != nil {
+		return err
+	}
+	kid, err := utils.SecureToken(12)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	retiredExpiresAt := now.Add(s.cfg.PublishGrace)
+	key := &domain.SigningKey{
+		Kid:        kid,
+		Algorithm:  s.cfg.Algorithm,
+		PrivateKey: privatePEM,
+		PublicKey:  publicPEM,
+		Created:    now,
+	}
+	if err := s.repo.Rotate(key, retiredExpiresAt); err != nil {
+		return err
+	}
+
+	if s.current != nil {
+		if cached, ok := s.publicKeys[s.current.Kid]; ok {
+			cached.expiresAt = &retiredExpiresAt
+			s.publicKeys[s.current.Kid] = cached
+		}
+	}
+
+	s.current = key
+	s.signer = signer
+	s.publicKeys[key.Kid] = cachedPublicKey{key: signer.Public()}
+	log.Printf("Nueva llave de firma %s (%s) generada", key.Kid, key.Algorithm)
+	return nil
+}
+
+func (s *KeyService) publicKey(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, ok := s.publicKeys[kid]
+	if !ok {
+		key, err := s.repo.GetByKid(kid)
+		if err != nil {
+			return nil, fmt.Errorf("llave %s desconocida: %w", kid, err)
+		}
+		pub, err := jwks.DecodePublicKey(key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		cached = cachedPublicKey{key: pub, expiresAt: key.ExpiresAt}
+		s.publicKeys[kid] = cached
+	}
+
+	if cached.expiresAt != nil && time.Now().After(*cached.expiresAt) {
+		return nil, fmt.Errorf("llave %s expirada", kid)
+	}
+	return cached.key, nil
+}
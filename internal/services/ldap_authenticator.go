@@ -0,0 +1,187 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/ldapauth"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var ErrLDAPNotConfigured = errors.New("El sistema usa LDAP pero no hay un directorio configurado")
+
+// GlobalDirectory identifica al directorio global (LDAP_*); el propio de un
+// sistema se identifica con SystemDirectory
+const GlobalDirectory = "global"
+
+// Límites de las columnas username y email de la tabla users
+const (
+	maxUsernameLength = 20
+	maxEmailLength    = 50
+)
+
+// SystemDirectory identifica al directorio propio del sistema
+func SystemDirectory(systemID uint64) string {
+	return "system-" + strconv.FormatUint(systemID, 10)
+}
+
+// ExternalID es el valor de users.external_id para una entrada del directorio.
+// Lleva el directorio delante porque el mismo DN puede existir en dos de ellos.
+func ExternalID(directory, dn string) string {
+	return directory + ":" + dn
+}
+
+// LDAPAuthenticator valida las credenciales contra el directorio del sistema,
+// o el global si el sistema no tiene uno propio. En el primer ingreso crea el
+// usuario local de la entrada, salvo que ya exista uno con el mismo nombre o
+// correo: ese solo se vincula desde la consola, para que un directorio no se
+// apropie de una cuenta ajena. El usuario no queda asociado al sistema; lo
+// asocia un administrador. Los usuarios que no existen en el directorio siguen
+// ingresando con su contraseña local.
+type LDAPAuthenticator struct {
+	global         ldapauth.Config
+	dial           ldapauth.Dialer
+	db             *gorm.DB
+	settingsRepo   *repositories.SystemLDAPSettingsRepository
+	userRepo       *repositories.UserRepository
+	systemUserRepo *repositories.SystemUserRepository
+	local          CredentialAuthenticator
+}
+
+// NewLDAPAuthenticator crea el autenticador. dial permite reemplazar la
+// conexión al directorio, por ejemplo por un servidor en proceso; nil usa TCP.
+func NewLDAPAuthenticator(global ldapauth.Config, dial ldapauth.Dialer, db *gorm.DB, settingsRepo *repositories.SystemLDAPSettingsRepository, userRepo *repositories.UserRepository, systemUserRepo *repositories.SystemUserRepository, local CredentialAuthenticator) *LDAPAuthenticator {
+	return &LDAPAuthenticator{
+		global:         global,
+		dial:           dial,
+		db:             db,
+		settingsRepo:   settingsRepo,
+		userRepo:       userRepo,
+		systemUserRepo: systemUserRepo,
+		local:          local,
+	}
+}
+
+func (a *LDAPAuthenticator) Authenticate(systemID uint64, username, plainPassword string) (domain.User, error) {
+	cfg, directoryKey, err := systemDirectory(a.settingsRepo, a.global, systemID)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	directory, err := ldapauth.New(cfg, a.dial)
+	if err != nil {
+		if errors.Is(err, ldapauth.ErrNotConfigured) {
+			return domain.User{}, ErrLDAPNotConfigured
+		}
+		return domain.User{}, err
+	}
+
+	entry, err := directory.Authenticate(username, plainPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, ldapauth.ErrUserNotFound):
+			return a.local.Authenticate(systemID, username, plainPassword)
+		case errors.Is(err, ldapauth.ErrInvalidCredentials):
+			return domain.User{}, ErrInvalidUserCredentials
+		}
+		return domain.User{}, fmt.Errorf("Error al consultar el directorio: %w", err)
+	}
+
+	user, err := a.provision(directoryKey, entry)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if _, err := a.systemUserRepo.FindSystemUser(a.db, uint(systemID), user.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("El usuario %d del directorio no está asociado al sistema %d; un administrador debe asociarlo", user.ID, systemID)
+			return domain.User{}, ErrInvalidUserCredentials
+		}
+		return domain.User{}, err
+	}
+
+	// La contraseña la validó el directorio: no aplica el vencimiento local
+	user.AuthSource = domain.AuthSourceLDAP
+	return user, nil
+}
+
+// provision devuelve el usuario local vinculado a la entrada o lo crea. Si ya
+// hay un usuario con el mismo nombre o correo no se vincula automáticamente y
+// el ingreso se rechaza.
+func (a *LDAPAuthenticator) provision(directoryKey string, entry *ldapauth.Entry) (domain.User, error) {
+	externalID := ExternalID(directoryKey, entry.DN)
+	user, err := a.userRepo.GetByExternalID(externalID)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.User{}, err
+	}
+
+	username := strings.ToLower(strings.TrimSpace(entry.Username))
+	email := strings.TrimSpace(entry.Email)
+	if username == "" || utf8.RuneCountInString(username) > maxUsernameLength || email == "" || utf8.RuneCountInString(email) > maxEmailLength {
+		log.Printf("La entrada del directorio %q no tiene un usuario y un correo válidos; un administrador debe vincularla a un usuario", externalID)
+		return domain.User{}, ErrInvalidUserCredentials
+	}
+
+	existing, err := a.userRepo.GetByIdentity(username, email)
+	if err == nil {
+		log.Printf("La entrada del directorio %q coincide con el usuario local %d; un administrador debe vincularlos", externalID, existing.ID)
+		return domain.User{}, ErrInvalidUserCredentials
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.User{}, err
+	}
+
+	now := time.Now()
+	user = domain.User{
+		Username:   username,
+		Email:      email,
+		Activated:  true,
+		AuthSource: domain.AuthSourceLDAP,
+		ExternalID: &externalID,
+		Created:    now,
+		Updated:    now,
+	}
+	if err := a.userRepo.Create(&user); err != nil {
+		return domain.User{}, fmt.Errorf("Error al crear el usuario del directorio: %w", err)
+	}
+	log.Printf("Usuario %d creado desde la entrada del directorio %q", user.ID, externalID)
+	return user, nil
+}
+
+// systemDirectory devuelve el directorio propio del sistema o el global, con
+// su identificador
+func systemDirectory(settingsRepo *repositories.SystemLDAPSettingsRepository, global ldapauth.Config, systemID uint64) (ldapauth.Config, string, error) {
+	settings, err := settingsRepo.GetBySystem(uint(systemID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return global, GlobalDirectory, nil
+		}
+		return ldapauth.Config{}, "", err
+	}
+	return settingsConfig(settings, global.Timeout), SystemDirectory(systemID), nil
+}
+
+func settingsConfig(settings domain.SystemLDAPSettings, timeout time.Duration) ldapauth.Config {
+	return ldapauth.Config{
+		URL:                settings.URL,
+		StartTLS:           settings.StartTLS,
+		InsecureSkipVerify: settings.InsecureSkipVerify,
+		BindDN:             settings.BindDN,
+		BindPassword:       settings.BindPassword,
+		BaseDN:             settings.BaseDN,
+		UserFilter:         settings.UserFilter,
+		UsernameAttribute:  settings.UsernameAttribute,
+		EmailAttribute:     settings.EmailAttribute,
+		Timeout:            timeout,
+	}
+}
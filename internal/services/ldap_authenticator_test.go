@@ -0,0 +1,342 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/testutil"
+	"accessv2/pkg/ldapauth"
+	"accessv2/pkg/ldapauth/ldaptest"
+	"accessv2/pkg/password"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const testServiceDN = "cn=svc,dc=test"
+
+// person es una entrada de usuario del directorio de prueba
+func person(uid, mail, plainPassword string) ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:         "uid=" + uid + ",ou=people,dc=test",
+		Password:   plainPassword,
+		Attributes: map[string][]string{"uid": {uid}, "mail": {mail}},
+	}
+}
+
+// ldapFixture es el sistema Uno con el directorio global, el sistema Dos con
+// uno propio y el sistema Tres con contraseñas locales. Los directorios son
+// servidores en proceso con la cuenta de servicio testServiceDN.
+type ldapFixture struct {
+	db            *gorm.DB
+	authenticator *LDAPAuthenticator
+	ldapService   *SystemLDAPService
+	hasher        *password.Hasher
+}
+
+func newLDAPFixture(t *testing.T, global, own []ldaptest.Entry) *ldapFixture {
+	t.Helper()
+	serviceAccount := ldaptest.Entry{DN: testServiceDN, Password: "svc"}
+	globalServer := ldaptest.NewServer(append(global, serviceAccount)...)
+	t.Cleanup(globalServer.Close)
+	ownServer := ldaptest.NewServer(append(own, serviceAccount)...)
+	t.Cleanup(ownServer.Close)
+
+	db := testutil.NewDB(t)
+	now := time.Now()
+	systems := []domain.System{
+		{Name: "Uno", AuthBackend: AuthBackendLDAP, Created: now, Updated: now},
+		{Name: "Dos", AuthBackend: AuthBackendLDAP, Created: now, Updated: now},
+		{Name: "Tres", AuthBackend: AuthBackendLocal, Created: now, Updated: now},
+	}
+	if err := db.Create(&systems).Error; err != nil {
+		t.Fatal(err)
+	}
+	settings := domain.SystemLDAPSettings{
+		SystemID:          systems[1].ID,
+		URL:               ownServer.URL,
+		BindDN:            testServiceDN,
+		BindPassword:      "svc",
+		BaseDN:            "dc=test",
+		UserFilter:        "(uid=%s)",
+		UsernameAttribute: "uid",
+		EmailAttribute:    "mail",
+		Created:           now,
+		Updated:           now,
+	}
+	if err := db.Create(&settings).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	hasher, err := password.NewHasher(password.Config{Algorithm: password.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	globalConfig := ldapauth.Config{URL: globalServer.URL, BindDN: testServiceDN, BindPassword: "svc", BaseDN: "dc=test", Timeout: time.Second}
+	userRepo := repositories.NewUserRepository(db)
+	settingsRepo := repositories.NewSystemLDAPSettingsRepository(db)
+	return &ldapFixture{
+		db:            db,
+		authenticator: NewLDAPAuthenticator(globalConfig, nil, db, settingsRepo, userRepo, repositories.NewSystemUserRepository(db), NewLocalAuthenticator(userRepo, hasher)),
+		ldapService:   NewSystemLDAPService(AuthBackendLDAP, globalConfig, nil, repositories.NewSystemRepository(db), settingsRepo, userRepo),
+		hasher:        hasher,
+	}
+}
+
+// addLocalUser crea un usuario con contraseña local con acceso a los sistemas
+// Uno y Tres
+func (f *ldapFixture) addLocalUser(t *testing.T, username, email, plainPassword string) domain.User {
+	t.Helper()
+	hash, err := f.hasher.Hash(plainPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	user := domain.User{Username: username, Password: hash, Email: email, Activated: true, Created: now, Updated: now}
+	if err := repositories.NewUserRepository(f.db).Create(&user); err != nil {
+		t.Fatal(err)
+	}
+	f.associate(t, 1, user.ID)
+	f.associate(t, 3, user.ID)
+	return user
+}
+
+func (f *ldapFixture) associate(t *testing.T, systemID, userID uint) {
+	t.Helper()
+	if err := f.db.Create(&domain.SystemUser{SystemID: systemID, UserID: userID, Created: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *ldapFixture) hasAccess(t *testing.T, userID uint) bool {
+	t.Helper()
+	var count int64
+	if err := f.db.Model(&domain.SystemUser{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count > 0
+}
+
+// user devuelve el usuario que cumple la condición o nil
+func (f *ldapFixture) user(t *testing.T, where string, args ...interface{}) *domain.User {
+	t.Helper()
+	var user domain.User
+	err := f.db.Where(where, args...).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+func (f *ldapFixture) countUsers(t *testing.T) int64 {
+	t.Helper()
+	var count int64
+	if err := f.db.Model(&domain.User{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestLDAPAuthenticatorProvisioning(t *testing.T) {
+	f := newLDAPFixture(t, []ldaptest.Entry{person("JDoe", "jdoe@example.com", "dir-pass")}, nil)
+
+	// Primer ingreso: se crea el usuario, pero sin acceso al sistema
+	if _, err := f.authenticator.Authenticate(1, "jdoe", "dir-pass"); !errors.Is(err, ErrInvalidUserCredentials) {
+		t.Fatalf("primer ingreso sin asociar: err = %v, se esperaba ErrInvalidUserCredentials", err)
+	}
+	created := f.user(t, "external_id = ?", "global:uid=JDoe,ou=people,dc=test")
+	if created == nil {
+		t.Fatal("no se creó el usuario del directorio")
+	}
+	if created.Username != "jdoe" || created.AuthSource != domain.AuthSourceLDAP || created.Password != "" {
+		t.Fatalf("el usuario creado debe ser del directorio y sin contraseña local: %+v", created)
+	}
+	if f.hasAccess(t, created.ID) {
+		t.Fatal("el ingreso no debe asociar al usuario al sistema")
+	}
+
+	// Asociado por un administrador, el mismo DN vuelve al mismo usuario
+	f.associate(t, 1, created.ID)
+	user, err := f.authenticator.Authenticate(1, "jdoe", "dir-pass")
+	if err != nil {
+		t.Fatalf("ingreso asociado: %v", err)
+	}
+	if user.ID != created.ID || !user.IsExternal() {
+		t.Fatalf("ingreso asociado devolvió %+v, se esperaba el usuario %d externo", user, created.ID)
+	}
+
+	if _, err := f.authenticator.Authenticate(1, "jdoe", "wrong"); !errors.Is(err, ErrInvalidUserCredentials) {
+		t.Fatalf("contraseña del directorio incorrecta: err = %v", err)
+	}
+}
+
+func TestLDAPAuthenticatorDoesNotCaptureExistingUsers(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		email    string
+		login    string
+	}{
+		{name: "mismo usuario", username: "admin", email: "admin@example.com", login: "admin"},
+		{name: "mismo usuario con otras mayúsculas", username: "Admin", email: "admin@example.com", login: "admin"},
+		{name: "mismo correo", username: "maria", email: "MARIA@example.com", login: "mlopez"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newLDAPFixture(t, []ldaptest.Entry{
+				person("admin", "other@example.com", "dir-pass"),
+				person("mlopez", "maria@example.com", "dir-pass"),
+			}, nil)
+			local := f.addLocalUser(t, tt.username, tt.email, "local-pass")
+			before := f.countUsers(t)
+
+			if _, err := f.authenticator.Authenticate(1, tt.login, "dir-pass"); !errors.Is(err, ErrInvalidUserCredentials) {
+				t.Fatalf("err = %v, se esperaba ErrInvalidUserCredentials", err)
+			}
+			if stored := f.user(t, "id = ?", local.ID); stored.ExternalID != nil || stored.AuthSource != domain.AuthSourceLocal {
+				t.Fatalf("el usuario local no debe vincularse solo: %+v", stored)
+			}
+			if after := f.countUsers(t); after != before {
+				t.Fatalf("no debe crearse otro usuario: había %d, hay %d", before, after)
+			}
+
+			// Su contraseña local sigue valiendo en los sistemas locales
+			if _, err := f.authenticator.local.Authenticate(3, tt.username, "local-pass"); err != nil {
+				t.Fatalf("ingreso local: %v", err)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticatorNamespacesDirectories(t *testing.T) {
+	f := newLDAPFixture(t,
+		[]ldaptest.Entry{person("jdoe", "jdoe@example.com", "dir-pass")},
+		[]ldaptest.Entry{person("jdoe", "jdoe2@example.com", "own-pass")},
+	)
+
+	if _, err := f.authenticator.Authenticate(1, "jdoe", "dir-pass"); !errors.Is(err, ErrInvalidUserCredentials) {
+		t.Fatalf("primer ingreso: %v", err)
+	}
+	globalUser := f.user(t, "external_id = ?", "global:uid=jdoe,ou=people,dc=test")
+	if globalUser == nil {
+		t.Fatal("no se creó el usuario del directorio global")
+	}
+	f.associate(t, 2, globalUser.ID)
+
+	// El mismo DN en el directorio del sistema Dos no es el usuario del global;
+	// como coincide en nombre, tampoco se crea ni se vincula
+	if _, err := f.authenticator.Authenticate(2, "jdoe", "own-pass"); !errors.Is(err, ErrInvalidUserCredentials) {
+		t.Fatalf("ingreso con el directorio del sistema: err = %v, se esperaba ErrInvalidUserCredentials", err)
+	}
+	if user := f.user(t, "external_id = ?", "system-2:uid=jdoe,ou=people,dc=test"); user != nil {
+		t.Fatalf("no debe vincularse la entrada del sistema Dos: %+v", user)
+	}
+}
+
+func TestLDAPAuthenticatorRejectsLongUsernames(t *testing.T) {
+	f := newLDAPFixture(t, []ldaptest.Entry{person("averyveryverylongusername", "long@example.com", "dir-pass")}, nil)
+	local := f.addLocalUser(t, "averyveryverylonguse", "someone@example.com", "local-pass")
+	before := f.countUsers(t)
+
+	if _, err := f.authenticator.Authenticate(1, "averyveryverylongusername", "dir-pass"); !errors.Is(err, ErrInvalidUserCredentials) {
+		t.Fatalf("err = %v, se esperaba ErrInvalidUserCredentials", err)
+	}
+	if after := f.countUsers(t); after != before {
+		t.Fatalf("no debe crearse un usuario truncado: había %d, hay %d", before, after)
+	}
+	if stored := f.user(t, "id = ?", local.ID); stored.ExternalID != nil {
+		t.Fatalf("el usuario con el nombre truncado no debe vincularse: %+v", stored)
+	}
+}
+
+func TestLDAPAuthenticatorFallsBackToLocal(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{name: "contraseña local", password: "local-pass"},
+		{name: "otra contraseña", password: "dir-pass", wantErr: ErrInvalidUserCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newLDAPFixture(t, []ldaptest.Entry{person("admin", "admin@example.com", "dir-pass")}, nil)
+			local := f.addLocalUser(t, "localonly", "localonly@example.com", "local-pass")
+
+			user, err := f.authenticator.Authenticate(1, "localonly", tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, se esperaba %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (user.ID != local.ID || user.IsExternal()) {
+				t.Fatalf("se esperaba el usuario local %d, se obtuvo %+v", local.ID, user)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticatorWithoutDirectory(t *testing.T) {
+	f := newLDAPFixture(t, nil, nil)
+	f.authenticator.global = ldapauth.Config{}
+
+	if _, err := f.authenticator.Authenticate(1, "jdoe", "dir-pass"); !errors.Is(err, ErrLDAPNotConfigured) {
+		t.Fatalf("err = %v, se esperaba ErrLDAPNotConfigured", err)
+	}
+}
+
+func TestSystemLDAPServiceLinkUser(t *testing.T) {
+	f := newLDAPFixture(t,
+		[]ldaptest.Entry{person("admin", "other@example.com", "dir-pass")},
+		[]ldaptest.Entry{person("jdoe", "jdoe@example.com", "own-pass")},
+	)
+	admin := f.addLocalUser(t, "admin", "admin@example.com", "local-pass")
+	other := f.addLocalUser(t, "otro", "otro@example.com", "local-pass")
+
+	if err := f.ldapService.LinkUser(admin.ID, GlobalDirectory, "admin"); err != nil {
+		t.Fatalf("vincular: %v", err)
+	}
+	linked := f.user(t, "id = ?", admin.ID)
+	if linked.ExternalID == nil || *linked.ExternalID != "global:uid=admin,ou=people,dc=test" || linked.AuthSource != domain.AuthSourceLocal {
+		t.Fatalf("vínculo inesperado: %+v", linked)
+	}
+
+	linkErrors := []struct {
+		name      string
+		directory string
+		login     string
+		wantErr   error
+	}{
+		{name: "entrada ya vinculada", directory: GlobalDirectory, login: "admin", wantErr: ErrLDAPEntryLinked},
+		{name: "entrada inexistente", directory: GlobalDirectory, login: "nadie", wantErr: ErrLDAPEntryNotFound},
+		{name: "sistema sin directorio", directory: SystemDirectory(3), login: "jdoe", wantErr: ErrLDAPDirectoryNotFound},
+		{name: "directorio desconocido", directory: "otro", login: "jdoe", wantErr: ErrLDAPDirectoryNotFound},
+	}
+	for _, tt := range linkErrors {
+		if err := f.ldapService.LinkUser(other.ID, tt.directory, tt.login); !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: err = %v, se esperaba %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// Vinculado, ingresa con el directorio en los sistemas LDAP y con su
+	// contraseña local en los demás
+	user, err := f.authenticator.Authenticate(1, "admin", "dir-pass")
+	if err != nil || user.ID != admin.ID {
+		t.Fatalf("ingreso con el directorio: user = %+v, err = %v", user, err)
+	}
+	if _, err := f.authenticator.local.Authenticate(3, "admin", "local-pass"); err != nil {
+		t.Fatalf("ingreso local del usuario vinculado: %v", err)
+	}
+
+	if err := f.ldapService.UnlinkUser(admin.ID); err != nil {
+		t.Fatalf("desvincular: %v", err)
+	}
+	if unlinked := f.user(t, "id = ?", admin.ID); unlinked.ExternalID != nil {
+		t.Fatalf("el vínculo debe eliminarse: %+v", unlinked)
+	}
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/responses"
+	"accessv2/pkg/totp"
+	"accessv2/pkg/utils"
+	"crypto/rand"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidMFAChallenge = errors.New("El desafío de verificación no es válido o expiró")
+	ErrInvalidMFACode      = errors.New("Código de verificación incorrecto")
+	ErrMFAAlreadyEnabled   = errors.New("El usuario ya tiene la verificación en dos pasos activa")
+	ErrMFANotPending       = errors.New("No hay una inscripción de verificación en dos pasos pendiente")
+)
+
+// Alfabeto de los códigos de recuperación, sin caracteres ambiguos
+const recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// MFAConfig agrupa la configuración de la verificación en dos pasos
+type MFAConfig struct {
+	Issuer        string        // nombre que muestran las aplicaciones autenticadoras
+	ChallengeTTL  time.Duration // vigencia del desafío entre la contraseña y el código
+	MaxAttempts   int           // códigos incorrectos permitidos por desafío
+	RecoveryCodes int           // cantidad de códigos de recuperación generados
+}
+
+// MFAService administra el segundo factor TOTP de los usuarios y los desafíos
+// que se emiten al iniciar sesión en sistemas que lo exigen. Los códigos
+// incorrectos cuentan como intentos fallidos del usuario en el sistema, igual
+// que las contraseñas, así que abrir desafíos nuevos no da más intentos.
+type MFAService struct {
+	cfg          MFAConfig
+	repo         *repositories.MFARepository
+	userRepo     *repositories.UserRepository
+	systemRepo   *repositories.SystemRepository
+	tokenService *TokenService
+	throttle     *SignInThrottleService
+}
+
+func NewMFAService(cfg MFAConfig, repo *repositories.MFARepository, userRepo *repositories.UserRepository, systemRepo *repositories.SystemRepository, tokenService *TokenService, throttle *SignInThrottleService) *MFAService {
+	return &MFAService{
+		cfg:          cfg,
+		repo:         repo,
+		userRepo:     userRepo,
+		systemRepo:   systemRepo,
+		tokenService: tokenService,
+		throttle:     throttle,
+	}
+}
+
+// GetUserMFA devuelve la inscripción del usuario (nil si no tiene) y los
+// códigos de recuperación que le quedan
+func (s *MFAService) GetUserMFA(userID uint) (*domain.UserMFA, int64, error) {
+	mfa, err := s.repo.GetByUser(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	remaining, err := s.repo.CountRecoveryCodes(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &mfa, remaining, nil
+}
+
+// BeginSignIn decide si el ingreso al sistema requiere segundo factor. Devuelve
+// nil si se pueden emitir los tokens directamente; si no, un desafío que se
+// completa en /api/v1/users/sign-in/mfa. Los intentos fallidos del usuario se
+// reinician solo cuando el ingreso queda completo.
+func (s *MFAService) BeginSignIn(user domain.User, systemID uint64) (*responses.MFAChallenge, error) {
+	mfa, _, err := s.GetUserMFA(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa != nil && mfa.ConfirmedAt != nil {
+		return s.newChallenge(user.ID, systemID, false)
+	}
+
+	system, err := s.systemRepo.GetByID(systemID)
+	if err != nil {
+		return nil, err
+	}
+	if system.MFARequired {
+		return s.newChallenge(user.ID, systemID, true)
+	}
+
+	s.recordSuccess(systemID, user)
+	return nil, nil
+}
+
+// StartEnrollment genera (o reutiliza, si ya estaba pendiente) el secreto TOTP
+// del usuario. La inscripción queda pendiente hasta confirmar un código.
+func (s *MFAService) StartEnrollment(userID uint) (responses.MFAEnrollment, error) {
+	user, err := s.userRepo.GetByID(uint64(userID))
+	if err != nil {
+		return responses.MFAEnrollment{}, err
+	}
+
+	mfa, _, err := s.GetUserMFA(userID)
+	if err != nil {
+		return responses.MFAEnrollment{}, err
+	}
+	if mfa != nil && mfa.ConfirmedAt != nil {
+		return responses.MFAEnrollment{}, ErrMFAAlreadyEnabled
+	}
+
+	if mfa == nil {
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return responses.MFAEnrollment{}, err
+		}
+		mfa = &domain.UserMFA{UserID: userID, Secret: secret, Created: time.Now()}
+		if err := s.repo.SavePending(mfa); err != nil {
+			return responses.MFAEnrollment{}, err
+		}
+	}
+
+	return responses.MFAEnrollment{
+		Secret:     mfa.Secret,
+		OTPAuthURI: totp.URI(s.cfg.Issuer, user.Username, mfa.Secret),
+	}, nil
+}
+
+// StartChallengeEnrollment inicia la inscripción con un desafío emitido porque
+// el sistema exige segundo factor y el usuario aún no lo tiene
+func (s *MFAService) StartChallengeEnrollment(challengeToken string, systemID uint64) (responses.MFAEnrollment, error) {
+	challenge, err := s.validChallenge(challengeToken, systemID)
+	if err != nil {
+		return responses.MFAEnrollment{}, err
+	}
+	if !challenge.Enrollment {
+		return responses.MFAEnrollment{}, ErrMFAAlreadyEnabled
+	}
+	return s.StartEnrollment(challenge.UserID)
+}
+
+// ConfirmEnrollment activa la inscripción pendiente con un código válido y
+// devuelve los códigos de recuperación, que solo se muestran esta vez
+func (s *MFAService) ConfirmEnrollment(userID uint, code string) ([]string, error) {
+	mfa, _, err := s.GetUserMFA(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, ErrMFANotPending
+	}
+	if mfa.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(mfa.Secret, code, time.Now(), 1)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Confirm(userID, step, hashes, time.Now()); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyChallenge completa el desafío con un código TOTP o de recuperación.
+// En los desafíos de inscripción el código confirma el secreto y se devuelven
+// los códigos de recuperación generados. Con systemID distinto de 0 el desafío
+// debe pertenecer a ese sistema. Si el usuario está en espera o bloqueado por
+// intentos fallidos devuelve *SignInBlockedError sin validar el código.
+func (s *MFAService) VerifyChallenge(challengeToken string, systemID uint64, code, recoveryCode string) (domain.User, uint64, []string, error) {
+	challenge, err := s.validChallenge(challengeToken, systemID)
+	if err != nil {
+		return domain.User{}, 0, nil, err
+	}
+
+	user, err := s.userRepo.GetBySystemAndID(uint64(challenge.SystemID), challenge.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.User{}, 0, nil, ErrInvalidMFAChallenge
+		}
+		return domain.User{}, 0, nil, err
+	}
+	if err := s.throttle.Check(uint64(challenge.SystemID), user.Username, ""); err != nil {
+		return domain.User{}, 0, nil, err
+	}
+
+	if err := s.repo.IncrementChallengeAttempts(challenge.ID); err != nil {
+		return domain.User{}, 0, nil, err
+	}
+
+	var recoveryCodes []string
+	if challenge.Enrollment {
+		recoveryCodes, err = s.ConfirmEnrollment(challenge.UserID, code)
+	} else {
+		err = s.verifyFactor(challenge.UserID, code, recoveryCode)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.throttle.RecordFailure(uint64(challenge.SystemID), user.Username, ""); err != nil {
+				log.Printf("No se pudo registrar el código incorrecto del usuario %d: %v", user.ID, err)
+			}
+		}
+		return domain.User{}, 0, nil, err
+	}
+
+	used, err := s.repo.MarkChallengeUsed(challenge.ID, time.Now())
+	if err != nil {
+		return domain.User{}, 0, nil, err
+	}
+	if !used {
+		return domain.User{}, 0, nil, ErrInvalidMFAChallenge
+	}
+
+	if !user.Activated {
+		return domain.User{}, 0, nil, ErrUserNotActive
+	}
+
+	s.recordSuccess(uint64(challenge.SystemID), user)
+	return user, uint64(challenge.SystemID), recoveryCodes, nil
+}
+
+// CompleteSignIn verifica el desafío y emite los tokens del sistema
+func (s *MFAService) CompleteSignIn(challengeToken string, systemID uint64, code, recoveryCode string) (responses.UserWithAccess, []string, error) {
+	user, systemID, recoveryCodes, err := s.VerifyChallenge(challengeToken, systemID, code, recoveryCode)
+	if err != nil {
+		return responses.UserWithAccess{}, nil, err
+	}
+
+	access, err := s.userRepo.GetUserNestedPermissionsBySystem(user.ID, systemID)
+	if err != nil {
+		return responses.UserWithAccess{}, nil, err
+	}
+	tokens, err := s.tokenService.IssueTokens(user, systemID, access.Roles, "")
+	if err != nil {
+		return responses.UserWithAccess{}, nil, err
+	}
+	return tokens, recoveryCodes, nil
+}
+
+// Reset elimina el segundo factor del usuario; en los sistemas que lo exigen
+// deberá inscribirse de nuevo en su próximo ingreso
+func (s *MFAService) Reset(userID uint) error {
+	return s.repo.Delete(userID)
+}
+
+// recordSuccess reinicia los intentos fallidos del usuario en el sistema
+func (s *MFAService) recordSuccess(systemID uint64, user domain.User) {
+	if err := s.throttle.RecordSuccess(systemID, user.Username); err != nil {
+		log.Printf("No se pudo reiniciar los intentos fallidos de %q: %v", user.Username, err)
+	}
+}
+
+func (s *MFAService) verifyFactor(userID uint, code, recoveryCode string) error {
+	mfa, _, err := s.GetUserMFA(userID)
+	if err != nil {
+		return err
+	}
+	if mfa == nil || mfa.ConfirmedAt == nil {
+		return ErrInvalidMFAChallenge
+	}
+
+	if recoveryCode != "" {
+		used, err := s.repo.UseRecoveryCode(userID, utils.HashToken(normalizeRecoveryCode(recoveryCode)), time.Now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	step, ok := totp.Validate(mfa.Secret, code, time.Now(), 1)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	// Un mismo código no se acepta dos veces
+	fresh, err := s.repo.UseStep(userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *MFAService) newChallenge(userID uint, systemID uint64, enrollment bool) (*responses.MFAChallenge, error) {
+	token, err := utils.SecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.repo.CreateChallenge(&domain.MFAChallenge{
+		TokenHash:  utils.HashToken(token),
+		UserID:     userID,
+		SystemID:   uint(systemID),
+		Enrollment: enrollment,
+		ExpiresAt:  now.Add(s.cfg.ChallengeTTL),
+		Created:    now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &responses.MFAChallenge{
+		ChallengeToken:     token,
+		ExpiresIn:          int64(s.cfg.ChallengeTTL.Seconds()),
+		EnrollmentRequired: enrollment,
+	}, nil
+}
+
+func (s *MFAService) validChallenge(challengeToken string, systemID uint64) (domain.MFAChallenge, error) {
+	if challengeToken == "" {
+		return domain.MFAChallenge{}, ErrInvalidMFAChallenge
+	}
+
+	challenge, err := s.repo.GetChallengeByHash(utils.HashToken(challengeToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.MFAChallenge{}, ErrInvalidMFAChallenge
+		}
+		return domain.MFAChallenge{}, err
+	}
+
+	if challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= s.cfg.MaxAttempts {
+		return domain.MFAChallenge{}, ErrInvalidMFAChallenge
+	}
+	if systemID != 0 && uint64(challenge.SystemID) != systemID {
+		return domain.MFAChallenge{}, ErrInvalidMFAChallenge
+	}
+	return challenge, nil
+}
+
+// generateRecoveryCodes devuelve los códigos en texto plano y sus hashes
+func (s *MFAService) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, s.cfg.RecoveryCodes)
+	hashes := make([]string, 0, s.cfg.RecoveryCodes)
+
+	for i := 0; i < s.cfg.RecoveryCodes; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		for j := range b {
+			b[j] = recoveryCodeAlphabet[int(b[j])%len(recoveryCodeAlphabet)]
+		}
+		code := string(b[:5]) + "-" + string(b[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashToken(normalizeRecoveryCode(code)))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
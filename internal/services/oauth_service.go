@@ -0,0 +1,517 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/forms"
+	"accessv2/internal/repositories"
+	"accessv2/internal/responses"
+	"accessv2/pkg/jwks"
+	"accessv2/pkg/utils"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// Códigos de error de OAuth 2.0 (RFC 6749 4.1.2.1 y 5.2)
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthAccessDenied            = "access_denied"
+	OAuthInvalidToken            = "invalid_token"
+	OAuthInvalidTarget           = "invalid_target" // RFC 8707
+)
+
+const pkceMethodS256 = "S256"
+
+// ErrOIDCDisabled indica que OpenID Connect está deshabilitado porque los
+// tokens se firman con HS256: un ID token firmado con JWT_KEY no se puede
+// validar con el JWKS, que queda vacío
+var ErrOIDCDisabled = errors.New("OpenID Connect requiere un JWT_ALGORITHM asimétrico (RS256, ES256 o EdDSA)")
+
+// OAuthError es un error que se devuelve al cliente con el formato de OAuth 2.0
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// OAuthConfig agrupa la configuración del proveedor OpenID Connect
+type OAuthConfig struct {
+	Issuer     string        // URL pública del servicio, sin "/" final
+	CodeTTL    time.Duration // vigencia de los códigos de autorización
+	IDTokenTTL time.Duration
+}
+
+// OAuthService implementa el flujo authorization code + PKCE de OpenID Connect.
+// Cada sistema es un cliente cuyo client_id es el ID del sistema.
+type OAuthService struct {
+	cfg           OAuthConfig
+	repo          *repositories.OAuthRepository
+	systemRepo    *repositories.SystemRepository
+	userRepo      *repositories.UserRepository
+	userService   *UserService
+	clientService *SystemClientService
+	tokenService  *TokenService
+	keyService    *KeyService
+	mfaService    *MFAService
+}
+
+func NewOAuthService(cfg OAuthConfig, repo *repositories.OAuthRepository, systemRepo *repositories.SystemRepository, userRepo *repositories.UserRepository, userService *UserService, clientService *SystemClientService, tokenService *TokenService, keyService *KeyService, mfaService *MFAService) *OAuthService {
+	return &OAuthService{
+		cfg:           cfg,
+		repo:          repo,
+		systemRepo:    systemRepo,
+		userRepo:      userRepo,
+		userService:   userService,
+		clientService: clientService,
+		tokenService:  tokenService,
+		keyService:    keyService,
+		mfaService:    mfaService,
+	}
+}
+
+// OIDCEnabled indica si el proveedor OpenID Connect está habilitado. El canje
+// client_credentials no emite ID tokens y funciona en cualquier caso.
+func (s *OAuthService) OIDCEnabled() bool {
+	return jwks.IsAsymmetric(s.keyService.Algorithm())
+}
+
+// Discovery arma el documento /.well-known/openid-configuration
+func (s *OAuthService) Discovery() (responses.OpenIDConfiguration, error) {
+	if !s.OIDCEnabled() {
+		return responses.OpenIDConfiguration{}, ErrOIDCDisabled
+	}
+	return responses.OpenIDConfiguration{
+		Issuer:                            s.cfg.Issuer,
+		AuthorizationEndpoint:             s.cfg.Issuer + "/oauth/authorize",
+		TokenEndpoint:                     s.cfg.Issuer + "/api/v1/oauth/token",
+		UserinfoEndpoint:                  s.cfg.Issuer + "/api/v1/oauth/userinfo",
+		JwksURI:                           s.cfg.Issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   []string{"openid", "profile", "email"},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{s.keyService.Algorithm()},
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{pkceMethodS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username", "email", "system_id", "roles"},
+	}, nil
+}
+
+// ValidateClient verifica el client_id y la redirect_uri. Si fallan no se debe
+// redirigir al cliente, sino mostrar el error al usuario.
+func (s *OAuthService) ValidateClient(clientID, redirectURI string) (domain.System, error) {
+	if !s.OIDCEnabled() {
+		return domain.System{}, newOAuthError(OAuthInvalidRequest, ErrOIDCDisabled.Error())
+	}
+
+	systemID, err := strconv.ParseUint(clientID, 10, 64)
+	if err != nil {
+		return domain.System{}, newOAuthError(OAuthInvalidClient, "client_id inválido")
+	}
+
+	system, err := s.systemRepo.GetByID(systemID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.System{}, newOAuthError(OAuthInvalidClient, "Cliente no registrado")
+		}
+		return domain.System{}, err
+	}
+
+	registered, err := s.repo.HasRedirectURI(systemID, redirectURI)
+	if err != nil {
+		return domain.System{}, err
+	}
+	if !registered {
+		return domain.System{}, newOAuthError(OAuthInvalidRequest, "redirect_uri no registrada para el cliente")
+	}
+
+	return system, nil
+}
+
+// ValidateAuthorizationRequest valida el resto de parámetros de la petición.
+// Estos errores sí se informan al cliente a través de la redirect_uri.
+func (s *OAuthService) ValidateAuthorizationRequest(req forms.AuthorizeRequest) error {
+	if req.ResponseType != "code" {
+		return newOAuthError(OAuthUnsupportedResponseType, "Solo se soporta response_type=code")
+	}
+	if !hasScope(req.Scope, "openid") {
+		return newOAuthError(OAuthInvalidScope, "El scope debe incluir openid")
+	}
+	if req.CodeChallenge == "" {
+		return newOAuthError(OAuthInvalidRequest, "code_challenge es requerido (PKCE)")
+	}
+	if req.CodeChallengeMethod != pkceMethodS256 {
+		return newOAuthError(OAuthInvalidRequest, "code_challenge_method debe ser S256")
+	}
+	return nil
+}
+
+// Authorize autentica al usuario final y devuelve la URL de retorno con el
+// código de autorización. Si el ingreso requiere segundo factor se devuelve el
+// desafío y el código se emite en AuthorizeMFA.
+func (s *OAuthService) Authorize(system domain.System, req forms.AuthorizeRequest, username, plainPassword, clientIP string) (string, *responses.MFAChallenge, error) {
+	user, err := s.userService.AuthenticateBySystem(uint64(system.ID), username, plainPassword, clientIP)
+	if err != nil {
+		return "", nil, err
+	}
+
+	challenge, err := s.mfaService.BeginSignIn(user, uint64(system.ID))
+	if err != nil {
+		return "", nil, err
+	}
+	if challenge != nil {
+		return "", challenge, nil
+	}
+
+	redirectURL, err := s.issueAuthorizationCode(system, req, user)
+	return redirectURL, nil, err
+}
+
+// StartMFAEnrollment entrega el secreto TOTP cuando el sistema exige segundo
+// factor y el usuario aún no lo tiene
+func (s *OAuthService) StartMFAEnrollment(system domain.System, challengeToken string) (responses.MFAEnrollment, error) {
+	return s.mfaService.StartChallengeEnrollment(challengeToken, uint64(system.ID))
+}
+
+// AuthorizeMFA completa el desafío de segundo factor y devuelve la URL de
+// retorno con el código de autorización. En las inscripciones se devuelven
+// además los códigos de recuperación generados.
+func (s *OAuthService) AuthorizeMFA(system domain.System, req forms.AuthorizeRequest, challengeToken, code, recoveryCode string) (string, []string, error) {
+	user, _, recoveryCodes, err := s.mfaService.VerifyChallenge(challengeToken, uint64(system.ID), code, recoveryCode)
+	if err != nil {
+		return "", nil, err
+	}
+
+	redirectURL, err := s.issueAuthorizationCode(system, req, user)
+	if err != nil {
+		return "", nil, err
+	}
+	return redirectURL, recoveryCodes, nil
+}
+
+func (s *OAuthService) issueAuthorizationCode(system domain.System, req forms.AuthorizeRequest, user domain.User) (string, error) {
+	code, err := utils.SecureToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := s.repo.CreateAuthorizationCode(&domain.AuthorizationCode{
+		CodeHash:            utils.HashToken(code),
+		SystemID:            system.ID,
+		UserID:              user.ID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		AuthTime:            now,
+		ExpiresAt:           now.Add(s.cfg.CodeTTL),
+		Created:             now,
+	}); err != nil {
+		return "", err
+	}
+
+	return RedirectWithParams(req.RedirectURI, url.Values{"code": {code}, "state": {req.State}}), nil
+}
+
+// Exchange atiende el endpoint de token según el grant_type solicitado
+func (s *OAuthService) Exchange(req forms.OAuthTokenRequest) (responses.OAuthTokenResponse, error) {
+	if (req.GrantType == "authorization_code" || req.GrantType == "refresh_token") && !s.OIDCEnabled() {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthUnsupportedGrantType, ErrOIDCDisabled.Error())
+	}
+
+	switch req.GrantType {
+	case "authorization_code":
+		return s.exchangeAuthorizationCode(req)
+	case "refresh_token":
+		return s.exchangeRefreshToken(req)
+	case "client_credentials":
+		return s.exchangeClientCredentials(req)
+	}
+	return responses.OAuthTokenResponse{}, newOAuthError(OAuthUnsupportedGrantType, "grant_type no soportado")
+}
+
+func (s *OAuthService) exchangeAuthorizationCode(req forms.OAuthTokenRequest) (responses.OAuthTokenResponse, error) {
+	if req.Code == "" || req.CodeVerifier == "" || req.ClientID == "" {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidRequest, "code, code_verifier y client_id son requeridos")
+	}
+
+	code, err := s.repo.GetAuthorizationCode(utils.HashToken(req.Code))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidGrant, "Código de autorización inválido")
+		}
+		return responses.OAuthTokenResponse{}, err
+	}
+
+	now := time.Now()
+	if code.UsedAt != nil || now.After(code.ExpiresAt) {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidGrant, "Código de autorización expirado o ya utilizado")
+	}
+	if req.ClientID != strconv.FormatUint(uint64(code.SystemID), 10) {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidGrant, "El código no pertenece al cliente")
+	}
+	if req.RedirectURI != code.RedirectURI {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidGrant, "redirect_uri no coincide")
+	}
+	if !verifyPKCE(code.CodeChallenge, req.CodeVerifier) {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidGrant, "code_verifier inválido")
+	}
+
+	used, err := s.repo.MarkAuthorizationCodeUsed(code.ID, now)
+	if err != nil {
+		return responses.OAuthTokenResponse{}, err
+	}
+	if !used {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidGrant, "Código de autorización ya utilizado")
+	}
+
+	systemID := uint64(code.SystemID)
+	user, err := s.userRepo.GetBySystemAndID(systemID, code.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidGrant, "El usuario ya no tiene acceso al sistema")
+		}
+		return responses.OAuthTokenResponse{}, err
+	}
+	if !user.Activated {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidGrant, ErrUserNotActive.Error())
+	}
+
+	access, err := s.userRepo.GetUserNestedPermissionsBySystem(user.ID, systemID)
+	if err != nil {
+		return responses.OAuthTokenResponse{}, err
+	}
+
+	tokens, err := s.tokenService.IssueTokens(user, systemID, access.Roles, "")
+	if err != nil {
+		return responses.OAuthTokenResponse{}, err
+	}
+
+	idToken, err := s.issueIDToken(user, systemID, access.Roles, code.Nonce, code.AuthTime)
+	if err != nil {
+		return responses.OAuthTokenResponse{}, err
+	}
+
+	return responses.OAuthTokenResponse{
+		AccessToken:  tokens.Token,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      idToken,
+		Scope:        code.Scope,
+	}, nil
+}
+
+func (s *OAuthService) exchangeRefreshToken(req forms.OAuthTokenRequest) (responses.OAuthTokenResponse, error) {
+	if req.RefreshToken == "" || req.ClientID == "" {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidRequest, "refresh_token y client_id son requeridos")
+	}
+
+	// El refresh token debe haber sido emitido para el mismo cliente
+	status, err := s.tokenService.Introspect(req.RefreshToken, "refresh_token")
+	if err != nil {
+		return responses.OAuthTokenResponse{}, err
+	}
+	if !status.Active || status.TokenType != "refresh_token" {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidGrant, ErrInvalidRefreshToken.Error())
+	}
+	if req.ClientID != strconv.FormatUint(status.SystemID, 10) {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidGrant, "El refresh token no pertenece al cliente")
+	}
+
+	tokens, err := s.tokenService.Refresh(status.SystemID, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) || errors.Is(err, ErrUserNotActive) {
+			return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidGrant, err.Error())
+		}
+		return responses.OAuthTokenResponse{}, err
+	}
+
+	user := domain.User{ID: tokens.User.ID, Username: tokens.User.Username, Email: tokens.User.Email}
+	idToken, err := s.issueIDToken(user, status.SystemID, tokens.Roles, "", time.Time{})
+	if err != nil {
+		return responses.OAuthTokenResponse{}, err
+	}
+
+	return responses.OAuthTokenResponse{
+		AccessToken:  tokens.Token,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      idToken,
+	}, nil
+}
+
+func (s *OAuthService) exchangeClientCredentials(req forms.OAuthTokenRequest) (responses.OAuthTokenResponse, error) {
+	client, err := s.clientService.Authenticate(req.ClientID, req.ClientSecret)
+	if err != nil {
+		if errors.Is(err, ErrInvalidClientCredentials) {
+			return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidClient, err.Error())
+		}
+		return responses.OAuthTokenResponse{}, err
+	}
+
+	return s.clientService.IssueToken(client, req.Audience)
+}
+
+// UserInfo devuelve los datos del usuario dueño de un access token vigente
+func (s *OAuthService) UserInfo(accessToken string) (responses.UserInfoResponse, error) {
+	if !s.OIDCEnabled() {
+		return responses.UserInfoResponse{}, newOAuthError(OAuthInvalidRequest, ErrOIDCDisabled.Error())
+	}
+
+	status, err := s.tokenService.Introspect(accessToken, "access_token")
+	if err != nil {
+		return responses.UserInfoResponse{}, err
+	}
+	if !status.Active || status.TokenType != "access_token" {
+		return responses.UserInfoResponse{}, newOAuthError(OAuthInvalidToken, ErrInvalidAccessToken.Error())
+	}
+
+	// Los tokens de client_credentials (sub "client:<id>") no representan a un
+	// usuario, así que no tienen userinfo
+	userID, err := strconv.ParseUint(status.Sub, 10, 64)
+	if err != nil || userID == 0 {
+		return responses.UserInfoResponse{}, newOAuthError(OAuthInvalidToken, "El token no pertenece a un usuario")
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.UserInfoResponse{}, newOAuthError(OAuthInvalidToken, ErrInvalidAccessToken.Error())
+		}
+		return responses.UserInfoResponse{}, err
+	}
+
+	roles := status.Roles
+	if roles == nil {
+		roles = []*responses.RoleAccess{}
+	}
+
+	return responses.UserInfoResponse{
+		Sub:               status.Sub,
+		PreferredUsername: user.Username,
+		Email:             user.Email,
+		SystemID:          status.SystemID,
+		Roles:             roles,
+	}, nil
+}
+
+func (s *OAuthService) issueIDToken(user domain.User, systemID uint64, roles []*responses.RoleAccess, nonce string, authTime time.Time) (string, error) {
+	now := time.Now()
+	claims := &responses.IDTokenClaims{
+		Nonce:             nonce,
+		PreferredUsername: user.Username,
+		Email:             user.Email,
+		SystemID:          systemID,
+		Roles:             roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.cfg.Issuer,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Audience:  jwt.ClaimStrings{strconv.FormatUint(systemID, 10)},
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.cfg.IDTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	if !authTime.IsZero() {
+		claims.AuthTime = authTime.Unix()
+	}
+	if claims.Roles == nil {
+		claims.Roles = []*responses.RoleAccess{}
+	}
+	return s.keyService.Sign(claims)
+}
+
+// GetRedirectURIs lista las URIs de retorno registradas para el sistema
+func (s *OAuthService) GetRedirectURIs(systemID uint64) ([]domain.SystemRedirectURI, error) {
+	return s.repo.GetRedirectURIs(systemID)
+}
+
+// AddRedirectURI registra una URI de retorno absoluta (http o https, sin fragmento)
+func (s *OAuthService) AddRedirectURI(systemID uint64, rawURI string) error {
+	rawURI = strings.TrimSpace(rawURI)
+	parsed, err := url.Parse(rawURI)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return errors.New("La URI debe ser absoluta y usar http o https")
+	}
+	if parsed.Fragment != "" {
+		return errors.New("La URI no puede incluir un fragmento (#)")
+	}
+
+	exists, err := s.repo.HasRedirectURI(systemID, rawURI)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("La URI ya está registrada")
+	}
+
+	return s.repo.CreateRedirectURI(&domain.SystemRedirectURI{
+		SystemID: uint(systemID),
+		URI:      rawURI,
+		Created:  time.Now(),
+	})
+}
+
+func (s *OAuthService) DeleteRedirectURI(systemID, id uint64) error {
+	return s.repo.DeleteRedirectURI(systemID, id)
+}
+
+// RedirectWithParams agrega parámetros a la query de una URI de retorno
+// conservando los que ya tuviera
+func RedirectWithParams(redirectURI string, params url.Values) string {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := parsed.Query()
+	for key, values := range params {
+		for _, value := range values {
+			if value != "" {
+				query.Add(key, value)
+			}
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// verifyPKCE compara BASE64URL(SHA256(code_verifier)) con el code_challenge (RFC 7636)
+func verifyPKCE(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func hasScope(scope, wanted string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == wanted {
+			return true
+		}
+	}
+	return false
+}
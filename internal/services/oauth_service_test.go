@@ -0,0 +1,217 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/forms"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/utils"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r7Wh8qXtJEGhzA"
+	testChallenge = "a2Oet1OcxPiakzS_LoPu_4r3tkFwynQeqfuzg_1tz7U"
+)
+
+const testRedirectURI = "https://app.example.com/callback"
+
+func TestVerifyPKCE(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		verifier  string
+		want      bool
+	}{
+		{name: "verificador correcto", challenge: testChallenge, verifier: testVerifier, want: true},
+		{name: "verificador distinto", challenge: testChallenge, verifier: testVerifier[:42] + "B"},
+		{name: "el challenge como verificador", challenge: testChallenge, verifier: testChallenge},
+		{name: "challenge en base64 con relleno", challenge: testChallenge + "=", verifier: testVerifier},
+		{name: "verificador corto", challenge: testChallenge, verifier: testVerifier[:42]},
+		{name: "verificador largo", challenge: testChallenge, verifier: strings.Repeat("a", 129)},
+		{name: "sin verificador", challenge: testChallenge},
+		{name: "sin challenge", verifier: testVerifier},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyPKCE(tt.challenge, tt.verifier); got != tt.want {
+				t.Fatalf("verifyPKCE = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOAuthServiceValidateAuthorizationRequestPKCE(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      forms.AuthorizeRequest
+		wantCode string
+	}{
+		{name: "S256", req: forms.AuthorizeRequest{ResponseType: "code", Scope: "openid", CodeChallenge: testChallenge, CodeChallengeMethod: "S256"}},
+		{name: "sin challenge", req: forms.AuthorizeRequest{ResponseType: "code", Scope: "openid", CodeChallengeMethod: "S256"}, wantCode: OAuthInvalidRequest},
+		{name: "método plain", req: forms.AuthorizeRequest{ResponseType: "code", Scope: "openid", CodeChallenge: testVerifier, CodeChallengeMethod: "plain"}, wantCode: OAuthInvalidRequest},
+		{name: "sin método", req: forms.AuthorizeRequest{ResponseType: "code", Scope: "openid", CodeChallenge: testChallenge}, wantCode: OAuthInvalidRequest},
+	}
+	s := &OAuthService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertOAuthError(t, s.ValidateAuthorizationRequest(tt.req), tt.wantCode)
+		})
+	}
+}
+
+// oauthFixture es el proveedor con el sistema Uno como cliente, sobre el mismo
+// usuario y servicio de tokens de tokenFixture. Firma con ES256, salvo que se
+// pida el HS256 por defecto.
+type oauthFixture struct {
+	*tokenFixture
+	oauth *OAuthService
+	repo  *repositories.OAuthRepository
+}
+
+func newOAuthFixture(t *testing.T) *oauthFixture {
+	t.Helper()
+	f := newHS256OAuthFixture(t)
+	f.switchToES256(t, false, 0)
+	f.oauth.keyService = f.service.keyService
+	return f
+}
+
+func newHS256OAuthFixture(t *testing.T) *oauthFixture {
+	t.Helper()
+	f := newTokenFixture(t)
+	repo := repositories.NewOAuthRepository(f.db)
+	oauth := NewOAuthService(
+		OAuthConfig{Issuer: "http://localhost", CodeTTL: time.Minute, IDTokenTTL: time.Hour},
+		repo, repositories.NewSystemRepository(f.db), repositories.NewUserRepository(f.db),
+		nil, nil, f.service, f.service.keyService, nil,
+	)
+	return &oauthFixture{tokenFixture: f, oauth: oauth, repo: repo}
+}
+
+// grantCode guarda el código que Authorize emite tras el ingreso del usuario
+func (f *oauthFixture) grantCode(t *testing.T, challenge string) string {
+	t.Helper()
+	now := time.Now()
+	err := f.repo.CreateAuthorizationCode(&domain.AuthorizationCode{
+		CodeHash:            utils.HashToken("the-code"),
+		SystemID:            1,
+		UserID:              f.user.ID,
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid",
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: pkceMethodS256,
+		AuthTime:            now,
+		ExpiresAt:           now.Add(time.Minute),
+		Created:             now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "the-code"
+}
+
+func (f *oauthFixture) exchange(code, verifier string) (string, error) {
+	got, err := f.oauth.Exchange(forms.OAuthTokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		ClientID:     "1",
+		CodeVerifier: verifier,
+	})
+	return got.IDToken, err
+}
+
+func TestOAuthServiceExchangeAuthorizationCodePKCE(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier string
+		wantCode string
+	}{
+		{name: "verificador correcto", verifier: testVerifier},
+		{name: "verificador distinto", verifier: strings.Repeat("x", 43), wantCode: OAuthInvalidGrant},
+		{name: "el challenge como verificador", verifier: testChallenge, wantCode: OAuthInvalidGrant},
+		{name: "sin verificador", wantCode: OAuthInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOAuthFixture(t)
+			code := f.grantCode(t, testChallenge)
+
+			idToken, err := f.exchange(code, tt.verifier)
+			assertOAuthError(t, err, tt.wantCode)
+			if tt.wantCode != "" {
+				// Un verificador incorrecto no consume el código
+				if _, err := f.exchange(code, testVerifier); err != nil {
+					t.Fatalf("canje con el verificador correcto después del fallo: %v", err)
+				}
+				return
+			}
+			if idToken == "" {
+				t.Fatal("no se emitió el ID token")
+			}
+			// El código no se puede canjear dos veces
+			_, err = f.exchange(code, testVerifier)
+			assertOAuthError(t, err, OAuthInvalidGrant)
+		})
+	}
+}
+
+func TestOAuthServiceDiscovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture func(t *testing.T) *oauthFixture
+		wantAlg string // vacío espera OpenID Connect deshabilitado
+	}{
+		{name: "HS256 por defecto", fixture: newHS256OAuthFixture},
+		{name: "ES256", fixture: newOAuthFixture, wantAlg: "ES256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.fixture(t)
+			discovery, err := f.oauth.Discovery()
+			if tt.wantAlg == "" {
+				if !errors.Is(err, ErrOIDCDisabled) {
+					t.Fatalf("err = %v, se esperaba ErrOIDCDisabled", err)
+				}
+				_, err := f.oauth.ValidateClient("1", testRedirectURI)
+				assertOAuthError(t, err, OAuthInvalidRequest)
+				_, err = f.exchange(f.grantCode(t, testChallenge), testVerifier)
+				assertOAuthError(t, err, OAuthUnsupportedGrantType)
+				_, err = f.oauth.UserInfo("cualquiera")
+				assertOAuthError(t, err, OAuthInvalidRequest)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(discovery.IDTokenSigningAlgValuesSupported) != 1 || discovery.IDTokenSigningAlgValuesSupported[0] != tt.wantAlg {
+				t.Fatalf("id_token_signing_alg_values_supported = %v, se esperaba [%s]", discovery.IDTokenSigningAlgValuesSupported, tt.wantAlg)
+			}
+			set, err := f.service.keyService.JWKS()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(set.Keys) == 0 {
+				t.Fatal("el JWKS debe publicar la llave que firma los ID tokens")
+			}
+		})
+	}
+}
+
+// assertOAuthError verifica el código de error OAuth; "" espera que no haya error
+func assertOAuthError(t *testing.T, err error, wantCode string) {
+	t.Helper()
+	if wantCode == "" {
+		if err != nil {
+			t.Fatalf("err = %v, no se esperaba error", err)
+		}
+		return
+	}
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != wantCode {
+		t.Fatalf("err = %v, se esperaba %s", err, wantCode)
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/password"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrPasswordReused   = errors.New("La contraseña ya se usó recientemente; elige una distinta")
+	ErrPasswordExpired  = errors.New("La contraseña venció; debe cambiarla")
+	ErrExternalPassword = errors.New("La contraseña de este usuario se administra en el directorio de la organización")
+)
+
+// IsPasswordRejected indica si el error se debe a que la contraseña nueva no
+// es aceptable, para mostrarlo al usuario en lugar de tratarlo como falla interna
+func IsPasswordRejected(err error) bool {
+	var policyErr *password.PolicyError
+	return errors.As(err, &policyErr) || errors.Is(err, ErrPasswordReused) || errors.Is(err, ErrPasswordRequired) ||
+		errors.Is(err, ErrExternalPassword)
+}
+
+// PasswordConfig agrupa la configuración del historial y el vencimiento
+type PasswordConfig struct {
+	HistorySize int           // contraseñas anteriores que no se pueden reutilizar
+	MaxAge      time.Duration // 0 desactiva el vencimiento
+}
+
+// PasswordService aplica la política de contraseñas, el historial y el
+// vencimiento a toda contraseña nueva, sin importar el flujo que la cambie
+type PasswordService struct {
+	cfg         PasswordConfig
+	policy      *password.Policy
+	hasher      *password.Hasher
+	historyRepo *repositories.PasswordHistoryRepository
+}
+
+func NewPasswordService(cfg PasswordConfig, policy *password.Policy, hasher *password.Hasher, historyRepo *repositories.PasswordHistoryRepository) *PasswordService {
+	return &PasswordService{cfg: cfg, policy: policy, hasher: hasher, historyRepo: historyRepo}
+}
+
+// Prepare valida la contraseña nueva del usuario y devuelve su hash. Para un
+// usuario existente también rechaza la actual y las del historial.
+func (s *PasswordService) Prepare(user domain.User, plainPassword string) (string, error) {
+	if err := s.policy.Validate(plainPassword, user.Username); err != nil {
+		return "", err
+	}
+
+	if user.ID != 0 && s.cfg.HistorySize > 0 {
+		previous := []string{user.Password}
+		history, err := s.historyRepo.GetRecent(user.ID, s.cfg.HistorySize)
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range history {
+			previous = append(previous, entry.PasswordHash)
+		}
+
+		for _, hash := range previous {
+			if hash == "" {
+				continue
+			}
+			match, _, err := s.hasher.Verify(hash, plainPassword)
+			if err != nil {
+				return "", err
+			}
+			if match {
+				return "", ErrPasswordReused
+			}
+		}
+	}
+
+	hash, err := s.hasher.Hash(plainPassword)
+	if err != nil {
+		return "", fmt.Errorf("Error al generar el hash de la contraseña: %w", err)
+	}
+	return hash, nil
+}
+
+// Record agrega la contraseña recién guardada al historial del usuario
+func (s *PasswordService) Record(userID uint, hash string, when time.Time) error {
+	if s.cfg.HistorySize <= 0 {
+		return nil
+	}
+	return s.historyRepo.Add(&domain.PasswordHistory{
+		UserID:       userID,
+		PasswordHash: hash,
+		Created:      when,
+	}, s.cfg.HistorySize)
+}
+
+// ExpiresAt devuelve el vencimiento de la contraseña del usuario, o nil si no vence
+func (s *PasswordService) ExpiresAt(user domain.User) *time.Time {
+	if s.cfg.MaxAge <= 0 {
+		return nil
+	}
+	changedAt := user.Created
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	expiresAt := changedAt.Add(s.cfg.MaxAge)
+	return &expiresAt
+}
+
+// IsExpired indica si el usuario debe cambiar su contraseña antes de ingresar
+func (s *PasswordService) IsExpired(user domain.User) bool {
+	expiresAt := s.ExpiresAt(user)
+	return expiresAt != nil && time.Now().After(*expiresAt)
+}
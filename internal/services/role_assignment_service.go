@@ -0,0 +1,176 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/responses"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleAssignmentRoleRequired       = errors.New("Debe indicar role_id o role")
+	ErrRoleAssignmentRoleNotFound       = errors.New("El rol no existe en el sistema")
+	ErrRoleAssignmentPermissionRequired = errors.New("Debe indicar permission_id o permission")
+	ErrRoleAssignmentPermissionNotFound = errors.New("El permiso no existe en el sistema")
+	ErrRoleAssignmentRoleNotAssigned    = errors.New("El usuario no tiene asignado el rol")
+)
+
+// RoleAssignmentService asigna roles completos a los usuarios de un sistema:
+// quien tiene un rol recibe todos sus permisos, también los que se agreguen
+// después, salvo los que se le quiten de forma individual
+type RoleAssignmentService struct {
+	repo     *repositories.RoleAssignmentRepository
+	userRepo *repositories.UserRepository
+}
+
+func NewRoleAssignmentService(repo *repositories.RoleAssignmentRepository, userRepo *repositories.UserRepository) *RoleAssignmentService {
+	return &RoleAssignmentService{repo: repo, userRepo: userRepo}
+}
+
+// FindRole busca el rol del sistema por ID o por nombre
+func (s *RoleAssignmentService) FindRole(systemID uint64, roleID uint, name string) (domain.Role, error) {
+	name = strings.TrimSpace(name)
+	if roleID == 0 && name == "" {
+		return domain.Role{}, ErrRoleAssignmentRoleRequired
+	}
+	role, err := s.repo.FindRole(systemID, roleID, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Role{}, ErrRoleAssignmentRoleNotFound
+		}
+		return domain.Role{}, err
+	}
+	return role, nil
+}
+
+// FindPermission busca el permiso del sistema por ID o por nombre
+func (s *RoleAssignmentService) FindPermission(systemID uint64, permissionID uint, name string) (domain.Permission, error) {
+	name = strings.TrimSpace(name)
+	if permissionID == 0 && name == "" {
+		return domain.Permission{}, ErrRoleAssignmentPermissionRequired
+	}
+	permission, err := s.repo.FindPermission(systemID, permissionID, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Permission{}, ErrRoleAssignmentPermissionNotFound
+		}
+		return domain.Permission{}, err
+	}
+	return permission, nil
+}
+
+// AssignRole asigna el rol al usuario, que debe estar asociado al sistema
+func (s *RoleAssignmentService) AssignRole(systemID uint64, userID uint, role domain.Role) error {
+	if err := s.checkScope(systemID, userID, role); err != nil {
+		return err
+	}
+	return s.repo.Assign(systemID, userID, role.ID)
+}
+
+// UnassignRole quita el rol al usuario; sus permisos asignados uno a uno y los
+// de sus grupos no cambian
+func (s *RoleAssignmentService) UnassignRole(systemID uint64, userID uint, role domain.Role) error {
+	if err := s.checkScope(systemID, userID, role); err != nil {
+		return err
+	}
+	return s.repo.Unassign(systemID, userID, role.ID)
+}
+
+func (s *RoleAssignmentService) HasRole(systemID uint64, userID uint, roleID uint) (bool, error) {
+	return s.repo.HasRole(systemID, userID, roleID)
+}
+
+// ExcludePermission le quita al usuario un permiso que recibe por sus roles
+func (s *RoleAssignmentService) ExcludePermission(systemID uint64, userID uint, permission domain.Permission) error {
+	if err := s.checkUser(systemID, userID); err != nil {
+		return err
+	}
+	return s.repo.Exclude(systemID, userID, permission.ID)
+}
+
+// IncludePermission deshace ExcludePermission
+func (s *RoleAssignmentService) IncludePermission(systemID uint64, userID uint, permission domain.Permission) error {
+	if err := s.checkUser(systemID, userID); err != nil {
+		return err
+	}
+	return s.repo.Include(systemID, userID, permission.ID)
+}
+
+// SetRolePermissions guarda, para un rol asignado, cuáles de sus permisos
+// conserva el usuario: los demás quedan excluidos
+func (s *RoleAssignmentService) SetRolePermissions(systemID uint64, userID uint, role domain.Role, permissionIDs []uint64) error {
+	assigned, err := s.repo.HasRole(systemID, userID, role.ID)
+	if err != nil {
+		return err
+	}
+	if !assigned {
+		return ErrRoleAssignmentRoleNotAssigned
+	}
+
+	keep := make(map[uint]bool, len(permissionIDs))
+	for _, id := range permissionIDs {
+		keep[uint(id)] = true
+	}
+	rolePermissionIDs, err := s.repo.GetRolePermissionIDs(role.ID)
+	if err != nil {
+		return err
+	}
+	var excluded []uint
+	for _, id := range rolePermissionIDs {
+		if !keep[id] {
+			excluded = append(excluded, id)
+		}
+	}
+
+	return s.repo.SetRoleExclusions(systemID, userID, role.ID, excluded)
+}
+
+// GetAssignments devuelve los roles asignados al usuario y los permisos que se
+// le quitaron
+func (s *RoleAssignmentService) GetAssignments(systemID uint64, userID uint) (responses.RoleAssignments, error) {
+	result := responses.RoleAssignments{
+		UserID:              userID,
+		SystemID:            systemID,
+		Roles:               []responses.AuthzRole{},
+		ExcludedPermissions: []string{},
+	}
+
+	roles, err := s.repo.GetRoles(systemID, userID)
+	if err != nil {
+		return responses.RoleAssignments{}, err
+	}
+	for _, role := range roles {
+		result.Roles = append(result.Roles, responses.AuthzRole{ID: role.ID, Name: role.Name})
+	}
+
+	excluded, err := s.repo.GetExcludedPermissions(systemID, userID)
+	if err != nil {
+		return responses.RoleAssignments{}, err
+	}
+	for _, permission := range excluded {
+		result.ExcludedPermissions = append(result.ExcludedPermissions, permission.Name)
+	}
+
+	return result, nil
+}
+
+// checkScope exige que el rol sea del sistema y el usuario esté asociado a él
+func (s *RoleAssignmentService) checkScope(systemID uint64, userID uint, role domain.Role) error {
+	if uint64(role.SystemID) != systemID {
+		return ErrRoleAssignmentRoleNotFound
+	}
+	return s.checkUser(systemID, userID)
+}
+
+func (s *RoleAssignmentService) checkUser(systemID uint64, userID uint) error {
+	if _, err := s.userRepo.GetBySystemAndID(systemID, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAuthzUserNotFound
+		}
+		return err
+	}
+	return nil
+}
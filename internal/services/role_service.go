@@ -5,9 +5,16 @@ import (
 	"accessv2/internal/forms"
 	"accessv2/internal/repositories"
 	"errors"
+	"sort"
 	"time"
 )
 
+var (
+	ErrRoleHasChildren   = errors.New("El rol tiene roles hijos; debe reasignarlos antes de eliminarlo")
+	ErrRoleParentInvalid = errors.New("El rol padre no existe en el sistema")
+	ErrRoleParentCycle   = errors.New("El rol padre no puede ser el mismo rol ni uno de sus descendientes")
+)
+
 type RoleService struct {
 	repo *repositories.RoleRepository
 }
@@ -53,6 +60,10 @@ func (s *RoleService) CreateRole(input *forms.RoleCreateInput, systemID int) (*d
 	role := &domain.Role{
 		Name:     input.Name,
 		SystemID: uint(systemID),
+		ParentID: parentIDOrNil(input.ParentID),
+	}
+	if err := s.validateParent(role); err != nil {
+		return nil, err
 	}
 
 	// Establecer fechas por defecto si no vienen
@@ -98,12 +109,143 @@ func (s *RoleService) UpdateRole(role *domain.Role) error {
 	if err != nil {
 		return err // Si se encuentra un error (otro rol con el mismo nombre), retornarlo.
 	}
+	if err := s.validateParent(role); err != nil {
+		return err
+	}
 
 	role.Updated = time.Now()
 	return s.repo.Update(role)
 }
 
-// DeleteRole usando el repository
+// DeleteRole elimina un rol sin hijos; si los tiene devuelve ErrRoleHasChildren
 func (s *RoleService) DeleteRole(id uint64) error {
+	children, err := s.repo.GetChildren(id)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return ErrRoleHasChildren
+	}
 	return s.repo.Delete(id)
 }
+
+// DeleteRoleReassigning elimina el rol y pasa sus hijos al nuevo padre; 0 los
+// deja sin padre. El nuevo padre no puede ser el rol ni uno de sus descendientes.
+func (s *RoleService) DeleteRoleReassigning(role domain.Role, newParentID uint) error {
+	parentID := parentIDOrNil(newParentID)
+	if parentID != nil {
+		probe := domain.Role{ID: role.ID, SystemID: role.SystemID, ParentID: parentID}
+		if err := s.validateParent(&probe); err != nil {
+			return err
+		}
+	}
+	return s.repo.DeleteReassigningChildren(uint64(role.ID), parentID)
+}
+
+// GetChildren devuelve los roles que heredan directamente del rol
+func (s *RoleService) GetChildren(id uint64) ([]domain.Role, error) {
+	return s.repo.GetChildren(id)
+}
+
+// GetRoleTree arma la jerarquía de roles del sistema ordenada por nombre
+func (s *RoleService) GetRoleTree(systemID int) ([]*domain.RoleNode, error) {
+	roles, err := s.repo.GetRolesBySystemID(systemID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+
+	nodes := make(map[uint]*domain.RoleNode, len(roles))
+	for _, role := range roles {
+		nodes[role.ID] = &domain.RoleNode{Role: role}
+	}
+
+	var tree []*domain.RoleNode
+	for _, role := range roles {
+		node := nodes[role.ID]
+		parent, ok := nodes[derefParent(role.ParentID)]
+		if role.ParentID == nil || !ok {
+			tree = append(tree, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	return tree, nil
+}
+
+// GetParentCandidates devuelve los roles del sistema que pueden ser padre del
+// rol: todos menos él mismo y sus descendientes. Con roleID 0 devuelve todos.
+func (s *RoleService) GetParentCandidates(systemID int, roleID uint) ([]domain.Role, error) {
+	roles, err := s.repo.GetRolesBySystemID(systemID)
+	if err != nil {
+		return nil, err
+	}
+	parents := parentMap(roles)
+
+	candidates := []domain.Role{}
+	for _, role := range roles {
+		if roleID != 0 && inheritsFrom(parents, role.ID, roleID) {
+			continue
+		}
+		candidates = append(candidates, role)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Name < candidates[j].Name })
+	return candidates, nil
+}
+
+// validateParent comprueba que el padre sea otro rol del mismo sistema y que
+// no se forme un ciclo: el padre no puede descender del rol
+func (s *RoleService) validateParent(role *domain.Role) error {
+	if role.ParentID == nil {
+		return nil
+	}
+
+	roles, err := s.repo.GetRolesBySystemID(int(role.SystemID))
+	if err != nil {
+		return err
+	}
+	parents := parentMap(roles)
+	if _, ok := parents[*role.ParentID]; !ok {
+		return ErrRoleParentInvalid
+	}
+	if role.ID != 0 && inheritsFrom(parents, *role.ParentID, role.ID) {
+		return ErrRoleParentCycle
+	}
+	return nil
+}
+
+// parentMap indexa el padre de cada rol; 0 si no tiene
+func parentMap(roles []domain.Role) map[uint]uint {
+	parents := make(map[uint]uint, len(roles))
+	for _, role := range roles {
+		parents[role.ID] = derefParent(role.ParentID)
+	}
+	return parents
+}
+
+// inheritsFrom indica si roleID es ancestorID o desciende de él. Se detiene si
+// encuentra un ciclo ya guardado.
+func inheritsFrom(parents map[uint]uint, roleID, ancestorID uint) bool {
+	seen := make(map[uint]bool)
+	for id := roleID; id != 0 && !seen[id]; id = parents[id] {
+		if id == ancestorID {
+			return true
+		}
+		seen[id] = true
+	}
+	return false
+}
+
+func parentIDOrNil(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+func derefParent(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/forms"
+	"accessv2/internal/repositories"
+	"accessv2/internal/testutil"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestInheritsFrom(t *testing.T) {
+	// 3 -> 2 -> 1 y un ciclo ya guardado 5 -> 6 -> 5
+	parents := map[uint]uint{1: 0, 2: 1, 3: 2, 4: 0, 5: 6, 6: 5}
+	tests := []struct {
+		name       string
+		role       uint
+		ancestor   uint
+		wantResult bool
+	}{
+		{name: "el mismo rol", role: 2, ancestor: 2, wantResult: true},
+		{name: "padre directo", role: 3, ancestor: 2, wantResult: true},
+		{name: "abuelo", role: 3, ancestor: 1, wantResult: true},
+		{name: "descendiente no es ancestro", role: 1, ancestor: 3},
+		{name: "otra rama", role: 3, ancestor: 4},
+		{name: "rol desconocido", role: 99, ancestor: 1},
+		{name: "ciclo guardado sin el ancestro", role: 5, ancestor: 1},
+		{name: "ciclo guardado con el ancestro", role: 5, ancestor: 6, wantResult: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inheritsFrom(parents, tt.role, tt.ancestor); got != tt.wantResult {
+				t.Fatalf("inheritsFrom(%d, %d) = %v, se esperaba %v", tt.role, tt.ancestor, got, tt.wantResult)
+			}
+		})
+	}
+}
+
+// roleTree es la jerarquía que arma newRoleTree, con los IDs por nombre
+type roleTree struct {
+	db      *gorm.DB
+	service *RoleService
+	ids     map[string]uint
+}
+
+// newRoleTree crea con el servicio, en el sistema Uno, Admin <- Manager <-
+// Operator y Auditor sin padre, y en el sistema Dos el rol Externo
+func newRoleTree(t *testing.T) *roleTree {
+	t.Helper()
+	db := testutil.NewDB(t)
+	now := time.Now()
+	if err := db.Create(&[]domain.System{{ID: 1, Name: "Uno", Created: now, Updated: now}, {ID: 2, Name: "Dos", Created: now, Updated: now}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tree := &roleTree{db: db, service: NewRoleService(repositories.NewRoleRepository(db)), ids: map[string]uint{}}
+	for _, role := range []struct {
+		name, parent string
+		system       int
+	}{
+		{"Admin", "", 1},
+		{"Manager", "Admin", 1},
+		{"Operator", "Manager", 1},
+		{"Auditor", "", 1},
+		{"Externo", "", 2},
+	} {
+		created, err := tree.service.CreateRole(&forms.RoleCreateInput{Name: role.name, ParentID: tree.ids[role.parent]}, role.system)
+		if err != nil {
+			t.Fatalf("crear %s: %v", role.name, err)
+		}
+		tree.ids[role.name] = created.ID
+	}
+	return tree
+}
+
+// parentOf devuelve el nombre del padre guardado del rol; "" si no tiene
+func (tree *roleTree) parentOf(t *testing.T, name string) string {
+	t.Helper()
+	var role domain.Role
+	if err := tree.db.First(&role, tree.ids[name]).Error; err != nil {
+		t.Fatal(err)
+	}
+	for parent, id := range tree.ids {
+		if role.ParentID != nil && *role.ParentID == id {
+			return parent
+		}
+	}
+	return ""
+}
+
+func TestRoleServiceCreateRoleParent(t *testing.T) {
+	tree := newRoleTree(t)
+
+	if _, err := tree.service.CreateRole(&forms.RoleCreateInput{Name: "Intruso", ParentID: tree.ids["Externo"]}, 1); !errors.Is(err, ErrRoleParentInvalid) {
+		t.Fatalf("padre de otro sistema: err = %v, se esperaba ErrRoleParentInvalid", err)
+	}
+	if got := tree.parentOf(t, "Operator"); got != "Manager" {
+		t.Fatalf("padre de Operator = %q, se esperaba Manager", got)
+	}
+}
+
+func TestRoleServiceUpdateRoleParent(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		parent  string // "" sin padre
+		wantErr error
+	}{
+		{name: "sin padre", role: "Operator"},
+		{name: "otro rol sin relación", role: "Operator", parent: "Auditor"},
+		{name: "un ancestro más lejano", role: "Operator", parent: "Admin"},
+		{name: "el mismo rol", role: "Manager", parent: "Manager", wantErr: ErrRoleParentCycle},
+		{name: "un hijo", role: "Admin", parent: "Manager", wantErr: ErrRoleParentCycle},
+		{name: "un nieto", role: "Admin", parent: "Operator", wantErr: ErrRoleParentCycle},
+		{name: "rol de otro sistema", role: "Manager", parent: "Externo", wantErr: ErrRoleParentInvalid},
+		{name: "rol inexistente", role: "Manager", parent: "Inexistente", wantErr: ErrRoleParentInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := newRoleTree(t)
+			tree.ids["Inexistente"] = 99
+			before := tree.parentOf(t, tt.role)
+
+			var role domain.Role
+			if err := tree.service.FetchRole(uint64(tree.ids[tt.role]), &role); err != nil {
+				t.Fatal(err)
+			}
+			role.ParentID = parentIDOrNil(tree.ids[tt.parent])
+
+			if err := tree.service.UpdateRole(&role); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, se esperaba %v", err, tt.wantErr)
+			}
+			want := tt.parent
+			if tt.wantErr != nil {
+				want = before
+			}
+			if got := tree.parentOf(t, tt.role); got != want {
+				t.Fatalf("padre guardado = %q, se esperaba %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRoleServiceDeleteRoleWithChildren(t *testing.T) {
+	tree := newRoleTree(t)
+
+	if err := tree.service.DeleteRole(uint64(tree.ids["Manager"])); !errors.Is(err, ErrRoleHasChildren) {
+		t.Fatalf("err = %v, se esperaba ErrRoleHasChildren", err)
+	}
+	if err := tree.service.DeleteRole(uint64(tree.ids["Operator"])); err != nil {
+		t.Fatalf("eliminar un rol sin hijos: %v", err)
+	}
+}
+
+func TestRoleServiceDeleteRoleReassigning(t *testing.T) {
+	tests := []struct {
+		name      string
+		newParent string // "" deja a los hijos sin padre
+		wantErr   error
+	}{
+		{name: "hijos sin padre"},
+		{name: "hijos al abuelo", newParent: "Admin"},
+		{name: "hijos a otra rama", newParent: "Auditor"},
+		{name: "hijos a un descendiente", newParent: "Operator", wantErr: ErrRoleParentCycle},
+		{name: "hijos a un rol de otro sistema", newParent: "Externo", wantErr: ErrRoleParentInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := newRoleTree(t)
+
+			manager := domain.Role{ID: tree.ids["Manager"], SystemID: 1}
+			if err := tree.service.DeleteRoleReassigning(manager, tree.ids[tt.newParent]); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, se esperaba %v", err, tt.wantErr)
+			}
+			want := tt.newParent
+			if tt.wantErr != nil {
+				want = "Manager"
+			}
+			if got := tree.parentOf(t, "Operator"); got != want {
+				t.Fatalf("padre de Operator = %q, se esperaba %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRoleServiceGetParentCandidates(t *testing.T) {
+	tree := newRoleTree(t)
+
+	candidates, err := tree.service.GetParentCandidates(1, tree.ids["Manager"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, role := range candidates {
+		names = append(names, role.Name)
+	}
+	// Manager no puede heredar de sí mismo ni de Operator
+	if len(names) != 2 || names[0] != "Admin" || names[1] != "Auditor" {
+		t.Fatalf("candidatos = %v, se esperaba [Admin Auditor]", names)
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/utils"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Códigos de error de los ingresos rechazados, pensados para los clientes de la API
+const (
+	SignInAccountLocked   = "account_locked"
+	SignInTooManyAttempts = "too_many_attempts"
+	SignInPasswordExpired = "password_expired"
+)
+
+// SignInBlockedError indica que el ingreso se rechazó sin validar la contraseña
+// por exceso de intentos fallidos
+type SignInBlockedError struct {
+	Code       string
+	RetryAfter time.Duration
+}
+
+func (e *SignInBlockedError) Error() string {
+	if e.Code == SignInAccountLocked {
+		return fmt.Sprintf("Cuenta bloqueada temporalmente por intentos fallidos; intenta de nuevo en %s", formatRetryAfter(e.RetryAfter))
+	}
+	return fmt.Sprintf("Demasiados intentos fallidos; intenta de nuevo en %s", formatRetryAfter(e.RetryAfter))
+}
+
+// SignInThrottleConfig agrupa los umbrales de la protección contra fuerza bruta
+type SignInThrottleConfig struct {
+	MaxFailures     int           // fallos por usuario antes del bloqueo temporal
+	IPMaxFailures   int           // fallos por IP antes del bloqueo temporal
+	FailureWindow   time.Duration // tras este tiempo sin fallos el contador vuelve a empezar
+	BackoffBase     time.Duration // espera tras el primer fallo; se duplica con cada uno
+	BackoffMax      time.Duration
+	LockoutDuration time.Duration
+}
+
+// SignInThrottleService lleva los intentos fallidos de inicio de sesión por
+// usuario y por IP dentro de cada sistema, imponiendo una espera exponencial
+// entre intentos y un bloqueo temporal al superar los umbrales
+type SignInThrottleService struct {
+	cfg  SignInThrottleConfig
+	repo *repositories.SignInThrottleRepository
+}
+
+func NewSignInThrottleService(cfg SignInThrottleConfig, repo *repositories.SignInThrottleRepository) *SignInThrottleService {
+	return &SignInThrottleService{cfg: cfg, repo: repo}
+}
+
+// Check rechaza el intento si el usuario o la IP están en espera o bloqueados.
+// Se llama antes de validar la contraseña.
+func (s *SignInThrottleService) Check(systemID uint64, username, clientIP string) error {
+	now := time.Now()
+
+	for _, subject := range s.subjects(username, clientIP) {
+		throttle, err := s.repo.Get(subject.scope, systemID, subject.value)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+
+		if throttle.IsLocked(now) {
+			code := SignInTooManyAttempts
+			if subject.scope == domain.ThrottleScopeUsername {
+				code = SignInAccountLocked
+			}
+			return &SignInBlockedError{Code: code, RetryAfter: throttle.LockedUntil.Sub(now)}
+		}
+		if throttle.RetryAt != nil && now.Before(*throttle.RetryAt) {
+			return &SignInBlockedError{Code: SignInTooManyAttempts, RetryAfter: throttle.RetryAt.Sub(now)}
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure suma el intento fallido al usuario y a la IP y calcula la
+// próxima espera o el bloqueo
+func (s *SignInThrottleService) RecordFailure(systemID uint64, username, clientIP string) error {
+	now := time.Now()
+
+	for _, subject := range s.subjects(username, clientIP) {
+		throttle, err := s.repo.IncrementFailures(subject.scope, systemID, subject.value, now, now.Add(-s.cfg.FailureWindow))
+		if err != nil {
+			return err
+		}
+
+		// La espera exponencial solo aplica al usuario; una IP compartida por
+		// muchos usuarios únicamente se bloquea al superar su umbral
+		maxFailures := s.cfg.MaxFailures
+		var retryAt *time.Time
+		if subject.scope == domain.ThrottleScopeIP {
+			maxFailures = s.cfg.IPMaxFailures
+		} else {
+			at := now.Add(s.backoff(throttle.Failures))
+			retryAt = &at
+		}
+
+		var lockedUntil *time.Time
+		if maxFailures > 0 && throttle.Failures >= maxFailures {
+			until := now.Add(s.cfg.LockoutDuration)
+			lockedUntil = &until
+			log.Printf("Inicio de sesión bloqueado para %s %q en el sistema %d hasta %s", subject.scope, subject.value, systemID, until.Format(time.RFC3339))
+		}
+
+		if err := s.repo.UpdateBlock(throttle.ID, retryAt, lockedUntil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccess reinicia el contador del usuario. El de la IP se conserva para
+// no perder el rastro de quien prueba contraseñas sobre varias cuentas.
+func (s *SignInThrottleService) RecordSuccess(systemID uint64, username string) error {
+	return s.repo.Delete(domain.ThrottleScopeUsername, systemID, normalizeThrottleSubject(username))
+}
+
+// GetUserThrottles devuelve el estado de los contadores del usuario en cada sistema
+func (s *SignInThrottleService) GetUserThrottles(username string) ([]domain.SignInThrottle, error) {
+	return s.repo.GetBySubject(domain.ThrottleScopeUsername, normalizeThrottleSubject(username))
+}
+
+// ClearUser desbloquea al usuario en todos los sistemas
+func (s *SignInThrottleService) ClearUser(username string) error {
+	return s.repo.DeleteBySubject(domain.ThrottleScopeUsername, normalizeThrottleSubject(username))
+}
+
+type throttleSubject struct {
+	scope string
+	value string
+}
+
+func (s *SignInThrottleService) subjects(username, clientIP string) []throttleSubject {
+	subjects := []throttleSubject{{scope: domain.ThrottleScopeUsername, value: normalizeThrottleSubject(username)}}
+	if clientIP != "" {
+		subjects = append(subjects, throttleSubject{scope: domain.ThrottleScopeIP, value: clientIP})
+	}
+	return subjects
+}
+
+// backoff devuelve BackoffBase * 2^(fallos-1), limitado a BackoffMax
+func (s *SignInThrottleService) backoff(failures int) time.Duration {
+	if failures < 1 || s.cfg.BackoffBase <= 0 {
+		return 0
+	}
+	wait := float64(s.cfg.BackoffBase) * math.Pow(2, float64(failures-1))
+	if s.cfg.BackoffMax > 0 && wait > float64(s.cfg.BackoffMax) {
+		return s.cfg.BackoffMax
+	}
+	return time.Duration(wait)
+}
+
+func normalizeThrottleSubject(username string) string {
+	return utils.NormalizeUsername(username)
+}
+
+// formatRetryAfter redondea la espera hacia arriba al segundo
+func formatRetryAfter(d time.Duration) string {
+	return (d + time.Second - 1).Truncate(time.Second).String()
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/testutil"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignInThrottleServiceBackoff(t *testing.T) {
+	s := &SignInThrottleService{cfg: SignInThrottleConfig{BackoffBase: time.Second, BackoffMax: 10 * time.Second}}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 1, want: time.Second},
+		{failures: 2, want: 2 * time.Second},
+		{failures: 4, want: 8 * time.Second},
+		{failures: 5, want: 10 * time.Second},
+		{failures: 60, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := s.backoff(tt.failures); got != tt.want {
+			t.Errorf("backoff(%d) = %s, se esperaba %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// throttleAttempt es un ingreso de la prueba: fallido salvo que success sea true
+type throttleAttempt struct {
+	system   uint64
+	username string
+	ip       string
+	success  bool
+}
+
+func TestSignInThrottleService(t *testing.T) {
+	failures := func(n int, system uint64, username, ip string) []throttleAttempt {
+		attempts := make([]throttleAttempt, n)
+		for i := range attempts {
+			attempts[i] = throttleAttempt{system: system, username: username, ip: ip}
+		}
+		return attempts
+	}
+	distinctUsers := func(n int, ip string) []throttleAttempt {
+		attempts := make([]throttleAttempt, n)
+		for i := range attempts {
+			attempts[i] = throttleAttempt{system: 1, username: string(rune('a'+i)) + "user", ip: ip}
+		}
+		return attempts
+	}
+
+	tests := []struct {
+		name        string
+		backoffBase time.Duration
+		attempts    []throttleAttempt
+		check       throttleAttempt
+		wantCode    string // "" si el ingreso se permite
+	}{
+		{
+			name:     "sin fallos",
+			check:    throttleAttempt{system: 1, username: "jdoe", ip: "10.0.0.1"},
+			wantCode: "",
+		},
+		{
+			name:     "bajo el umbral del usuario",
+			attempts: failures(2, 1, "jdoe", "10.0.0.1"),
+			check:    throttleAttempt{system: 1, username: "jdoe", ip: "10.0.0.1"},
+		},
+		{
+			name:     "bloqueo del usuario",
+			attempts: failures(3, 1, "jdoe", "10.0.0.1"),
+			check:    throttleAttempt{system: 1, username: "jdoe", ip: "10.0.0.2"},
+			wantCode: SignInAccountLocked,
+		},
+		{
+			name:     "el usuario se compara normalizado",
+			attempts: append(failures(2, 1, "JDoe", "10.0.0.1"), throttleAttempt{system: 1, username: " jdoe ", ip: "10.0.0.1"}),
+			check:    throttleAttempt{system: 1, username: "JDOE", ip: "10.0.0.2"},
+			wantCode: SignInAccountLocked,
+		},
+		{
+			name:     "el bloqueo es por sistema",
+			attempts: failures(3, 1, "jdoe", "10.0.0.1"),
+			check:    throttleAttempt{system: 2, username: "jdoe", ip: "10.0.0.2"},
+		},
+		{
+			name:     "el bloqueo del usuario no alcanza a otro desde la misma IP",
+			attempts: failures(3, 1, "jdoe", "10.0.0.1"),
+			check:    throttleAttempt{system: 1, username: "mlopez", ip: "10.0.0.1"},
+		},
+		{
+			name:     "bloqueo de la IP",
+			attempts: distinctUsers(5, "10.0.0.1"),
+			check:    throttleAttempt{system: 1, username: "mlopez", ip: "10.0.0.1"},
+			wantCode: SignInTooManyAttempts,
+		},
+		{
+			name:     "otra IP no queda bloqueada",
+			attempts: distinctUsers(5, "10.0.0.1"),
+			check:    throttleAttempt{system: 1, username: "mlopez", ip: "10.0.0.2"},
+		},
+		{
+			name:     "sin IP solo cuenta el usuario",
+			attempts: distinctUsers(5, ""),
+			check:    throttleAttempt{system: 1, username: "mlopez", ip: ""},
+		},
+		{
+			name:     "el éxito reinicia al usuario",
+			attempts: append(failures(2, 1, "jdoe", "10.0.0.1"), throttleAttempt{system: 1, username: "jdoe", success: true}, throttleAttempt{system: 1, username: "jdoe", ip: "10.0.0.1"}),
+			check:    throttleAttempt{system: 1, username: "jdoe", ip: "10.0.0.1"},
+		},
+		{
+			name:     "el éxito no reinicia a la IP",
+			attempts: append(distinctUsers(4, "10.0.0.1"), throttleAttempt{system: 1, username: "auser", success: true}, throttleAttempt{system: 1, username: "zuser", ip: "10.0.0.1"}),
+			check:    throttleAttempt{system: 1, username: "auser", ip: "10.0.0.1"},
+			wantCode: SignInTooManyAttempts,
+		},
+		{
+			name:        "espera tras un fallo",
+			backoffBase: time.Minute,
+			attempts:    failures(1, 1, "jdoe", "10.0.0.1"),
+			check:       throttleAttempt{system: 1, username: "jdoe", ip: "10.0.0.2"},
+			wantCode:    SignInTooManyAttempts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSignInThrottleService(SignInThrottleConfig{
+				MaxFailures:     3,
+				IPMaxFailures:   5,
+				FailureWindow:   time.Hour,
+				BackoffBase:     tt.backoffBase,
+				LockoutDuration: time.Hour,
+			}, repositories.NewSignInThrottleRepository(testutil.NewDB(t)))
+
+			for _, attempt := range tt.attempts {
+				var err error
+				if attempt.success {
+					err = s.RecordSuccess(attempt.system, attempt.username)
+				} else {
+					err = s.RecordFailure(attempt.system, attempt.username, attempt.ip)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := s.Check(tt.check.system, tt.check.username, tt.check.ip)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("err = %v, se esperaba permitir el ingreso", err)
+				}
+				return
+			}
+			var blocked *SignInBlockedError
+			if !errors.As(err, &blocked) || blocked.Code != tt.wantCode {
+				t.Fatalf("err = %v, se esperaba %s", err, tt.wantCode)
+			}
+			if blocked.RetryAfter <= 0 {
+				t.Fatalf("RetryAfter = %s, se esperaba una espera", blocked.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestSignInThrottleServiceFailureWindow(t *testing.T) {
+	db := testutil.NewDB(t)
+	s := NewSignInThrottleService(SignInThrottleConfig{
+		MaxFailures:     3,
+		FailureWindow:   time.Hour,
+		LockoutDuration: time.Hour,
+	}, repositories.NewSignInThrottleRepository(db))
+
+	for i := 0; i < 2; i++ {
+		if err := s.RecordFailure(1, "jdoe", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Los fallos fuera de la ventana no cuentan
+	err := db.Model(&domain.SignInThrottle{}).
+		Where("scope = ?", domain.ThrottleScopeUsername).
+		Update("last_failure_at", time.Now().Add(-2*time.Hour)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RecordFailure(1, "jdoe", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Check(1, "jdoe", ""); err != nil {
+		t.Fatalf("err = %v, el contador debía reiniciarse fuera de la ventana", err)
+	}
+}
+
+func TestSignInThrottleServiceClearUser(t *testing.T) {
+	s := NewSignInThrottleService(SignInThrottleConfig{
+		MaxFailures:     1,
+		FailureWindow:   time.Hour,
+		LockoutDuration: time.Hour,
+	}, repositories.NewSignInThrottleRepository(testutil.NewDB(t)))
+
+	for _, systemID := range []uint64{1, 2} {
+		if err := s.RecordFailure(systemID, "jdoe", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	throttles, err := s.GetUserThrottles("JDoe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(throttles) != 2 {
+		t.Fatalf("contadores del usuario = %d, se esperaban 2", len(throttles))
+	}
+
+	// El administrador desbloquea al usuario en todos los sistemas
+	if err := s.ClearUser(" JDOE "); err != nil {
+		t.Fatal(err)
+	}
+	for _, systemID := range []uint64{1, 2} {
+		if err := s.Check(systemID, "jdoe", "10.0.0.2"); err != nil {
+			t.Fatalf("sistema %d: err = %v, se esperaba el usuario desbloqueado", systemID, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/utils"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAPIKey            = errors.New("Llave de API inválida o revocada")
+	ErrAPIKeyCannotManageAccess = errors.New("La llave de API no puede administrar accesos")
+)
+
+// apiKeyPrefix identifica las llaves emitidas por este servicio
+const apiKeyPrefix = "ak_"
+
+// Solo se registra el último uso si pasó al menos este tiempo, para no
+// escribir en cada petición
+const apiKeyLastUsedGranularity = time.Minute
+
+type SystemAPIKeyService struct {
+	repo *repositories.SystemAPIKeyRepository
+}
+
+func NewSystemAPIKeyService(repo *repositories.SystemAPIKeyRepository) *SystemAPIKeyService {
+	return &SystemAPIKeyService{repo: repo}
+}
+
+func (s *SystemAPIKeyService) GetSystemAPIKeys(systemID uint64) ([]domain.SystemAPIKey, error) {
+	return s.repo.GetBySystem(systemID)
+}
+
+// CreateAPIKey genera una nueva llave para el sistema. La llave en texto plano
+// solo se devuelve aquí; se guarda únicamente su hash. Con manageAccess la
+// llave también puede asignar roles y quitar permisos.
+func (s *SystemAPIKeyService) CreateAPIKey(systemID uint64, name string, manageAccess bool) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("El nombre de la llave es requerido")
+	}
+
+	plain, err := newAPIKey()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := s.repo.Create(&domain.SystemAPIKey{
+		SystemID:     uint(systemID),
+		Name:         name,
+		Prefix:       plain[:len(apiKeyPrefix)+6],
+		KeyHash:      utils.HashToken(plain),
+		RotatedAt:    now,
+		Created:      now,
+		ManageAccess: manageAccess,
+	}); err != nil {
+		return "", err
+	}
+
+	return plain, nil
+}
+
+// RotateAPIKey reemplaza el valor de una llave vigente; el anterior deja de funcionar
+func (s *SystemAPIKeyService) RotateAPIKey(systemID, id uint64) (string, error) {
+	key, err := s.repo.GetBySystemAndID(systemID, id)
+	if err != nil {
+		return "", err
+	}
+	if key.RevokedAt != nil {
+		return "", errors.New("No se puede rotar una llave revocada")
+	}
+
+	plain, err := newAPIKey()
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.UpdateKey(key.ID, plain[:len(apiKeyPrefix)+6], utils.HashToken(plain), time.Now()); err != nil {
+		return "", err
+	}
+
+	return plain, nil
+}
+
+// RevokeAPIKey revoca la llave; se conserva para consultar su último uso
+func (s *SystemAPIKeyService) RevokeAPIKey(systemID, id uint64) error {
+	key, err := s.repo.GetBySystemAndID(systemID, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Revoke(key.ID, time.Now())
+}
+
+// ValidateAPIKey devuelve el sistema dueño de una llave vigente
+func (s *SystemAPIKeyService) ValidateAPIKey(plain string) (uint, error) {
+	key, err := s.validate(plain)
+	if err != nil {
+		return 0, err
+	}
+	return key.SystemID, nil
+}
+
+// ValidateAccessManagerKey devuelve el sistema dueño de una llave vigente
+// creada para administrar accesos
+func (s *SystemAPIKeyService) ValidateAccessManagerKey(plain string) (uint, error) {
+	key, err := s.validate(plain)
+	if err != nil {
+		return 0, err
+	}
+	if !key.ManageAccess {
+		return 0, ErrAPIKeyCannotManageAccess
+	}
+	return key.SystemID, nil
+}
+
+func (s *SystemAPIKeyService) validate(plain string) (domain.SystemAPIKey, error) {
+	if !strings.HasPrefix(plain, apiKeyPrefix) {
+		return domain.SystemAPIKey{}, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetByHash(utils.HashToken(plain))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.SystemAPIKey{}, ErrInvalidAPIKey
+		}
+		return domain.SystemAPIKey{}, err
+	}
+	if key.RevokedAt != nil {
+		return domain.SystemAPIKey{}, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyLastUsedGranularity {
+		if err := s.repo.UpdateLastUsed(key.ID, now); err != nil {
+			log.Printf("No se pudo registrar el uso de la llave %s: %v", key.Prefix, err)
+		}
+	}
+
+	return key, nil
+}
+
+func newAPIKey() (string, error) {
+	token, err := utils.SecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + token, nil
+}
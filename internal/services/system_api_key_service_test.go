@@ -0,0 +1,82 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/testutil"
+	"errors"
+	"testing"
+	"time"
+)
+
+// revokeAPIKey revoca la clave del sistema 1 con ese nombre
+func revokeAPIKey(t *testing.T, service *SystemAPIKeyService, name string) {
+	t.Helper()
+	keys, err := service.GetSystemAPIKeys(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if key.Name == name {
+			if err := service.RevokeAPIKey(1, uint64(key.ID)); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+	}
+	t.Fatalf("no existe la clave %q", name)
+}
+
+func TestSystemAPIKeyServiceValidateAccessManagerKey(t *testing.T) {
+	db := testutil.NewDB(t)
+	now := time.Now()
+	if err := db.Create(&domain.System{Name: "Uno", Created: now, Updated: now}).Error; err != nil {
+		t.Fatal(err)
+	}
+	service := NewSystemAPIKeyService(repositories.NewSystemAPIKeyRepository(db))
+
+	readKey, err := service.CreateAPIKey(1, "lectura", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	managerKey, err := service.CreateAPIKey(1, "administración", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedKey, err := service.CreateAPIKey(1, "revocada", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokeAPIKey(t, service, "revocada")
+
+	tests := []struct {
+		name       string
+		key        string
+		wantAPI    error
+		wantManage error
+	}{
+		{name: "solo consulta", key: readKey, wantManage: ErrAPIKeyCannotManageAccess},
+		{name: "administra accesos", key: managerKey},
+		{name: "revocada", key: revokedKey, wantAPI: ErrInvalidAPIKey, wantManage: ErrInvalidAPIKey},
+		{name: "desconocida", key: "ak_desconocida", wantAPI: ErrInvalidAPIKey, wantManage: ErrInvalidAPIKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			systemID, err := service.ValidateAPIKey(tt.key)
+			if !errors.Is(err, tt.wantAPI) {
+				t.Fatalf("ValidateAPIKey: err = %v, se esperaba %v", err, tt.wantAPI)
+			}
+			if err == nil && systemID != 1 {
+				t.Fatalf("ValidateAPIKey: sistema %d, se esperaba 1", systemID)
+			}
+
+			systemID, err = service.ValidateAccessManagerKey(tt.key)
+			if !errors.Is(err, tt.wantManage) {
+				t.Fatalf("ValidateAccessManagerKey: err = %v, se esperaba %v", err, tt.wantManage)
+			}
+			if err == nil && systemID != 1 {
+				t.Fatalf("ValidateAccessManagerKey: sistema %d, se esperaba 1", systemID)
+			}
+		})
+	}
+}
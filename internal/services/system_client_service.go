@@ -0,0 +1,200 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/responses"
+	"accessv2/pkg/utils"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidClientCredentials = errors.New("Credenciales de cliente inválidas")
+
+// SystemClientService administra las credenciales client_credentials de los
+// sistemas y emite sus tokens
+type SystemClientService struct {
+	repo         *repositories.SystemClientRepository
+	tokenService *TokenService
+}
+
+func NewSystemClientService(repo *repositories.SystemClientRepository, tokenService *TokenService) *SystemClientService {
+	return &SystemClientService{repo: repo, tokenService: tokenService}
+}
+
+func (s *SystemClientService) GetSystemClients(systemID uint64) ([]domain.SystemClient, error) {
+	return s.repo.GetBySystem(systemID)
+}
+
+func (s *SystemClientService) GetSystemClient(systemID, id uint64) (domain.SystemClient, error) {
+	return s.repo.GetBySystemAndID(systemID, id)
+}
+
+// CreateClient genera un nuevo par client_id/secret. El secreto en texto plano
+// solo se devuelve aquí; se guarda únicamente su hash.
+func (s *SystemClientService) CreateClient(systemID uint64, name string) (domain.SystemClient, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return domain.SystemClient{}, "", errors.New("El nombre del cliente es requerido")
+	}
+
+	clientID, err := utils.SecureToken(18)
+	if err != nil {
+		return domain.SystemClient{}, "", err
+	}
+	secret, err := utils.SecureToken(32)
+	if err != nil {
+		return domain.SystemClient{}, "", err
+	}
+
+	now := time.Now()
+	client := domain.SystemClient{
+		SystemID:        uint(systemID),
+		Name:            name,
+		ClientID:        clientID,
+		SecretHash:      utils.HashToken(secret),
+		SecretRotatedAt: now,
+		Created:         now,
+	}
+	if err := s.repo.Create(&client); err != nil {
+		return domain.SystemClient{}, "", err
+	}
+
+	return client, secret, nil
+}
+
+// RotateSecret reemplaza el secreto del cliente; el anterior deja de ser válido
+func (s *SystemClientService) RotateSecret(systemID, id uint64) (domain.SystemClient, string, error) {
+	client, err := s.repo.GetBySystemAndID(systemID, id)
+	if err != nil {
+		return domain.SystemClient{}, "", err
+	}
+
+	secret, err := utils.SecureToken(32)
+	if err != nil {
+		return domain.SystemClient{}, "", err
+	}
+	if err := s.repo.UpdateSecret(client.ID, utils.HashToken(secret), time.Now()); err != nil {
+		return domain.SystemClient{}, "", err
+	}
+
+	return client, secret, nil
+}
+
+// DeleteClient elimina el cliente y revoca los tokens que tenga vigentes
+func (s *SystemClientService) DeleteClient(systemID, id uint64) error {
+	client, err := s.repo.GetBySystemAndID(systemID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.tokenService.RevokeClientTokens(client.ID, RevokeReasonClientDeleted); err != nil {
+		return err
+	}
+	return s.repo.Delete(client.ID)
+}
+
+// GetClientPermissions agrupa por sistema y rol todos los permisos, marcando
+// los otorgados al cliente
+func (s *SystemClientService) GetClientPermissions(client domain.SystemClient) ([]domain.SystemWithRolePermissions, error) {
+	rows, err := s.repo.GetPermissionMatrix(client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var systems []domain.SystemWithRolePermissions
+	systemIndex := make(map[uint]int)
+	roleIndex := make(map[uint]int)
+
+	for _, row := range rows {
+		si, ok := systemIndex[row.SystemID]
+		if !ok {
+			systems = append(systems, domain.SystemWithRolePermissions{ID: row.SystemID, Name: row.SystemName})
+			si = len(systems) - 1
+			systemIndex[row.SystemID] = si
+		}
+
+		ri, ok := roleIndex[row.RoleID]
+		if !ok {
+			systems[si].Roles = append(systems[si].Roles, domain.RoleWithPermissions{ID: row.RoleID, Name: row.RoleName})
+			ri = len(systems[si].Roles) - 1
+			roleIndex[row.RoleID] = ri
+		}
+
+		role := &systems[si].Roles[ri]
+		role.Permissions = append(role.Permissions, domain.UserPermission{
+			ID:         row.PermissionID,
+			Name:       row.PermissionName,
+			IsAssigned: row.IsAssigned,
+		})
+	}
+
+	return systems, nil
+}
+
+// SetClientPermissions reemplaza los permisos otorgados al cliente
+func (s *SystemClientService) SetClientPermissions(client domain.SystemClient, permissionIDs []uint64) error {
+	return s.repo.ReplacePermissions(client.ID, permissionIDs)
+}
+
+// Authenticate valida el client_id y el secreto presentados
+func (s *SystemClientService) Authenticate(clientID, secret string) (domain.SystemClient, error) {
+	if clientID == "" || secret == "" {
+		return domain.SystemClient{}, ErrInvalidClientCredentials
+	}
+
+	client, err := s.repo.GetByClientID(clientID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.SystemClient{}, ErrInvalidClientCredentials
+		}
+		return domain.SystemClient{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(utils.HashToken(secret))) != 1 {
+		return domain.SystemClient{}, ErrInvalidClientCredentials
+	}
+
+	if err := s.repo.UpdateLastUsed(client.ID, time.Now()); err != nil {
+		log.Printf("No se pudo registrar el uso del cliente %s: %v", client.ClientID, err)
+	}
+
+	return client, nil
+}
+
+// IssueToken emite un access token para el cliente. Con audience se indica el
+// sistema destino; por defecto es el sistema dueño del cliente.
+func (s *SystemClientService) IssueToken(client domain.SystemClient, audience string) (responses.OAuthTokenResponse, error) {
+	systemID := uint64(client.SystemID)
+	if audience != "" {
+		target, err := strconv.ParseUint(audience, 10, 64)
+		if err != nil {
+			return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidTarget, "audience debe ser el ID de un sistema")
+		}
+		systemID = target
+	}
+
+	access, err := s.repo.GetNestedPermissionsBySystem(client.ID, systemID)
+	if err != nil {
+		return responses.OAuthTokenResponse{}, err
+	}
+	if systemID != uint64(client.SystemID) && len(access.Roles) == 0 {
+		return responses.OAuthTokenResponse{}, newOAuthError(OAuthInvalidTarget, "El cliente no tiene permisos en el sistema indicado")
+	}
+
+	token, expiresAt, err := s.tokenService.IssueClientToken(client, systemID, access.Roles)
+	if err != nil {
+		return responses.OAuthTokenResponse{}, err
+	}
+
+	return responses.OAuthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   expiresIn(expiresAt),
+	}, nil
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/forms"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/ldapauth"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLDAPDirectoryNotFound = errors.New("El directorio no existe")
+	ErrLDAPEntryNotFound     = errors.New("El usuario no existe en el directorio")
+	ErrLDAPEntryLinked       = errors.New("La entrada del directorio ya está vinculada a otro usuario")
+)
+
+// SystemLDAPService administra el backend de autenticación y el directorio
+// propio de cada sistema, y los vínculos de los usuarios con las entradas del
+// directorio
+type SystemLDAPService struct {
+	defaultBackend string
+	global         ldapauth.Config
+	dial           ldapauth.Dialer
+	systemRepo     *repositories.SystemRepository
+	settingsRepo   *repositories.SystemLDAPSettingsRepository
+	userRepo       *repositories.UserRepository
+}
+
+// NewSystemLDAPService crea el servicio; dial es la conexión al directorio, como
+// en NewLDAPAuthenticator
+func NewSystemLDAPService(defaultBackend string, global ldapauth.Config, dial ldapauth.Dialer, systemRepo *repositories.SystemRepository, settingsRepo *repositories.SystemLDAPSettingsRepository, userRepo *repositories.UserRepository) *SystemLDAPService {
+	return &SystemLDAPService{
+		defaultBackend: defaultBackend,
+		global:         global,
+		dial:           dial,
+		systemRepo:     systemRepo,
+		settingsRepo:   settingsRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// DefaultBackend devuelve el backend global
+func (s *SystemLDAPService) DefaultBackend() string {
+	return s.defaultBackend
+}
+
+// GlobalURL devuelve la URL del directorio global, vacía si no hay uno
+func (s *SystemLDAPService) GlobalURL() string {
+	return s.global.URL
+}
+
+// GetSettings devuelve el directorio propio del sistema, o nil si usa el global
+func (s *SystemLDAPService) GetSettings(systemID uint64) (*domain.SystemLDAPSettings, error) {
+	settings, err := s.settingsRepo.GetBySystem(uint(systemID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Save guarda el backend del sistema y su directorio. Sin URL se elimina el
+// directorio propio y se usa el global.
+func (s *SystemLDAPService) Save(systemID uint64, form forms.SystemLDAPForm) error {
+	backend := strings.TrimSpace(form.AuthBackend)
+	if backend != "" && backend != AuthBackendLocal && backend != AuthBackendLDAP {
+		return errors.New("Backend de autenticación inválido")
+	}
+
+	ldapURL := strings.TrimSpace(form.URL)
+	usesLDAP := backend == AuthBackendLDAP || (backend == "" && s.defaultBackend == AuthBackendLDAP)
+	if usesLDAP && ldapURL == "" && s.global.URL == "" {
+		return ErrLDAPNotConfigured
+	}
+	if ldapURL == "" {
+		if err := s.settingsRepo.Delete(uint(systemID)); err != nil {
+			return err
+		}
+		return s.systemRepo.UpdateAuthBackend(systemID, backend)
+	}
+
+	if !strings.HasPrefix(ldapURL, "ldap://") && !strings.HasPrefix(ldapURL, "ldaps://") {
+		return errors.New("La URL del directorio debe comenzar con ldap:// o ldaps://")
+	}
+	if strings.TrimSpace(form.BaseDN) == "" {
+		return errors.New("El DN base del directorio es requerido")
+	}
+	userFilter := strings.TrimSpace(form.UserFilter)
+	if userFilter == "" {
+		userFilter = "(uid=%s)"
+	}
+	if strings.Count(userFilter, "%s") != 1 {
+		return errors.New("El filtro de usuarios debe contener %s una sola vez")
+	}
+
+	now := time.Now()
+	settings, err := s.GetSettings(systemID)
+	if err != nil {
+		return err
+	}
+	if settings == nil {
+		settings = &domain.SystemLDAPSettings{SystemID: uint(systemID), Created: now}
+	}
+
+	settings.URL = ldapURL
+	settings.StartTLS = form.StartTLS
+	settings.InsecureSkipVerify = form.InsecureSkipVerify
+	settings.BindDN = strings.TrimSpace(form.BindDN)
+	if form.BindPassword != "" {
+		settings.BindPassword = form.BindPassword
+	}
+	settings.BaseDN = strings.TrimSpace(form.BaseDN)
+	settings.UserFilter = userFilter
+	settings.UsernameAttribute = defaultString(strings.TrimSpace(form.UsernameAttribute), "uid")
+	settings.EmailAttribute = defaultString(strings.TrimSpace(form.EmailAttribute), "mail")
+	settings.Updated = now
+
+	if err := s.settingsRepo.Save(settings); err != nil {
+		return err
+	}
+	return s.systemRepo.UpdateAuthBackend(systemID, backend)
+}
+
+// Directories lista los directorios a los que se puede vincular un usuario
+func (s *SystemLDAPService) Directories() ([]domain.LDAPDirectory, error) {
+	var directories []domain.LDAPDirectory
+	if s.global.URL != "" {
+		directories = append(directories, domain.LDAPDirectory{Key: GlobalDirectory, Name: "Directorio global"})
+	}
+
+	systems, err := s.settingsRepo.GetSystems()
+	if err != nil {
+		return nil, err
+	}
+	for _, system := range systems {
+		directories = append(directories, domain.LDAPDirectory{Key: SystemDirectory(uint64(system.ID)), Name: "Directorio de " + system.Name})
+	}
+	return directories, nil
+}
+
+// LinkUser vincula al usuario local con la entrada del directorio que
+// corresponde a login. Desde entonces ingresa con la contraseña del directorio
+// en los sistemas que lo usan; su contraseña local no cambia.
+func (s *SystemLDAPService) LinkUser(userID uint, directoryKey, login string) error {
+	cfg, err := s.directoryConfig(directoryKey)
+	if err != nil {
+		return err
+	}
+	directory, err := ldapauth.New(cfg, s.dial)
+	if err != nil {
+		if errors.Is(err, ldapauth.ErrNotConfigured) {
+			return ErrLDAPDirectoryNotFound
+		}
+		return err
+	}
+
+	entry, err := directory.Lookup(strings.TrimSpace(login))
+	if err != nil {
+		if errors.Is(err, ldapauth.ErrUserNotFound) {
+			return ErrLDAPEntryNotFound
+		}
+		return err
+	}
+
+	externalID := ExternalID(directoryKey, entry.DN)
+	linked, err := s.userRepo.GetByExternalID(externalID)
+	if err == nil {
+		if linked.ID == userID {
+			return nil
+		}
+		return ErrLDAPEntryLinked
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return s.userRepo.LinkExternal(userID, externalID)
+}
+
+// UnlinkUser quita el vínculo del usuario con el directorio
+func (s *SystemLDAPService) UnlinkUser(userID uint) error {
+	return s.userRepo.UnlinkExternal(userID)
+}
+
+// directoryConfig devuelve la configuración del directorio identificado por
+// GlobalDirectory o SystemDirectory
+func (s *SystemLDAPService) directoryConfig(directoryKey string) (ldapauth.Config, error) {
+	if directoryKey == GlobalDirectory {
+		return s.global, nil
+	}
+
+	systemID, err := strconv.ParseUint(strings.TrimPrefix(directoryKey, "system-"), 10, 64)
+	if err != nil || !strings.HasPrefix(directoryKey, "system-") {
+		return ldapauth.Config{}, ErrLDAPDirectoryNotFound
+	}
+	settings, err := s.settingsRepo.GetBySystem(uint(systemID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ldapauth.Config{}, ErrLDAPDirectoryNotFound
+		}
+		return ldapauth.Config{}, err
+	}
+	return settingsConfig(settings, s.global.Timeout), nil
+}
+
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -49,6 +49,7 @@ func (s *SystemService) CreateSystem(input *forms.SystemCreateInput) (*domain.Sy
 		Name:        input.Name,
 		Description: input.Description,
 		Repository:  input.Repository,
+		MFARequired: input.MFARequired,
 		Created:     input.Created,
 		Updated:     input.Updated,
 	}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"accessv2/internal/forms"
+	"accessv2/internal/repositories"
+	"accessv2/pkg/utils"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Límites de la configuración de tokens de un sistema
+const (
+	MaxSystemAccessTTL = 24 * time.Hour
+	MinSystemAccessTTL = time.Minute
+	MaxSystemClockSkew = 5 * time.Minute
+)
+
+// SystemTokenService administra la configuración de los tokens de cada sistema:
+// emisor, audiencia, vigencia, tolerancia de reloj y secreto de firma propio
+type SystemTokenService struct {
+	tokenService *TokenService
+	systemRepo   *repositories.SystemRepository
+}
+
+func NewSystemTokenService(tokenService *TokenService, systemRepo *repositories.SystemRepository) *SystemTokenService {
+	return &SystemTokenService{tokenService: tokenService, systemRepo: systemRepo}
+}
+
+// Defaults devuelve el emisor y la vigencia globales
+func (s *SystemTokenService) Defaults() (string, time.Duration) {
+	return s.tokenService.Defaults()
+}
+
+// Save valida y guarda la configuración. La audiencia no puede coincidir con
+// la de otro sistema, porque sus tokens serían intercambiables.
+func (s *SystemTokenService) Save(systemID uint64, form forms.SystemTokenForm) error {
+	issuer := strings.TrimSpace(form.Issuer)
+	audience := strings.TrimSpace(form.Audience)
+
+	if len(issuer) > 255 || len(audience) > 255 {
+		return errors.New("El emisor y la audiencia admiten hasta 255 caracteres")
+	}
+	if strings.ContainsAny(audience, " \t") {
+		return errors.New("La audiencia no puede contener espacios")
+	}
+
+	ttl := time.Duration(form.AccessTokenTTL) * time.Second
+	if form.AccessTokenTTL != 0 && (ttl < MinSystemAccessTTL || ttl > MaxSystemAccessTTL) {
+		return fmt.Errorf("La vigencia del access token debe estar entre %d y %d segundos", int(MinSystemAccessTTL.Seconds()), int(MaxSystemAccessTTL.Seconds()))
+	}
+	skew := time.Duration(form.ClockSkew) * time.Second
+	if skew < 0 || skew > MaxSystemClockSkew {
+		return fmt.Errorf("La tolerancia de reloj debe estar entre 0 y %d segundos", int(MaxSystemClockSkew.Seconds()))
+	}
+
+	if audience != "" {
+		// Los IDs son la audiencia por defecto de cada sistema
+		if _, err := strconv.ParseUint(audience, 10, 64); err == nil && audience != strconv.FormatUint(systemID, 10) {
+			return errors.New("Una audiencia numérica solo puede ser el ID del propio sistema")
+		}
+		inUse, err := s.systemRepo.TokenAudienceInUse(audience, systemID)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return errors.New("Otro sistema ya usa esa audiencia")
+		}
+	}
+
+	return s.systemRepo.UpdateTokenSettings(systemID, issuer, audience, form.AccessTokenTTL, form.ClockSkew)
+}
+
+// RotateSecret genera un nuevo secreto HS256 para el sistema y lo devuelve para
+// mostrarlo una sola vez. Los tokens firmados con el anterior dejan de validar.
+func (s *SystemTokenService) RotateSecret(systemID uint64) (string, error) {
+	if _, err := s.systemRepo.GetByID(systemID); err != nil {
+		return "", err
+	}
+	secret, err := utils.SecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err := s.systemRepo.UpdateTokenSecret(systemID, secret); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// DeleteSecret vuelve a firmar los tokens del sistema con las llaves globales
+func (s *SystemTokenService) DeleteSecret(systemID uint64) error {
+	return s.systemRepo.UpdateTokenSecret(systemID, "")
+}
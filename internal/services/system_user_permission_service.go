@@ -33,16 +33,24 @@ func (s *UserPermissionService) GetUserRolesAndPermissions(systemID uint64, user
 	for _, p := range flatPermissions {
 		if _, exists := rolesMap[uint64(p.RoleID)]; !exists {
 			rolesMap[uint64(p.RoleID)] = &domain.RoleWithPermissions{
-				ID:   p.RoleID,
-				Name: p.RoleName,
+				ID:       p.RoleID,
+				Name:     p.RoleName,
+				Assigned: p.RoleAssigned,
 			}
 		}
 
+		// Con el rol asignado, el usuario tiene todos sus permisos salvo los excluidos
+		isAssigned := p.IsAssigned
+		if p.RoleAssigned {
+			isAssigned = !p.IsExcluded
+		}
+
 		role := rolesMap[uint64(p.RoleID)]
 		role.Permissions = append(role.Permissions, domain.UserPermission{
 			ID:         p.PermissionID,
 			Name:       p.PermissionName,
-			IsAssigned: p.IsAssigned,
+			IsAssigned: isAssigned,
+			Groups:     p.GroupNames,
 		})
 	}
 
@@ -54,6 +62,33 @@ func (s *UserPermissionService) GetUserRolesAndPermissions(systemID uint64, user
 	return result, nil
 }
 
+// GetEffectivePermissions devuelve los roles del usuario con sus permisos
+// vigentes, incluidos los heredados de los roles ancestros
+func (s *UserPermissionService) GetEffectivePermissions(systemID uint64, userID uint64) ([]domain.RoleWithPermissions, error) {
+	flatPermissions, err := s.repo.GetEffectivePermissions(systemID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []domain.RoleWithPermissions{}
+	index := make(map[uint64]int)
+	for _, p := range flatPermissions {
+		i, exists := index[p.RoleID]
+		if !exists {
+			i = len(result)
+			index[p.RoleID] = i
+			result = append(result, domain.RoleWithPermissions{ID: uint(p.RoleID), Name: p.RoleName})
+		}
+		result[i].Permissions = append(result[i].Permissions, domain.UserPermission{
+			ID:         uint(p.PermissionID),
+			Name:       p.PermissionName,
+			IsAssigned: true,
+		})
+	}
+
+	return result, nil
+}
+
 func (s *UserPermissionService) AssociatePermissions(systemID uint, userID uint, roleID uint, permissionIDs []uint64) error {
 	// Eliminar los permisos previos que no están en la lista de permisos seleccionados
 	if err := s.repo.DeletePermissions(systemID, userID, roleID); err != nil {
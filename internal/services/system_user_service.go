@@ -4,6 +4,7 @@ import (
 	"accessv2/internal/domain"
 	"accessv2/internal/repositories"
 	"errors"
+	"log"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,15 +12,17 @@ import (
 
 // SystemUserService es la implementación del servicio.
 type SystemUserService struct {
-	repo *repositories.SystemUserRepository
-	db   *gorm.DB
+	repo         *repositories.SystemUserRepository
+	db           *gorm.DB
+	tokenService *TokenService
 }
 
 // NewSystemUserService crea una nueva instancia del servicio.
-func NewSystemUserService(db *gorm.DB, repo *repositories.SystemUserRepository) *SystemUserService {
+func NewSystemUserService(db *gorm.DB, repo *repositories.SystemUserRepository, tokenService *TokenService) *SystemUserService {
 	return &SystemUserService{
-		db:   db,
-		repo: repo,
+		db:           db,
+		repo:         repo,
+		tokenService: tokenService,
 	}
 }
 
@@ -36,6 +39,9 @@ func (s *SystemUserService) SaveSystemUsers(systemID uint, items []domain.System
 	// si ocurre un error o si la función retorna antes del Commit.
 	defer tx.Rollback()
 
+	// Usuarios desasociados, para revocar sus tokens luego del Commit
+	var removedUserIDs []uint
+
 	// 3. Iterar sobre los elementos recibidos.
 	for _, item := range items {
 		// Llamar al repositorio para buscar una relación existente dentro de la transacción.
@@ -67,6 +73,7 @@ func (s *SystemUserService) SaveSystemUsers(systemID uint, items []domain.System
 				if err := s.repo.DeleteSystemUser(tx, systemID, uint(item.ID)); err != nil {
 					return err
 				}
+				removedUserIDs = append(removedUserIDs, uint(item.ID))
 			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 				// Si es un error de la base de datos (que no sea "registro no encontrado"), lo retornamos.
 				return err
@@ -76,5 +83,16 @@ func (s *SystemUserService) SaveSystemUsers(systemID uint, items []domain.System
 	}
 
 	// 4. Si el bucle finaliza sin errores, se confirma la transacción.
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	// 5. Revocar los tokens emitidos para el sistema a los usuarios desasociados.
+	for _, userID := range removedUserIDs {
+		if err := s.tokenService.RevokeUserTokens(userID, uint64(systemID), RevokeReasonSystemAccessLost); err != nil {
+			log.Printf("No se pudieron revocar los tokens del usuario %d en el sistema %d: %v", userID, systemID, err)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,644 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/responses"
+	"accessv2/pkg/jwks"
+	"accessv2/pkg/utils"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("Refresh token inválido o expirado")
+	ErrRefreshTokenReused  = errors.New("Refresh token reutilizado, se revocó la sesión")
+	ErrInvalidAccessToken  = errors.New("Token inválido o expirado")
+
+	ErrImpersonationTokenNotAllowed = errors.New("Un token de suplantación no permite esta operación")
+)
+
+// Motivos de revocación registrados en access_tokens.revoke_reason
+const (
+	RevokeReasonSignOut          = "sign_out"
+	RevokeReasonRevoked          = "revoked"
+	RevokeReasonUserDeactivated  = "user_deactivated"
+	RevokeReasonUserDeleted      = "user_deleted"
+	RevokeReasonSystemAccessLost = "system_access_removed"
+	RevokeReasonClientDeleted    = "client_deleted"
+	RevokeReasonPasswordReset    = "password_reset"
+	RevokeReasonPasswordChanged  = "password_changed"
+	RevokeReasonImpersonationEnd = "impersonation_ended"
+)
+
+// TokenConfig agrupa la configuración para emitir tokens. Issuer y AccessTTL
+// son los valores por defecto de los sistemas sin configuración propia.
+type TokenConfig struct {
+	Issuer     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+type TokenService struct {
+	cfg         TokenConfig
+	keyService  *KeyService
+	userRepo    *repositories.UserRepository
+	systemRepo  *repositories.SystemRepository
+	clientRepo  *repositories.SystemClientRepository
+	refreshRepo *repositories.RefreshTokenRepository
+	accessRepo  *repositories.AccessTokenRepository
+}
+
+func NewTokenService(cfg TokenConfig, keyService *KeyService, userRepo *repositories.UserRepository, systemRepo *repositories.SystemRepository, clientRepo *repositories.SystemClientRepository, refreshRepo *repositories.RefreshTokenRepository, accessRepo *repositories.AccessTokenRepository) *TokenService {
+	return &TokenService{
+		cfg:         cfg,
+		keyService:  keyService,
+		userRepo:    userRepo,
+		systemRepo:  systemRepo,
+		clientRepo:  clientRepo,
+		refreshRepo: refreshRepo,
+		accessRepo:  accessRepo,
+	}
+}
+
+// systemTokenSettings es la configuración efectiva de los tokens de un sistema
+type systemTokenSettings struct {
+	issuer    string
+	audience  string
+	accessTTL time.Duration
+	clockSkew time.Duration
+	secret    string // vacío firma con las llaves globales
+}
+
+// tokenSettings combina la configuración del sistema con la global
+func (s *TokenService) tokenSettings(systemID uint64) (systemTokenSettings, error) {
+	system, err := s.systemRepo.GetByID(systemID)
+	if err != nil {
+		return systemTokenSettings{}, err
+	}
+
+	settings := systemTokenSettings{
+		issuer:    defaultString(system.TokenIssuer, s.cfg.Issuer),
+		audience:  system.Audience(),
+		accessTTL: s.cfg.AccessTTL,
+		clockSkew: time.Duration(system.TokenClockSkew) * time.Second,
+		secret:    system.TokenSecret,
+	}
+	if system.AccessTokenTTL > 0 {
+		settings.accessTTL = time.Duration(system.AccessTokenTTL) * time.Second
+	}
+	return settings, nil
+}
+
+// sign firma los claims con el secreto del sistema o, si no tiene, con la llave global
+func (s *TokenService) sign(claims jwt.Claims, settings systemTokenSettings) (string, error) {
+	if settings.secret != "" {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(settings.secret))
+	}
+	return s.keyService.Sign(claims)
+}
+
+// IssueAccessToken firma un JWT de corta duración con los roles del usuario en el sistema
+func (s *TokenService) IssueAccessToken(user domain.User, systemID uint64, roles []*responses.RoleAccess) (string, time.Time, error) {
+	jti, err := utils.SecureToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return s.issueUserToken(jti, user, systemID, roles, 0, nil)
+}
+
+// IssueImpersonationToken firma un access token del usuario con el claim act
+// del administrador que lo suplanta. No se emite refresh token: al vencer, la
+// suplantación termina. jti lo genera quien registra la suplantación; ttl
+// reemplaza la vigencia configurada en el sistema.
+func (s *TokenService) IssueImpersonationToken(jti string, user domain.User, systemID uint64, roles []*responses.RoleAccess, ttl time.Duration, actor responses.ActorClaim) (string, time.Time, error) {
+	return s.issueUserToken(jti, user, systemID, roles, ttl, &actor)
+}
+
+// issueUserToken firma el access token del usuario con la configuración del
+// sistema; ttl en 0 usa la vigencia del sistema
+func (s *TokenService) issueUserToken(jti string, user domain.User, systemID uint64, roles []*responses.RoleAccess, ttl time.Duration, actor *responses.ActorClaim) (string, time.Time, error) {
+	settings, err := s.tokenSettings(systemID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if ttl == 0 {
+		ttl = settings.accessTTL
+	}
+
+	now := time.Now()
+	expirationTime := now.Add(ttl)
+	claims := &responses.CustomClaims{
+		UserID:   uint64(user.ID),
+		Username: user.Username,
+		Email:    user.Email,
+		SystemID: systemID,
+		Act:      actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Audience:  jwt.ClaimStrings{settings.audience},
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    settings.issuer,
+		},
+		Roles: roles,
+	}
+
+	tokenString, err := s.sign(claims, settings)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("Error al generar token: %w", err)
+	}
+
+	// Registrar el jti para poder revocarlo más adelante
+	userID := user.ID
+	if err := s.accessRepo.Create(&domain.AccessToken{
+		JTI:       jti,
+		UserID:    &userID,
+		SystemID:  uint(systemID),
+		ExpiresAt: expirationTime,
+		Created:   now,
+	}); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expirationTime, nil
+}
+
+// IssueClientToken firma un JWT para un cliente (grant client_credentials) con
+// los permisos que tiene otorgados en el sistema destino
+func (s *TokenService) IssueClientToken(client domain.SystemClient, systemID uint64, roles []*responses.RoleAccess) (string, time.Time, error) {
+	jti, err := utils.SecureToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	settings, err := s.tokenSettings(systemID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expirationTime := now.Add(settings.accessTTL)
+	claims := &responses.CustomClaims{
+		SystemID: systemID,
+		ClientID: client.ClientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   "client:" + client.ClientID,
+			Audience:  jwt.ClaimStrings{settings.audience},
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    settings.issuer,
+		},
+		Roles: roles,
+	}
+
+	tokenString, err := s.sign(claims, settings)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("Error al generar token: %w", err)
+	}
+
+	clientID := client.ID
+	if err := s.accessRepo.Create(&domain.AccessToken{
+		JTI:            jti,
+		SystemClientID: &clientID,
+		SystemID:       uint(systemID),
+		ExpiresAt:      expirationTime,
+		Created:        now,
+	}); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expirationTime, nil
+}
+
+// Defaults devuelve el emisor y la vigencia de los sistemas sin configuración propia
+func (s *TokenService) Defaults() (string, time.Duration) {
+	return s.cfg.Issuer, s.cfg.AccessTTL
+}
+
+// expiresIn son los segundos que le quedan a un token que vence en expiresAt
+func expiresIn(expiresAt time.Time) int64 {
+	return int64(time.Until(expiresAt).Round(time.Second).Seconds())
+}
+
+// IssueRefreshToken crea un refresh token ligado al usuario y al sistema. Si
+// familyID está vacío se inicia una nueva familia (nuevo inicio de sesión).
+func (s *TokenService) IssueRefreshToken(userID uint, systemID uint64, familyID string) (string, error) {
+	if familyID == "" {
+		id, err := utils.SecureToken(24)
+		if err != nil {
+			return "", err
+		}
+		familyID = id
+	}
+
+	plain, err := utils.SecureToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := &domain.RefreshToken{
+		TokenHash: utils.HashToken(plain),
+		FamilyID:  familyID,
+		UserID:    userID,
+		SystemID:  uint(systemID),
+		ExpiresAt: now.Add(s.cfg.RefreshTTL),
+		Created:   now,
+	}
+	if err := s.refreshRepo.Create(token); err != nil {
+		return "", err
+	}
+
+	return plain, nil
+}
+
+// IssueTokens emite el par access/refresh para un usuario ya autenticado
+func (s *TokenService) IssueTokens(user domain.User, systemID uint64, roles []*responses.RoleAccess, familyID string) (responses.UserWithAccess, error) {
+	accessToken, expiresAt, err := s.IssueAccessToken(user, systemID, roles)
+	if err != nil {
+		return responses.UserWithAccess{}, err
+	}
+
+	refreshToken, err := s.IssueRefreshToken(user.ID, systemID, familyID)
+	if err != nil {
+		return responses.UserWithAccess{}, fmt.Errorf("Error al generar refresh token: %w", err)
+	}
+
+	return responses.UserWithAccess{
+		User: responses.UserAccess{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+		},
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    expiresIn(expiresAt),
+		Roles:        roles,
+	}, nil
+}
+
+// Refresh consume un refresh token y emite un nuevo par de tokens con los roles
+// vigentes del usuario. Reutilizar un token ya consumido revoca toda su familia.
+func (s *TokenService) Refresh(systemID uint64, refreshToken string) (responses.UserWithAccess, error) {
+	stored, err := s.refreshRepo.GetByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.UserWithAccess{}, ErrInvalidRefreshToken
+		}
+		return responses.UserWithAccess{}, err
+	}
+	// Un sistema no puede consumir los refresh tokens de otro
+	if uint64(stored.SystemID) != systemID {
+		return responses.UserWithAccess{}, ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+
+	if stored.RevokedAt != nil {
+		return responses.UserWithAccess{}, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return responses.UserWithAccess{}, s.revokeReusedFamily(stored.FamilyID, now)
+	}
+	if now.After(stored.ExpiresAt) {
+		return responses.UserWithAccess{}, ErrInvalidRefreshToken
+	}
+
+	marked, err := s.refreshRepo.MarkUsed(stored.ID, now)
+	if err != nil {
+		return responses.UserWithAccess{}, err
+	}
+	if !marked {
+		// Otra petición consumió el token al mismo tiempo
+		return responses.UserWithAccess{}, s.revokeReusedFamily(stored.FamilyID, now)
+	}
+
+	user, err := s.userRepo.GetBySystemAndID(systemID, stored.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.UserWithAccess{}, ErrInvalidRefreshToken
+		}
+		return responses.UserWithAccess{}, err
+	}
+	if !user.Activated {
+		return responses.UserWithAccess{}, ErrUserNotActive
+	}
+
+	access, err := s.userRepo.GetUserNestedPermissionsBySystem(user.ID, systemID)
+	if err != nil {
+		return responses.UserWithAccess{}, err
+	}
+
+	return s.IssueTokens(user, systemID, access.Roles, stored.FamilyID)
+}
+
+func (s *TokenService) revokeReusedFamily(familyID string, now time.Time) error {
+	if err := s.refreshRepo.RevokeFamily(familyID, now); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// ParseAccessToken valida la firma de un access token y devuelve sus claims.
+// La llave se elige según el sistema del token, de modo que un token firmado
+// con el secreto de un sistema no sirve para otro. Con validateClaims además
+// se verifican la vigencia, con la tolerancia de reloj del sistema, el emisor
+// y la audiencia; en false se aceptan tokens expirados (útil para revocar).
+func (s *TokenService) ParseAccessToken(tokenString string, validateClaims bool) (*responses.CustomClaims, error) {
+	methods := s.keyService.ValidMethods()
+
+	claims := &responses.CustomClaims{}
+	var settings systemTokenSettings
+	keyfunc := func(token *jwt.Token) (interface{}, error) {
+		var err error
+		if settings, err = s.tokenSettings(claims.SystemID); err != nil {
+			return nil, err
+		}
+		if settings.secret != "" {
+			if token.Method.Alg() != jwks.AlgHS256 {
+				return nil, errors.New("el sistema firma sus tokens con su propio secreto")
+			}
+			return []byte(settings.secret), nil
+		}
+		if !slices.Contains(methods, token.Method.Alg()) {
+			return nil, fmt.Errorf("algoritmo %s no aceptado", token.Method.Alg())
+		}
+		return s.keyService.Keyfunc(token)
+	}
+
+	// Los algoritmos se comprueban en keyfunc, que conoce el sistema del token:
+	// HS256 solo vale con el secreto propio del sistema o como token heredado
+	_, err := jwt.ParseWithClaims(tokenString, claims, keyfunc, jwt.WithoutClaimsValidation())
+	if err != nil || claims.ID == "" {
+		return nil, ErrInvalidAccessToken
+	}
+
+	if validateClaims {
+		validator := jwt.NewValidator(
+			jwt.WithLeeway(settings.clockSkew),
+			jwt.WithIssuer(settings.issuer),
+			jwt.WithAudience(settings.audience),
+			jwt.WithExpirationRequired(),
+		)
+		if err := validator.Validate(claims); err != nil {
+			return nil, ErrInvalidAccessToken
+		}
+	}
+
+	return claims, nil
+}
+
+// Revoke revoca un access token (por su jti) o un refresh token (toda su familia).
+// Al igual que RFC 7009, un token desconocido no se considera un error.
+func (s *TokenService) Revoke(systemID uint64, token, tokenTypeHint string) error {
+	if tokenTypeHint != "refresh_token" {
+		if claims, err := s.ParseAccessToken(token, false); err == nil {
+			// Los tokens de otro sistema se ignoran, igual que los desconocidos
+			if claims.SystemID != systemID {
+				return nil
+			}
+			return s.accessRepo.Revoke(claims.ID, RevokeReasonRevoked, time.Now())
+		}
+	}
+
+	stored, err := s.refreshRepo.GetByHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if uint64(stored.SystemID) != systemID {
+		return nil
+	}
+	return s.refreshRepo.RevokeFamily(stored.FamilyID, time.Now())
+}
+
+// SignOut revoca el access token presentado y, si se envía, la familia del refresh token
+func (s *TokenService) SignOut(systemID uint64, accessToken, refreshToken string) error {
+	claims, err := s.ParseAccessToken(accessToken, false)
+	if err != nil {
+		return err
+	}
+	if claims.SystemID != systemID {
+		return ErrInvalidAccessToken
+	}
+
+	now := time.Now()
+	if err := s.accessRepo.Revoke(claims.ID, RevokeReasonSignOut, now); err != nil {
+		return err
+	}
+
+	if refreshToken != "" {
+		stored, err := s.refreshRepo.GetByHash(utils.HashToken(refreshToken))
+		if err == nil && uint64(stored.UserID) == claims.UserID && uint64(stored.SystemID) == systemID {
+			return s.refreshRepo.RevokeFamily(stored.FamilyID, now)
+		}
+	}
+
+	return nil
+}
+
+// RevokeUserTokens revoca todos los tokens vigentes del usuario en el sistema
+// indicado (o en todos, con systemID igual a 0)
+func (s *TokenService) RevokeUserTokens(userID uint, systemID uint64, reason string) error {
+	now := time.Now()
+	if err := s.accessRepo.RevokeByUser(userID, systemID, reason, now); err != nil {
+		return err
+	}
+	return s.refreshRepo.RevokeByUser(userID, systemID, now)
+}
+
+// Introspect informa si un token sigue activo (RFC 7662). Además de la firma y
+// la expiración se verifica la revocación, que el usuario siga activo y que
+// conserve acceso al sistema; los roles se leen en el momento de la consulta.
+func (s *TokenService) Introspect(token, tokenTypeHint string) (responses.IntrospectionResponse, error) {
+	inactive := responses.IntrospectionResponse{Active: false}
+
+	if tokenTypeHint != "refresh_token" {
+		if claims, err := s.ParseAccessToken(token, true); err == nil {
+			return s.introspectAccessToken(claims)
+		}
+	}
+
+	stored, err := s.refreshRepo.GetByHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return inactive, nil
+		}
+		return inactive, err
+	}
+	if stored.RevokedAt != nil || stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return inactive, nil
+	}
+
+	systemID := uint64(stored.SystemID)
+	user, active, err := s.activeUser(systemID, stored.UserID)
+	if err != nil || !active {
+		return inactive, err
+	}
+
+	return responses.IntrospectionResponse{
+		Active:    true,
+		TokenType: "refresh_token",
+		Sub:       strconv.FormatUint(uint64(user.ID), 10),
+		Username:  user.Username,
+		SystemID:  systemID,
+		Exp:       stored.ExpiresAt.Unix(),
+		Iat:       stored.Created.Unix(),
+	}, nil
+}
+
+func (s *TokenService) introspectAccessToken(claims *responses.CustomClaims) (responses.IntrospectionResponse, error) {
+	inactive := responses.IntrospectionResponse{Active: false}
+
+	issued, err := s.accessRepo.GetByJTI(claims.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return inactive, nil
+		}
+		return inactive, err
+	}
+	if issued.RevokedAt != nil {
+		return inactive, nil
+	}
+	if claims.ClientID != "" {
+		return s.introspectClientToken(claims, issued)
+	}
+
+	user, active, err := s.activeUser(claims.SystemID, uint(claims.UserID))
+	if err != nil || !active {
+		return inactive, err
+	}
+
+	access, err := s.userRepo.GetUserNestedPermissionsBySystem(user.ID, claims.SystemID)
+	if err != nil {
+		return inactive, err
+	}
+
+	response := responses.IntrospectionResponse{
+		Active:    true,
+		TokenType: "access_token",
+		Sub:       claims.Subject,
+		Username:  user.Username,
+		SystemID:  claims.SystemID,
+		Iss:       claims.Issuer,
+		Aud:       strings.Join(claims.Audience, " "),
+		JTI:       claims.ID,
+		Roles:     access.Roles,
+		Act:       claims.Act,
+	}
+	if claims.ExpiresAt != nil {
+		response.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response.Iat = claims.IssuedAt.Unix()
+	}
+	return response, nil
+}
+
+func (s *TokenService) introspectClientToken(claims *responses.CustomClaims, issued domain.AccessToken) (responses.IntrospectionResponse, error) {
+	inactive := responses.IntrospectionResponse{Active: false}
+
+	// El cliente pudo eliminarse después de emitir el token
+	client, err := s.clientRepo.GetByClientID(claims.ClientID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return inactive, nil
+		}
+		return inactive, err
+	}
+	if issued.SystemClientID == nil || *issued.SystemClientID != client.ID {
+		return inactive, nil
+	}
+
+	access, err := s.clientRepo.GetNestedPermissionsBySystem(client.ID, claims.SystemID)
+	if err != nil {
+		return inactive, err
+	}
+
+	response := responses.IntrospectionResponse{
+		Active:    true,
+		TokenType: "access_token",
+		Sub:       claims.Subject,
+		ClientID:  client.ClientID,
+		SystemID:  claims.SystemID,
+		Iss:       claims.Issuer,
+		Aud:       strings.Join(claims.Audience, " "),
+		JTI:       claims.ID,
+		Roles:     access.Roles,
+	}
+	if claims.ExpiresAt != nil {
+		response.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response.Iat = claims.IssuedAt.Unix()
+	}
+	return response, nil
+}
+
+// AuthenticatedUser devuelve el usuario de un access token de usuario vigente
+// emitido para el sistema indicado. Los tokens de suplantación no sirven para
+// cambiar las credenciales del usuario.
+func (s *TokenService) AuthenticatedUser(systemID uint64, accessToken string) (uint, error) {
+	status, err := s.Introspect(accessToken, "access_token")
+	if err != nil {
+		return 0, err
+	}
+	if !status.Active || status.TokenType != "access_token" || status.ClientID != "" || status.SystemID != systemID {
+		return 0, ErrInvalidAccessToken
+	}
+	if status.Act != nil {
+		return 0, ErrImpersonationTokenNotAllowed
+	}
+
+	userID, err := strconv.ParseUint(status.Sub, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAccessToken
+	}
+	return uint(userID), nil
+}
+
+// RevokeToken revoca el access token con el jti indicado
+func (s *TokenService) RevokeToken(jti, reason string) error {
+	return s.accessRepo.Revoke(jti, reason, time.Now())
+}
+
+// RevokeClientTokens revoca los tokens vigentes emitidos al cliente
+func (s *TokenService) RevokeClientTokens(systemClientID uint, reason string) error {
+	return s.accessRepo.RevokeByClient(systemClientID, reason, time.Now())
+}
+
+// activeUser indica si el usuario sigue activo y asociado al sistema
+func (s *TokenService) activeUser(systemID uint64, userID uint) (domain.User, bool, error) {
+	user, err := s.userRepo.GetBySystemAndID(systemID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.User{}, false, nil
+		}
+		return domain.User{}, false, err
+	}
+	return user, user.Activated, nil
+}
+
+// GetTokenStatus devuelve el registro del token emitido con el jti indicado
+func (s *TokenService) GetTokenStatus(jti string) (domain.AccessToken, error) {
+	return s.accessRepo.GetByJTI(jti)
+}
+
+// GetRevokedTokens lista los tokens revocados que aún no han expirado
+func (s *TokenService) GetRevokedTokens(systemID uint64, since time.Time) ([]domain.AccessToken, error) {
+	return s.accessRepo.GetRevoked(systemID, since)
+}
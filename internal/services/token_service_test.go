@@ -0,0 +1,219 @@
+package services
+
+import (
+	"accessv2/internal/domain"
+	"accessv2/internal/repositories"
+	"accessv2/internal/testutil"
+	"accessv2/pkg/utils"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// tokenFixture es un usuario activo del sistema Uno, que no tiene acceso al
+// sistema Dos, con el servicio que emite y rota sus tokens
+type tokenFixture struct {
+	db      *gorm.DB
+	service *TokenService
+	user    domain.User
+}
+
+func newTokenFixture(t *testing.T) *tokenFixture {
+	t.Helper()
+	db := testutil.NewDB(t)
+	now := time.Now()
+
+	systems := []domain.System{{Name: "Uno", Created: now, Updated: now}, {Name: "Dos", Created: now, Updated: now}}
+	if err := db.Create(&systems).Error; err != nil {
+		t.Fatal(err)
+	}
+	userRepo := repositories.NewUserRepository(db)
+	user := domain.User{Username: "jdoe", Email: "jdoe@example.com", Activated: true, Created: now, Updated: now}
+	if err := userRepo.Create(&user); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&domain.SystemUser{SystemID: systems[0].ID, UserID: user.ID, Created: now}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	keyService, err := NewKeyService(KeyConfig{Algorithm: "HS256", Secret: "test-secret"}, repositories.NewSigningKeyRepository(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewTokenService(
+		TokenConfig{Issuer: "http://localhost", AccessTTL: 15 * time.Minute, RefreshTTL: time.Hour},
+		keyService,
+		userRepo,
+		repositories.NewSystemRepository(db),
+		repositories.NewSystemClientRepository(db),
+		repositories.NewRefreshTokenRepository(db),
+		repositories.NewAccessTokenRepository(db),
+	)
+	return &tokenFixture{db: db, service: service, user: user}
+}
+
+// issue inicia una nueva familia, como un ingreso
+func (f *tokenFixture) issue(t *testing.T) string {
+	t.Helper()
+	token, err := f.service.IssueRefreshToken(f.user.ID, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func (f *tokenFixture) setTokenColumn(t *testing.T, token, column string, value interface{}) {
+	t.Helper()
+	err := f.db.Model(&domain.RefreshToken{}).Where("token_hash = ?", utils.HashToken(token)).Update(column, value).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenServiceRefresh(t *testing.T) {
+	tests := []struct {
+		name        string
+		token       string // vacío usa uno recién emitido
+		otherSystem bool
+		expired     bool
+		revoked     bool
+		used        bool
+		inactive    bool
+		wantErr     error
+	}{
+		{name: "token vigente"},
+		{name: "token desconocido", token: "desconocido", wantErr: ErrInvalidRefreshToken},
+		{name: "token de otro sistema", otherSystem: true, wantErr: ErrInvalidRefreshToken},
+		{name: "token vencido", expired: true, wantErr: ErrInvalidRefreshToken},
+		{name: "token revocado", revoked: true, wantErr: ErrInvalidRefreshToken},
+		{name: "token ya usado", used: true, wantErr: ErrRefreshTokenReused},
+		{name: "usuario inactivo", inactive: true, wantErr: ErrUserNotActive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTokenFixture(t)
+			token := tt.token
+			if token == "" {
+				token = f.issue(t)
+			}
+			if tt.expired {
+				f.setTokenColumn(t, token, "expires_at", time.Now().Add(-time.Minute))
+			}
+			if tt.revoked {
+				f.setTokenColumn(t, token, "revoked_at", time.Now())
+			}
+			if tt.used {
+				if _, err := f.service.Refresh(1, token); err != nil {
+					t.Fatalf("primer uso: %v", err)
+				}
+			}
+			if tt.inactive {
+				if err := f.db.Model(&f.user).Update("activated", false).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			systemID := uint64(1)
+			if tt.otherSystem {
+				systemID = 2
+			}
+			got, err := f.service.Refresh(systemID, token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, se esperaba %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (got.Token == "" || got.RefreshToken == "" || got.RefreshToken == token) {
+				t.Fatalf("no se emitió un nuevo par de tokens: %+v", got)
+			}
+		})
+	}
+}
+
+func TestTokenServiceRefreshReuseRevokesFamily(t *testing.T) {
+	f := newTokenFixture(t)
+	first := f.issue(t)
+	other := f.issue(t)
+
+	rotated, err := f.service.Refresh(1, first)
+	if err != nil {
+		t.Fatalf("rotar: %v", err)
+	}
+	second, err := f.service.Refresh(1, rotated.RefreshToken)
+	if err != nil {
+		t.Fatalf("rotar otra vez: %v", err)
+	}
+
+	// Reutilizar el primero revoca los tokens que salieron de él
+	if _, err := f.service.Refresh(1, first); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reutilizar: err = %v, se esperaba ErrRefreshTokenReused", err)
+	}
+	if _, err := f.service.Refresh(1, second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("último de la familia: err = %v, se esperaba ErrInvalidRefreshToken", err)
+	}
+
+	// Las otras sesiones del usuario no se tocan
+	if _, err := f.service.Refresh(1, other); err != nil {
+		t.Fatalf("token de otra familia: %v", err)
+	}
+}
+
+// switchToES256 pasa el servicio a ES256 conservando JWT_KEY, como al cambiar
+// JWT_ALGORITHM. Con firstKeyAge mayor que cero crea la primera llave
+// asimétrica con esa antigüedad.
+func (f *tokenFixture) switchToES256(t *testing.T, legacy bool, firstKeyAge time.Duration) {
+	t.Helper()
+	cfg := KeyConfig{Algorithm: "ES256", Secret: "test-secret", RotationPeriod: 24 * time.Hour, PublishGrace: time.Hour, LegacyHS256: legacy}
+	keyService, err := NewKeyService(cfg, repositories.NewSigningKeyRepository(f.db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if firstKeyAge > 0 {
+		if err := keyService.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.db.Model(&domain.SigningKey{}).Where("1 = 1").Update("created", time.Now().Add(-firstKeyAge)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.service.keyService = keyService
+}
+
+func TestTokenServiceParseAccessTokenAfterAlgorithmSwitch(t *testing.T) {
+	tests := []struct {
+		name        string
+		legacy      bool
+		firstKeyAge time.Duration
+		wantErr     error
+	}{
+		{name: "sin aceptar HS256", wantErr: ErrInvalidAccessToken},
+		{name: "sin aceptar HS256 con llave asimétrica", firstKeyAge: time.Minute, wantErr: ErrInvalidAccessToken},
+		{name: "HS256 heredado sin llave asimétrica todavía", legacy: true},
+		{name: "HS256 heredado dentro del plazo", legacy: true, firstKeyAge: time.Minute},
+		{name: "HS256 heredado vencido el plazo", legacy: true, firstKeyAge: 2 * time.Hour, wantErr: ErrInvalidAccessToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTokenFixture(t)
+			legacyToken, _, err := f.service.IssueAccessToken(f.user, 1, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f.switchToES256(t, tt.legacy, tt.firstKeyAge)
+			if _, err := f.service.ParseAccessToken(legacyToken, true); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("token HS256: err = %v, se esperaba %v", err, tt.wantErr)
+			}
+
+			token, _, err := f.service.IssueAccessToken(f.user, 1, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.service.ParseAccessToken(token, true); err != nil {
+				t.Fatalf("token ES256: %v", err)
+			}
+		})
+	}
+}
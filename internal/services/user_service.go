@@ -5,26 +5,50 @@ import (
 	"accessv2/internal/forms"
 	"accessv2/internal/repositories"
 	"accessv2/internal/responses"
-	"os"
 
-	"accessv2/pkg/utils"
+	"accessv2/pkg/password"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrUserNotActive          = errors.New("Usuario no activo")
+	ErrInvalidUserCredentials = errors.New("Usuario y/o contraseña incorrectos")
+)
+
+// Formas de identificar al usuario al iniciar sesión
+const (
+	IdentifierUsername = "username"
+	IdentifierEmail    = "email"
+	IdentifierAny      = "identifier" // usuario o correo
+)
+
 type UserService struct {
-	repo *repositories.UserRepository
-	db   *gorm.DB
+	repo         *repositories.UserRepository
+	db           *gorm.DB
+	hasher       *password.Hasher
+	tokenService *TokenService
+	mfaService   *MFAService
+	throttle     *SignInThrottleService
+	passwords    *PasswordService
+	credentials  CredentialAuthenticator
 }
 
-func NewUserService(db *gorm.DB, repo *repositories.UserRepository) *UserService {
+func NewUserService(db *gorm.DB, repo *repositories.UserRepository, hasher *password.Hasher, tokenService *TokenService, mfaService *MFAService, throttle *SignInThrottleService, passwords *PasswordService, credentials CredentialAuthenticator) *UserService {
 	return &UserService{
-		db:   db,
-		repo: repo}
+		db:           db,
+		repo:         repo,
+		hasher:       hasher,
+		tokenService: tokenService,
+		mfaService:   mfaService,
+		throttle:     throttle,
+		passwords:    passwords,
+		credentials:  credentials}
 }
 
 func (s *UserService) GetAllUsers() ([]domain.User, error) {
@@ -65,14 +89,17 @@ func (s *UserService) CreateUser(input *forms.UserCreateInput) (*domain.User, er
 		activated = true
 	}
 
+	passwordHash, err := s.passwords.Prepare(domain.User{Username: input.Username}, input.Password)
+	if err != nil {
+		return nil, err
+	}
+
 	// Crear objeto del dominio
 	user := &domain.User{
-		Username:      input.Username,
-		Password:      input.Password,
-		Email:         input.Email,
-		ResetKey:      utils.RandomString(30),
-		ActivationKey: utils.RandomString(30),
-		Activated:     activated,
+		Username:  input.Username,
+		Password:  passwordHash,
+		Email:     input.Email,
+		Activated: activated,
 	}
 
 	// Establecer fechas por defecto si no vienen
@@ -82,12 +109,17 @@ func (s *UserService) CreateUser(input *forms.UserCreateInput) (*domain.User, er
 	if user.Updated.IsZero() {
 		user.Updated = user.Created
 	}
+	user.PasswordChangedAt = &user.Created
 
 	// Guardar en la base de datos
 	if err := s.repo.Create(user); err != nil {
 		return nil, err
 	}
 
+	if err := s.passwords.Record(user.ID, passwordHash, user.Created); err != nil {
+		log.Printf("No se pudo registrar el historial de contraseñas del usuario %d: %v", user.ID, err)
+	}
+
 	return user, nil
 }
 
@@ -119,70 +151,213 @@ func (s *UserService) UpdateUser(user *domain.User) error {
 		return err // Si se encuentra un error (otro rol con el mismo nombre o correo), retornarlo.
 	}
 
+	previous, err := s.repo.GetByID(uint64(user.ID))
+	if err != nil {
+		return err
+	}
+
+	// Una contraseña nueva debe cumplir la política; una fila heredada en texto
+	// plano que no cambió solo se hashea
+	passwordChanged := false
+	if !password.IsHashed(user.Password) {
+		var passwordHash string
+		if user.Password == previous.Password {
+			passwordHash, err = s.hasher.Hash(user.Password)
+			if err != nil {
+				return fmt.Errorf("Error al generar el hash de la contraseña: %w", err)
+			}
+		} else {
+			passwordHash, err = s.passwords.Prepare(previous, user.Password)
+			if err != nil {
+				return err
+			}
+			passwordChanged = true
+		}
+		user.Password = passwordHash
+	}
+
 	user.Updated = time.Now()
-	return s.repo.Update(user)
+	if passwordChanged {
+		user.PasswordChangedAt = &user.Updated
+	}
+	if err := s.repo.Update(user); err != nil {
+		return err
+	}
+
+	if passwordChanged {
+		if err := s.passwords.Record(user.ID, user.Password, user.Updated); err != nil {
+			log.Printf("No se pudo registrar el historial de contraseñas del usuario %d: %v", user.ID, err)
+		}
+	}
+
+	// Al desactivar al usuario se revocan sus tokens en todos los sistemas
+	if previous.Activated && !user.Activated {
+		if err := s.tokenService.RevokeUserTokens(user.ID, 0, RevokeReasonUserDeactivated); err != nil {
+			return fmt.Errorf("Usuario actualizado, pero no se pudieron revocar sus tokens: %w", err)
+		}
+	}
+
+	return nil
 }
 
 // DeleteSystem usando el repository
 func (s *UserService) DeleteUser(id uint64) error {
+	if err := s.tokenService.RevokeUserTokens(uint(id), 0, RevokeReasonUserDeleted); err != nil {
+		return err
+	}
 	return s.repo.Delete(id)
 }
 
-func (s *UserService) ValidateBySystemUsernamePassword(systemID uint64, username, password string) (responses.UserWithAccess, error) {
-	user, err := s.repo.GetBySystemUsernamePassword(systemID, username, password)
+// SignOut cierra la sesión de la API revocando los tokens del usuario
+func (s *UserService) SignOut(systemID uint64, accessToken, refreshToken string) error {
+	return s.tokenService.SignOut(systemID, accessToken, refreshToken)
+}
+
+// ValidateBySystemUsernamePassword valida las credenciales y emite los tokens.
+// Si el ingreso requiere segundo factor no se emiten tokens y se devuelve el
+// desafío a completar.
+func (s *UserService) ValidateBySystemUsernamePassword(systemID uint64, username, plainPassword, clientIP string) (responses.UserWithAccess, *responses.MFAChallenge, error) {
+	user, err := s.AuthenticateBySystem(systemID, username, plainPassword, clientIP)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return responses.UserWithAccess{}, errors.New("Usuario y/o contraseña incorrectos")
-		}
-		return responses.UserWithAccess{}, fmt.Errorf("Error al validar usuario: %w", err)
+		return responses.UserWithAccess{}, nil, err
 	}
 
-	if user.Activated == false {
-		return responses.UserWithAccess{}, errors.New("Usuario no activo")
+	challenge, err := s.mfaService.BeginSignIn(user, systemID)
+	if err != nil {
+		return responses.UserWithAccess{}, nil, err
+	}
+	if challenge != nil {
+		return responses.UserWithAccess{}, challenge, nil
 	}
 
 	access, err := s.repo.GetUserNestedPermissionsBySystem(user.ID, systemID)
 	if err != nil {
-		return responses.UserWithAccess{}, err
+		return responses.UserWithAccess{}, nil, err
+	}
+
+	// Generar el access token y el refresh token
+	tokens, err := s.tokenService.IssueTokens(user, systemID, access.Roles, "")
+	return tokens, nil, err
+}
+
+// ValidateBySystemIdentifier es ValidateBySystemUsernamePassword identificando
+// al usuario por nombre de usuario, por correo o por cualquiera de los dos
+func (s *UserService) ValidateBySystemIdentifier(systemID uint64, kind, identifier, plainPassword, clientIP string) (responses.UserWithAccess, *responses.MFAChallenge, error) {
+	username, err := s.resolveUsername(systemID, kind, identifier)
+	if err != nil {
+		return responses.UserWithAccess{}, nil, err
+	}
+	return s.ValidateBySystemUsernamePassword(systemID, username, plainPassword, clientIP)
+}
+
+// resolveUsername devuelve el nombre de usuario que corresponde al
+// identificador. Si no hay coincidencia se devuelve el identificador tal cual,
+// para que el intento falle y cuente como fallido igual que uno por usuario.
+// Con IdentifierAny se prefiere el usuario sobre el correo.
+func (s *UserService) resolveUsername(systemID uint64, kind, identifier string) (string, error) {
+	identifier = strings.TrimSpace(identifier)
+
+	switch kind {
+	case IdentifierUsername:
+		return identifier, nil
+	case IdentifierAny:
+		user, err := s.repo.GetBySystemUsername(systemID, identifier)
+		if err == nil {
+			return user.Username, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+		fallthrough
+	case IdentifierEmail:
+		user, err := s.repo.GetBySystemEmail(systemID, identifier)
+		if err == nil {
+			return user.Username, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+		return identifier, nil
+	}
+
+	return "", fmt.Errorf("tipo de identificador no soportado: %s", kind)
+}
+
+// AuthenticateBySystem verifica las credenciales de un usuario asociado al
+// sistema sin emitir tokens. Los intentos fallidos se cuentan por usuario y por
+// IP; con demasiados fallos se devuelve *SignInBlockedError sin validar la
+// contraseña. Si la contraseña venció devuelve ErrPasswordExpired.
+func (s *UserService) AuthenticateBySystem(systemID uint64, username, plainPassword, clientIP string) (domain.User, error) {
+	user, err := s.authenticate(systemID, username, plainPassword, clientIP)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	// El vencimiento de una contraseña del directorio lo controla el directorio
+	if !user.IsExternal() && s.passwords.IsExpired(user) {
+		return domain.User{}, ErrPasswordExpired
+	}
+
+	return user, nil
+}
+
+// ChangePassword reemplaza la contraseña de un usuario que conoce la actual,
+// aunque esté vencida, y revoca sus tokens vigentes
+func (s *UserService) ChangePassword(systemID uint64, username, currentPassword, newPassword, clientIP string) error {
+	user, err := s.authenticate(systemID, username, currentPassword, clientIP)
+	if err != nil {
+		return err
+	}
+	if user.IsExternal() {
+		return ErrExternalPassword
 	}
 
-	// Generar el token JWT
-	expirationTime := time.Now().Add(24 * time.Hour) // Token válido por 24 horas
-	claims := &responses.CustomClaims{
-		UserID:   uint64(user.ID),
-		Username: user.Username,
-		Email:    user.Email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "tu-aplicacion",
-		},
-		Roles: access.Roles,
+	passwordHash, err := s.passwords.Prepare(user, newPassword)
+	if err != nil {
+		return err
 	}
 
-	jwtSecret := os.Getenv("JWT_KEY")
+	now := time.Now()
+	if err := s.repo.ChangePassword(user.ID, passwordHash, now); err != nil {
+		return err
+	}
+	if err := s.passwords.Record(user.ID, passwordHash, now); err != nil {
+		log.Printf("No se pudo registrar el historial de contraseñas del usuario %d: %v", user.ID, err)
+	}
+
+	// Las sesiones abiertas con la contraseña anterior dejan de ser válidas
+	return s.tokenService.RevokeUserTokens(user.ID, 0, RevokeReasonPasswordChanged)
+}
 
-	if jwtSecret == "" {
-		return responses.UserWithAccess{}, errors.New("credenciales de administrador no configuradas")
+// PasswordExpiresAt devuelve el vencimiento de la contraseña del usuario, o nil si no vence
+func (s *UserService) PasswordExpiresAt(user domain.User) *time.Time {
+	if user.IsExternal() {
+		return nil
 	}
+	return s.passwords.ExpiresAt(user)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+func (s *UserService) authenticate(systemID uint64, username, plainPassword, clientIP string) (domain.User, error) {
+	if err := s.throttle.Check(systemID, username, clientIP); err != nil {
+		return domain.User{}, err
+	}
+
+	user, err := s.credentials.Authenticate(systemID, username, plainPassword)
 	if err != nil {
-		return responses.UserWithAccess{}, fmt.Errorf("Error al generar token: %w", err)
+		if errors.Is(err, ErrInvalidUserCredentials) {
+			if err := s.throttle.RecordFailure(systemID, username, clientIP); err != nil {
+				log.Printf("No se pudo registrar el intento fallido de %q: %v", username, err)
+			}
+		}
+		return domain.User{}, err
 	}
 
-	userWithAccess := responses.UserWithAccess{
-		User: responses.UserAccess{
-			ID:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			// Puedes incluir otros campos si los necesitas
-		},
-		Token: tokenString,
-		Roles: access.Roles,
+	// El contador no se reinicia aquí: si el sistema pide segundo factor, los
+	// códigos incorrectos siguen sumando hasta que el ingreso se complete
+	// (MFAService.BeginSignIn o VerifyChallenge)
+	if user.Activated == false {
+		return domain.User{}, ErrUserNotActive
 	}
 
-	return userWithAccess, nil
+	return user, nil
 }
@@ -0,0 +1,45 @@
+// internal/testutil/db.go
+
+// Package testutil reúne lo que comparten las pruebas de los paquetes internos
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewDB crea una base sqlite temporal con el esquema de db/schema.sql, el
+// mismo que deja dbmate al aplicar las migraciones. Es un archivo y no
+// :memory: para que, como en producción, cada conexión del pool vea los mismos
+// datos.
+func NewDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	_, file, _, _ := runtime.Caller(0)
+	schema, err := os.ReadFile(filepath.Join(filepath.Dir(file), "..", "..", "db", "schema.sql"))
+	if err != nil {
+		t.Fatalf("leer el esquema: %v", err)
+	}
+
+	dsn := filepath.Join(t.TempDir(), "app.db") + "?_busy_timeout=5000&_sync=OFF"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("abrir la base: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("abrir la base: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.Exec(string(schema)).Error; err != nil {
+		t.Fatalf("crear el esquema: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,106 @@
+// pkg/accessclient/claims.go
+
+// Package accessclient permite a los sistemas que consumen este servicio
+// validar sus tokens, leer los roles y permisos del usuario y proteger rutas
+// por permiso, sin reimplementar la estructura de los claims.
+package accessclient
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims son los datos de un access token emitido por el servicio
+type Claims struct {
+	UserID   uint64 `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+	SystemID uint64 `json:"system_id"`
+	ClientID string `json:"client_id,omitempty"` // solo en tokens client_credentials
+	Roles    []Role `json:"roles"`
+	Act      *Actor `json:"act,omitempty"` // solo en tokens de suplantación
+	jwt.RegisteredClaims
+}
+
+// Role es un rol del usuario en el sistema con los permisos que otorga
+type Role struct {
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	Permissions []Permission `json:"permissions"`
+}
+
+type Permission struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// Actor identifica al administrador que suplanta al usuario del token
+type Actor struct {
+	Sub             string `json:"sub"` // "admin:<id>"
+	Username        string `json:"username"`
+	ImpersonationID uint   `json:"impersonation_id"`
+}
+
+// HasPermission indica si alguno de los roles otorga el permiso
+func (c *Claims) HasPermission(name string) bool {
+	return len(c.RolesWithPermission(name)) > 0
+}
+
+// HasAllPermissions indica si el usuario tiene todos los permisos
+func (c *Claims) HasAllPermissions(names ...string) bool {
+	for _, name := range names {
+		if !c.HasPermission(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAnyPermission indica si el usuario tiene al menos uno de los permisos
+func (c *Claims) HasAnyPermission(names ...string) bool {
+	return slices.ContainsFunc(names, c.HasPermission)
+}
+
+// HasRole compara el nombre del rol sin distinguir mayúsculas
+func (c *Claims) HasRole(name string) bool {
+	return slices.ContainsFunc(c.Roles, func(role Role) bool {
+		return strings.EqualFold(role.Name, name)
+	})
+}
+
+// RolesWithPermission devuelve los roles que otorgan el permiso
+func (c *Claims) RolesWithPermission(name string) []Role {
+	var roles []Role
+	for _, role := range c.Roles {
+		if slices.ContainsFunc(role.Permissions, func(p Permission) bool { return p.Name == name }) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// Permissions devuelve los nombres de todos los permisos, sin repetir
+func (c *Claims) Permissions() []string {
+	var names []string
+	for _, role := range c.Roles {
+		for _, p := range role.Permissions {
+			if !slices.Contains(names, p.Name) {
+				names = append(names, p.Name)
+			}
+		}
+	}
+	return names
+}
+
+// IsImpersonated indica si un administrador actúa en nombre del usuario
+func (c *Claims) IsImpersonated() bool {
+	return c.Act != nil
+}
+
+// IsClient indica si el token es de un cliente (client_credentials) y no de
+// un usuario
+func (c *Claims) IsClient() bool {
+	return c.ClientID != ""
+}
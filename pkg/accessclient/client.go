@@ -0,0 +1,353 @@
+// pkg/accessclient/client.go
+package accessclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// APIKeyHeader es la cabecera con la llave de API del sistema
+const APIKeyHeader = "X-API-Key"
+
+// ClientConfig describe cómo llamar a las APIs remotas del servicio
+type ClientConfig struct {
+	BaseURL string
+	APIKey  string // llave de API del sistema, creada en /systems/:id/edit
+	// CacheTTL guarda las respuestas de verificación e introspección durante
+	// ese tiempo; 0 desactiva la caché. Un cambio de permisos o una
+	// revocación tarda como máximo CacheTTL en notarse.
+	CacheTTL   time.Duration
+	HTTPClient *http.Client
+}
+
+// Client consulta la verificación de permisos y la introspección de tokens
+type Client struct {
+	cfg   ClientConfig
+	cache *cache
+}
+
+// APIError es una respuesta de error del servicio
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("el servicio de acceso respondió %d: %s", e.StatusCode, e.Message)
+}
+
+// RoleRef es un rol que otorga el permiso consultado
+type RoleRef struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// CheckRequest indica el usuario, por ID o por nombre, y los permisos a
+// verificar. Match es all (por defecto) o any; el lote lo ignora.
+type CheckRequest struct {
+	UserID      uint     `json:"user_id,omitempty"`
+	Username    string   `json:"username,omitempty"`
+	Permissions []string `json:"permissions"`
+	Match       string   `json:"match,omitempty"`
+}
+
+// CheckResult es la decisión sobre los permisos pedidos en conjunto
+type CheckResult struct {
+	UserID   uint      `json:"user_id"`
+	SystemID uint64    `json:"system_id"`
+	Allowed  bool      `json:"allowed"`
+	Reason   string    `json:"reason,omitempty"`
+	Roles    []RoleRef `json:"roles"`
+	Missing  []string  `json:"missing"`
+}
+
+// PermissionDecision es la decisión sobre un permiso del lote
+type PermissionDecision struct {
+	Permission string    `json:"permission"`
+	Allowed    bool      `json:"allowed"`
+	Roles      []RoleRef `json:"roles"`
+}
+
+// BatchResult tiene una decisión por permiso, en el orden pedido
+type BatchResult struct {
+	UserID   uint                 `json:"user_id"`
+	SystemID uint64               `json:"system_id"`
+	Reason   string               `json:"reason,omitempty"`
+	Results  []PermissionDecision `json:"results"`
+}
+
+// Introspection es la respuesta de RFC 7662 del servicio
+type Introspection struct {
+	Active    bool   `json:"active"`
+	TokenType string `json:"token_type,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Username  string `json:"username,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	SystemID  uint64 `json:"system_id,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	JTI       string `json:"jti,omitempty"`
+	Roles     []Role `json:"roles,omitempty"`
+	Act       *Actor `json:"act,omitempty"`
+}
+
+func NewClient(cfg ClientConfig) (*Client, error) {
+	if cfg.BaseURL == "" || cfg.APIKey == "" {
+		return nil, errors.New("se requieren BaseURL y APIKey")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	client := &Client{cfg: cfg}
+	if cfg.CacheTTL > 0 {
+		client.cache = newCache()
+	}
+	return client, nil
+}
+
+// Check verifica los permisos del usuario en POST /api/v1/authz/check
+func (c *Client) Check(ctx context.Context, req CheckRequest) (*CheckResult, error) {
+	var result CheckResult
+	if err := c.cached("check", req, &result, func() error {
+		return c.call(ctx, "/api/v1/authz/check", req, &result)
+	}); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CheckBatch verifica cada permiso por separado en POST /api/v1/authz/check/batch
+func (c *Client) CheckBatch(ctx context.Context, req CheckRequest) (*BatchResult, error) {
+	req.Match = ""
+	var result BatchResult
+	if err := c.cached("batch", req, &result, func() error {
+		return c.call(ctx, "/api/v1/authz/check/batch", req, &result)
+	}); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Allowed indica si el usuario tiene el permiso
+func (c *Client) Allowed(ctx context.Context, userID uint, permission string) (bool, error) {
+	result, err := c.Check(ctx, CheckRequest{UserID: userID, Permissions: []string{permission}})
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// Introspect consulta el estado del token en POST /api/v1/token/introspect.
+// En la caché se guarda por su hash y nunca más allá de su expiración.
+func (c *Client) Introspect(ctx context.Context, token string) (*Introspection, error) {
+	var result Introspection
+	key := hashToken(token)
+	ttl := c.cfg.CacheTTL
+
+	if c.cache != nil {
+		if c.cache.get(key, &result) {
+			return &result, nil
+		}
+	}
+
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+"/api/v1/token/introspect", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := c.do(req, &result); err != nil {
+		return nil, err
+	}
+
+	if c.cache != nil {
+		if result.Active {
+			if left := time.Until(time.Unix(result.Exp, 0)); left < ttl {
+				ttl = left
+			}
+		}
+		if ttl > 0 {
+			c.cache.set(key, result, ttl)
+		}
+	}
+	return &result, nil
+}
+
+// Verify valida el token con la introspección, de modo que se detectan las
+// revocaciones (con el retraso de la caché, si la hay)
+func (c *Client) Verify(ctx context.Context, token string) (*Claims, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	result, err := c.Introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Active {
+		return nil, ErrInvalidToken
+	}
+	return result.Claims(), nil
+}
+
+// Claims convierte la introspección en los claims del token
+func (i *Introspection) Claims() *Claims {
+	claims := &Claims{
+		Username: i.Username,
+		SystemID: i.SystemID,
+		ClientID: i.ClientID,
+		Roles:    i.Roles,
+		Act:      i.Act,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: i.Sub,
+			Issuer:  i.Iss,
+			ID:      i.JTI,
+		},
+	}
+	if i.ClientID == "" {
+		claims.UserID, _ = strconv.ParseUint(i.Sub, 10, 64)
+	}
+	if i.Aud != "" {
+		claims.Audience = jwt.ClaimStrings{i.Aud}
+	}
+	if i.Exp != 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Unix(i.Exp, 0))
+	}
+	if i.Iat != 0 {
+		claims.IssuedAt = jwt.NewNumericDate(time.Unix(i.Iat, 0))
+	}
+	return claims
+}
+
+// cached devuelve la respuesta guardada para la petición o llama a fetch
+func (c *Client) cached(kind string, req CheckRequest, out interface{}, fetch func() error) error {
+	if c.cache == nil {
+		return fetch()
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	key := kind + ":" + string(body)
+	if c.cache.get(key, out) {
+		return nil
+	}
+	if err := fetch(); err != nil {
+		return err
+	}
+	c.cache.set(key, out, c.cfg.CacheTTL)
+	return nil
+}
+
+// call envía la petición JSON y lee el campo data de la respuesta
+func (c *Client) call(ctx context.Context, path string, payload, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	envelope := struct {
+		Data interface{} `json:"data"`
+	}{Data: out}
+	return c.do(req, &envelope)
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(APIKeyHeader, c.cfg.APIKey)
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("consulta al servicio de acceso: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Message string `json:"message"`
+			Error   string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		message := failure.Error
+		if message == "" {
+			message = failure.Message
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: message}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// cache guarda las respuestas serializadas con su vencimiento
+type cache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	sets    int
+}
+
+type cacheEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func newCache() *cache {
+	return &cache{entries: make(map[string]cacheEntry)}
+}
+
+func (c *cache) get(key string, out interface{}) bool {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		return false
+	}
+	return json.Unmarshal(entry.value, out) == nil
+}
+
+func (c *cache) set(key string, value interface{}, ttl time.Duration) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.entries[key] = cacheEntry{value: raw, expiresAt: now.Add(ttl)}
+
+	// Limpia las entradas vencidas de vez en cuando para acotar la memoria
+	c.sets++
+	if c.sets%1000 == 0 {
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+}
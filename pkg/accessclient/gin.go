@@ -0,0 +1,48 @@
+// pkg/accessclient/gin.go
+package accessclient
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ginClaimsKey = "accessClaims"
+
+// GinAuthenticate es Authenticate para Gin
+func GinAuthenticate(verifier TokenVerifier) gin.HandlerFunc {
+	return GinRequirePermission(verifier)
+}
+
+// GinRequirePermission es RequirePermission para Gin. Los claims quedan en el
+// contexto de Gin (GinClaims) y en el de la petición (ClaimsFromContext).
+func GinRequirePermission(verifier TokenVerifier, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, status, message := authorize(c.Request, verifier, permissions)
+		if claims == nil {
+			if status == http.StatusUnauthorized {
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			}
+			c.AbortWithStatusJSON(status, gin.H{
+				"error":   http.StatusText(status),
+				"message": message,
+			})
+			return
+		}
+
+		c.Set(ginClaimsKey, claims)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), claimsKey{}, claims))
+		c.Next()
+	}
+}
+
+// GinClaims devuelve los claims que dejó el middleware
+func GinClaims(c *gin.Context) (*Claims, bool) {
+	value, ok := c.Get(ginClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,81 @@
+// pkg/accessclient/middleware.go
+package accessclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type claimsKey struct{}
+
+// ClaimsFromContext devuelve los claims que dejó el middleware en la petición
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// BearerToken extrae el token de la cabecera Authorization
+func BearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// Authenticate exige un access token válido y deja sus claims en el contexto
+func Authenticate(verifier TokenVerifier) func(http.Handler) http.Handler {
+	return RequirePermission(verifier)
+}
+
+// RequirePermission exige un access token válido que otorgue todos los
+// permisos indicados. Responde 401 si el token falta o no es válido y 403 si
+// no tiene los permisos.
+func RequirePermission(verifier TokenVerifier, permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, status, message := authorize(r, verifier, permissions)
+			if claims == nil {
+				writeError(w, status, message)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})
+	}
+}
+
+// authorize valida el token de la petición y sus permisos. Si falla devuelve
+// claims nil con el estado y el mensaje de la respuesta.
+func authorize(r *http.Request, verifier TokenVerifier, permissions []string) (*Claims, int, string) {
+	claims, err := verifier.Verify(r.Context(), BearerToken(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMissingToken):
+			return nil, http.StatusUnauthorized, "Missing bearer token"
+		case errors.Is(err, ErrInvalidToken):
+			return nil, http.StatusUnauthorized, "Invalid or expired token"
+		}
+		log.Printf("No se pudo validar el token: %v", err)
+		return nil, http.StatusServiceUnavailable, "Access service unavailable"
+	}
+	if !claims.HasAllPermissions(permissions...) {
+		return nil, http.StatusForbidden, "Missing permission"
+	}
+	return claims, http.StatusOK, ""
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":   http.StatusText(status),
+		"message": message,
+	})
+}
@@ -0,0 +1,184 @@
+// pkg/accessclient/verifier.go
+package accessclient
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"accessv2/pkg/jwks"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrMissingToken = errors.New("falta el token de acceso")
+	ErrInvalidToken = errors.New("token de acceso inválido")
+)
+
+// TokenVerifier valida un access token y devuelve sus claims. Lo cumplen
+// Verifier, que valida localmente, y Client, que consulta la introspección.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*Claims, error)
+}
+
+// VerifierConfig describe qué tokens acepta el sistema
+type VerifierConfig struct {
+	BaseURL  string // URL del servicio, p. ej. https://access.example.com
+	SystemID uint64 // sistema que consume los tokens; 0 no lo verifica
+	Issuer   string // iss esperado; vacío no lo verifica
+	Audience string // aud esperado; por defecto el SystemID
+	// Secret es el secreto HS256 propio del sistema, o JWT_KEY si el
+	// servicio firma con HS256. Con Secret no se consultan las llaves públicas.
+	Secret     string
+	Leeway     time.Duration // tolerancia de reloj al validar exp y nbf
+	JWKSMaxAge time.Duration // cada cuánto se vuelven a leer las llaves; por defecto 5 minutos
+	HTTPClient *http.Client
+}
+
+// Verifier valida localmente la firma y los claims de los access tokens con
+// las llaves publicadas en /.well-known/jwks.json. No detecta revocaciones;
+// para eso se usa Client.Verify.
+type Verifier struct {
+	cfg      VerifierConfig
+	audience string
+
+	mu          sync.Mutex
+	keys        map[string]jwksKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+type jwksKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// minJWKSRefresh evita consultar las llaves en cada token con un kid
+// desconocido o mientras el servicio no responde
+const minJWKSRefresh = 30 * time.Second
+
+func NewVerifier(cfg VerifierConfig) (*Verifier, error) {
+	if cfg.Secret == "" && cfg.BaseURL == "" {
+		return nil, errors.New("se requiere BaseURL o Secret para validar los tokens")
+	}
+	if cfg.JWKSMaxAge <= 0 {
+		cfg.JWKSMaxAge = 5 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	audience := cfg.Audience
+	if audience == "" && cfg.SystemID != 0 {
+		audience = strconv.FormatUint(cfg.SystemID, 10)
+	}
+	return &Verifier{cfg: cfg, audience: audience}, nil
+}
+
+// Verify valida el token y devuelve sus claims
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	methods := []string{jwks.AlgRS256, jwks.AlgES256, jwks.AlgEdDSA}
+	if v.cfg.Secret != "" {
+		methods = []string{jwks.AlgHS256}
+	}
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(v.cfg.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if v.cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(v.cfg.Issuer))
+	}
+	if v.audience != "" {
+		options = append(options, jwt.WithAudience(v.audience))
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if v.cfg.Secret != "" {
+			return []byte(v.cfg.Secret), nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		return v.publicKey(ctx, kid, t.Method.Alg())
+	}, options...)
+	if err != nil || claims.ID == "" {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if v.cfg.SystemID != 0 && claims.SystemID != v.cfg.SystemID {
+		return nil, fmt.Errorf("%w: el token es de otro sistema", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// publicKey busca la llave del kid y vuelve a leer el JWKS si no la conoce,
+// porque el servicio rota sus llaves periódicamente
+func (v *Verifier) publicKey(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	found, ok := v.keys[kid]
+	stale := time.Since(v.fetchedAt) >= v.cfg.JWKSMaxAge
+	if (stale || !ok) && time.Since(v.attemptedAt) >= minJWKSRefresh {
+		v.attemptedAt = time.Now()
+		if err := v.fetchKeys(ctx); err != nil {
+			// Con llaves previas se sigue validando aunque el servicio no responda
+			if !ok {
+				return nil, err
+			}
+		}
+		found, ok = v.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("llave %q desconocida", kid)
+	}
+	if found.alg != alg {
+		return nil, fmt.Errorf("la llave %q es %s y el token usa %s", kid, found.alg, alg)
+	}
+	return found.key, nil
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.BaseURL+"/.well-known/jwks.json", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("consulta de las llaves públicas: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("consulta de las llaves públicas: estado %d", resp.StatusCode)
+	}
+
+	var set jwks.Set
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("llaves públicas inválidas: %w", err)
+	}
+
+	keys := make(map[string]jwksKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = jwksKey{alg: jwk.Alg, key: key}
+	}
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,199 @@
+// pkg/ldapauth/ldapauth.go
+package ldapauth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("credenciales de directorio incorrectas")
+	ErrUserNotFound       = errors.New("el usuario no existe en el directorio")
+	ErrAmbiguousUser      = errors.New("el filtro de usuarios devolvió más de una entrada")
+	ErrNotConfigured      = errors.New("el directorio LDAP no está configurado")
+)
+
+// Config describe cómo conectarse al directorio y ubicar a los usuarios
+type Config struct {
+	URL                string // ldap://host:389 o ldaps://host:636
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string // cuenta de servicio para la búsqueda; vacío usa bind anónimo
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // filtro con un %s para el usuario, p. ej. (uid=%s) o (sAMAccountName=%s)
+	UsernameAttribute  string
+	EmailAttribute     string
+	Timeout            time.Duration
+}
+
+// Entry son los datos del usuario encontrados en el directorio
+type Entry struct {
+	DN       string
+	Username string
+	Email    string
+}
+
+// Conn son las operaciones del directorio que usa el autenticador. *ldap.Conn
+// la cumple; las pruebas pueden usar un servidor en proceso o un doble.
+type Conn interface {
+	Bind(username, password string) error
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// Dialer abre una conexión al directorio
+type Dialer func(cfg Config) (Conn, error)
+
+// Authenticator valida credenciales con bind + search: ubica la entrada del
+// usuario con la cuenta de servicio y luego hace bind con su DN y contraseña
+type Authenticator struct {
+	cfg  Config
+	dial Dialer
+}
+
+// New crea el autenticador. Con dial nil se usa DialTCP.
+func New(cfg Config, dial Dialer) (*Authenticator, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, ErrNotConfigured
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(uid=%s)"
+	}
+	if !strings.Contains(cfg.UserFilter, "%s") {
+		return nil, fmt.Errorf("el filtro de usuarios %q debe contener %%s", cfg.UserFilter)
+	}
+	if cfg.UsernameAttribute == "" {
+		cfg.UsernameAttribute = "uid"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if dial == nil {
+		dial = DialTCP
+	}
+	return &Authenticator{cfg: cfg, dial: dial}, nil
+}
+
+// Authenticate devuelve la entrada del usuario si la contraseña es correcta
+func (a *Authenticator) Authenticate(username, plainPassword string) (*Entry, error) {
+	// Un bind con contraseña vacía es anónimo y el servidor lo acepta
+	if strings.TrimSpace(username) == "" || plainPassword == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := a.dial(a.cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := a.search(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, plainPassword); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("bind del usuario: %w", err)
+	}
+	return a.toEntry(entry, username), nil
+}
+
+// Lookup busca la entrada del usuario con la cuenta de servicio, sin validar
+// su contraseña. Sirve para vincular un usuario local desde la consola.
+func (a *Authenticator) Lookup(username string) (*Entry, error) {
+	if strings.TrimSpace(username) == "" {
+		return nil, ErrUserNotFound
+	}
+
+	conn, err := a.dial(a.cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := a.search(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	return a.toEntry(entry, username), nil
+}
+
+// search ubica la única entrada que cumple el filtro de usuarios
+func (a *Authenticator) search(conn Conn, username string) (*ldap.Entry, error) {
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("bind de la cuenta de servicio: %w", err)
+		}
+	}
+
+	request := ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(a.cfg.Timeout.Seconds()), false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(username)),
+		[]string{a.cfg.UsernameAttribute, a.cfg.EmailAttribute},
+		nil,
+	)
+	result, err := conn.Search(request)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("búsqueda del usuario: %w", err)
+	}
+	if result == nil || len(result.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		return nil, ErrAmbiguousUser
+	}
+	return result.Entries[0], nil
+}
+
+func (a *Authenticator) toEntry(entry *ldap.Entry, username string) *Entry {
+	found := &Entry{
+		DN:       entry.DN,
+		Username: entry.GetAttributeValue(a.cfg.UsernameAttribute),
+		Email:    entry.GetAttributeValue(a.cfg.EmailAttribute),
+	}
+	if found.Username == "" {
+		found.Username = username
+	}
+	return found
+}
+
+// DialTCP se conecta al servidor de la configuración, con StartTLS si se pide
+func DialTCP(cfg Config) (Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if u := strings.TrimPrefix(strings.TrimPrefix(cfg.URL, "ldaps://"), "ldap://"); u != "" {
+		if host, _, err := net.SplitHostPort(u); err == nil {
+			tlsConfig.ServerName = host
+		}
+	}
+
+	conn, err := ldap.DialURL(cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: cfg.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("conexión al directorio: %w", err)
+	}
+	if cfg.Timeout > 0 {
+		conn.SetTimeout(cfg.Timeout)
+	}
+
+	if cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS: %w", err)
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,87 @@
+package ldapauth_test
+
+import (
+	"accessv2/pkg/ldapauth"
+	"accessv2/pkg/ldapauth/ldaptest"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAuthenticatorAuthenticate(t *testing.T) {
+	server := ldaptest.NewServer(
+		ldaptest.Entry{DN: "cn=svc,dc=test", Password: "svc"},
+		ldaptest.Entry{DN: "uid=jdoe,ou=people,dc=test", Password: "dir-pass", Attributes: map[string][]string{"uid": {"jdoe"}, "mail": {"jdoe@example.com"}}},
+		ldaptest.Entry{DN: "uid=twin,ou=people,dc=test", Password: "dir-pass", Attributes: map[string][]string{"uid": {"twin"}}},
+		ldaptest.Entry{DN: "uid=twin,ou=staff,dc=test", Password: "dir-pass", Attributes: map[string][]string{"uid": {"twin"}}},
+		ldaptest.Entry{DN: "uid=ajeno,dc=other", Password: "dir-pass", Attributes: map[string][]string{"uid": {"ajeno"}}},
+	)
+	defer server.Close()
+
+	authenticator, err := ldapauth.New(ldapauth.Config{
+		URL:          server.URL,
+		BindDN:       "cn=svc,dc=test",
+		BindPassword: "svc",
+		BaseDN:       "dc=test",
+		Timeout:      time.Second,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		username  string
+		password  string
+		wantEntry ldapauth.Entry
+		wantErr   error
+	}{
+		{
+			name:      "credenciales correctas",
+			username:  "jdoe",
+			password:  "dir-pass",
+			wantEntry: ldapauth.Entry{DN: "uid=jdoe,ou=people,dc=test", Username: "jdoe", Email: "jdoe@example.com"},
+		},
+		{name: "contraseña incorrecta", username: "jdoe", password: "otra", wantErr: ldapauth.ErrInvalidCredentials},
+		{name: "contraseña vacía", username: "jdoe", wantErr: ldapauth.ErrInvalidCredentials},
+		{name: "usuario inexistente", username: "nadie", password: "dir-pass", wantErr: ldapauth.ErrUserNotFound},
+		{name: "usuario fuera de la base", username: "ajeno", password: "dir-pass", wantErr: ldapauth.ErrUserNotFound},
+		{name: "filtro ambiguo", username: "twin", password: "dir-pass", wantErr: ldapauth.ErrAmbiguousUser},
+		{name: "filtro escapado", username: "jdoe)(uid=*", password: "dir-pass", wantErr: ldapauth.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authenticator.Authenticate(tt.username, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, se esperaba %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && *got != tt.wantEntry {
+				t.Fatalf("entrada = %+v, se esperaba %+v", *got, tt.wantEntry)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorServiceAccount(t *testing.T) {
+	server := ldaptest.NewServer(
+		ldaptest.Entry{DN: "uid=jdoe,dc=test", Password: "dir-pass", Attributes: map[string][]string{"uid": {"jdoe"}}},
+	)
+	defer server.Close()
+
+	authenticator, err := ldapauth.New(ldapauth.Config{URL: server.URL, BindDN: "cn=svc,dc=test", BindPassword: "mala", BaseDN: "dc=test"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Un error de la cuenta de servicio no se confunde con credenciales del usuario
+	if _, err := authenticator.Authenticate("jdoe", "dir-pass"); err == nil || errors.Is(err, ldapauth.ErrInvalidCredentials) {
+		t.Fatalf("err = %v, se esperaba un error de la cuenta de servicio", err)
+	}
+
+	anonymous, err := ldapauth.New(ldapauth.Config{URL: server.URL, BaseDN: "dc=test"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous.Authenticate("jdoe", "dir-pass"); err != nil {
+		t.Fatalf("búsqueda con bind anónimo: %v", err)
+	}
+}
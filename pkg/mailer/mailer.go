@@ -0,0 +1,76 @@
+// pkg/mailer/mailer.go
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"time"
+)
+
+// Drivers de envío soportados
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+)
+
+var ErrUnsupportedDriver = errors.New("driver de correo no soportado")
+
+// Message es un correo de texto plano
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer envía correos; permite cambiar SMTP por un buzón local en desarrollo
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Config agrupa la configuración de los drivers
+type Config struct {
+	Driver       string // smtp o file
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	OutboxDir    string // directorio donde el driver file deja los correos
+}
+
+// New construye el Mailer del driver configurado
+func New(cfg Config) (Mailer, error) {
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("remitente inválido %q: %w", cfg.From, err)
+	}
+
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	case DriverFile:
+		return NewFileMailer(cfg.From, cfg.OutboxDir)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedDriver, cfg.Driver)
+}
+
+// build arma el mensaje en formato RFC 5322
+func build(from string, msg Message, now time.Time) ([]byte, error) {
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("destinatario inválido %q: %w", msg.To, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,50 @@
+// pkg/mailer/smtp.go
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPMailer envía los correos a un servidor SMTP. Si se configura usuario
+// se autentica con PLAIN, que net/smtp solo permite sobre TLS o localhost.
+type SMTPMailer struct {
+	from     string
+	addr     string
+	host     string
+	username string
+	password string
+}
+
+func NewSMTPMailer(cfg Config) *SMTPMailer {
+	return &SMTPMailer{
+		from:     cfg.From,
+		addr:     cfg.SMTPHost + ":" + strconv.Itoa(cfg.SMTPPort),
+		host:     cfg.SMTPHost,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+	}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	data, err := build(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	from, _ := mail.ParseAddress(m.from)
+	to, _ := mail.ParseAddress(msg.To)
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	if err := smtp.SendMail(m.addr, auth, from.Address, []string{to.Address}, data); err != nil {
+		return fmt.Errorf("error al enviar el correo por SMTP: %w", err)
+	}
+	return nil
+}
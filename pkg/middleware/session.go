@@ -2,6 +2,8 @@
 package middleware
 
 import (
+	"time"
+
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
@@ -10,7 +12,11 @@ import (
 type SessionData struct {
 	IsAuthenticated bool
 	Username        string
-	UserID          int
+	UserID          int // ID del administrador (tabla admins) que inició sesión
+	// Suplantación iniciada desde esta sesión que sigue vigente; se muestra en la barra
+	ImpersonationID   int
+	ImpersonatedUser  string
+	ImpersonatedUntil time.Time
 	// ... otros campos
 }
 
@@ -25,6 +31,12 @@ func SessionMiddleware() gin.HandlerFunc {
 			UserID:          getIntFromSession(session, "UserID"),
 		}
 
+		if until := time.Unix(getInt64FromSession(session, "ImpersonatedUntil"), 0); time.Now().Before(until) {
+			data.ImpersonationID = getIntFromSession(session, "ImpersonationID")
+			data.ImpersonatedUser = getStringFromSession(session, "ImpersonatedUser")
+			data.ImpersonatedUntil = until
+		}
+
 		// Guardamos el struct en el contexto de Gin
 		c.Set("sessionData", data)
 
@@ -51,3 +63,12 @@ func getIntFromSession(session sessions.Session, key string) int {
 	}
 	return 0 // Valor por defecto
 }
+
+func getInt64FromSession(session sessions.Session, key string) int64 {
+	if val := session.Get(key); val != nil {
+		if num, ok := val.(int64); ok {
+			return num
+		}
+	}
+	return 0 // Valor por defecto
+}
@@ -0,0 +1,194 @@
+// pkg/password/hasher.go
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var ErrUnsupportedAlgorithm = errors.New("algoritmo de hash no soportado")
+
+// Config define el algoritmo y el costo usados para generar nuevos hashes.
+type Config struct {
+	Algorithm     string
+	BcryptCost    int
+	Argon2Time    uint32
+	Argon2Memory  uint32 // en KiB
+	Argon2Threads uint8
+	Argon2KeyLen  uint32
+	Argon2SaltLen uint32
+}
+
+// DefaultConfig devuelve una configuración segura por defecto (bcrypt, costo 12).
+func DefaultConfig() Config {
+	return Config{
+		Algorithm:     AlgorithmBcrypt,
+		BcryptCost:    12,
+		Argon2Time:    3,
+		Argon2Memory:  64 * 1024,
+		Argon2Threads: 2,
+		Argon2KeyLen:  32,
+		Argon2SaltLen: 16,
+	}
+}
+
+// Hasher genera y verifica hashes de contraseñas.
+type Hasher struct {
+	cfg Config
+}
+
+// NewHasher crea un Hasher validando la configuración recibida.
+func NewHasher(cfg Config) (*Hasher, error) {
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("costo de bcrypt inválido: %d", cfg.BcryptCost)
+		}
+	case AlgorithmArgon2id:
+		if cfg.Argon2Time == 0 || cfg.Argon2Memory == 0 || cfg.Argon2Threads == 0 {
+			return nil, errors.New("parámetros de argon2id inválidos")
+		}
+		if cfg.Argon2KeyLen == 0 {
+			cfg.Argon2KeyLen = 32
+		}
+		if cfg.Argon2SaltLen == 0 {
+			cfg.Argon2SaltLen = 16
+		}
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return &Hasher{cfg: cfg}, nil
+}
+
+// Hash genera el hash de una contraseña en texto plano con el algoritmo configurado.
+func (h *Hasher) Hash(plain string) (string, error) {
+	if h.cfg.Algorithm == AlgorithmArgon2id {
+		return h.hashArgon2id(plain)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(plain), h.cfg.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify compara una contraseña contra el valor almacenado. Además de indicar si
+// coincide, informa si el valor almacenado debe regenerarse: filas antiguas en
+// texto plano, otro algoritmo o un costo menor al configurado.
+func (h *Hasher) Verify(stored, plain string) (match bool, needsRehash bool, err error) {
+	switch {
+	case isBcrypt(stored):
+		if err := bcrypt.CompareHashAndPassword([]byte(stored), []byte(plain)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, false, nil
+			}
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost([]byte(stored))
+		if err != nil {
+			return true, true, nil
+		}
+		return true, h.cfg.Algorithm != AlgorithmBcrypt || cost < h.cfg.BcryptCost, nil
+	case isArgon2id(stored):
+		params, salt, key, err := decodeArgon2id(stored)
+		if err != nil {
+			return false, false, err
+		}
+		other := argon2.IDKey([]byte(plain), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, false, nil
+		}
+		weak := params.time < h.cfg.Argon2Time ||
+			params.memory < h.cfg.Argon2Memory ||
+			params.threads < h.cfg.Argon2Threads
+		return true, h.cfg.Algorithm != AlgorithmArgon2id || weak, nil
+	default:
+		// Contraseña heredada guardada en texto plano
+		if stored == "" {
+			return false, false, nil
+		}
+		match := subtle.ConstantTimeCompare([]byte(stored), []byte(plain)) == 1
+		return match, match, nil
+	}
+}
+
+// IsHashed indica si el valor ya corresponde a un hash soportado.
+func IsHashed(stored string) bool {
+	return isBcrypt(stored) || isArgon2id(stored)
+}
+
+func isBcrypt(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") ||
+		strings.HasPrefix(stored, "$2b$") ||
+		strings.HasPrefix(stored, "$2y$")
+}
+
+func isArgon2id(stored string) bool {
+	return strings.HasPrefix(stored, "$argon2id$")
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// hashArgon2id genera un hash con el formato estándar PHC:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func (h *Hasher) hashArgon2id(plain string) (string, error) {
+	salt := make([]byte, h.cfg.Argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(plain), salt, h.cfg.Argon2Time, h.cfg.Argon2Memory, h.cfg.Argon2Threads, h.cfg.Argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.cfg.Argon2Memory,
+		h.cfg.Argon2Time,
+		h.cfg.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func decodeArgon2id(stored string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errors.New("hash argon2id con formato inválido")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, errors.New("versión de argon2 incompatible")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	return params, salt, key, nil
+}
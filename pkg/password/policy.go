@@ -0,0 +1,130 @@
+// pkg/password/policy.go
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+// PolicyConfig define las reglas que deben cumplir las contraseñas nuevas
+type PolicyConfig struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// BlocklistFile agrega contraseñas prohibidas, una por línea, a la lista
+	// de contraseñas comunes incluida
+	BlocklistFile string
+}
+
+// PolicyError enumera las reglas que la contraseña no cumple
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "La contraseña no cumple la política: " + strings.Join(e.Violations, "; ")
+}
+
+// Policy valida contraseñas nuevas contra la configuración y la lista de
+// contraseñas comunes
+type Policy struct {
+	cfg       PolicyConfig
+	blocklist map[string]struct{}
+}
+
+// NewPolicy crea la política cargando la lista incluida y, si se indica, la
+// lista adicional de BlocklistFile
+func NewPolicy(cfg PolicyConfig) (*Policy, error) {
+	p := &Policy{cfg: cfg, blocklist: make(map[string]struct{})}
+
+	if err := p.loadBlocklist(strings.NewReader(commonPasswords)); err != nil {
+		return nil, err
+	}
+	if cfg.BlocklistFile != "" {
+		f, err := os.Open(cfg.BlocklistFile)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudo abrir la lista de contraseñas prohibidas: %w", err)
+		}
+		defer f.Close()
+		if err := p.loadBlocklist(f); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Validate revisa la contraseña; username (opcional) no puede formar parte de ella
+func (p *Policy) Validate(plain, username string) error {
+	var violations []string
+
+	if strings.TrimSpace(plain) == "" {
+		return &PolicyError{Violations: []string{"es requerida"}}
+	}
+
+	if utf8.RuneCountInString(plain) < p.cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("debe tener al menos %d caracteres", p.cfg.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range plain {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.cfg.RequireUpper && !hasUpper {
+		violations = append(violations, "debe incluir una mayúscula")
+	}
+	if p.cfg.RequireLower && !hasLower {
+		violations = append(violations, "debe incluir una minúscula")
+	}
+	if p.cfg.RequireDigit && !hasDigit {
+		violations = append(violations, "debe incluir un número")
+	}
+	if p.cfg.RequireSymbol && !hasSymbol {
+		violations = append(violations, "debe incluir un símbolo")
+	}
+
+	lower := strings.ToLower(plain)
+	if _, common := p.blocklist[lower]; common {
+		violations = append(violations, "es una contraseña común o filtrada")
+	}
+	if username = strings.ToLower(strings.TrimSpace(username)); len(username) >= 3 && strings.Contains(lower, username) {
+		violations = append(violations, "no debe contener el nombre de usuario")
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func (p *Policy) loadBlocklist(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
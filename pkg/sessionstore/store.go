@@ -0,0 +1,325 @@
+// Package sessionstore implementa un almacén de sesiones para
+// gin-contrib/sessions que guarda los datos en una tabla de la base de datos
+// mediante GORM. La cookie solo lleva un identificador aleatorio firmado; en la
+// tabla se guarda su hash, de modo que una sesión se puede listar y terminar
+// desde el servidor. Las sesiones sin dueño (visitantes que aún no inician
+// sesión, que solo llevan el token CSRF o algún mensaje) no se guardan en la
+// tabla: sus datos viajan firmados en la propia cookie.
+package sessionstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+	"gorm.io/gorm"
+)
+
+// Config agrupa la tabla y los tiempos de vida de las sesiones
+type Config struct {
+	Table         string        // tabla con las columnas de Record
+	IdleTimeout   time.Duration // sin actividad durante este tiempo la sesión vence; 0 lo desactiva
+	MaxAge        time.Duration // vida máxima de la sesión desde que se creó
+	TouchInterval time.Duration // frecuencia mínima con la que se registra la actividad
+	OwnerKey      string        // valor de la sesión que identifica al dueño (p. ej. "UserID")
+}
+
+// Record es una fila de la tabla de sesiones
+type Record struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	TokenHash  string    `gorm:"size:64;unique;not null"`
+	AdminID    *uint     `gorm:"column:admin_id"`
+	Data       string    `gorm:"not null"`
+	Created    time.Time `gorm:"not null"`
+	LastSeenAt time.Time `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"not null"`
+}
+
+// cookieValue es el contenido firmado de la cookie: el identificador de una
+// sesión guardada en la tabla o, si la sesión no tiene dueño, sus datos
+type cookieValue struct {
+	Token string
+	Data  string
+}
+
+// Store guarda las sesiones en la base de datos
+type Store struct {
+	db      *gorm.DB
+	cfg     Config
+	Codecs  []securecookie.Codec
+	options *gsessions.Options
+}
+
+// New crea el almacén. keyPairs firma la cookie con el identificador, igual
+// que en el almacén de cookies.
+func New(db *gorm.DB, cfg Config, keyPairs ...[]byte) *Store {
+	s := &Store{
+		db:      db,
+		cfg:     cfg,
+		Codecs:  securecookie.CodecsFromPairs(keyPairs...),
+		options: &gsessions.Options{Path: "/", MaxAge: int(cfg.MaxAge.Seconds())},
+	}
+
+	for _, codec := range s.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(int(cfg.MaxAge.Seconds()))
+		}
+	}
+	return s
+}
+
+// Options fija la configuración de la cookie
+func (s *Store) Options(options sessions.Options) {
+	s.options = options.ToGorillaOptions()
+}
+
+// Get devuelve la sesión registrándola en la petición
+func (s *Store) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+// New carga la sesión de la cookie o crea una vacía si no existe o ya venció
+func (s *Store) New(r *http.Request, name string) (*gsessions.Session, error) {
+	session := gsessions.NewSession(s, name)
+	opts := *s.options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, errCookie := r.Cookie(name)
+	if errCookie != nil {
+		return session, nil
+	}
+
+	var value cookieValue
+	if err := securecookie.DecodeMulti(name, c.Value, &value, s.Codecs...); err != nil {
+		return session, err
+	}
+
+	if value.Token == "" {
+		if err := decodeValues(value.Data, &session.Values); err != nil {
+			return session, err
+		}
+		session.IsNew = false
+		return session, nil
+	}
+
+	token := value.Token
+	record, err := s.load(token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return session, nil
+		}
+		return session, err
+	}
+
+	if err := decodeValues(record.Data, &session.Values); err != nil {
+		return session, err
+	}
+	session.ID = token
+	session.IsNew = false
+	return session, nil
+}
+
+// Save guarda la sesión y envía la cookie. Con Options.MaxAge <= 0 la sesión
+// se elimina. Una sesión sin dueño se guarda solo en la cookie; la fila se crea
+// cuando la sesión pasa a tener dueño, con un identificador nuevo para evitar
+// la fijación de sesión.
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *gsessions.Session) error {
+	if session.Options.MaxAge <= 0 {
+		if err := s.deleteRecord(session); err != nil {
+			return err
+		}
+		http.SetCookie(w, gsessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	data, err := encodeValues(session.Values)
+	if err != nil {
+		return err
+	}
+	owner := s.owner(session.Values)
+	now := time.Now()
+
+	if owner == nil {
+		if err := s.deleteRecord(session); err != nil {
+			return err
+		}
+		return s.setCookie(w, session, cookieValue{Data: data})
+	}
+
+	var record Record
+	found := false
+	if session.ID != "" {
+		err := s.db.Table(s.cfg.Table).Where("token_hash = ?", HashToken(session.ID)).First(&record).Error
+		switch {
+		case err == nil:
+			found = true
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+	}
+
+	if found && !sameOwner(record.AdminID, owner) {
+		if err := s.db.Table(s.cfg.Table).Delete(&Record{}, record.ID).Error; err != nil {
+			return err
+		}
+		found = false
+	}
+
+	if found {
+		err = s.db.Table(s.cfg.Table).Where("id = ?", record.ID).Updates(map[string]interface{}{
+			"data":         data,
+			"last_seen_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+	} else {
+		session.ID = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(securecookie.GenerateRandomKey(32))
+		record = Record{
+			TokenHash:  HashToken(session.ID),
+			AdminID:    owner,
+			Data:       data,
+			Created:    now,
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(s.cfg.MaxAge),
+		}
+		if err := s.db.Table(s.cfg.Table).Create(&record).Error; err != nil {
+			return err
+		}
+	}
+
+	return s.setCookie(w, session, cookieValue{Token: session.ID})
+}
+
+// deleteRecord borra la fila de la sesión, si tenía una
+func (s *Store) deleteRecord(session *gsessions.Session) error {
+	if session.ID == "" {
+		return nil
+	}
+	if err := s.db.Table(s.cfg.Table).Where("token_hash = ?", HashToken(session.ID)).Delete(&Record{}).Error; err != nil {
+		return err
+	}
+	session.ID = ""
+	return nil
+}
+
+func (s *Store) setCookie(w http.ResponseWriter, session *gsessions.Session, value cookieValue) error {
+	encoded, err := securecookie.EncodeMulti(session.Name(), value, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// DeleteExpired borra las sesiones vencidas por inactividad o por edad
+func (s *Store) DeleteExpired() (int64, error) {
+	now := time.Now()
+	query := s.db.Table(s.cfg.Table).Where("expires_at <= ?", now)
+	if s.cfg.IdleTimeout > 0 {
+		query = query.Or("last_seen_at <= ?", now.Add(-s.cfg.IdleTimeout))
+	}
+	result := query.Delete(&Record{})
+	return result.RowsAffected, result.Error
+}
+
+// StartCleanup borra periódicamente las sesiones vencidas
+func (s *Store) StartCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := s.DeleteExpired(); err != nil {
+				log.Printf("Error al borrar las sesiones vencidas: %v", err)
+			}
+		}
+	}()
+}
+
+// HashToken devuelve el hash con el que se guarda el identificador de la sesión
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// load busca la sesión vigente y registra la actividad. Una sesión vencida se
+// borra y se trata como inexistente.
+func (s *Store) load(token string) (*Record, error) {
+	var record Record
+	if err := s.db.Table(s.cfg.Table).Where("token_hash = ?", HashToken(token)).First(&record).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	idle := s.cfg.IdleTimeout > 0 && now.Sub(record.LastSeenAt) >= s.cfg.IdleTimeout
+	if idle || !now.Before(record.ExpiresAt) {
+		if err := s.db.Table(s.cfg.Table).Delete(&Record{}, record.ID).Error; err != nil {
+			return nil, err
+		}
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	if now.Sub(record.LastSeenAt) >= s.cfg.TouchInterval {
+		if err := s.db.Table(s.cfg.Table).Where("id = ?", record.ID).Update("last_seen_at", now).Error; err != nil {
+			log.Printf("No se pudo registrar la actividad de la sesión %d: %v", record.ID, err)
+		}
+	}
+	return &record, nil
+}
+
+// owner devuelve el dueño de la sesión según Config.OwnerKey
+func (s *Store) owner(values map[interface{}]interface{}) *uint {
+	if s.cfg.OwnerKey == "" {
+		return nil
+	}
+	var id uint
+	switch v := values[s.cfg.OwnerKey].(type) {
+	case int:
+		id = uint(v)
+	case int64:
+		id = uint(v)
+	case uint:
+		id = v
+	}
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+func sameOwner(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func encodeValues(values map[interface{}]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decodeValues(data string, values *map[interface{}]interface{}) error {
+	if data == "" {
+		return nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(bytes.NewReader(raw)).Decode(values)
+}
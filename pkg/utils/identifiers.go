@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeUsername deja el nombre de usuario en la forma con la que se
+// compara: sin espacios alrededor, en NFKC y en minúsculas. Así "JPerez",
+// " jperez " y la variante con caracteres de ancho completo coinciden.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(norm.NFKC.String(strings.TrimSpace(username)))
+}
+
+// NormalizeEmail compara correos sin distinguir mayúsculas
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,36 @@
+// pkg/utils/tokens.go
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// SecureToken genera un token aleatorio criptográficamente seguro de n bytes,
+// codificado en base64 apto para URLs
+func SecureToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken devuelve el SHA-256 en hexadecimal de un token de alta entropía
+// para guardarlo en la base de datos sin exponer su valor original
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// BearerToken extrae el token de una cabecera "Authorization: Bearer <token>"
+func BearerToken(header string) string {
+	const prefix = "Bearer "
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return strings.TrimSpace(header[len(prefix):])
+	}
+	return ""
+}